@@ -0,0 +1,657 @@
+// color_schemes.go - Named terminal color schemes and their importers
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2/theme"
+	"gopkg.in/yaml.v3"
+)
+
+// A color scheme sets the 16 ANSI colors plus the default foreground and
+// background, cursor and selection. Built-in schemes can be replaced or
+// extended by name from color_schemes.yaml in the app home directory, in
+// the same format; imported schemes are added there. No scheme ("") keeps
+// the colors of the light or dark theme.
+
+// ansiColorNames are the gopyte names of the 16 ANSI colors, in index order
+var ansiColorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright_black", "bright_red", "bright_green", "bright_yellow",
+	"bright_blue", "bright_magenta", "bright_cyan", "bright_white",
+}
+
+// builtinColorSchemes are the schemes shipped with the app
+const builtinColorSchemes = `
+schemes:
+  - name: Solarized Dark
+    colors: [073642, dc322f, 859900, b58900, 268bd2, d33682, 2aa198, eee8d5,
+             002b36, cb4b16, 586e75, 657b83, 839496, 6c71c4, 93a1a1, fdf6e3]
+    foreground: 839496
+    background: 002b36
+    cursor: 93a1a1
+    selection: 073642
+  - name: Solarized Light
+    colors: [073642, dc322f, 859900, b58900, 268bd2, d33682, 2aa198, eee8d5,
+             002b36, cb4b16, 586e75, 657b83, 839496, 6c71c4, 93a1a1, fdf6e3]
+    foreground: 657b83
+    background: fdf6e3
+    cursor: 586e75
+    selection: eee8d5
+  - name: Production Red
+    colors: [1a0505, ff5555, 5fd75f, ffd75f, 5f87ff, ff5fd7, 5fd7d7, e8d0d0,
+             7a4a4a, ff8787, 87ff87, ffff87, 87afff, ff87ff, 87ffff, ffffff]
+    foreground: e8d0d0
+    background: 2a0b0b
+    cursor: ff6060
+    selection: 6a1f1f
+`
+
+// ColorScheme is a named terminal palette, colors as hex
+type ColorScheme struct {
+	Name       string   `yaml:"name"`
+	Colors     []string `yaml:"colors,flow"` // ANSI 0-15
+	Foreground string   `yaml:"foreground,omitempty"`
+	Background string   `yaml:"background,omitempty"`
+	Cursor     string   `yaml:"cursor,omitempty"`
+	Selection  string   `yaml:"selection,omitempty"`
+}
+
+// colorSchemesFile is the layout of color_schemes.yaml
+type colorSchemesFile struct {
+	Schemes []ColorScheme `yaml:"schemes"`
+}
+
+// validate checks a scheme has a name and readable colors
+func (s ColorScheme) validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("color scheme has no name")
+	}
+	if len(s.Colors) != len(ansiColorNames) {
+		return fmt.Errorf("color scheme %q: %d colors, want %d", s.Name, len(s.Colors), len(ansiColorNames))
+	}
+	for i, hex := range s.Colors {
+		if ParseHexColor(hex) == nil {
+			return fmt.Errorf("color scheme %q: %s is not a color: %q", s.Name, ansiColorNames[i], hex)
+		}
+	}
+	// The rest are optional and follow the theme when empty
+	for _, hex := range []string{s.Foreground, s.Background, s.Cursor, s.Selection} {
+		if hex != "" && ParseHexColor(hex) == nil {
+			return fmt.Errorf("color scheme %q: %q is not a color", s.Name, hex)
+		}
+	}
+	return nil
+}
+
+// terminalPalette is a scheme resolved to colors. Nil fields follow the
+// app theme, so the default palette looks as it did before schemes.
+type terminalPalette struct {
+	colors     map[string]color.Color // ANSI colors by gopyte name
+	foreground color.Color
+	background color.Color
+	cursor     color.Color
+	selection  color.Color
+}
+
+// defaultSelectionColor highlights selected text without a scheme
+var defaultSelectionColor = color.RGBA{0x0C, 0x7A, 0xCC, 0xFF}
+
+// palette resolves the scheme's colors
+func (s ColorScheme) palette() *terminalPalette {
+	p := &terminalPalette{colors: make(map[string]color.Color, len(ansiColorNames)+1)}
+	for i, name := range ansiColorNames {
+		if i < len(s.Colors) {
+			p.colors[name] = ParseHexColor(s.Colors[i])
+		}
+	}
+	p.foreground = ParseHexColor(s.Foreground)
+	p.background = ParseHexColor(s.Background)
+	p.cursor = ParseHexColor(s.Cursor)
+	p.selection = ParseHexColor(s.Selection)
+	if p.foreground != nil {
+		p.colors["default"] = p.foreground
+	}
+	return p
+}
+
+// themePalette is the palette without a scheme: the light or dark mappings
+func themePalette() *terminalPalette {
+	return &terminalPalette{colors: GetTerminalColorMappings()}
+}
+
+// ansi maps a gopyte color name, nil for the default color. 256-color
+// names ("color123") use the ANSI colors below 16 and xterm's above;
+// direct colors ("#rrggbb") are used as they are.
+func (p *terminalPalette) ansi(name string) color.Color {
+	switch {
+	case name == "" || name == "default":
+		return nil
+	case name == "brown":
+		name = "yellow"
+	case strings.HasPrefix(name, "#"):
+		if c, ok := gopyte.ParseColorSpec(name); ok {
+			return c
+		}
+	}
+	if c := p.colors[name]; c != nil {
+		return c
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "color")); err == nil && n >= 0 && n < 256 {
+		if n < len(ansiColorNames) {
+			return p.ansi(ansiColorNames[n])
+		}
+		return gopyte.DefaultPaletteColor(n)
+	}
+	return p.colors["white"]
+}
+
+// withOverrides layers the colors a host set with OSC 4/10/11/12 over the
+// palette
+func (p *terminalPalette) withOverrides(overrides map[int]color.RGBA) *terminalPalette {
+	if len(overrides) == 0 {
+		return p
+	}
+	layered := *p
+	layered.colors = make(map[string]color.Color, len(p.colors)+len(overrides))
+	for name, c := range p.colors {
+		layered.colors[name] = c
+	}
+	for slot, c := range overrides {
+		switch {
+		case slot < len(ansiColorNames):
+			layered.colors[ansiColorNames[slot]] = c
+		case slot < gopyte.ColorForeground:
+			layered.colors[fmt.Sprintf("color%d", slot)] = c
+		case slot == gopyte.ColorForeground:
+			layered.foreground = c
+			layered.colors["default"] = c
+		case slot == gopyte.ColorBackground:
+			layered.background = c
+		case slot == gopyte.ColorCursor:
+			layered.cursor = c
+		}
+	}
+	return &layered
+}
+
+// slotColor returns a palette slot's color for OSC queries
+func (p *terminalPalette) slotColor(slot int) (color.RGBA, bool) {
+	var c color.Color
+	switch {
+	case slot < len(ansiColorNames):
+		c = p.ansi(ansiColorNames[slot])
+	case slot == gopyte.ColorForeground:
+		c = p.Foreground()
+	case slot == gopyte.ColorBackground:
+		c = p.Background()
+	case slot == gopyte.ColorCursor:
+		c = p.Cursor()
+	}
+	if c == nil {
+		return color.RGBA{}, false
+	}
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}, true
+}
+
+// Foreground returns the default text color
+func (p *terminalPalette) Foreground() color.Color {
+	if p.foreground != nil {
+		return p.foreground
+	}
+	return theme.Color(theme.ColorNameForeground)
+}
+
+// Background returns the terminal background
+func (p *terminalPalette) Background() color.Color {
+	if p.background != nil {
+		return p.background
+	}
+	return theme.Color(theme.ColorNameBackground)
+}
+
+// Cursor returns the cursor color
+func (p *terminalPalette) Cursor() color.Color {
+	if p.cursor != nil {
+		return p.cursor
+	}
+	return p.Foreground()
+}
+
+// Selection returns the background of selected text
+func (p *terminalPalette) Selection() color.Color {
+	if p.selection != nil {
+		return p.selection
+	}
+	return defaultSelectionColor
+}
+
+var (
+	colorSchemesMutex  sync.Mutex
+	colorSchemesByName map[string]ColorScheme
+	colorSchemeOrder   []string
+	settingsPalette    *terminalPalette // Palette of the settings scheme, built on first use
+)
+
+// loadColorSchemesLocked reads the built-in schemes and the user's file
+func loadColorSchemesLocked() {
+	colorSchemesByName = make(map[string]ColorScheme)
+	colorSchemeOrder = nil
+	add := func(schemes []ColorScheme, source string) {
+		for _, scheme := range schemes {
+			if err := scheme.validate(); err != nil {
+				log.Printf("Color schemes: %s: %v", source, err)
+				continue
+			}
+			if _, ok := colorSchemesByName[scheme.Name]; !ok {
+				colorSchemeOrder = append(colorSchemeOrder, scheme.Name)
+			}
+			colorSchemesByName[scheme.Name] = scheme
+		}
+	}
+
+	var builtin colorSchemesFile
+	if err := yaml.Unmarshal([]byte(builtinColorSchemes), &builtin); err != nil {
+		log.Printf("Color schemes: built-in schemes: %v", err)
+	}
+	add(builtin.Schemes, "built-in")
+
+	user, err := readColorSchemesFile()
+	if err != nil {
+		log.Printf("Color schemes: %v", err)
+	}
+	add(user.Schemes, GetColorSchemesPath())
+}
+
+// readColorSchemesFile reads the user's schemes; a missing file is empty
+func readColorSchemesFile() (colorSchemesFile, error) {
+	var file colorSchemesFile
+	data, err := os.ReadFile(GetColorSchemesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+		return file, fmt.Errorf("failed to read color schemes: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("failed to parse color schemes: %w", err)
+	}
+	return file, nil
+}
+
+// ReloadColorSchemes drops the loaded schemes so the next use reads them
+// again, picking up the theme and settings scheme too
+func ReloadColorSchemes() {
+	colorSchemesMutex.Lock()
+	colorSchemesByName = nil
+	settingsPalette = nil
+	colorSchemesMutex.Unlock()
+}
+
+// colorSchemeNames lists the schemes, built-in first
+func colorSchemeNames() []string {
+	colorSchemesMutex.Lock()
+	defer colorSchemesMutex.Unlock()
+	if colorSchemesByName == nil {
+		loadColorSchemesLocked()
+	}
+	return append([]string(nil), colorSchemeOrder...)
+}
+
+// colorSchemeByName returns a scheme by name
+func colorSchemeByName(name string) (ColorScheme, bool) {
+	colorSchemesMutex.Lock()
+	defer colorSchemesMutex.Unlock()
+	if colorSchemesByName == nil {
+		loadColorSchemesLocked()
+	}
+	scheme, ok := colorSchemesByName[name]
+	return scheme, ok
+}
+
+// colorSchemePalette resolves a scheme name to a palette. "" uses the
+// scheme from settings; an unknown name falls back to the theme colors.
+func colorSchemePalette(name string) *terminalPalette {
+	if name == "" {
+		if settings := GetSettings(); settings != nil {
+			name = settings.Get().ColorScheme
+		}
+	}
+	if name == "" {
+		return themePalette()
+	}
+	scheme, ok := colorSchemeByName(name)
+	if !ok {
+		log.Printf("Color schemes: no scheme named %q; using the theme colors", name)
+		return themePalette()
+	}
+	return scheme.palette()
+}
+
+// currentPalette returns the palette of the settings scheme, for views
+// that are not tied to a session
+func currentPalette() *terminalPalette {
+	colorSchemesMutex.Lock()
+	p := settingsPalette
+	colorSchemesMutex.Unlock()
+	if p != nil {
+		return p
+	}
+	p = colorSchemePalette("")
+	colorSchemesMutex.Lock()
+	settingsPalette = p
+	colorSchemesMutex.Unlock()
+	return p
+}
+
+// saveColorSchemes adds schemes to the user's file, replacing any of the
+// same name
+func saveColorSchemes(schemes []ColorScheme) error {
+	file, err := readColorSchemesFile()
+	if err != nil {
+		return err
+	}
+	for _, scheme := range schemes {
+		replaced := false
+		for i := range file.Schemes {
+			if file.Schemes[i].Name == scheme.Name {
+				file.Schemes[i] = scheme
+				replaced = true
+			}
+		}
+		if !replaced {
+			file.Schemes = append(file.Schemes, scheme)
+		}
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal color schemes: %w", err)
+	}
+	if err := os.WriteFile(GetColorSchemesPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write color schemes: %w", err)
+	}
+	ReloadColorSchemes()
+	return nil
+}
+
+// importColorSchemes reads the schemes in an iTerm2 .itermcolors file, a
+// Windows Terminal JSON file or a base16 YAML file
+func importColorSchemes(name string, r io.Reader) ([]ColorScheme, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+
+	var schemes []ColorScheme
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".itermcolors":
+		var scheme ColorScheme
+		scheme, err = parseITermColors(data, base)
+		schemes = []ColorScheme{scheme}
+	case ".json":
+		schemes, err = parseWindowsTerminalSchemes(data)
+	case ".yaml", ".yml":
+		var scheme ColorScheme
+		scheme, err = parseBase16Scheme(data, base)
+		schemes = []ColorScheme{scheme}
+	default:
+		return nil, fmt.Errorf("%s: expected .itermcolors, .json or .yaml", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(schemes) == 0 {
+		return nil, fmt.Errorf("%s: no color schemes found", name)
+	}
+	for _, scheme := range schemes {
+		if err := scheme.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return schemes, nil
+}
+
+// parseITermColors reads an .itermcolors plist: a dict of "Ansi N Color",
+// "Foreground Color" and so on, each a dict of 0-1 color components
+func parseITermColors(data []byte, name string) (ColorScheme, error) {
+	// Components alternate <key> and a value element (<real>, <string>)
+	type plistEntry struct {
+		Nodes []struct {
+			XMLName xml.Name
+			Text    string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	var plist struct {
+		Dict struct {
+			Keys   []string     `xml:"key"`
+			Colors []plistEntry `xml:"dict"`
+		} `xml:"dict"`
+	}
+	if err := xml.Unmarshal(data, &plist); err != nil {
+		return ColorScheme{}, fmt.Errorf("not an iTerm2 color file: %w", err)
+	}
+	if len(plist.Dict.Keys) != len(plist.Dict.Colors) {
+		return ColorScheme{}, fmt.Errorf("not an iTerm2 color file")
+	}
+
+	scheme := ColorScheme{Name: name, Colors: make([]string, len(ansiColorNames))}
+	for i, key := range plist.Dict.Keys {
+		entry := plist.Dict.Colors[i]
+		var rgb [3]float64
+		for j := 0; j+1 < len(entry.Nodes); j += 2 {
+			if entry.Nodes[j].XMLName.Local != "key" || entry.Nodes[j+1].XMLName.Local != "real" {
+				continue
+			}
+			component := entry.Nodes[j].Text
+			value, err := strconv.ParseFloat(strings.TrimSpace(entry.Nodes[j+1].Text), 64)
+			if err != nil {
+				return ColorScheme{}, fmt.Errorf("%s: %s: %w", key, component, err)
+			}
+			switch component {
+			case "Red Component":
+				rgb[0] = value
+			case "Green Component":
+				rgb[1] = value
+			case "Blue Component":
+				rgb[2] = value
+			}
+		}
+		hex := fmt.Sprintf("%02x%02x%02x", unitToByte(rgb[0]), unitToByte(rgb[1]), unitToByte(rgb[2]))
+
+		var index int
+		switch {
+		case key == "Foreground Color":
+			scheme.Foreground = hex
+		case key == "Background Color":
+			scheme.Background = hex
+		case key == "Cursor Color":
+			scheme.Cursor = hex
+		case key == "Selection Color":
+			scheme.Selection = hex
+		default:
+			if _, err := fmt.Sscanf(key, "Ansi %d Color", &index); err == nil && index >= 0 && index < len(ansiColorNames) {
+				scheme.Colors[index] = hex
+			}
+		}
+	}
+	return scheme, nil
+}
+
+// unitToByte scales a 0-1 color component to 0-255
+func unitToByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// windowsTerminalScheme is a scheme in Windows Terminal's settings.json
+type windowsTerminalScheme struct {
+	Name                string `json:"name"`
+	Foreground          string `json:"foreground"`
+	Background          string `json:"background"`
+	CursorColor         string `json:"cursorColor"`
+	SelectionBackground string `json:"selectionBackground"`
+	Black               string `json:"black"`
+	Red                 string `json:"red"`
+	Green               string `json:"green"`
+	Yellow              string `json:"yellow"`
+	Blue                string `json:"blue"`
+	Purple              string `json:"purple"`
+	Cyan                string `json:"cyan"`
+	White               string `json:"white"`
+	BrightBlack         string `json:"brightBlack"`
+	BrightRed           string `json:"brightRed"`
+	BrightGreen         string `json:"brightGreen"`
+	BrightYellow        string `json:"brightYellow"`
+	BrightBlue          string `json:"brightBlue"`
+	BrightPurple        string `json:"brightPurple"`
+	BrightCyan          string `json:"brightCyan"`
+	BrightWhite         string `json:"brightWhite"`
+}
+
+// parseWindowsTerminalSchemes reads one scheme object, a list of them, or a
+// whole settings.json with a "schemes" list. Comments and trailing commas,
+// which Windows Terminal allows in settings.json, are dropped first.
+func parseWindowsTerminalSchemes(data []byte) ([]ColorScheme, error) {
+	data = stripJSONComments(data)
+
+	var list []windowsTerminalScheme
+	var settings struct {
+		Schemes []windowsTerminalScheme `json:"schemes"`
+	}
+	var single windowsTerminalScheme
+	switch {
+	case json.Unmarshal(data, &list) == nil:
+	case json.Unmarshal(data, &settings) == nil && len(settings.Schemes) > 0:
+		list = settings.Schemes
+	case json.Unmarshal(data, &single) == nil && single.Name != "":
+		list = []windowsTerminalScheme{single}
+	default:
+		return nil, fmt.Errorf("not a Windows Terminal color scheme")
+	}
+
+	var schemes []ColorScheme
+	for _, s := range list {
+		schemes = append(schemes, ColorScheme{
+			Name: s.Name,
+			Colors: []string{s.Black, s.Red, s.Green, s.Yellow, s.Blue, s.Purple, s.Cyan, s.White,
+				s.BrightBlack, s.BrightRed, s.BrightGreen, s.BrightYellow,
+				s.BrightBlue, s.BrightPurple, s.BrightCyan, s.BrightWhite},
+			Foreground: s.Foreground,
+			Background: s.Background,
+			Cursor:     s.CursorColor,
+			Selection:  s.SelectionBackground,
+		})
+	}
+	return schemes, nil
+}
+
+// stripJSONComments blanks out // and /* */ comments outside strings and
+// drops commas before a closing bracket, turning JSONC into JSON
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+			out = append(out, ' ')
+		case c == '}' || c == ']':
+			// Drop a trailing comma, keeping the whitespace after it
+			j := len(out) - 1
+			for j >= 0 && strings.IndexByte(" \t\r\n", out[j]) >= 0 {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// parseBase16Scheme reads a base16 scheme (base00-base0F) and maps it the
+// way base16-shell does. Classic files have the colors at the top level
+// next to "scheme"; tinted-theming files nest them under "palette" next to
+// "name".
+func parseBase16Scheme(data []byte, name string) (ColorScheme, error) {
+	// Strings, so unquoted colors such as 282828 keep their digits
+	var file struct {
+		Scheme  string            `yaml:"scheme"`
+		Name    string            `yaml:"name"`
+		Palette map[string]string `yaml:"palette"`
+		Colors  map[string]string `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return ColorScheme{}, fmt.Errorf("not a base16 scheme: %w", err)
+	}
+	colors := file.Colors
+	if file.Palette != nil {
+		colors = file.Palette
+	}
+	base := func(n int) string {
+		// Keys are base00-base0F; some files use lower case hex
+		if v, ok := colors[fmt.Sprintf("base%02X", n)]; ok {
+			return v
+		}
+		return colors[fmt.Sprintf("base%02x", n)]
+	}
+	for n := 0; n < 16; n++ {
+		if base(n) == "" {
+			return ColorScheme{}, fmt.Errorf("not a base16 scheme: base%02X missing", n)
+		}
+	}
+	if file.Scheme != "" {
+		name = file.Scheme
+	} else if file.Name != "" {
+		name = file.Name
+	}
+
+	return ColorScheme{
+		Name: name,
+		Colors: []string{base(0x0), base(0x8), base(0xB), base(0xA), base(0xD), base(0xE), base(0xC), base(0x5),
+			base(0x3), base(0x8), base(0xB), base(0xA), base(0xD), base(0xE), base(0xC), base(0x7)},
+		Foreground: base(0x5),
+		Background: base(0x0),
+		Cursor:     base(0x5),
+		Selection:  base(0x2),
+	}, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportColorSchemes(t *testing.T) {
+	tomorrowNight := func(hash string) ColorScheme {
+		c := func(hex string) string { return hash + hex }
+		return ColorScheme{
+			Name: "Tomorrow Night",
+			Colors: []string{c("1d1f21"), c("cc6666"), c("b5bd68"), c("f0c674"), c("81a2be"), c("b294bb"), c("8abeb7"), c("c5c8c6"),
+				c("969896"), c("cc6666"), c("b5bd68"), c("f0c674"), c("81a2be"), c("b294bb"), c("8abeb7"), c("ffffff")},
+			Foreground: c("c5c8c6"),
+			Background: c("1d1f21"),
+			Cursor:     c("c5c8c6"),
+			Selection:  c("373b41"),
+		}
+	}
+
+	tests := []struct {
+		file string
+		want []ColorScheme
+	}{
+		{
+			"Builtin Dark.itermcolors",
+			[]ColorScheme{{
+				Name: "Builtin Dark",
+				Colors: []string{"000000", "c91b00", "00c200", "c7c400", "0225c7", "ca30c7", "00c5c7", "c7c7c7",
+					"686868", "ff6e67", "5ffa68", "fffc67", "6871ff", "ff77ff", "60fdff", "ffffff"},
+				Foreground: "c7c7c7",
+				Background: "000000",
+				Cursor:     "c7c7c7",
+				Selection:  "c6dcfc",
+			}},
+		},
+		{
+			"windows_terminal_settings.json",
+			[]ColorScheme{
+				{
+					Name: "Campbell",
+					Colors: []string{"#0C0C0C", "#C50F1F", "#13A10E", "#C19C00", "#0037DA", "#881798", "#3A96DD", "#CCCCCC",
+						"#767676", "#E74856", "#16C60C", "#F9F1A5", "#3B78FF", "#B4009E", "#61D6D6", "#F2F2F2"},
+					Foreground: "#CCCCCC",
+					Background: "#0C0C0C",
+					Cursor:     "#FFFFFF",
+					Selection:  "#FFFFFF",
+				},
+				{
+					Name: "One Half Dark // not a comment",
+					Colors: []string{"#282C34", "#E06C75", "#98C379", "#E5C07B", "#61AFEF", "#C678DD", "#56B6C2", "#DCDFE4",
+						"#5A6374", "#E06C75", "#98C379", "#E5C07B", "#61AFEF", "#C678DD", "#56B6C2", "#DCDFE4"},
+					Foreground: "#DCDFE4",
+					Background: "#282C34",
+					Cursor:     "#FFFFFF",
+					Selection:  "#FFFFFF",
+				},
+			},
+		},
+		{"base16-tomorrow-night.yaml", []ColorScheme{tomorrowNight("")}},
+		{"tinted-tomorrow-night.yaml", []ColorScheme{tomorrowNight("#")}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := importColorSchemes(tc.file, f)
+			if err != nil {
+				t.Fatalf("importColorSchemes: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("importColorSchemes =\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImportColorSchemeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want string
+	}{
+		{"Unknown extension", "scheme.txt", "", "expected .itermcolors, .json or .yaml"},
+		{"Not a plist", "x.itermcolors", "<plist", "not an iTerm2 color file"},
+		{"Not JSON", "x.json", "{\"schemes\": [", "not a Windows Terminal color scheme"},
+		{"Unterminated comment", "x.json", "[{\"name\": \"x\"} /* oops", "not a Windows Terminal color scheme"},
+		{"Missing color", "x.json", "[{\"name\": \"x\", \"black\": \"#000000\"}]", "is not a color"},
+		{"Missing base", "x.yaml", "scheme: x\nbase00: \"000000\"\n", "base01 missing"},
+		{"Palette missing base", "x.yaml", "name: x\npalette:\n  base00: \"#000000\"\n", "base01 missing"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := importColorSchemes(tc.file, strings.NewReader(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
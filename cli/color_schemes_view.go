@@ -0,0 +1,145 @@
+// color_schemes_view.go - Color scheme picker, preview and import
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+// Labels for no scheme: the theme colors in settings, the settings scheme
+// in a session
+const (
+	colorSchemeThemeLabel    = "Theme colors"
+	colorSchemeSettingsLabel = "Default (settings)"
+)
+
+// colorSchemeOptions lists the schemes with the label for "" first
+func colorSchemeOptions(session bool) []string {
+	if session {
+		return append([]string{colorSchemeSettingsLabel}, colorSchemeNames()...)
+	}
+	return append([]string{colorSchemeThemeLabel}, colorSchemeNames()...)
+}
+
+// colorSchemeToLabel returns the option shown for a scheme name
+func colorSchemeToLabel(name string, session bool) string {
+	switch {
+	case name != "":
+		return name
+	case session:
+		return colorSchemeSettingsLabel
+	default:
+		return colorSchemeThemeLabel
+	}
+}
+
+// colorSchemeLabelToName returns the scheme name of an option
+func colorSchemeLabelToName(label string) string {
+	if label == colorSchemeThemeLabel || label == colorSchemeSettingsLabel {
+		return ""
+	}
+	return label
+}
+
+// previewPalette resolves a scheme for the preview; "" is the theme colors
+func previewPalette(name string) *terminalPalette {
+	if scheme, ok := colorSchemeByName(name); ok {
+		return scheme.palette()
+	}
+	return themePalette()
+}
+
+// createColorSchemePreview draws a prompt, a selection, the cursor and the
+// 16 colors on the scheme background
+func createColorSchemePreview(name string) *fyne.Container {
+	preview := container.NewStack()
+	refreshColorSchemePreview(preview, name)
+	return preview
+}
+
+// refreshColorSchemePreview redraws the preview for another scheme
+func refreshColorSchemePreview(preview *fyne.Container, name string) {
+	p := previewPalette(name)
+
+	bg := canvas.NewRectangle(p.Background())
+	bg.SetMinSize(fyne.NewSize(320, 120))
+
+	text := func(s string, c color.Color) *canvas.Text {
+		t := canvas.NewText(s, c)
+		t.TextStyle = fyne.TextStyle{Monospace: true}
+		return t
+	}
+	selected := container.NewStack(canvas.NewRectangle(p.Selection()), text("selected", color.White))
+	cursor := canvas.NewRectangle(withAlpha(p.Cursor(), 0x99))
+	cursor.SetMinSize(fyne.NewSize(8, 16))
+	prompt := container.NewHBox(
+		text("router#", p.ansi("bright_green")),
+		text("show ip int", p.Foreground()),
+		cursor,
+	)
+	status := container.NewHBox(
+		text("up", p.ansi("green")),
+		text("down", p.ansi("red")),
+		text("admin", p.ansi("yellow")),
+		selected,
+	)
+
+	swatchRow := func(from int) *fyne.Container {
+		row := container.NewHBox()
+		for _, c := range p.schemeSwatches()[from : from+8] {
+			swatch := canvas.NewRectangle(c)
+			swatch.SetMinSize(fyne.NewSize(24, 12))
+			row.Add(swatch)
+		}
+		return row
+	}
+
+	preview.Objects = []fyne.CanvasObject{
+		bg,
+		container.NewPadded(container.NewVBox(prompt, status, swatchRow(0), swatchRow(8))),
+	}
+	preview.Refresh()
+}
+
+// showColorSchemeImport asks for an .itermcolors, Windows Terminal JSON or
+// base16 YAML file and adds its schemes to color_schemes.yaml
+func showColorSchemeImport(window fyne.Window, onImported func(names []string)) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		schemes, err := importColorSchemes(reader.URI().Name(), reader)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if err := saveColorSchemes(schemes); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		var names []string
+		for _, scheme := range schemes {
+			names = append(names, scheme.Name)
+		}
+		dialog.ShowInformation("Color Schemes",
+			fmt.Sprintf("Imported %d scheme(s) into %s", len(schemes), GetColorSchemesPath()), window)
+		if onImported != nil {
+			onImported(names)
+		}
+	}, window)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".itermcolors", ".json", ".yaml", ".yml"}))
+	d.Show()
+}
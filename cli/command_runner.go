@@ -0,0 +1,353 @@
+// command_runner.go - Running a command list on many sessions at once
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command run modes
+const (
+	CommandRunExec  = "exec"  // One exec channel per command
+	CommandRunShell = "shell" // One shell, commands ended by the prompt
+)
+
+// Command runner limits
+const (
+	defaultRunConcurrency = 5
+	maxRunConcurrency     = 50
+	commandRunTimeout     = 60 * time.Second // Per command in shell mode
+	maxCommandOutput      = 16 << 20         // Per command in shell mode
+)
+
+// fallbackPromptPattern ends commands on devices without a prompt pattern
+const fallbackPromptPattern = `^[\w.@:~/\-]*(?:\[[^\]\n]*\])?[>#$%]`
+
+// CommandOutput is the output of one command on one device
+type CommandOutput struct {
+	Command string
+	Output  string
+}
+
+// DeviceRunResult is everything one device returned
+type DeviceRunResult struct {
+	Session SessionInfo
+	Outputs []CommandOutput
+	Err     error
+	Elapsed time.Duration
+}
+
+// Text renders the result with a header line per command
+func (r *DeviceRunResult) Text() string {
+	var b strings.Builder
+	for _, o := range r.Outputs {
+		fmt.Fprintf(&b, "### %s\n%s", o.Command, o.Output)
+		if o.Output != "" && !strings.HasSuffix(o.Output, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	if r.Err != nil {
+		fmt.Fprintf(&b, "### error: %v\n", r.Err)
+	}
+	return b.String()
+}
+
+// CommandRunner runs a command list on sessions over headless connections
+type CommandRunner struct {
+	Commands    []string
+	Mode        string // CommandRunExec or CommandRunShell
+	Concurrency int
+	Password    string // For sessions with no profile or stored password
+
+	// DeviceCommands, when set, picks each session's commands instead
+	DeviceCommands func(session SessionInfo) []string
+	// AfterDevice, when set, is called with each successful result before
+	// it is reported and may set its error
+	AfterDevice func(result *DeviceRunResult)
+}
+
+// Run runs the commands on every session, at most Concurrency at a time,
+// calling started and finished from the worker goroutines. Cancelling ctx
+// closes the connections.
+func (r *CommandRunner) Run(ctx context.Context, sessions []SessionInfo, started, finished func(index int, result *DeviceRunResult)) []*DeviceRunResult {
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultRunConcurrency
+	}
+	results := make([]*DeviceRunResult, len(sessions))
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, session := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				results[i] = &DeviceRunResult{Session: session, Err: ctx.Err()}
+				finished(i, results[i])
+				return
+			}
+
+			started(i, &DeviceRunResult{Session: session})
+			results[i] = r.runDevice(ctx, session)
+			finished(i, results[i])
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runDevice connects to one session and runs the commands
+func (r *CommandRunner) runDevice(ctx context.Context, session SessionInfo) *DeviceRunResult {
+	start := time.Now()
+	result := &DeviceRunResult{Session: session}
+
+	username, password := r.deviceCredentials(session)
+	config := sessionSSHConfig(session, username, password)
+	config.NoShell = r.Mode == CommandRunExec
+	config.KeepAliveInterval = 0
+
+	backend := NewSSHBackend(config)
+	if err := backend.Connect(); err != nil {
+		result.Err = err
+		result.Elapsed = time.Since(start)
+		return result
+	}
+	defer backend.Close()
+
+	// Closing the connection ends a run in progress
+	stop := context.AfterFunc(ctx, func() { backend.Close() })
+	defer stop()
+
+	commands := r.Commands
+	if r.DeviceCommands != nil {
+		commands = r.DeviceCommands(session)
+	}
+	if r.Mode == CommandRunExec {
+		result.Err = r.runExec(ctx, backend, commands, result)
+	} else {
+		result.Err = r.runShell(backend, session, password, commands, result)
+	}
+	if ctx.Err() != nil {
+		result.Err = ctx.Err()
+	}
+	if result.Err == nil && r.AfterDevice != nil {
+		r.AfterDevice(result)
+	}
+	result.Elapsed = time.Since(start)
+	log.Printf("Run on devices: %s finished in %s (error: %v)", session.Name, result.Elapsed.Round(time.Millisecond), result.Err)
+	return result
+}
+
+// deviceCredentials returns the username and password to log in to a
+// session with. Its credential profile's win, as in loginSecrets; the
+// password then falls back to the session's stored one, then the runner's.
+func (r *CommandRunner) deviceCredentials(session SessionInfo) (username, password string) {
+	username, password = session.Username, session.Password
+	if profile, ok := loadCredentialProfile(session.CredsID); ok {
+		if profile.Username != "" {
+			username = profile.Username
+		}
+		if profile.Password != "" {
+			password = profile.Password
+		}
+	}
+	if password == "" {
+		password = r.Password
+	}
+	return username, password
+}
+
+// runExec runs each command on its own exec channel
+func (r *CommandRunner) runExec(ctx context.Context, backend *SSHBackend, commands []string, result *DeviceRunResult) error {
+	for _, command := range commands {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		output, err := backend.Exec(command)
+		result.Outputs = append(result.Outputs, CommandOutput{Command: command, Output: normalizeCommandOutput(string(output))})
+		if err != nil {
+			// A non-zero exit still returns output worth keeping
+			var exitErr interface{ ExitStatus() int }
+			if errors.As(err, &exitErr) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runShell types the commands into a shell, running the session's login
+// script first to disable paging, and ends each command at the prompt
+func (r *CommandRunner) runShell(backend *SSHBackend, session SessionInfo, password string, commands []string, result *DeviceRunResult) error {
+	buf := newExpectBuffer(maxCommandOutput)
+	defer buf.stop()
+	go func() {
+		data := make([]byte, 32*1024)
+		for {
+			n, err := backend.Read(data)
+			if n > 0 {
+				buf.feed(data[:n])
+			}
+			if err != nil {
+				buf.stop()
+				return
+			}
+		}
+	}()
+
+	prompt, err := commandPromptPattern(session.DeviceType)
+	if err != nil {
+		return err
+	}
+
+	if script := loginScriptFor(session); script != nil {
+		if step, err := script.run(buf, writeBackend(backend), loginSecrets(session, password), func(string) {}); err != nil {
+			return fmt.Errorf("login script %s step %d: %w", script.Name, step+1, err)
+		}
+	} else if _, _, err := buf.expect(prompt, commandRunTimeout); err != nil {
+		return fmt.Errorf("waiting for prompt: %w", err)
+	}
+
+	for _, command := range commands {
+		buf.discard()
+		if _, err := backend.Write([]byte(command + "\r")); err != nil {
+			return err
+		}
+		output, _, err := buf.expect(prompt, commandRunTimeout)
+		if err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+		result.Outputs = append(result.Outputs, CommandOutput{Command: command, Output: shellCommandOutput(output)})
+	}
+	return nil
+}
+
+// writeBackend adapts a backend to the login script writer
+func writeBackend(backend *SSHBackend) func([]byte) error {
+	return func(data []byte) error {
+		_, err := backend.Write(data)
+		return err
+	}
+}
+
+// commandPromptPattern matches the prompt at the end of output, using the
+// prompt pattern for the device type
+func commandPromptPattern(deviceType string) (*regexp.Regexp, error) {
+	pattern := fallbackPromptPattern
+	if settings := GetSettings(); settings != nil {
+		if p := settings.Get().PromptPatterns[strings.ToLower(deviceType)]; p != "" {
+			pattern = p
+		}
+	}
+	re, err := regexp.Compile(`(?m)(?:` + pattern + `)\s*\z`)
+	if err != nil {
+		return nil, fmt.Errorf("prompt pattern for %q: %w", deviceType, err)
+	}
+	return re, nil
+}
+
+// shellCommandOutput drops the echoed command line from shell output
+func shellCommandOutput(output string) string {
+	output = normalizeCommandOutput(output)
+	if _, rest, ok := strings.Cut(output, "\n"); ok {
+		return rest
+	}
+	return ""
+}
+
+// normalizeCommandOutput turns CRLF line endings into LF
+func normalizeCommandOutput(output string) string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	return strings.ReplaceAll(output, "\r", "")
+}
+
+// Diff line prefixes
+const (
+	diffSame    = "  "
+	diffRemoved = "- "
+	diffAdded   = "+ "
+)
+
+// maxDiffCells caps the work of a diff; larger outputs are compared line
+// by line instead
+const maxDiffCells = 4_000_000
+
+// diffLines compares two texts line by line, returning every line prefixed
+// with diffSame, diffRemoved (only in a) or diffAdded (only in b)
+func diffLines(a, b string) []string {
+	left := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	right := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	if len(left)*len(right) > maxDiffCells {
+		return naiveDiffLines(left, right)
+	}
+
+	// lcs[i][j] is the longest common subsequence of left[i:] and right[j:]
+	lcs := make([][]int32, len(left)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		switch {
+		case left[i] == right[j]:
+			lines = append(lines, diffSame+left[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffRemoved+left[i])
+			i++
+		default:
+			lines = append(lines, diffAdded+right[j])
+			j++
+		}
+	}
+	for ; i < len(left); i++ {
+		lines = append(lines, diffRemoved+left[i])
+	}
+	for ; j < len(right); j++ {
+		lines = append(lines, diffAdded+right[j])
+	}
+	return lines
+}
+
+// naiveDiffLines compares lines at the same position
+func naiveDiffLines(left, right []string) []string {
+	var lines []string
+	for i := 0; i < len(left) || i < len(right); i++ {
+		switch {
+		case i >= len(left):
+			lines = append(lines, diffAdded+right[i])
+		case i >= len(right):
+			lines = append(lines, diffRemoved+left[i])
+		case left[i] == right[i]:
+			lines = append(lines, diffSame+left[i])
+		default:
+			lines = append(lines, diffRemoved+left[i], diffAdded+right[i])
+		}
+	}
+	return lines
+}
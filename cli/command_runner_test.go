@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceCredentials(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	credentials := "credentials:\n" +
+		"  - id: lab\n    username: netops\n    password: profile-secret\n" +
+		"  - id: nopass\n    username: admin\n" +
+		"  - id: nouser\n    password: profile-secret\n"
+	if err := os.WriteFile(filepath.Join(GetAppHome(), "credentials.yaml"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		session      SessionInfo
+		typed        string
+		wantUsername string
+		wantPassword string
+	}{
+		{"Profile", SessionInfo{Username: "root", CredsID: "lab", Password: "stored"}, "typed", "netops", "profile-secret"},
+		{"Profile without a password", SessionInfo{Username: "root", CredsID: "nopass", Password: "stored"}, "", "admin", "stored"},
+		{"Profile without a username", SessionInfo{Username: "root", CredsID: "nouser"}, "", "root", "profile-secret"},
+		{"Missing profile", SessionInfo{Username: "root", CredsID: "gone", Password: "stored"}, "", "root", "stored"},
+		{"Stored", SessionInfo{Username: "root", Password: "stored"}, "typed", "root", "stored"},
+		{"Stored for scheduled backups", SessionInfo{Username: "root", Password: "stored"}, "", "root", "stored"},
+		{"Typed", SessionInfo{Username: "root"}, "typed", "root", "typed"},
+		{"None", SessionInfo{Username: "root"}, "", "root", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := backupRunner(tc.typed)
+			username, password := r.deviceCredentials(tc.session)
+			if username != tc.wantUsername || password != tc.wantPassword {
+				t.Errorf("deviceCredentials = %q, %q; want %q, %q", username, password, tc.wantUsername, tc.wantPassword)
+			}
+			if config := sessionSSHConfig(tc.session, username, password); config.Username != tc.wantUsername {
+				t.Errorf("SSH username = %q, want %q", config.Username, tc.wantUsername)
+			}
+		})
+	}
+}
@@ -0,0 +1,503 @@
+// command_runner_view.go - "Run on devices" dialog and results tab
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Run mode labels in the dialog
+const (
+	runModeShellLabel = "Interactive shell (prompt detection)"
+	runModeExecLabel  = "Exec channel (one per command)"
+)
+
+// Device states shown in the results list
+const (
+	deviceQueued = iota
+	deviceRunning
+	deviceDone
+	deviceFailed
+)
+
+// CommandRunView shows per-device results as a run progresses
+type CommandRunView struct {
+	sessions []SessionInfo
+	results  []*DeviceRunResult
+	states   []int
+	mutex    sync.Mutex
+	cancel   context.CancelFunc
+	window   fyne.Window
+
+	list       *widget.List
+	output     *widget.TextGrid
+	statusText *widget.Label
+	stopButton *widget.Button
+	buttons    *fyne.Container
+	content    fyne.CanvasObject
+	selected   int
+	finished   int
+}
+
+// NewCommandRunView starts a run and returns its view
+func NewCommandRunView(runner *CommandRunner, sessions []SessionInfo, window fyne.Window) *CommandRunView {
+	ctx, cancel := context.WithCancel(context.Background())
+	v := &CommandRunView{
+		sessions: sessions,
+		results:  make([]*DeviceRunResult, len(sessions)),
+		states:   make([]int, len(sessions)),
+		cancel:   cancel,
+		window:   window,
+		selected: -1,
+	}
+
+	v.list = widget.NewList(
+		func() int { return len(v.sessions) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(widget.NewIcon(theme.MoreHorizontalIcon()), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			icon, label := v.deviceStatus(id)
+			row.Objects[0].(*widget.Icon).SetResource(icon)
+			row.Objects[1].(*widget.Label).SetText(label)
+		})
+	v.list.OnSelected = func(id widget.ListItemID) {
+		v.selected = id
+		v.showOutput()
+	}
+
+	v.output = widget.NewTextGrid()
+	v.output.ShowLineNumbers = false
+	v.output.ShowWhitespace = false
+
+	v.statusText = widget.NewLabel("")
+	v.stopButton = widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), v.Stop)
+	diffButton := widget.NewButtonWithIcon("Diff", theme.ViewRefreshIcon(), v.showDiffDialog)
+	saveButton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), v.showSaveDialog)
+
+	v.buttons = container.NewHBox(diffButton, saveButton, v.stopButton)
+	toolbar := container.NewBorder(nil, nil, nil, v.buttons, v.statusText)
+	split := container.NewHSplit(v.list, container.NewScroll(v.output))
+	split.SetOffset(0.25)
+	v.content = container.NewBorder(toolbar, nil, nil, nil, split)
+	v.updateStatus()
+
+	go func() {
+		runner.Run(ctx, sessions,
+			func(i int, _ *DeviceRunResult) { v.setState(i, deviceRunning, nil) },
+			func(i int, result *DeviceRunResult) {
+				state := deviceDone
+				if result.Err != nil {
+					state = deviceFailed
+				}
+				v.setState(i, state, result)
+			})
+		fyne.Do(func() { v.stopButton.Disable() })
+	}()
+	return v
+}
+
+// Content returns the tab content
+func (v *CommandRunView) Content() fyne.CanvasObject {
+	return v.content
+}
+
+// AddButton adds a toolbar button before the standard ones
+func (v *CommandRunView) AddButton(button *widget.Button) {
+	v.buttons.Objects = append([]fyne.CanvasObject{button}, v.buttons.Objects...)
+	v.buttons.Refresh()
+}
+
+// SelectedSession returns the session selected in the list
+func (v *CommandRunView) SelectedSession() (SessionInfo, bool) {
+	if v.selected < 0 {
+		return SessionInfo{}, false
+	}
+	return v.sessions[v.selected], true
+}
+
+// Stop cancels the run; devices not finished report the cancellation
+func (v *CommandRunView) Stop() {
+	v.cancel()
+}
+
+// setState records a device's progress from a worker goroutine
+func (v *CommandRunView) setState(i, state int, result *DeviceRunResult) {
+	v.mutex.Lock()
+	v.states[i] = state
+	if result != nil {
+		v.results[i] = result
+		v.finished++
+	}
+	v.mutex.Unlock()
+
+	fyne.Do(func() {
+		v.list.RefreshItem(i)
+		v.updateStatus()
+		if i == v.selected {
+			v.showOutput()
+		}
+	})
+}
+
+// deviceStatus returns the icon and label of a device in the list
+func (v *CommandRunView) deviceStatus(i int) (fyne.Resource, string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	name := v.sessions[i].Name
+	switch v.states[i] {
+	case deviceRunning:
+		return theme.MediaPlayIcon(), name + " (running)"
+	case deviceDone:
+		return theme.ConfirmIcon(), fmt.Sprintf("%s (%s)", name, v.results[i].Elapsed.Round(100*time.Millisecond))
+	case deviceFailed:
+		return theme.ErrorIcon(), name + " (failed)"
+	}
+	return theme.MoreHorizontalIcon(), name
+}
+
+// updateStatus shows how many devices have finished
+func (v *CommandRunView) updateStatus() {
+	v.mutex.Lock()
+	failed := 0
+	for _, state := range v.states {
+		if state == deviceFailed {
+			failed++
+		}
+	}
+	text := fmt.Sprintf("%d of %d devices finished, %d failed", v.finished, len(v.sessions), failed)
+	v.mutex.Unlock()
+	v.statusText.SetText(text)
+}
+
+// showOutput shows the selected device's output
+func (v *CommandRunView) showOutput() {
+	if v.selected < 0 {
+		return
+	}
+	v.mutex.Lock()
+	result := v.results[v.selected]
+	v.mutex.Unlock()
+
+	if result == nil {
+		v.output.SetText("Waiting for results...")
+		return
+	}
+	v.output.SetText(result.Text())
+}
+
+// finishedResults returns the results so far, in session order
+func (v *CommandRunView) finishedResults() []*DeviceRunResult {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	var results []*DeviceRunResult
+	for _, r := range v.results {
+		if r != nil {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// showDiffDialog compares the output of two devices
+func (v *CommandRunView) showDiffDialog() {
+	results := v.finishedResults()
+	if len(results) < 2 {
+		dialog.ShowInformation("Diff", "At least two devices must have finished.", v.window)
+		return
+	}
+
+	byName := make(map[string]*DeviceRunResult)
+	var names []string
+	for _, r := range results {
+		name := r.Session.Name
+		for n := 2; byName[name] != nil; n++ {
+			name = fmt.Sprintf("%s (%d)", r.Session.Name, n)
+		}
+		byName[name] = r
+		names = append(names, name)
+	}
+
+	grid := widget.NewTextGrid()
+	grid.ShowLineNumbers = false
+	grid.ShowWhitespace = false
+	left := widget.NewSelect(names, nil)
+	right := widget.NewSelect(names, nil)
+	update := func(string) {
+		a, b := byName[left.Selected], byName[right.Selected]
+		if a == nil || b == nil {
+			return
+		}
+		renderDiff(grid, diffLines(a.Text(), b.Text()))
+	}
+	left.OnChanged = update
+	right.OnChanged = update
+	left.SetSelected(names[0])
+	right.SetSelected(names[1])
+
+	pickers := container.NewGridWithColumns(2, left, right)
+	d := dialog.NewCustom("Diff Between Devices", "Close",
+		container.NewBorder(pickers, nil, nil, nil, container.NewScroll(grid)), v.window)
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
+
+// renderDiff shows diff lines with removed lines red and added lines green
+func renderDiff(grid *widget.TextGrid, lines []string) {
+	removed := &widget.CustomTextGridStyle{FGColor: mapTerminalColor("red")}
+	added := &widget.CustomTextGridStyle{FGColor: mapTerminalColor("green")}
+
+	rows := make([]widget.TextGridRow, len(lines))
+	for y, line := range lines {
+		var style widget.TextGridStyle
+		switch {
+		case strings.HasPrefix(line, diffRemoved):
+			style = removed
+		case strings.HasPrefix(line, diffAdded):
+			style = added
+		}
+		var cells []widget.TextGridCell
+		for _, r := range line {
+			cells = append(cells, widget.TextGridCell{Rune: r, Style: style})
+		}
+		rows[y] = widget.TextGridRow{Cells: cells}
+	}
+	grid.Rows = rows
+	grid.Refresh()
+}
+
+// showSaveDialog saves one text file per finished device into a directory
+func (v *CommandRunView) showSaveDialog() {
+	results := v.finishedResults()
+	if len(results) == 0 {
+		dialog.ShowInformation("Save", "No device has finished yet.", v.window)
+		return
+	}
+
+	dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, v.window)
+			return
+		}
+		if dir == nil {
+			return
+		}
+
+		used := make(map[string]bool)
+		for _, r := range results {
+			base := unsafeFileChars.ReplaceAllString(r.Session.Name, "_")
+			name := base + ".txt"
+			for n := 2; used[name]; n++ {
+				name = fmt.Sprintf("%s_%d.txt", base, n)
+			}
+			used[name] = true
+
+			if err := saveRunResult(dir, name, r); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to save %s: %w", name, err), v.window)
+				return
+			}
+		}
+		log.Printf("Run on devices: saved %d results to %s", len(results), dir.Path())
+		dialog.ShowInformation("Save", fmt.Sprintf("Saved %d files to %s", len(results), dir.Path()), v.window)
+	}, v.window)
+}
+
+// saveRunResult writes one device's output into a directory
+func saveRunResult(dir fyne.ListableURI, name string, result *DeviceRunResult) error {
+	uri, err := storage.Child(dir, name)
+	if err != nil {
+		return err
+	}
+	w, err := storage.Writer(uri)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(result.Text())); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// sessionPicker is a checklist of saved sessions with a folder shortcut,
+// starting with the sidebar selection checked
+type sessionPicker struct {
+	content fyne.CanvasObject
+	checks  *widget.CheckGroup
+	labels  []string
+	byLabel map[string]SessionInfo
+}
+
+// newSessionPicker builds a picker over the saved sessions
+func (sm *SessionManager) newSessionPicker() *sessionPicker {
+	p := &sessionPicker{byLabel: make(map[string]SessionInfo)}
+
+	// One option per session, "Folder / Name"
+	byFolder := make(map[string][]string)
+	var folders []string
+	for _, s := range sm.savedSessions {
+		label := fmt.Sprintf("%s / %s", s.Group, s.Name)
+		if _, ok := p.byLabel[label]; ok {
+			label = fmt.Sprintf("%s / %s (%s)", s.Group, s.Name, s.Host)
+		}
+		p.byLabel[label] = s
+		p.labels = append(p.labels, label)
+		if _, ok := byFolder[s.Group]; !ok {
+			folders = append(folders, s.Group)
+		}
+		byFolder[s.Group] = append(byFolder[s.Group], label)
+	}
+	sort.Strings(p.labels)
+	sort.Strings(folders)
+
+	p.checks = widget.NewCheckGroup(p.labels, nil)
+	switch {
+	case sm.selectedSession != nil:
+		for label, s := range p.byLabel {
+			if s.ID == sm.selectedSession.ID {
+				p.checks.SetSelected([]string{label})
+			}
+		}
+	case strings.HasPrefix(sm.selectedNodeID, "folder:"):
+		p.checks.SetSelected(byFolder[strings.TrimPrefix(sm.selectedNodeID, "folder:")])
+	}
+
+	folderSelect := widget.NewSelect(folders, nil)
+	folderSelect.PlaceHolder = "Add a folder..."
+	folderSelect.OnChanged = func(folder string) {
+		if folder == "" {
+			return
+		}
+		selected := p.checks.Selected
+		for _, label := range byFolder[folder] {
+			if !slices.Contains(selected, label) {
+				selected = append(selected, label)
+			}
+		}
+		p.checks.SetSelected(selected)
+		folderSelect.ClearSelected()
+	}
+	clearButton := widget.NewButton("Clear", func() { p.checks.SetSelected(nil) })
+
+	scroll := container.NewVScroll(p.checks)
+	scroll.SetMinSize(fyne.NewSize(0, 200))
+	p.content = container.NewBorder(nil,
+		container.NewBorder(nil, nil, nil, clearButton, folderSelect), nil, nil, scroll)
+	return p
+}
+
+// Selected returns the checked sessions in list order
+func (p *sessionPicker) Selected() []SessionInfo {
+	var sessions []SessionInfo
+	for _, label := range p.labels {
+		if slices.Contains(p.checks.Selected, label) {
+			sessions = append(sessions, p.byLabel[label])
+		}
+	}
+	return sessions
+}
+
+// showRunOnDevicesDialog picks sessions and commands and opens a results tab
+func (sm *SessionManager) showRunOnDevicesDialog() {
+	if len(sm.savedSessions) == 0 {
+		dialog.ShowInformation("Run on Devices", "There are no saved sessions.", sm.window)
+		return
+	}
+	picker := sm.newSessionPicker()
+
+	commandsEntry := widget.NewMultiLineEntry()
+	commandsEntry.SetPlaceHolder("show version\nshow ip interface brief")
+	commandsEntry.SetMinRowsVisible(5)
+
+	modeRadio := widget.NewRadioGroup([]string{runModeShellLabel, runModeExecLabel}, nil)
+	modeRadio.SetSelected(runModeShellLabel)
+
+	concurrencyEntry := widget.NewEntry()
+	concurrencyEntry.SetText(strconv.Itoa(defaultRunConcurrency))
+
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("For sessions without a credential profile")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Sessions", picker.content),
+		widget.NewFormItem("Commands", commandsEntry),
+		widget.NewFormItem("Mode", modeRadio),
+		widget.NewFormItem("Concurrency", concurrencyEntry),
+		widget.NewFormItem("Password", passwordEntry),
+	}
+
+	d := dialog.NewForm("Run on Devices", "Run", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		sessions := picker.Selected()
+		var commands []string
+		for _, line := range strings.Split(commandsEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				commands = append(commands, line)
+			}
+		}
+		concurrency, err := strconv.Atoi(strings.TrimSpace(concurrencyEntry.Text))
+
+		switch {
+		case len(sessions) == 0:
+			dialog.ShowError(fmt.Errorf("select at least one session"), sm.window)
+			return
+		case len(commands) == 0:
+			dialog.ShowError(fmt.Errorf("enter at least one command"), sm.window)
+			return
+		case err != nil || concurrency < 1 || concurrency > maxRunConcurrency:
+			dialog.ShowError(fmt.Errorf("concurrency must be between 1 and %d", maxRunConcurrency), sm.window)
+			return
+		}
+
+		mode := CommandRunShell
+		if modeRadio.Selected == runModeExecLabel {
+			mode = CommandRunExec
+		}
+		sm.openRunnerTab(&CommandRunner{
+			Commands:    commands,
+			Mode:        mode,
+			Concurrency: concurrency,
+			Password:    passwordEntry.Text,
+		}, sessions)
+	}, sm.window)
+	d.Resize(fyne.NewSize(600, 650))
+	d.Show()
+}
+
+// openRunnerTab starts a run in a new results tab
+func (sm *SessionManager) openRunnerTab(runner *CommandRunner, sessions []SessionInfo) {
+	title := fmt.Sprintf("Run: %s", runner.Commands[0])
+	if len(runner.Commands) > 1 {
+		title += fmt.Sprintf(" (+%d)", len(runner.Commands)-1)
+	}
+	sm.addRunnerTab(title, theme.ListIcon(), NewCommandRunView(runner, sessions, sm.window))
+	log.Printf("Run on devices: %d commands on %d sessions (%s, %d at a time)",
+		len(runner.Commands), len(sessions), runner.Mode, runner.Concurrency)
+}
+
+// addRunnerTab shows a run's view in a new tab
+func (sm *SessionManager) addRunnerTab(title string, icon fyne.Resource, view *CommandRunView) {
+	tabItem := container.NewTabItemWithIcon(title, icon, view.Content())
+	sm.toolTabs[tabItem] = view
+	sm.tabContainer.Append(tabItem)
+	sm.tabContainer.Select(tabItem)
+}
@@ -0,0 +1,241 @@
+// config_backup.go - Capturing device configurations to timestamped files
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Backups are taken with the command runner in shell mode, so the session's
+// login script disables paging first. Each capture is written to
+// backups/<folder>/<session>/<timestamp>.txt in the app home directory.
+
+// backupTimeFormat names capture files; it sorts by time, and milliseconds
+// keep captures taken within a second apart
+const backupTimeFormat = "20060102-150405.000"
+
+// defaultBackupCommands are the configuration commands per DeviceType
+var defaultBackupCommands = map[string]string{
+	"cisco_ios":     "show running-config",
+	"cisco_xe":      "show running-config",
+	"cisco_nxos":    "show running-config",
+	"cisco_asa":     "show running-config",
+	"arista_eos":    "show running-config",
+	"juniper_junos": "show configuration | display set",
+}
+
+// pagerArtifact matches pager prompts such as "--More--" or "---(more 45%)---"
+// and the backspaces that erase them; commandNoise matches lines the
+// command prints around the configuration
+var (
+	pagerArtifact = regexp.MustCompile(`(?i) ?-+ ?\(?more[^)\n-]*\)? ?-+ *|\x08+ *\x08*`)
+	commandNoise  = regexp.MustCompile(`(?m)^(?:Building configuration\.\.\.|Current configuration : \d+ bytes)\n`)
+)
+
+// backupCommandFor returns the configuration command of a session's device
+// type, "" if it has none
+func backupCommandFor(session SessionInfo) string {
+	if settings := GetSettings(); settings != nil {
+		return settings.Get().BackupCommands[strings.ToLower(session.DeviceType)]
+	}
+	return defaultBackupCommands[strings.ToLower(session.DeviceType)]
+}
+
+// formatBackupCommands renders commands as "device_type = command" lines
+func formatBackupCommands(commands map[string]string) string {
+	var deviceTypes []string
+	for deviceType := range commands {
+		deviceTypes = append(deviceTypes, deviceType)
+	}
+	sort.Strings(deviceTypes)
+
+	var b strings.Builder
+	for _, deviceType := range deviceTypes {
+		fmt.Fprintf(&b, "%s = %s\n", deviceType, commands[deviceType])
+	}
+	return b.String()
+}
+
+// parseBackupCommands parses "device_type = command" lines
+func parseBackupCommands(text string) (map[string]string, error) {
+	commands := make(map[string]string)
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		deviceType, command, ok := strings.Cut(line, "=")
+		deviceType = strings.TrimSpace(deviceType)
+		command = strings.TrimSpace(command)
+		if !ok || deviceType == "" || command == "" {
+			return nil, fmt.Errorf("line %d: expected device_type = command", n+1)
+		}
+		commands[strings.ToLower(deviceType)] = command
+	}
+	return commands, nil
+}
+
+// cleanBackupOutput removes pager prompts, command noise, trailing blanks
+// and surrounding blank lines
+func cleanBackupOutput(output string) string {
+	output = pagerArtifact.ReplaceAllString(output, "")
+	output = commandNoise.ReplaceAllString(output, "")
+
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// backupDir returns the directory holding a session's captures
+func backupDir(session SessionInfo) string {
+	folder := unsafeFileChars.ReplaceAllString(session.Group, "_")
+	if folder == "" {
+		folder = "Default"
+	}
+	return filepath.Join(GetBackupsDir(), folder, unsafeFileChars.ReplaceAllString(session.Name, "_"))
+}
+
+// saveBackup writes a successful run result as a capture
+func saveBackup(result *DeviceRunResult) {
+	if len(result.Outputs) == 0 {
+		result.Err = fmt.Errorf("no output captured")
+		return
+	}
+	dir := backupDir(result.Session)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		result.Err = err
+		return
+	}
+	path := filepath.Join(dir, time.Now().Format(backupTimeFormat)+".txt")
+	if err := os.WriteFile(path, []byte(cleanBackupOutput(result.Outputs[0].Output)), 0600); err != nil {
+		result.Err = err
+		return
+	}
+	log.Printf("Backups: saved %s", path)
+}
+
+// listBackups returns a session's capture files, newest first
+func listBackups(session SessionInfo) []string {
+	matches, _ := filepath.Glob(filepath.Join(backupDir(session), "*.txt"))
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches
+}
+
+// backupRunner returns a runner that captures each session's configuration
+func backupRunner(password string) *CommandRunner {
+	return &CommandRunner{
+		Commands:    []string{"backup"},
+		Mode:        CommandRunShell,
+		Concurrency: defaultRunConcurrency,
+		Password:    password,
+		DeviceCommands: func(session SessionInfo) []string {
+			return []string{backupCommandFor(session)}
+		},
+		AfterDevice: saveBackup,
+	}
+}
+
+// splitBackupSessions separates sessions with a backup command from those
+// whose device type has none
+func splitBackupSessions(sessions []SessionInfo) (supported, unsupported []SessionInfo) {
+	for _, s := range sessions {
+		if backupCommandFor(s) != "" {
+			supported = append(supported, s)
+		} else {
+			unsupported = append(unsupported, s)
+		}
+	}
+	return supported, unsupported
+}
+
+// backupScheduler runs backups of saved sessions on an interval
+type backupScheduler struct {
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+}
+
+// ApplyBackupSchedule restarts scheduled backups with new settings; an
+// interval of zero turns them off
+func (sm *SessionManager) ApplyBackupSchedule(settings *AppSettings) {
+	sm.backups.mutex.Lock()
+	defer sm.backups.mutex.Unlock()
+
+	if sm.backups.cancel != nil {
+		sm.backups.cancel()
+		sm.backups.cancel = nil
+	}
+	if settings.BackupIntervalHours <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sm.backups.cancel = cancel
+	interval := time.Duration(settings.BackupIntervalHours) * time.Hour
+	folders := settings.BackupFolders
+	log.Printf("Backups: every %s for folders %q", interval, folders)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sm.runScheduledBackups(ctx, folders)
+			}
+		}
+	}()
+}
+
+// runScheduledBackups backs up the sessions of the folders, or every
+// session with a backup command when no folders are set. Sessions log in
+// with their profile or stored password; those needing a typed password
+// fail and are logged.
+func (sm *SessionManager) runScheduledBackups(ctx context.Context, folders []string) {
+	var sessions []SessionInfo
+	for _, s := range sm.sessionStore.GetSessions() {
+		if len(folders) == 0 || containsFold(folders, s.Group) {
+			sessions = append(sessions, s)
+		}
+	}
+	sessions, _ = splitBackupSessions(sessions)
+	if len(sessions) == 0 {
+		return
+	}
+
+	failed := 0
+	for _, r := range backupRunner("").Run(ctx, sessions, func(int, *DeviceRunResult) {}, func(int, *DeviceRunResult) {}) {
+		if r.Err != nil {
+			failed++
+			log.Printf("Backups: %s failed: %v", r.Session.Name, r.Err)
+		}
+	}
+	message := fmt.Sprintf("Backed up %d of %d sessions", len(sessions)-failed, len(sessions))
+	log.Printf("Backups: %s", message)
+	fyne.Do(func() {
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("Configuration backups", message))
+	})
+}
+
+// containsFold reports whether a list holds a string, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
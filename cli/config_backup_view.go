@@ -0,0 +1,172 @@
+// config_backup_view.go - Backup dialog and side-by-side capture compare
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showBackupDialog picks sessions and opens a backup run tab
+func (sm *SessionManager) showBackupDialog() {
+	if len(sm.savedSessions) == 0 {
+		dialog.ShowInformation("Back Up Configurations", "There are no saved sessions.", sm.window)
+		return
+	}
+	picker := sm.newSessionPicker()
+
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("For sessions without a credential profile")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Sessions", picker.content),
+		widget.NewFormItem("Password", passwordEntry),
+	}
+
+	d := dialog.NewForm("Back Up Configurations", "Back Up", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		selected := picker.Selected()
+		if len(selected) == 0 {
+			dialog.ShowError(fmt.Errorf("select at least one session"), sm.window)
+			return
+		}
+		sessions, unsupported := splitBackupSessions(selected)
+		if len(sessions) == 0 {
+			dialog.ShowError(fmt.Errorf("no backup command for the device types of the selected sessions\n"+
+				"(add one in Settings > Backups)"), sm.window)
+			return
+		}
+		if len(unsupported) > 0 {
+			var names []string
+			for _, s := range unsupported {
+				names = append(names, s.Name)
+			}
+			dialog.ShowInformation("Back Up Configurations",
+				"Skipping sessions with no backup command for their device type:\n"+strings.Join(names, ", "),
+				sm.window)
+		}
+		sm.openBackupTab(sessions, passwordEntry.Text)
+	}, sm.window)
+	d.Resize(fyne.NewSize(600, 500))
+	d.Show()
+}
+
+// openBackupTab starts a backup run in a new results tab
+func (sm *SessionManager) openBackupTab(sessions []SessionInfo, password string) {
+	view := NewCommandRunView(backupRunner(password), sessions, sm.window)
+	view.AddButton(widget.NewButtonWithIcon("Compare", theme.ViewRefreshIcon(), func() {
+		session, ok := view.SelectedSession()
+		if !ok {
+			dialog.ShowInformation("Compare", "Select a device first.", sm.window)
+			return
+		}
+		sm.showBackupCompare(session)
+	}))
+
+	title := fmt.Sprintf("Backup: %s", sessions[0].Name)
+	if len(sessions) > 1 {
+		title += fmt.Sprintf(" (+%d)", len(sessions)-1)
+	}
+	sm.addRunnerTab(title, theme.DownloadIcon(), view)
+	log.Printf("Backups: backing up %d sessions", len(sessions))
+}
+
+// showBackupCompare shows the two most recent captures of a session side
+// by side
+func (sm *SessionManager) showBackupCompare(session SessionInfo) {
+	captures := listBackups(session)
+	if len(captures) < 2 {
+		dialog.ShowInformation("Compare",
+			fmt.Sprintf("%s needs at least two captures in\n%s", session.Name, backupDir(session)), sm.window)
+		return
+	}
+
+	older, err := os.ReadFile(captures[1])
+	if err != nil {
+		dialog.ShowError(err, sm.window)
+		return
+	}
+	newer, err := os.ReadFile(captures[0])
+	if err != nil {
+		dialog.ShowError(err, sm.window)
+		return
+	}
+
+	left, right := sideBySideLines(diffLines(string(older), string(newer)))
+	leftGrid := widget.NewTextGrid()
+	rightGrid := widget.NewTextGrid()
+	renderDiff(leftGrid, left)
+	renderDiff(rightGrid, right)
+
+	headers := container.NewGridWithColumns(2,
+		widget.NewLabelWithStyle(captureLabel(captures[1]), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(captureLabel(captures[0]), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	// One scroll keeps both sides on the same lines
+	body := container.NewScroll(container.NewGridWithColumns(2, leftGrid, rightGrid))
+
+	d := dialog.NewCustom("Compare Backups: "+session.Name, "Close",
+		container.NewBorder(headers, nil, nil, nil, body), sm.window)
+	d.Resize(fyne.NewSize(1100, 700))
+	d.Show()
+}
+
+// sideBySideLines splits diff lines into aligned left (older) and right
+// (newer) columns, pairing removed lines with the added lines after them
+// and padding the shorter side with blank rows
+func sideBySideLines(lines []string) (left, right []string) {
+	var removed, added []string
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			l, r := "", ""
+			if i < len(removed) {
+				l = removed[i]
+			}
+			if i < len(added) {
+				r = added[i]
+			}
+			left = append(left, l)
+			right = append(right, r)
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, diffRemoved):
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, line)
+		case strings.HasPrefix(line, diffAdded):
+			added = append(added, line)
+		default:
+			flush()
+			left = append(left, line)
+			right = append(right, line)
+		}
+	}
+	flush()
+	return left, right
+}
+
+// captureLabel names a capture file by the time it was taken
+func captureLabel(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".txt")
+	if t, err := time.ParseInLocation(backupTimeFormat, name, time.Local); err == nil {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return name
+}
@@ -0,0 +1,306 @@
+// highlight_profiles.go - Keyword highlighting of device output by DeviceType
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"tetherssh/internal/gopyte"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Highlighting colors interface names, addresses, link states and ACL
+// actions in output the host sent without colors of its own. It is applied
+// at render time and never changes the screen, so copying and exporting
+// still see the plain text. Cells with any attribute set by the host are
+// left alone.
+//
+// Profiles are picked by session DeviceType. The built-in profiles below can
+// be replaced or extended by name from highlight_profiles.yaml in the app
+// home directory, in the same format.
+
+// builtinHighlightProfiles are the default profiles. Rules are tried in
+// order and the first to color a cell wins.
+const builtinHighlightProfiles = `
+profiles:
+  - name: addresses
+    rules:
+      - name: mac
+        pattern: '\b[0-9a-fA-F]{4}\.[0-9a-fA-F]{4}\.[0-9a-fA-F]{4}\b|\b(?:[0-9a-fA-F]{2}[:-]){5}[0-9a-fA-F]{2}\b'
+        style: yellow
+      - name: ipv4
+        pattern: '\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b'
+        style: bright_magenta
+      - name: ipv6
+        pattern: '\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b|\b(?:[0-9a-fA-F]{1,4}:)+:(?:[0-9a-fA-F]{1,4}(?::[0-9a-fA-F]{1,4})*)?(?:/\d{1,3})?'
+        style: bright_magenta
+
+  - name: cisco
+    base: addresses
+    device_types: [cisco_ios, cisco_xe, cisco_nxos, cisco_asa]
+    rules:
+      - name: interface
+        pattern: '\b(?:(?:Fast|Gigabit|TenGigabit|TwentyFiveGig|FortyGigabit|HundredGig)Ethernet|Ethernet|Port-channel|Loopback|Tunnel|Serial|Vlan|mgmt|Gi|Te|Twe|Fo|Hu|Fa|Eth?|Po|Lo|Tu|Se|Vl)\d+(?:/\d+)*(?:\.\d+)?\b'
+        style: cyan
+      - name: err-disabled
+        pattern: '\berr-disabled\b'
+        style: bold white on red
+      - name: admin-down
+        pattern: '\badministratively down\b'
+        style: yellow
+      - name: down
+        pattern: '\b(?:down|notconnect|disabled)\b'
+        style: bold red
+      - name: up
+        pattern: '\b(?:up|connected)\b'
+        style: bold green
+      - name: permit
+        pattern: '\bpermit\b'
+        style: green
+      - name: deny
+        pattern: '\bdeny\b'
+        style: red
+
+  - name: arista
+    base: addresses
+    device_types: [arista_eos]
+    rules:
+      - name: interface
+        pattern: '\b(?:Ethernet|Port-Channel|Loopback|Management|Vlan|Vxlan|Et|Po|Lo|Ma|Vl|Vx)\d+(?:/\d+)*(?:\.\d+)?\b'
+        style: cyan
+      - name: err-disabled
+        pattern: '\berr-?disabled\b'
+        style: bold white on red
+      - name: admin-down
+        pattern: '\badministratively down\b'
+        style: yellow
+      - name: down
+        pattern: '\b(?:down|notconnect|disabled)\b'
+        style: bold red
+      - name: up
+        pattern: '\b(?:up|connected)\b'
+        style: bold green
+      - name: permit
+        pattern: '\bpermit\b'
+        style: green
+      - name: deny
+        pattern: '\bdeny\b'
+        style: red
+
+  - name: juniper
+    base: addresses
+    device_types: [juniper_junos, juniper]
+    rules:
+      - name: interface
+        pattern: '\b(?:(?:ge|xe|et|fe|gr|ip|lt|mt|st)-\d+/\d+/\d+(?::\d+)?|(?:ae|lo|em|fxp|me|vme|reth|irb)\d*)(?:\.\d+)?\b'
+        style: cyan
+      - name: down
+        pattern: '\b(?:[Dd]own|[Dd]isabled)\b'
+        style: bold red
+      - name: up
+        pattern: '\b(?:[Uu]p|[Ee]nabled)\b'
+        style: bold green
+      - name: accept
+        pattern: '\b(?:accept|permit)\b'
+        style: green
+      - name: reject
+        pattern: '\b(?:reject|discard|deny)\b'
+        style: red
+
+  - name: linux
+    base: addresses
+    device_types: [linux]
+    rules:
+      - name: interface
+        pattern: '\b(?:eth\d+|en[opsx]\w+|wl[opx]?\w+|bond\d+|br-?\w+|docker\d+|veth\w+|virbr\d+|tun\d+|tap\d+|lo)\b'
+        style: cyan
+      - name: down
+        pattern: '\b(?:DOWN|NO-CARRIER|DROP|REJECT)\b'
+        style: bold red
+      - name: up
+        pattern: '\b(?:UP|LOWER_UP|ACCEPT)\b'
+        style: bold green
+`
+
+// HighlightRule colors matches of a regex
+type HighlightRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Style   string `yaml:"style"` // As in highlight triggers: "bold white on red"
+
+	re    *regexp.Regexp
+	attrs gopyte.Attributes
+}
+
+// HighlightProfile is a named set of rules for some device types
+type HighlightProfile struct {
+	Name        string          `yaml:"name"`
+	Base        string          `yaml:"base"` // Profile whose rules come first
+	DeviceTypes []string        `yaml:"device_types"`
+	Rules       []HighlightRule `yaml:"rules"`
+}
+
+// highlightProfilesFile is the YAML layout
+type highlightProfilesFile struct {
+	Profiles []HighlightProfile `yaml:"profiles"`
+}
+
+var (
+	highlightMutex    sync.RWMutex
+	highlightByDevice map[string]*HighlightProfile // nil until loaded
+)
+
+// parseHighlightProfiles parses and compiles profiles from YAML
+func parseHighlightProfiles(data []byte) ([]HighlightProfile, error) {
+	var file highlightProfilesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Profiles {
+		p := &file.Profiles[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("profile %d has no name", i+1)
+		}
+		for j := range p.Rules {
+			r := &p.Rules[j]
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s rule %q: %v", p.Name, r.Name, err)
+			}
+			attrs, err := parseHighlightStyle(r.Style)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s rule %q: %v", p.Name, r.Name, err)
+			}
+			r.re, r.attrs = re, attrs
+		}
+	}
+	return file.Profiles, nil
+}
+
+// loadHighlightProfiles builds the device type lookup from the built-in
+// profiles and the user's file, whose profiles replace built-ins by name
+func loadHighlightProfiles() map[string]*HighlightProfile {
+	profiles, err := parseHighlightProfiles([]byte(builtinHighlightProfiles))
+	if err != nil {
+		log.Printf("Highlighting: built-in profiles: %v", err)
+	}
+
+	byName := make(map[string]*HighlightProfile)
+	var order []string
+	add := func(list []HighlightProfile) {
+		for i := range list {
+			p := &list[i]
+			if _, ok := byName[p.Name]; !ok {
+				order = append(order, p.Name)
+			}
+			byName[p.Name] = p
+		}
+	}
+	add(profiles)
+
+	path := GetHighlightProfilesPath()
+	if data, err := os.ReadFile(path); err == nil {
+		if user, err := parseHighlightProfiles(data); err == nil {
+			add(user)
+			log.Printf("Highlighting: loaded %d profiles from %s", len(user), path)
+		} else {
+			log.Printf("Highlighting: %s: %v", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Printf("Highlighting: %v", err)
+	}
+
+	byDevice := make(map[string]*HighlightProfile)
+	for _, name := range order {
+		p := byName[name]
+		// Put the base profile's rules first, one level deep
+		if base, ok := byName[p.Base]; ok && base != p {
+			p.Rules = append(append([]HighlightRule(nil), base.Rules...), p.Rules...)
+		}
+		for _, deviceType := range p.DeviceTypes {
+			byDevice[strings.ToLower(deviceType)] = p
+		}
+	}
+	return byDevice
+}
+
+// ReloadHighlightProfiles rereads the user's profiles on the next render
+func ReloadHighlightProfiles() {
+	highlightMutex.Lock()
+	highlightByDevice = nil
+	highlightMutex.Unlock()
+}
+
+// highlightProfileFor returns the profile of a device type, nil if none
+func highlightProfileFor(deviceType string) *HighlightProfile {
+	if deviceType == "" {
+		return nil
+	}
+	highlightMutex.RLock()
+	byDevice := highlightByDevice
+	highlightMutex.RUnlock()
+
+	if byDevice == nil {
+		byDevice = loadHighlightProfiles()
+		highlightMutex.Lock()
+		highlightByDevice = byDevice
+		highlightMutex.Unlock()
+	}
+	return byDevice[strings.ToLower(deviceType)]
+}
+
+// isDefaultAttributes reports whether the host left a cell uncolored
+func isDefaultAttributes(a gopyte.Attributes) bool {
+	if a.Fg == "default" {
+		a.Fg = ""
+	}
+	if a.Bg == "default" {
+		a.Bg = ""
+	}
+	return a == gopyte.Attributes{}
+}
+
+// apply returns the line's attributes with matches colored. The given
+// slice belongs to the screen and is copied before the first change.
+func (p *HighlightProfile) apply(line string, attrs []gopyte.Attributes) []gopyte.Attributes {
+	result := attrs
+	copied := false
+	colored := make([]bool, len(attrs))
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		for _, m := range r.re.FindAllStringIndex(line, -1) {
+			start := len([]rune(line[:m[0]]))
+			end := start + len([]rune(line[m[0]:m[1]]))
+			for x := start; x < end && x < len(attrs); x++ {
+				if colored[x] || !isDefaultAttributes(attrs[x]) {
+					continue
+				}
+				if !copied {
+					result = append([]gopyte.Attributes(nil), attrs...)
+					copied = true
+				}
+				result[x] = r.attrs
+				colored[x] = true
+			}
+		}
+	}
+	return result
+}
+
+// highlightLine applies the session's highlighting profile to a rendered
+// line, if highlighting is on
+func (t *NativeTerminalWidget) highlightLine(line string, attrs []gopyte.Attributes) []gopyte.Attributes {
+	if settings := GetSettings(); settings == nil || !settings.Get().SyntaxHighlighting {
+		return attrs
+	}
+	if p := highlightProfileFor(t.deviceType); p != nil {
+		return p.apply(line, attrs)
+	}
+	return attrs
+}
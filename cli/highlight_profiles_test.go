@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// userHighlightProfiles is a sample highlight_profiles.yaml
+const userHighlightProfiles = `
+profiles:
+  - name: lab
+    base: addresses
+    device_types: [Lab_OS]
+    rules:
+      - name: vrf
+        pattern: '\bvrf \w+'
+        style: bold yellow on blue
+`
+
+func TestParseHighlightProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"Built-in", builtinHighlightProfiles, ""},
+		{"User", userHighlightProfiles, ""},
+		{"No name", "profiles:\n  - rules: []\n", "profile 1 has no name"},
+		{"Bad pattern", "profiles:\n  - name: x\n    rules:\n      - {name: r, pattern: '(', style: red}\n", `profile x rule "r"`},
+		{"Bad style", "profiles:\n  - name: x\n    rules:\n      - {name: r, pattern: 'a', style: plaid}\n", `unknown highlight style "plaid"`},
+		{"No style", "profiles:\n  - name: x\n    rules:\n      - {name: r, pattern: 'a'}\n", "needs a style"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseHighlightProfiles([]byte(tc.yaml))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("error = %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHighlightProfileSpans(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	if err := os.WriteFile(GetHighlightProfilesPath(), []byte(userHighlightProfiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ReloadHighlightProfiles()
+	t.Cleanup(ReloadHighlightProfiles)
+
+	type span struct {
+		text  string
+		style string
+	}
+	tests := []struct {
+		name       string
+		deviceType string
+		line       string
+		want       []span
+	}{
+		{
+			"Cisco interface status", "cisco_ios",
+			"Gi0/1 is up, line protocol is down",
+			[]span{{"Gi0/1", "cyan"}, {"up", "bold green"}, {"down", "bold red"}},
+		},
+		{
+			"Cisco base profile", "CISCO_XE",
+			"Vlan10 10.0.0.1/24 err-disabled",
+			[]span{{"Vlan10", "cyan"}, {"10.0.0.1/24", "bright_magenta"}, {"err-disabled", "bold white on red"}},
+		},
+		{
+			"User profile with base", "lab_os",
+			"vrf mgmt via 192.168.1.1",
+			[]span{{"vrf mgmt", "bold yellow on blue"}, {"192.168.1.1", "bright_magenta"}},
+		},
+		{"Plain text", "cisco_ios", "show version", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := highlightProfileFor(tc.deviceType)
+			if p == nil {
+				t.Fatalf("no profile for %q", tc.deviceType)
+			}
+			attrs := make([]gopyte.Attributes, len(tc.line))
+			for i := range attrs {
+				attrs[i] = gopyte.DefaultAttributes()
+			}
+
+			want := append([]gopyte.Attributes(nil), attrs...)
+			for _, s := range tc.want {
+				style, err := parseHighlightStyle(s.style)
+				if err != nil {
+					t.Fatal(err)
+				}
+				start := strings.Index(tc.line, s.text)
+				for x := start; x < start+len(s.text); x++ {
+					want[x] = style
+				}
+			}
+
+			got := p.apply(tc.line, attrs)
+			for x := range want {
+				if got[x] != want[x] {
+					t.Errorf("cell %d (%q) = %+v, want %+v", x, tc.line[x], got[x], want[x])
+				}
+			}
+			if attrs[0] != gopyte.DefaultAttributes() {
+				t.Errorf("apply changed the screen's attributes")
+			}
+		})
+	}
+
+	if p := highlightProfileFor("autodetect"); p != nil {
+		t.Errorf("autodetect has profile %s, want none", p.Name)
+	}
+}
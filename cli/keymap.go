@@ -0,0 +1,577 @@
+// keymap.go - Key chords bound to terminal and window actions
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"gopkg.in/yaml.v3"
+)
+
+// The keymap lives in keymap.yaml in the app home directory. It picks a
+// style of defaults and adds or replaces bindings by chord:
+//
+//	style: linux
+//	bindings:
+//	  - keys: Ctrl+Shift+K
+//	    action: send
+//	    text: '\e[15~'
+//	  - keys: Ctrl+Shift+D
+//	    action: none
+//
+// Action "none" removes a default so the chord reaches the host.
+
+// Keymap styles
+const (
+	KeymapStyleLinux   = "linux"
+	KeymapStyleWindows = "windows"
+	KeymapStyleMacOS   = "macos"
+)
+
+// Keymap actions
+const (
+	KeyActionNone            = "none"
+	KeyActionCopy            = "copy"
+	KeyActionPaste           = "paste"
+	KeyActionCopyOrInterrupt = "copy_or_interrupt" // Copy the selection, else send Ctrl+C
+	KeyActionNewTab          = "new_tab"           // Open the active session again
+	KeyActionNextTab         = "next_tab"
+	KeyActionPreviousTab     = "previous_tab"
+	KeyActionFind            = "find"
+	KeyActionSplit           = "split"
+	KeyActionZoomIn          = "zoom_in"
+	KeyActionZoomOut         = "zoom_out"
+	KeyActionZoomReset       = "zoom_reset"
+	KeyActionPreviousPrompt  = "previous_prompt"
+	KeyActionNextPrompt      = "next_prompt"
+	KeyActionCopyOutput      = "copy_output" // Copy the last command's output
+	KeyActionSend            = "send"        // Send Text to the host
+)
+
+// keyActions lists the actions in the order the editor describes them
+var keyActions = []string{
+	KeyActionCopy, KeyActionPaste, KeyActionCopyOrInterrupt,
+	KeyActionNewTab, KeyActionNextTab, KeyActionPreviousTab,
+	KeyActionFind, KeyActionSplit,
+	KeyActionZoomIn, KeyActionZoomOut, KeyActionZoomReset,
+	KeyActionPreviousPrompt, KeyActionNextPrompt, KeyActionCopyOutput,
+	KeyActionSend, KeyActionNone,
+}
+
+// keyNameAliases are friendlier names for fyne key names
+var keyNameAliases = map[string]fyne.KeyName{
+	"pageup":    fyne.KeyPageUp,
+	"pagedown":  fyne.KeyPageDown,
+	"enter":     fyne.KeyReturn,
+	"esc":       fyne.KeyEscape,
+	"plus":      fyne.KeyEqual,
+	"backspace": fyne.KeyBackspace,
+	"delete":    fyne.KeyDelete,
+	"space":     fyne.KeySpace,
+	"tab":       fyne.KeyTab,
+	"up":        fyne.KeyUp,
+	"down":      fyne.KeyDown,
+	"left":      fyne.KeyLeft,
+	"right":     fyne.KeyRight,
+	"home":      fyne.KeyHome,
+	"end":       fyne.KeyEnd,
+	"insert":    fyne.KeyInsert,
+}
+
+// keyChord is a modifier set and a key
+type keyChord struct {
+	Modifier fyne.KeyModifier
+	Key      fyne.KeyName
+}
+
+// parseKeyChord parses a chord such as "Ctrl+Shift+C" or "Cmd+PageDown".
+// A modifier is required so plain typing is never captured.
+func parseKeyChord(text string) (keyChord, error) {
+	parts := strings.Split(text, "+")
+	var chord keyChord
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "ctrl", "control":
+			chord.Modifier |= fyne.KeyModifierControl
+		case "alt", "option":
+			chord.Modifier |= fyne.KeyModifierAlt
+		case "shift":
+			chord.Modifier |= fyne.KeyModifierShift
+		case "super", "cmd", "command", "win":
+			chord.Modifier |= fyne.KeyModifierSuper
+		default:
+			return keyChord{}, fmt.Errorf("unknown modifier %q in %q", part, text)
+		}
+	}
+	key := strings.TrimSpace(parts[len(parts)-1])
+	if chord.Modifier == 0 || key == "" {
+		return keyChord{}, fmt.Errorf("%q needs a modifier and a key", text)
+	}
+	// Letters and function keys are upper case in fyne key names
+	if alias, ok := keyNameAliases[strings.ToLower(key)]; ok {
+		chord.Key = alias
+	} else if len(key) == 1 || strings.HasPrefix(strings.ToUpper(key), "F") {
+		chord.Key = fyne.KeyName(strings.ToUpper(key))
+	} else {
+		chord.Key = fyne.KeyName(key)
+	}
+	return chord, nil
+}
+
+// String renders the chord the way parseKeyChord reads it
+func (c keyChord) String() string {
+	var parts []string
+	if c.Modifier&fyne.KeyModifierControl != 0 {
+		parts = append(parts, "Ctrl")
+	}
+	if c.Modifier&fyne.KeyModifierSuper != 0 {
+		parts = append(parts, "Cmd")
+	}
+	if c.Modifier&fyne.KeyModifierAlt != 0 {
+		parts = append(parts, "Alt")
+	}
+	if c.Modifier&fyne.KeyModifierShift != 0 {
+		parts = append(parts, "Shift")
+	}
+	switch c.Key {
+	case fyne.KeyPageUp:
+		parts = append(parts, "PageUp")
+	case fyne.KeyPageDown:
+		parts = append(parts, "PageDown")
+	default:
+		parts = append(parts, string(c.Key))
+	}
+	return strings.Join(parts, "+")
+}
+
+// KeyBinding binds a chord to an action
+type KeyBinding struct {
+	Keys   string `yaml:"keys"`
+	Action string `yaml:"action"`
+	Text   string `yaml:"text,omitempty"` // For send; \r, \n, \t and \e escapes
+}
+
+// keymapFile is the layout of keymap.yaml
+type keymapFile struct {
+	Style    string       `yaml:"style,omitempty"`
+	Bindings []KeyBinding `yaml:"bindings"`
+}
+
+// Keymap resolves chords to bindings
+type Keymap struct {
+	Style    string
+	bindings map[keyChord]KeyBinding
+}
+
+// defaultKeymapStyle matches the platform the app runs on
+func defaultKeymapStyle() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return KeymapStyleMacOS
+	case "windows":
+		return KeymapStyleWindows
+	}
+	return KeymapStyleLinux
+}
+
+// defaultKeyBindings returns a style's bindings. Linux keeps Ctrl+C and
+// Ctrl+V for the host; Windows copies with Ctrl+C when text is selected.
+func defaultKeyBindings(style string) []KeyBinding {
+	if style == KeymapStyleMacOS {
+		return []KeyBinding{
+			{Keys: "Cmd+C", Action: KeyActionCopy},
+			{Keys: "Cmd+V", Action: KeyActionPaste},
+			{Keys: "Cmd+T", Action: KeyActionNewTab},
+			{Keys: "Cmd+Shift+]", Action: KeyActionNextTab},
+			{Keys: "Cmd+Shift+[", Action: KeyActionPreviousTab},
+			{Keys: "Cmd+F", Action: KeyActionFind},
+			{Keys: "Cmd+D", Action: KeyActionSplit},
+			{Keys: "Cmd+=", Action: KeyActionZoomIn},
+			{Keys: "Cmd+-", Action: KeyActionZoomOut},
+			{Keys: "Cmd+0", Action: KeyActionZoomReset},
+			{Keys: "Cmd+Up", Action: KeyActionPreviousPrompt},
+			{Keys: "Cmd+Down", Action: KeyActionNextPrompt},
+			{Keys: "Cmd+Shift+O", Action: KeyActionCopyOutput},
+		}
+	}
+
+	bindings := []KeyBinding{
+		{Keys: "Ctrl+Shift+C", Action: KeyActionCopy},
+		{Keys: "Ctrl+Shift+V", Action: KeyActionPaste},
+		{Keys: "Ctrl+Shift+T", Action: KeyActionNewTab},
+		{Keys: "Ctrl+PageDown", Action: KeyActionNextTab},
+		{Keys: "Ctrl+PageUp", Action: KeyActionPreviousTab},
+		{Keys: "Ctrl+Shift+F", Action: KeyActionFind},
+		{Keys: "Ctrl+Shift+D", Action: KeyActionSplit},
+		{Keys: "Ctrl+=", Action: KeyActionZoomIn},
+		{Keys: "Ctrl+-", Action: KeyActionZoomOut},
+		{Keys: "Ctrl+0", Action: KeyActionZoomReset},
+		{Keys: "Ctrl+Shift+Up", Action: KeyActionPreviousPrompt},
+		{Keys: "Ctrl+Shift+Down", Action: KeyActionNextPrompt},
+		{Keys: "Ctrl+Shift+O", Action: KeyActionCopyOutput},
+	}
+	if style == KeymapStyleWindows {
+		bindings = append(bindings,
+			KeyBinding{Keys: "Ctrl+C", Action: KeyActionCopyOrInterrupt},
+			KeyBinding{Keys: "Ctrl+V", Action: KeyActionPaste},
+		)
+	}
+	return bindings
+}
+
+// buildKeymap lays a file's bindings over its style's defaults. A chord
+// bound twice in the file is a conflict.
+func buildKeymap(file keymapFile) (*Keymap, error) {
+	style := file.Style
+	if style == "" {
+		style = defaultKeymapStyle()
+	}
+	if style != KeymapStyleLinux && style != KeymapStyleWindows && style != KeymapStyleMacOS {
+		return nil, fmt.Errorf("unknown style %q (linux, windows or macos)", style)
+	}
+
+	keymap := &Keymap{Style: style, bindings: make(map[keyChord]KeyBinding)}
+	for _, binding := range defaultKeyBindings(style) {
+		chord, _ := parseKeyChord(binding.Keys)
+		keymap.bindings[chord] = binding
+	}
+
+	seen := make(map[keyChord]string)
+	var conflicts []string
+	for _, binding := range file.Bindings {
+		chord, err := parseKeyChord(binding.Keys)
+		if err != nil {
+			return nil, err
+		}
+		if !isKeyAction(binding.Action) {
+			return nil, fmt.Errorf("%s: unknown action %q", binding.Keys, binding.Action)
+		}
+		if binding.Action == KeyActionSend && binding.Text == "" {
+			return nil, fmt.Errorf("%s: send needs text", binding.Keys)
+		}
+		if previous, ok := seen[chord]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s is bound to both %s and %s", chord, previous, binding.Action))
+			continue
+		}
+		seen[chord] = binding.Action
+		keymap.bindings[chord] = binding
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(conflicts, "; "))
+	}
+	return keymap, nil
+}
+
+// keymapStyleLabels maps styles to their settings labels
+var keymapStyleLabels = []struct{ style, label string }{
+	{KeymapStyleLinux, "Linux (Ctrl+Shift+C/V)"},
+	{KeymapStyleWindows, "Windows (Ctrl+C copies a selection)"},
+	{KeymapStyleMacOS, "macOS (Cmd+C/V)"},
+}
+
+// keymapStyleOptions returns the style labels for a Select
+func keymapStyleOptions() []string {
+	var options []string
+	for _, s := range keymapStyleLabels {
+		options = append(options, s.label)
+	}
+	return options
+}
+
+// keymapStyleToLabel converts a style to its label
+func keymapStyleToLabel(style string) string {
+	for _, s := range keymapStyleLabels {
+		if s.style == style {
+			return s.label
+		}
+	}
+	return keymapStyleLabels[0].label
+}
+
+// keymapLabelToStyle converts a Select label back to a style
+func keymapLabelToStyle(label string) string {
+	for _, s := range keymapStyleLabels {
+		if s.label == label {
+			return s.style
+		}
+	}
+	return KeymapStyleLinux
+}
+
+// keymapFileFromEditor builds the keymap file from the settings editor,
+// adding problems and conflicts with snippet shortcuts to errs
+func keymapFileFromEditor(styleLabel, text string, errs *[]string) keymapFile {
+	file := keymapFile{Style: keymapLabelToStyle(styleLabel)}
+	bindings, err := parseKeyBindings(text)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("Key Bindings %v", err))
+		return file
+	}
+	file.Bindings = bindings
+
+	keymap, err := buildKeymap(file)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("Key Bindings %v", err))
+		return file
+	}
+	snippets := NewSnippetStore(GetSnippetsPath())
+	if err := snippets.Load(); err != nil {
+		log.Printf("Keymap: %v", err)
+	}
+	for _, snippet := range snippets.GetSnippets() {
+		chord, err := parseKeyChord(snippet.Shortcut)
+		if err != nil {
+			continue
+		}
+		if binding, ok := keymap.Lookup(chord.Modifier, chord.Key); ok {
+			*errs = append(*errs, fmt.Sprintf("Key Bindings: %s is bound to %s and to snippet %q",
+				chord, binding.Action, snippet.Name))
+		}
+	}
+	return file
+}
+
+// isKeyAction reports whether an action name is known
+func isKeyAction(action string) bool {
+	for _, a := range keyActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the binding of a chord; chords bound to none are unbound
+func (k *Keymap) Lookup(modifier fyne.KeyModifier, key fyne.KeyName) (KeyBinding, bool) {
+	binding, ok := k.bindings[keyChord{Modifier: modifier, Key: key}]
+	if !ok || binding.Action == KeyActionNone {
+		return KeyBinding{}, false
+	}
+	return binding, true
+}
+
+// Chords returns the bound chords of an action, sorted
+func (k *Keymap) Chords(action string) []keyChord {
+	var chords []keyChord
+	for chord, binding := range k.bindings {
+		if binding.Action == action {
+			chords = append(chords, chord)
+		}
+	}
+	sort.Slice(chords, func(i, j int) bool { return chords[i].String() < chords[j].String() })
+	return chords
+}
+
+var (
+	keymapMutex   sync.RWMutex
+	currentKeymap *Keymap
+)
+
+// loadKeymapFile reads keymap.yaml; a missing file means the platform
+// defaults
+func loadKeymapFile() (keymapFile, error) {
+	var file keymapFile
+	data, err := os.ReadFile(GetKeymapPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+		return file, err
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return file, err
+	}
+	return file, nil
+}
+
+// saveKeymapFile writes keymap.yaml
+func saveKeymapFile(file keymapFile) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal keymap: %w", err)
+	}
+	if err := os.WriteFile(GetKeymapPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write keymap: %w", err)
+	}
+	return nil
+}
+
+// CurrentKeymap returns the keymap, loading it on first use. A broken
+// keymap file is logged and the platform defaults are used.
+func CurrentKeymap() *Keymap {
+	keymapMutex.RLock()
+	keymap := currentKeymap
+	keymapMutex.RUnlock()
+	if keymap != nil {
+		return keymap
+	}
+
+	file, err := loadKeymapFile()
+	if err == nil {
+		keymap, err = buildKeymap(file)
+	}
+	if err != nil {
+		log.Printf("Keymap: %s: %v; using defaults", GetKeymapPath(), err)
+		keymap, _ = buildKeymap(keymapFile{})
+	}
+
+	keymapMutex.Lock()
+	currentKeymap = keymap
+	keymapMutex.Unlock()
+	return keymap
+}
+
+// ReloadKeymap drops the loaded keymap so the next lookup reads the file
+func ReloadKeymap() {
+	keymapMutex.Lock()
+	currentKeymap = nil
+	keymapMutex.Unlock()
+}
+
+// formatKeyBindings renders bindings as "chord = action [text]" lines
+func formatKeyBindings(bindings []KeyBinding) string {
+	var b strings.Builder
+	for _, binding := range bindings {
+		fmt.Fprintf(&b, "%s = %s", binding.Keys, binding.Action)
+		if binding.Text != "" {
+			fmt.Fprintf(&b, " %s", binding.Text)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// parseKeyBindings parses "chord = action [text]" lines
+func parseKeyBindings(text string) ([]KeyBinding, error) {
+	var bindings []KeyBinding
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys, rest, ok := strings.Cut(line, " = ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected chord = action", n+1)
+		}
+		action, sendText, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		bindings = append(bindings, KeyBinding{
+			Keys:   strings.TrimSpace(keys),
+			Action: action,
+			Text:   strings.TrimSpace(sendText),
+		})
+	}
+	return bindings, nil
+}
+
+// handleKeyChord runs the keymap action bound to a shortcut, then offers
+// unbound chords to the shortcut handler. It reports whether the chord
+// was used.
+func (t *NativeTerminalWidget) handleKeyChord(shortcut fyne.KeyboardShortcut) bool {
+	binding, ok := CurrentKeymap().Lookup(shortcut.Mod(), shortcut.Key())
+	if !ok {
+		return t.onShortcut != nil && t.onShortcut(shortcut)
+	}
+
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+	switch binding.Action {
+	case KeyActionCopy:
+		if t.selection != nil && t.selection.HasSelection() {
+			t.selection.CopyToClipboard()
+		}
+	case KeyActionCopyOrInterrupt:
+		if t.selection != nil && t.selection.HasSelection() {
+			t.selection.CopyToClipboard()
+			t.selection.Clear()
+		} else {
+			t.WriteToPTY([]byte{0x03})
+		}
+	case KeyActionPaste:
+		if content := window.Clipboard().Content(); content != "" {
+			t.WriteToPTY([]byte(content))
+		}
+	case KeyActionSend:
+		t.WriteToPTY([]byte(sendEscapes.Replace(binding.Text)))
+	case KeyActionPreviousPrompt:
+		t.jumpToPrompt(-1)
+	case KeyActionNextPrompt:
+		t.jumpToPrompt(1)
+	case KeyActionCopyOutput:
+		t.copyLastCommandOutput()
+	case KeyActionZoomIn:
+		t.zoom(1)
+	case KeyActionZoomOut:
+		t.zoom(-1)
+	case KeyActionZoomReset:
+		t.resetZoom()
+	default:
+		if t.onKeyAction == nil || !t.onKeyAction(binding.Action) {
+			log.Printf("Keymap: %s (%s) is not available here", binding.Action, binding.Keys)
+		}
+	}
+	return true
+}
+
+// windowKeyActions are the keymap actions that work without a focused
+// terminal
+var windowKeyActions = []string{
+	KeyActionNewTab, KeyActionNextTab, KeyActionPreviousTab, KeyActionFind, KeyActionSplit,
+}
+
+// handleKeyAction runs a keymap action on the window, reporting whether it
+// is available
+func (sm *SessionManager) handleKeyAction(action string) bool {
+	switch action {
+	case KeyActionNewTab:
+		if tab := sm.activeSessionTab(); tab != nil {
+			sm.connectToSession(tab.Info)
+		} else {
+			sm.showQuickConnectDialog()
+		}
+	case KeyActionNextTab, KeyActionPreviousTab:
+		count := len(sm.tabContainer.Items)
+		if count == 0 {
+			return true
+		}
+		step := 1
+		if action == KeyActionPreviousTab {
+			step = count - 1
+		}
+		sm.tabContainer.SelectIndex((sm.tabContainer.SelectedIndex() + step) % count)
+		if terminal := sm.getActiveTerminal(); terminal != nil {
+			sm.window.Canvas().Focus(terminal)
+		}
+	case KeyActionFind:
+		sm.showFindBar()
+	case KeyActionSplit:
+		sm.toggleSplit()
+	default:
+		return false
+	}
+	return true
+}
+
+// applyWindowShortcuts binds the window actions of the keymap on the
+// canvas, for when no terminal has focus
+func (sm *SessionManager) applyWindowShortcuts() {
+	canvas := sm.window.Canvas()
+	for _, shortcut := range sm.windowShortcuts {
+		canvas.RemoveShortcut(shortcut)
+	}
+	sm.windowShortcuts = nil
+
+	keymap := CurrentKeymap()
+	for _, action := range windowKeyActions {
+		for _, chord := range keymap.Chords(action) {
+			shortcut := &desktop.CustomShortcut{KeyName: chord.Key, Modifier: chord.Modifier}
+			canvas.AddShortcut(shortcut, func(fyne.Shortcut) { sm.handleKeyAction(action) })
+			sm.windowShortcuts = append(sm.windowShortcuts, shortcut)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+// login_scripts.go - Post-login automation: expect/send steps run after connecting
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A login script runs once ConnectSSH succeeds, for example to disable the
+// pager and enter enable mode. Each step:
+//
+//  1. is skipped unless When matches the text the last Expect matched
+//  2. sends Send, with \r \n \t \e escapes and {username}, {password} and
+//     {enable_secret} filled in
+//  3. waits up to Timeout seconds for Expect in the output that follows
+//
+// A step that times out stops the script unless it is Optional. The session
+// is never closed by the script.
+//
+// Sessions run the script named in their LoginScript, or else the first one
+// listing their DeviceType; "none" turns scripts off for a session. The
+// built-in presets can be replaced or extended by name from login_scripts.yaml
+// in the app home directory. Secrets come from the session's password or from
+// the credentials.yaml profile its Creds ID names.
+
+// LoginScriptNone disables login scripts for a session
+const LoginScriptNone = "none"
+
+// Login script limits
+const (
+	defaultLoginStepTimeout = 10              // Seconds
+	maxLoginOutput          = 16 * 1024       // Output kept for matching
+	loginStatusLinger       = 5 * time.Second // How long a failure shows in the tab title
+)
+
+// builtinLoginScripts are the vendor presets
+const builtinLoginScripts = `
+scripts:
+  - name: cisco
+    device_types: [cisco_ios, cisco_xe, cisco_nxos, cisco_asa]
+    steps:
+      - expect: '[>#]\s*$'
+      - when: '>\s*$'
+        send: 'enable\r'
+        expect: '(?i)password:\s*$|#\s*$'
+      - when: '(?i)password:\s*$'
+        send: '{enable_secret}\r'
+        expect: '#\s*$'
+      - send: 'terminal length 0\r'
+        expect: '[>#]\s*$'
+      - send: 'terminal width 511\r'
+        expect: '[>#]\s*$'
+        optional: true
+
+  - name: arista
+    device_types: [arista_eos]
+    steps:
+      - expect: '[>#]\s*$'
+      - when: '>\s*$'
+        send: 'enable\r'
+        expect: '(?i)password:\s*$|#\s*$'
+      - when: '(?i)password:\s*$'
+        send: '{enable_secret}\r'
+        expect: '#\s*$'
+      - send: 'terminal length 0\r'
+        expect: '[>#]\s*$'
+      - send: 'terminal width 32767\r'
+        expect: '[>#]\s*$'
+        optional: true
+
+  - name: juniper
+    device_types: [juniper_junos, juniper]
+    steps:
+      - expect: '[>#%]\s*$'
+      - when: '%\s*$'
+        send: 'cli\r'
+        expect: '[>#]\s*$'
+      - send: 'set cli screen-length 0\r'
+        expect: '[>#]\s*$'
+      - send: 'set cli screen-width 0\r'
+        expect: '[>#]\s*$'
+        optional: true
+`
+
+// LoginStep is one expect/send step
+type LoginStep struct {
+	When     string `yaml:"when,omitempty"`   // Regex on the last Expect match; skip the step if it fails
+	Send     string `yaml:"send,omitempty"`   // Text to send
+	Expect   string `yaml:"expect,omitempty"` // Regex to wait for after sending
+	Timeout  int    `yaml:"timeout,omitempty"`
+	Optional bool   `yaml:"optional,omitempty"` // A timeout moves on instead of stopping
+
+	when, expect *regexp.Regexp
+}
+
+// LoginScript is a named list of steps for some device types
+type LoginScript struct {
+	Name        string      `yaml:"name"`
+	DeviceTypes []string    `yaml:"device_types"`
+	Steps       []LoginStep `yaml:"steps"`
+}
+
+// loginScriptsFile is the login_scripts.yaml layout
+type loginScriptsFile struct {
+	Scripts []LoginScript `yaml:"scripts"`
+}
+
+// CredentialProfile holds the secrets a Creds ID refers to
+type CredentialProfile struct {
+	ID           string `yaml:"id"`
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	EnableSecret string `yaml:"enable_secret,omitempty"`
+}
+
+// credentialsFile is the credentials.yaml layout
+type credentialsFile struct {
+	Credentials []CredentialProfile `yaml:"credentials"`
+}
+
+// parseLoginScripts parses and compiles scripts from YAML
+func parseLoginScripts(data []byte) ([]LoginScript, error) {
+	var file loginScriptsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Scripts {
+		s := &file.Scripts[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("script %d has no name", i+1)
+		}
+		for j := range s.Steps {
+			step := &s.Steps[j]
+			var err error
+			if step.When != "" {
+				if step.when, err = regexp.Compile(step.When); err != nil {
+					return nil, fmt.Errorf("script %s step %d: %v", s.Name, j+1, err)
+				}
+			}
+			if step.Expect != "" {
+				if step.expect, err = regexp.Compile(step.Expect); err != nil {
+					return nil, fmt.Errorf("script %s step %d: %v", s.Name, j+1, err)
+				}
+			}
+			if step.Timeout <= 0 {
+				step.Timeout = defaultLoginStepTimeout
+			}
+		}
+	}
+	return file.Scripts, nil
+}
+
+// loadLoginScripts returns the built-in scripts followed by the user's,
+// which replace built-ins of the same name
+func loadLoginScripts() []LoginScript {
+	scripts, err := parseLoginScripts([]byte(builtinLoginScripts))
+	if err != nil {
+		log.Printf("Login scripts: built-in scripts: %v", err)
+	}
+
+	path := GetLoginScriptsPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Login scripts: %v", err)
+		}
+		return scripts
+	}
+	user, err := parseLoginScripts(data)
+	if err != nil {
+		log.Printf("Login scripts: %s: %v", path, err)
+		return scripts
+	}
+
+	// User scripts come first so they win device type lookups too
+	result := user
+	for _, s := range scripts {
+		replaced := false
+		for _, u := range user {
+			replaced = replaced || u.Name == s.Name
+		}
+		if !replaced {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// loginScriptFor picks a session's script, nil for none
+func loginScriptFor(session SessionInfo) *LoginScript {
+	name := strings.TrimSpace(session.LoginScript)
+	if strings.EqualFold(name, LoginScriptNone) {
+		return nil
+	}
+	if name == "" && session.DeviceType == "" {
+		return nil
+	}
+
+	scripts := loadLoginScripts()
+	for i := range scripts {
+		s := &scripts[i]
+		if name != "" {
+			if strings.EqualFold(s.Name, name) {
+				return s
+			}
+			continue
+		}
+		for _, deviceType := range s.DeviceTypes {
+			if strings.EqualFold(deviceType, session.DeviceType) {
+				return s
+			}
+		}
+	}
+	if name != "" {
+		log.Printf("Login scripts: %s has no script named %q", session.Name, name)
+	}
+	return nil
+}
+
+// loadCredentialProfile returns the credentials.yaml profile with an ID
+func loadCredentialProfile(id string) (CredentialProfile, bool) {
+	if id == "" {
+		return CredentialProfile{}, false
+	}
+	data, err := os.ReadFile(GetCredentialsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Credentials: %v", err)
+		}
+		return CredentialProfile{}, false
+	}
+	var file credentialsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		log.Printf("Credentials: %s: %v", GetCredentialsPath(), err)
+		return CredentialProfile{}, false
+	}
+	for _, c := range file.Credentials {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	log.Printf("Credentials: no profile %q", id)
+	return CredentialProfile{}, false
+}
+
+// loginSecrets fills in the placeholders of send text. The profile named by
+// the session's Creds ID wins over the session's own username and password;
+// the enable secret falls back to the password.
+func loginSecrets(session SessionInfo, password string) *strings.Replacer {
+	username := session.Username
+	enableSecret := ""
+	if profile, ok := loadCredentialProfile(session.CredsID); ok {
+		if profile.Username != "" {
+			username = profile.Username
+		}
+		if profile.Password != "" {
+			password = profile.Password
+		}
+		enableSecret = profile.EnableSecret
+	}
+	if enableSecret == "" {
+		enableSecret = password
+	}
+	return strings.NewReplacer("{username}", username, "{password}", password, "{enable_secret}", enableSecret)
+}
+
+// errExpectClosed ends a wait whose session went away
+var errExpectClosed = errors.New("session closed")
+
+// ansiSequence matches escape sequences, stripped before matching output;
+// incompleteEscape matches one cut off at the end of a read
+var (
+	ansiSequence     = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\^_]|[\x00\x07]`)
+	incompleteEscape = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*|\][^\x07\x1b]*)?$`)
+)
+
+// expectOverlap is how far back a wait searches output it has already
+// searched, for matches that span reads
+const expectOverlap = 4096
+
+// expectBuffer collects session output, without escape sequences, for
+// expect-style matching
+type expectBuffer struct {
+	mutex    sync.Mutex
+	text     []byte // Output not yet consumed by a match
+	pending  []byte // Escape sequence cut off at the end of the last read
+	searched int    // Length of text already searched
+	limit    int    // Oldest output is dropped beyond this
+
+	notify chan struct{} // Signalled when output arrives
+	done   chan struct{} // Closed when the session disconnects
+	once   sync.Once
+}
+
+// newExpectBuffer starts collecting up to limit bytes of output
+func newExpectBuffer(limit int) *expectBuffer {
+	return &expectBuffer{
+		limit:  limit,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// feed adds output
+func (b *expectBuffer) feed(data []byte) {
+	b.mutex.Lock()
+	raw := append(b.pending, data...)
+	b.pending = nil
+	if loc := incompleteEscape.FindIndex(raw); loc != nil && len(raw)-loc[0] < expectOverlap {
+		b.pending = append([]byte(nil), raw[loc[0]:]...)
+		raw = raw[:loc[0]]
+	}
+	b.text = append(b.text, ansiSequence.ReplaceAll(raw, nil)...)
+	if drop := len(b.text) - b.limit; drop > 0 {
+		b.text = append(b.text[:0], b.text[drop:]...)
+		b.searched = max(b.searched-drop, 0)
+	}
+	b.mutex.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// discard drops output seen so far, before sending
+func (b *expectBuffer) discard() {
+	b.mutex.Lock()
+	b.text = b.text[:0]
+	b.searched = 0
+	b.mutex.Unlock()
+}
+
+// stop ends waits with errExpectClosed
+func (b *expectBuffer) stop() {
+	b.once.Do(func() { close(b.done) })
+}
+
+// find looks for a pattern in output not searched yet, consuming output up
+// to the end of a match
+func (b *expectBuffer) find(pattern *regexp.Regexp) (before, match string, ok bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Start at a line start so ^ only matches real line starts
+	from := max(b.searched-expectOverlap, 0)
+	from = bytes.LastIndexByte(b.text[:from], '\n') + 1
+	loc := pattern.FindIndex(b.text[from:])
+	if loc == nil {
+		b.searched = len(b.text)
+		return "", "", false
+	}
+
+	before = string(b.text[:from+loc[0]])
+	match = string(b.text[from+loc[0] : from+loc[1]])
+	b.text = append(b.text[:0], b.text[from+loc[1]:]...)
+	b.searched = 0
+	return before, match, true
+}
+
+// expect waits for a pattern in the output, consuming output up to the end
+// of the match, and returns the text before the match and the match
+func (b *expectBuffer) expect(pattern *regexp.Regexp, timeout time.Duration) (string, string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if before, match, ok := b.find(pattern); ok {
+			return before, match, nil
+		}
+
+		select {
+		case <-b.notify:
+		case <-timer.C:
+			return "", "", fmt.Errorf("timed out waiting for %s", pattern)
+		case <-b.done:
+			return "", "", errExpectClosed
+		}
+	}
+}
+
+// run runs the steps against a session's output, reporting each through
+// progress, and returns the index of a failed step and its error
+func (s *LoginScript) run(buf *expectBuffer, write func([]byte) error, secrets *strings.Replacer, progress func(status string)) (int, error) {
+	lastMatch := ""
+	for i, step := range s.Steps {
+		progress(fmt.Sprintf("%s %d/%d", s.Name, i+1, len(s.Steps)))
+		if step.when != nil && !step.when.MatchString(lastMatch) {
+			continue
+		}
+
+		if step.Send != "" {
+			buf.discard()
+			if err := write([]byte(secrets.Replace(sendEscapes.Replace(step.Send)))); err != nil {
+				return i, err
+			}
+		}
+
+		if step.expect != nil {
+			_, match, err := buf.expect(step.expect, time.Duration(step.Timeout)*time.Second)
+			if err != nil && !step.Optional {
+				return i, err
+			}
+			if err == nil {
+				lastMatch = match
+			}
+		}
+	}
+	return -1, nil
+}
+
+// loginScriptOutput passes session output to a running script
+func (w *SSHTerminalWidget) loginScriptOutput(data []byte) {
+	w.loginMutex.Lock()
+	buf := w.loginOutput
+	w.loginMutex.Unlock()
+	if buf != nil {
+		buf.feed(data)
+	}
+}
+
+// StartLoginCapture collects output for a login script from now on, so the
+// first prompt is not missed while connecting. Call it before ConnectSSH.
+func (w *SSHTerminalWidget) StartLoginCapture() {
+	w.loginMutex.Lock()
+	w.loginOutput = newExpectBuffer(maxLoginOutput)
+	w.loginMutex.Unlock()
+}
+
+// StopLoginScript stops a running script and output capture
+func (w *SSHTerminalWidget) StopLoginScript() {
+	w.loginMutex.Lock()
+	buf := w.loginOutput
+	w.loginOutput = nil
+	w.loginMutex.Unlock()
+	if buf != nil {
+		buf.stop()
+	}
+}
+
+// RunLoginScript runs a script's steps, reporting each through progress;
+// an empty status means done. A failed step stops the script and leaves
+// the session open.
+func (w *SSHTerminalWidget) RunLoginScript(script *LoginScript, secrets *strings.Replacer, progress func(status string)) {
+	w.loginMutex.Lock()
+	buf := w.loginOutput
+	w.loginMutex.Unlock()
+	if buf == nil {
+		return
+	}
+	defer w.StopLoginScript()
+
+	log.Printf("Login script %s: starting for %s", script.Name, w.sshConfig.Host)
+	step, err := script.run(buf, w.WriteToPTY, secrets, progress)
+	switch {
+	case errors.Is(err, errExpectClosed):
+		log.Printf("Login script %s: stopped, %s disconnected", script.Name, w.sshConfig.Host)
+	case err != nil:
+		log.Printf("Login script %s: step %d failed for %s: %v", script.Name, step+1, w.sshConfig.Host, err)
+		progress(fmt.Sprintf("%s failed at step %d", script.Name, step+1))
+		time.AfterFunc(loginStatusLinger, func() { progress("") })
+	default:
+		log.Printf("Login script %s: finished for %s", script.Name, w.sshConfig.Host)
+		progress("")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLoginScripts(t *testing.T) {
+	const yaml = `
+scripts:
+  - name: lab
+    device_types: [lab_os]
+    steps:
+      - expect: '[>#]\s*$'
+      - when: '>\s*$'
+        send: 'enable\r'
+        expect: '#\s*$'
+        timeout: 3
+      - send: 'terminal pager 0\r'
+        optional: true
+`
+	scripts, err := parseLoginScripts([]byte(yaml))
+	if err != nil {
+		t.Fatalf("parseLoginScripts: %v", err)
+	}
+	if len(scripts) != 1 || scripts[0].Name != "lab" || len(scripts[0].Steps) != 3 {
+		t.Fatalf("scripts = %+v", scripts)
+	}
+
+	tests := []struct {
+		name     string
+		step     LoginStep
+		when     string
+		expect   string
+		send     string
+		timeout  int
+		optional bool
+	}{
+		{"Expect only", scripts[0].Steps[0], "", `[>#]\s*$`, "", defaultLoginStepTimeout, false},
+		{"When and timeout", scripts[0].Steps[1], `>\s*$`, `#\s*$`, `enable\r`, 3, false},
+		{"Optional send", scripts[0].Steps[2], "", "", `terminal pager 0\r`, defaultLoginStepTimeout, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.step
+			if s.Send != tc.send || s.Timeout != tc.timeout || s.Optional != tc.optional {
+				t.Errorf("step = %+v", s)
+			}
+			if (s.when == nil) != (tc.when == "") || s.when != nil && s.when.String() != tc.when {
+				t.Errorf("when = %v, want %q", s.when, tc.when)
+			}
+			if (s.expect == nil) != (tc.expect == "") || s.expect != nil && s.expect.String() != tc.expect {
+				t.Errorf("expect = %v, want %q", s.expect, tc.expect)
+			}
+		})
+	}
+}
+
+func TestParseLoginScriptErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"No name", "scripts:\n  - steps: []\n", "script 1 has no name"},
+		{"Bad when", "scripts:\n  - name: x\n    steps:\n      - when: '('\n", "script x step 1"},
+		{"Bad expect", "scripts:\n  - name: x\n    steps:\n      - expect: 'a'\n      - expect: '['\n", "script x step 2"},
+		{"Not YAML", "scripts: [", "yaml"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseLoginScripts([]byte(tc.yaml)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpectBufferSplitReads(t *testing.T) {
+	tests := []struct {
+		name       string
+		reads      []string
+		pattern    string
+		wantBefore string
+		wantMatch  string
+		wantOK     bool
+	}{
+		{"One read", []string{"banner\r\nRouter#"}, `\w+#\s*$`, "banner\r\n", "Router#", true},
+		{"Prompt split", []string{"banner\r\nRou", "ter", "#"}, `Router#\s*$`, "banner\r\n", "Router#", true},
+		{"Escape sequence split", []string{"Router\x1b[", "1m#"}, `Router#\s*$`, "", "Router#", true},
+		{"OSC split", []string{"\x1b]0;ti", "tle\x07Password:"}, `(?i)password:\s*$`, "", "Password:", true},
+		{"Line start across reads", []string{"no Router#", "\r\n", "Router#"}, `(?m)^Router#$`, "no Router#\r\n", "Router#", true},
+		{"No match", []string{"Router", ">"}, `#\s*$`, "", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := newExpectBuffer(maxLoginOutput)
+			pattern := regexp.MustCompile(tc.pattern)
+			var before, match string
+			var ok bool
+			// Search after every read, as a waiting step does
+			for _, read := range tc.reads {
+				buf.feed([]byte(read))
+				if before, match, ok = buf.find(pattern); ok {
+					break
+				}
+			}
+			if ok != tc.wantOK || before != tc.wantBefore || match != tc.wantMatch {
+				t.Errorf("find = %q, %q, %v; want %q, %q, %v", before, match, ok, tc.wantBefore, tc.wantMatch, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestLoginScriptRun(t *testing.T) {
+	scripts, err := parseLoginScripts([]byte(builtinLoginScripts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cisco := &scripts[0]
+
+	// A device that starts in user mode and asks for the enable secret
+	replies := map[string]string{
+		"enable\r":             "\r\nPassword: ",
+		"s3cret\r":             "\r\nRouter#",
+		"terminal length 0\r":  "\r\nRouter#",
+		"terminal width 511\r": "\r\nRouter#",
+	}
+	buf := newExpectBuffer(maxLoginOutput)
+	var sent []string
+	write := func(data []byte) error {
+		sent = append(sent, string(data))
+		buf.feed([]byte(replies[string(data)]))
+		return nil
+	}
+
+	buf.feed([]byte("Welcome\r\nRouter>"))
+	done := make(chan struct{})
+	var step int
+	go func() {
+		step, err = cisco.run(buf, write, strings.NewReplacer("{enable_secret}", "s3cret"), func(string) {})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not finish")
+	}
+
+	if err != nil {
+		t.Fatalf("step %d failed: %v", step+1, err)
+	}
+	want := []string{"enable\r", "s3cret\r", "terminal length 0\r", "terminal width 511\r"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
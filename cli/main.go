@@ -38,11 +38,16 @@ func main() {
 	// Create session manager
 	sessionManager := NewSessionManager(myWindow)
 	myWindow.SetContent(sessionManager.GetContainer())
+	sessionManager.ApplyBackupSchedule(s)
+
+	// Scan for fonts now so the settings dialog rarely waits for them
+	go installedMonospaceFonts()
 
 	// Set up settings save callback for live theme updates
 	settings.SetOnSave(func(newSettings *AppSettings) {
 		myApp.Settings().SetTheme(NewNativeTheme(newSettings.DarkTheme))
-		log.Printf("Settings updated - theme applied")
+		sessionManager.ApplySettings(newSettings)
+		log.Printf("Settings updated - theme, scrollback, triggers and highlighting applied")
 	})
 
 	// Close interceptor with window size saving
@@ -49,4 +49,52 @@ func GetSettingsPath() string {
 // GetSessionsFilePath returns the path to sessions.yaml (~/.velocitycmd/sessions/sessions.yaml)
 func GetSessionsFilePath() string {
 	return filepath.Join(GetSessionsDir(), "sessions.yaml")
-}
\ No newline at end of file
+}
+
+// GetLoginScriptsPath returns the path to the user's login scripts
+// (~/.velocitycmd/login_scripts.yaml)
+func GetLoginScriptsPath() string {
+	return filepath.Join(GetAppHome(), "login_scripts.yaml")
+}
+
+// GetCredentialsPath returns the path to the credential profiles that
+// sessions name by Creds ID (~/.velocitycmd/credentials.yaml)
+func GetCredentialsPath() string {
+	return filepath.Join(GetAppHome(), "credentials.yaml")
+}
+
+// GetHighlightProfilesPath returns the path to the user's highlighting profiles
+// (~/.velocitycmd/highlight_profiles.yaml)
+func GetHighlightProfilesPath() string {
+	return filepath.Join(GetAppHome(), "highlight_profiles.yaml")
+}
+
+// GetBackupsDir returns the directory holding configuration backups
+// (~/.velocitycmd/backups/<folder>/<session>/<timestamp>.txt)
+func GetBackupsDir() string {
+	return filepath.Join(GetAppHome(), "backups")
+}
+
+// GetSnippetsPath returns the path to the snippet library
+// (~/.velocitycmd/snippets.yaml)
+func GetSnippetsPath() string {
+	return filepath.Join(GetAppHome(), "snippets.yaml")
+}
+
+// GetKeymapPath returns the path to the user's key bindings
+// (~/.velocitycmd/keymap.yaml)
+func GetKeymapPath() string {
+	return filepath.Join(GetAppHome(), "keymap.yaml")
+}
+
+// GetFontsDir returns the directory searched first for terminal fonts
+// (~/.velocitycmd/fonts)
+func GetFontsDir() string {
+	return filepath.Join(GetAppHome(), "fonts")
+}
+
+// GetColorSchemesPath returns the path to the user's terminal color schemes
+// (~/.velocitycmd/color_schemes.yaml)
+func GetColorSchemesPath() string {
+	return filepath.Join(GetAppHome(), "color_schemes.yaml")
+}
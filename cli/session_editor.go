@@ -324,6 +324,16 @@ func (e *SessionEditor) showSessionFormDialog(title string, session SessionInfo,
 	credsIDEntry.SetText(session.CredsID)
 	credsIDEntry.SetPlaceHolder("Credentials reference")
 
+	clipboardSelect := widget.NewSelect(clipboardPolicyOptions(true), nil)
+	clipboardSelect.SetSelected(clipboardPolicyToLabel(session.ClipboardPolicy))
+
+	loginScriptEntry := widget.NewEntry()
+	loginScriptEntry.SetText(session.LoginScript)
+	loginScriptEntry.SetPlaceHolder("By device type; none to skip")
+
+	colorSchemeSelect := widget.NewSelect(colorSchemeOptions(true), nil)
+	colorSchemeSelect.SetSelected(colorSchemeToLabel(session.ColorScheme, true))
+
 	// Toggle key path based on auth type
 	authSelect.OnChanged = func(s string) {
 		if s == "SSH Key" {
@@ -353,6 +363,9 @@ func (e *SessionEditor) showSessionFormDialog(title string, session SessionInfo,
 		widget.NewFormItem("Vendor", vendorEntry),
 		widget.NewFormItem("Model", modelEntry),
 		widget.NewFormItem("Creds ID", credsIDEntry),
+		widget.NewFormItem("Remote Clipboard", clipboardSelect),
+		widget.NewFormItem("Login Script", loginScriptEntry),
+		widget.NewFormItem("Color Scheme", colorSchemeSelect),
 	}
 
 	d := dialog.NewForm(title, "Save", "Cancel", items,
@@ -400,6 +413,10 @@ func (e *SessionEditor) showSessionFormDialog(title string, session SessionInfo,
 				Model:         modelEntry.Text,
 				CredsID:       credsIDEntry.Text,
 				Group:         e.selectedFolder,
+
+				ClipboardPolicy: clipboardLabelToPolicy(clipboardSelect.Selected),
+				LoginScript:     loginScriptEntry.Text,
+				ColorScheme:     colorSchemeLabelToName(colorSchemeSelect.Selected),
 			}
 
 			// Default display name to user@host if not provided
@@ -489,6 +506,10 @@ func (e *SessionEditor) showImportDialog() {
 						Vendor:        sess.Vendor,
 						Model:         sess.Model,
 						CredsID:       sess.CredsID,
+
+						ClipboardPolicy: sess.ClipboardPolicy,
+						LoginScript:     sess.LoginScript,
+						ColorScheme:     sess.ColorScheme,
 					})
 					imported++
 
@@ -564,6 +585,9 @@ func (s *SessionStore) UpdateSession(sessionID string, updated SessionInfo) bool
 					CredsID:         updated.CredsID,
 					SerialNumber:    s.folders[fi].Sessions[si].SerialNumber,
 					SoftwareVersion: s.folders[fi].Sessions[si].SoftwareVersion,
+					ClipboardPolicy: updated.ClipboardPolicy,
+					LoginScript:     updated.LoginScript,
+					ColorScheme:     updated.ColorScheme,
 				}
 				log.Printf("Updated session %s: AuthType=%s, KeyPath=%s",
 					sessionID, updated.AuthType, updated.KeyPath)
@@ -39,6 +39,11 @@ type SessionYAML struct {
 	SoftwareVersion string `yaml:"SoftwareVersion,omitempty"`
 	Vendor          string `yaml:"Vendor,omitempty"`
 	CredsID         string `yaml:"credsid,omitempty"`
+
+	// Terminal behavior (TetherSSH extensions)
+	ClipboardPolicy string `yaml:"clipboard_policy,omitempty"` // OSC 52: deny, write, readwrite; empty = settings
+	LoginScript     string `yaml:"login_script,omitempty"`     // Script name; empty = by DeviceType, "none" = off
+	ColorScheme     string `yaml:"color_scheme,omitempty"`     // Scheme name; empty = settings
 }
 
 // SessionStore handles loading and saving sessions
@@ -261,6 +266,10 @@ func (s *SessionStore) yamlToSessionInfo(folderName string, index int, sess Sess
 		Vendor:     sess.Vendor,
 		Model:      sess.Model,
 		CredsID:    sess.CredsID,
+
+		ClipboardPolicy: sess.ClipboardPolicy,
+		LoginScript:     sess.LoginScript,
+		ColorScheme:     sess.ColorScheme,
 	}
 }
 
@@ -278,6 +287,10 @@ func (s *SessionStore) sessionInfoToYAML(session SessionInfo) SessionYAML {
 		Vendor:        session.Vendor,
 		Model:         session.Model,
 		CredsID:       session.CredsID,
+
+		ClipboardPolicy: session.ClipboardPolicy,
+		LoginScript:     session.LoginScript,
+		ColorScheme:     session.ColorScheme,
 	}
 }
 
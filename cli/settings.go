@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"tetherssh/internal/gopyte"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
@@ -44,8 +47,19 @@ type AppSettings struct {
 	ColOffset int `json:"col_offset"` // Column adjustment for terminal sizing (default: 0)
 	FontSize  int `json:"font_size"`  // Terminal font size in points (default: 12)
 
+	// Terminal font file, any monospace TTF or OTF (default: "" for the bundled font)
+	FontFamily string `json:"font_family"`
+
+	// Row height as a multiple of the font's line height, 1.0-2.0 (default: 1.0)
+	LineHeight float64 `json:"line_height"`
+
+	// Cursor (hosts may override both with DECSCUSR)
+	CursorShape string `json:"cursor_shape"` // block, underline or bar (default: block)
+	CursorBlink bool   `json:"cursor_blink"` // Blink the cursor (default: false)
+
 	// Appearance
-	DarkTheme bool `json:"dark_theme"` // Use dark theme (default: true)
+	DarkTheme   bool   `json:"dark_theme"`   // Use dark theme (default: true)
+	ColorScheme string `json:"color_scheme"` // Terminal color scheme (default: "" for the theme colors)
 
 	// Theme Color Overrides
 	DarkThemeColors  ColorOverrides `json:"dark_theme_colors"`
@@ -59,6 +73,7 @@ type AppSettings struct {
 	// Connection
 	ConnectionTimeout int `json:"connection_timeout"` // SSH connection timeout in seconds (default: 30)
 	KeepaliveInterval int `json:"keepalive_interval"` // Keepalive interval in seconds (default: 60)
+	RunLoginScripts   bool `json:"run_login_scripts"`  // Run login scripts after connecting (default: true)
 
 	// Logging
 	EnableLogging bool   `json:"enable_logging"` // Enable per-session logging (default: false)
@@ -66,8 +81,24 @@ type AppSettings struct {
 	TimestampLogs bool   `json:"timestamp_logs"` // Add timestamps to log entries (default: true)
 
 	// Terminal Behavior
-	ScrollbackLines int  `json:"scrollback_lines"` // Number of scrollback lines (default: 1000)
-	CopyOnSelect    bool `json:"copy_on_select"`   // Copy to clipboard on selection (default: false)
+	ScrollbackLines int    `json:"scrollback_lines"` // Number of scrollback lines (default: 1000)
+	ScrollbackSpill bool   `json:"scrollback_spill"` // Keep old scrollback in a temp file (default: false)
+	CopyOnSelect    bool   `json:"copy_on_select"`   // Copy to clipboard on selection (default: false)
+	ClipboardPolicy string `json:"clipboard_policy"` // OSC 52 access: deny, write, readwrite (default: write)
+
+	// Keyword highlighting of uncolored output by session DeviceType
+	SyntaxHighlighting bool `json:"syntax_highlighting"` // (default: true)
+
+	// Prompt regex per session DeviceType, for hosts without OSC 133 marks
+	PromptPatterns map[string]string `json:"prompt_patterns"`
+
+	// Output triggers, for all sessions or by folder or device type
+	Triggers []TriggerRule `json:"triggers"`
+
+	// Configuration backups
+	BackupCommands      map[string]string `json:"backup_commands"`       // Config command per session DeviceType
+	BackupIntervalHours int               `json:"backup_interval_hours"` // Scheduled backups, 0 for off (default: 0)
+	BackupFolders       []string          `json:"backup_folders"`        // Folders to back up, empty for all
 
 	// Window
 	RememberWindowSize bool `json:"remember_window_size"` // Remember window size on exit (default: true)
@@ -86,6 +117,12 @@ func DefaultSettings() *AppSettings {
 		ColOffset: 0,
 		FontSize:  12,
 
+		LineHeight: defaultLineHeight,
+
+		// Cursor
+		CursorShape: CursorShapeBlock,
+		CursorBlink: false,
+
 		// Appearance
 		DarkTheme: true,
 
@@ -101,6 +138,7 @@ func DefaultSettings() *AppSettings {
 		// Connection
 		ConnectionTimeout: 30,
 		KeepaliveInterval: 60,
+		RunLoginScripts:   true,
 
 		// Logging
 		EnableLogging: false,
@@ -109,7 +147,16 @@ func DefaultSettings() *AppSettings {
 
 		// Terminal Behavior
 		ScrollbackLines: 1000,
+		ScrollbackSpill: false,
 		CopyOnSelect:    false,
+		ClipboardPolicy: ClipboardPolicyWrite,
+		PromptPatterns:  copyPromptPatterns(defaultPromptPatterns),
+
+		SyntaxHighlighting: true,
+
+		// Configuration backups
+		BackupCommands:      maps.Clone(defaultBackupCommands),
+		BackupIntervalHours: 0,
 
 		// Window
 		RememberWindowSize: true,
@@ -630,30 +677,97 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 	fontSizeEntry := widget.NewEntry()
 	fontSizeEntry.SetText(strconv.Itoa(editSettings.FontSize))
 	fontSizeEntry.SetPlaceHolder("12")
-	fontSizeEntry.Disable() // TODO: Not yet implemented
+
+	lineHeightEntry := widget.NewEntry()
+	lineHeightEntry.SetText(strconv.FormatFloat(editSettings.LineHeight, 'f', -1, 64))
+	lineHeightEntry.SetPlaceHolder("1.0")
+
+	// Fonts are listed by name and saved by path. The list fills in once
+	// the font scan is done, keeping whatever is selected by then.
+	var fontPaths map[string]string
+	fontFamilySelect := widget.NewSelect(nil, nil)
+	setFontOptions := func(fonts []MonospaceFont, path string) {
+		fontPaths = map[string]string{fontFamilyBundledLabel: ""}
+		fontOptions := []string{fontFamilyBundledLabel}
+		fontSelected := fontFamilyBundledLabel
+		for _, font := range fonts {
+			fontPaths[font.Name] = font.Path
+			fontOptions = append(fontOptions, font.Name)
+			if font.Path == path {
+				fontSelected = font.Name
+			}
+		}
+		if fontSelected == fontFamilyBundledLabel && path != "" {
+			// Keep a font that is set but not found (yet)
+			fontPaths[path] = path
+			fontOptions = append(fontOptions, path)
+			fontSelected = path
+		}
+		fontFamilySelect.Options = fontOptions
+		fontFamilySelect.SetSelected(fontSelected)
+	}
+	setFontOptions(nil, editSettings.FontFamily)
+	loadMonospaceFonts(func(fonts []MonospaceFont) {
+		setFontOptions(fonts, fontPaths[fontFamilySelect.Selected])
+	})
+
+	cursorShapeSelect := widget.NewSelect(cursorShapeOptions(), nil)
+	cursorShapeSelect.SetSelected(cursorShapeToLabel(editSettings.CursorShape))
+
+	cursorBlinkCheck := widget.NewCheck("Blinking cursor", nil)
+	cursorBlinkCheck.SetChecked(editSettings.CursorBlink)
 
 	scrollbackEntry := widget.NewEntry()
 	scrollbackEntry.SetText(strconv.Itoa(editSettings.ScrollbackLines))
 	scrollbackEntry.SetPlaceHolder("1000")
-	scrollbackEntry.Disable() // TODO: Not yet implemented
+
+	scrollbackSpillCheck := widget.NewCheck(
+		fmt.Sprintf("Keep scrollback beyond %d lines in a temp file", gopyte.ScrollbackMemoryLines), nil)
+	scrollbackSpillCheck.SetChecked(editSettings.ScrollbackSpill)
 
 	copyOnSelectCheck := widget.NewCheck("Copy text to clipboard when selected", nil)
 	copyOnSelectCheck.SetChecked(editSettings.CopyOnSelect)
 	copyOnSelectCheck.Disable() // TODO: Not yet implemented
 
+	clipboardPolicySelect := widget.NewSelect(clipboardPolicyOptions(false), nil)
+	clipboardPolicySelect.SetSelected(clipboardPolicyToLabel(editSettings.ClipboardPolicy))
+	if clipboardPolicySelect.Selected == clipboardPolicyDefaultLabel {
+		clipboardPolicySelect.SetSelected(clipboardPolicyToLabel(ClipboardPolicyWrite))
+	}
+
+	syntaxHighlightingCheck := widget.NewCheck("Highlight device output (interfaces, addresses, states)", nil)
+	syntaxHighlightingCheck.SetChecked(editSettings.SyntaxHighlighting)
+
+	promptPatternsEntry := widget.NewMultiLineEntry()
+	promptPatternsEntry.SetText(formatPromptPatterns(editSettings.PromptPatterns))
+	promptPatternsEntry.SetPlaceHolder("cisco_ios = ^[\\w.-]+[>#]")
+	promptPatternsEntry.SetMinRowsVisible(4)
+
 	terminalForm := widget.NewForm(
 		widget.NewFormItem("Row Offset", container.NewBorder(nil, nil, nil,
 			widget.NewLabel("(increase for Retina: 4)"), rowOffsetEntry)),
 		widget.NewFormItem("Column Offset", colOffsetEntry),
+		widget.NewFormItem("Font", fontFamilySelect),
 		widget.NewFormItem("Font Size", fontSizeEntry),
+		widget.NewFormItem("Line Height", container.NewBorder(nil, nil, nil,
+			widget.NewLabel("(times the font's, e.g. 1.2)"), lineHeightEntry)),
+		widget.NewFormItem("Cursor Shape", cursorShapeSelect),
+		widget.NewFormItem("", cursorBlinkCheck),
 		widget.NewFormItem("Scrollback Lines", scrollbackEntry),
+		widget.NewFormItem("", scrollbackSpillCheck),
 		widget.NewFormItem("", copyOnSelectCheck),
+		widget.NewFormItem("Remote Clipboard (OSC 52)", clipboardPolicySelect),
+		widget.NewFormItem("", syntaxHighlightingCheck),
+		widget.NewFormItem("Prompt Patterns", promptPatternsEntry),
 	)
 
 	terminalTab := container.NewVBox(
 		widget.NewLabel("Terminal Display Settings"),
 		widget.NewSeparator(),
 		terminalForm,
+		widget.NewLabel("Prompt patterns find prompts by device type when the host\n"+
+			"sends no shell integration marks (Ctrl+Shift+Up/Down to jump)."),
+		widget.NewLabel("Fonts dropped into "+GetFontsDir()+" are listed after a restart."),
 	)
 
 	// === Appearance Tab ===
@@ -663,15 +777,32 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 	rememberSizeCheck := widget.NewCheck("Remember window size on exit", nil)
 	rememberSizeCheck.SetChecked(editSettings.RememberWindowSize)
 
+	// Terminal colors, previewed as they are picked
+	colorSchemePreview := createColorSchemePreview(editSettings.ColorScheme)
+	colorSchemeSelect := widget.NewSelect(colorSchemeOptions(false), func(label string) {
+		refreshColorSchemePreview(colorSchemePreview, colorSchemeLabelToName(label))
+	})
+	colorSchemeSelect.SetSelected(colorSchemeToLabel(editSettings.ColorScheme, false))
+	importSchemeBtn := widget.NewButtonWithIcon("Import...", theme.FolderOpenIcon(), func() {
+		showColorSchemeImport(window, func(names []string) {
+			colorSchemeSelect.Options = colorSchemeOptions(false)
+			colorSchemeSelect.SetSelected(names[0])
+		})
+	})
+
 	appearanceForm := widget.NewForm(
 		widget.NewFormItem("", darkThemeCheck),
 		widget.NewFormItem("", rememberSizeCheck),
+		widget.NewFormItem("Terminal Colors", container.NewBorder(nil, nil, nil, importSchemeBtn, colorSchemeSelect)),
+		widget.NewFormItem("", colorSchemePreview),
 	)
 
 	appearanceTab := container.NewVBox(
 		widget.NewLabel("Appearance Settings"),
 		widget.NewSeparator(),
 		appearanceForm,
+		widget.NewLabel("Import .itermcolors, Windows Terminal JSON or base16 YAML schemes.\n"+
+			"Sessions can pick their own scheme in the session editor."),
 	)
 
 	// === Colors Tab ===
@@ -719,12 +850,16 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 	keepaliveEntry.SetPlaceHolder("60")
 	keepaliveEntry.Disable() // TODO: Not yet implemented
 
+	loginScriptsCheck := widget.NewCheck("Run login scripts after connecting", nil)
+	loginScriptsCheck.SetChecked(editSettings.RunLoginScripts)
+
 	sshForm := widget.NewForm(
 		widget.NewFormItem("Default SSH Key", defaultKeyEntry),
 		widget.NewFormItem("Default Port", defaultPortEntry),
 		widget.NewFormItem("Default Username", defaultUserEntry),
 		widget.NewFormItem("Connection Timeout (s)", timeoutEntry),
 		widget.NewFormItem("Keepalive Interval (s)", keepaliveEntry),
+		widget.NewFormItem("", loginScriptsCheck),
 	)
 
 	sshTab := container.NewVBox(
@@ -761,6 +896,96 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 		widget.NewLabel("Log files: {session_name}_{timestamp}.log"),
 	)
 
+	// === Triggers Tab ===
+	triggersEntry := widget.NewMultiLineEntry()
+	triggersEntry.SetText(formatTriggerRules(editSettings.Triggers))
+	triggersEntry.SetPlaceHolder("highlight bold red : %LINEPROTO-5-UPDOWN.*down")
+	triggersEntry.SetMinRowsVisible(8)
+
+	triggersTab := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Output Triggers"),
+			widget.NewSeparator(),
+		),
+		widget.NewLabel("One rule per line:  [scope] action argument : regex\n"+
+			"Scope: none for all sessions, [folder:<name>] or [device:<type>]\n"+
+			"Actions: highlight <style>, notify <message>, bell, send <text>,\n"+
+			"start_log [name], stop_log. Add ! to the action (send!) to also match\n"+
+			"prompts that do not end the line. $1 inserts a match group, \\r a return."),
+		nil, nil,
+		triggersEntry,
+	)
+
+	// === Backups Tab ===
+	backupCommandsEntry := widget.NewMultiLineEntry()
+	backupCommandsEntry.SetText(formatBackupCommands(editSettings.BackupCommands))
+	backupCommandsEntry.SetPlaceHolder("cisco_ios = show running-config")
+	backupCommandsEntry.SetMinRowsVisible(6)
+
+	backupIntervalEntry := widget.NewEntry()
+	backupIntervalEntry.SetText(strconv.Itoa(editSettings.BackupIntervalHours))
+	backupIntervalEntry.SetPlaceHolder("0")
+
+	backupFoldersEntry := widget.NewEntry()
+	backupFoldersEntry.SetText(strings.Join(editSettings.BackupFolders, ", "))
+	backupFoldersEntry.SetPlaceHolder("(all folders)")
+
+	backupsForm := widget.NewForm(
+		widget.NewFormItem("Backup Commands", backupCommandsEntry),
+		widget.NewFormItem("Schedule (hours)", container.NewBorder(nil, nil, nil,
+			widget.NewLabel("(0 = off)"), backupIntervalEntry)),
+		widget.NewFormItem("Folders", backupFoldersEntry),
+	)
+
+	backupsTab := container.NewVBox(
+		widget.NewLabel("Configuration Backups"),
+		widget.NewSeparator(),
+		backupsForm,
+		widget.NewLabel("One command per device type:  device_type = command\n"+
+			"Captures are saved to "+GetBackupsDir()+"/<folder>/<session>.\n"+
+			"Scheduled backups use stored passwords and credential profiles."),
+	)
+
+	// === Keys Tab ===
+	keymapFile, keymapErr := loadKeymapFile()
+	if keymapErr != nil {
+		log.Printf("Keymap: %v", keymapErr)
+	}
+
+	keymapStyleSelect := widget.NewSelect(keymapStyleOptions(), nil)
+	keymapDefaultsLabel := widget.NewLabel("")
+	keymapStyleSelect.OnChanged = func(label string) {
+		keymapDefaultsLabel.SetText("Defaults:\n" + formatKeyBindings(defaultKeyBindings(keymapLabelToStyle(label))))
+	}
+	if keymapFile.Style != "" {
+		keymapStyleSelect.SetSelected(keymapStyleToLabel(keymapFile.Style))
+	} else {
+		keymapStyleSelect.SetSelected(keymapStyleToLabel(defaultKeymapStyle()))
+	}
+
+	keyBindingsEntry := widget.NewMultiLineEntry()
+	keyBindingsEntry.SetText(formatKeyBindings(keymapFile.Bindings))
+	keyBindingsEntry.SetPlaceHolder("Ctrl+Shift+K = send \\e[15~")
+	keyBindingsEntry.SetMinRowsVisible(5)
+
+	keysForm := widget.NewForm(
+		widget.NewFormItem("Style", keymapStyleSelect),
+		widget.NewFormItem("Bindings", keyBindingsEntry),
+	)
+
+	keysTab := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Key Bindings"),
+			widget.NewSeparator(),
+			keysForm,
+			widget.NewLabel("One binding per line:  chord = action [text]. Bindings replace the\n"+
+				"defaults for their chord; none gives the chord back to the host.\n"+
+				"Actions: "+strings.Join(keyActions, ", ")),
+		),
+		nil, nil, nil,
+		container.NewVScroll(keymapDefaultsLabel),
+	)
+
 	// === Create Tabs ===
 	tabs := container.NewAppTabs(
 		container.NewTabItemWithIcon("Terminal", theme.ComputerIcon(), terminalTab),
@@ -768,6 +993,9 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 		container.NewTabItemWithIcon("Colors", theme.ColorChromaticIcon(), colorsTab),
 		container.NewTabItemWithIcon("SSH", theme.SettingsIcon(), sshTab),
 		container.NewTabItemWithIcon("Logging", theme.DocumentIcon(), loggingTab),
+		container.NewTabItemWithIcon("Triggers", theme.WarningIcon(), triggersTab),
+		container.NewTabItemWithIcon("Backups", theme.DownloadIcon(), backupsTab),
+		container.NewTabItemWithIcon("Keys", theme.AccountIcon(), keysTab),
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
 
@@ -806,10 +1034,16 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 				parseErrors = append(parseErrors, "Column Offset must be a number")
 			}
 
-			if v, err := strconv.Atoi(fontSizeEntry.Text); err == nil && v > 0 {
+			if v, err := strconv.Atoi(fontSizeEntry.Text); err == nil && v >= minFontSize && v <= maxFontSize {
 				editSettings.FontSize = v
 			} else {
-				parseErrors = append(parseErrors, "Font Size must be a positive number")
+				parseErrors = append(parseErrors, fmt.Sprintf("Font Size must be %d-%d", minFontSize, maxFontSize))
+			}
+
+			if v, err := strconv.ParseFloat(lineHeightEntry.Text, 64); err == nil && v >= minLineHeight && v <= maxLineHeight {
+				editSettings.LineHeight = v
+			} else {
+				parseErrors = append(parseErrors, fmt.Sprintf("Line Height must be %.1f-%.1f", minLineHeight, maxLineHeight))
 			}
 
 			if v, err := strconv.Atoi(scrollbackEntry.Text); err == nil && v > 0 {
@@ -836,6 +1070,32 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 				parseErrors = append(parseErrors, "Keepalive must be a non-negative number")
 			}
 
+			if v, err := parsePromptPatterns(promptPatternsEntry.Text); err == nil {
+				editSettings.PromptPatterns = v
+			} else {
+				parseErrors = append(parseErrors, fmt.Sprintf("Prompt Patterns %v", err))
+			}
+
+			if v, err := parseTriggerRules(triggersEntry.Text); err == nil {
+				editSettings.Triggers = v
+			} else {
+				parseErrors = append(parseErrors, fmt.Sprintf("Triggers %v", err))
+			}
+
+			if v, err := parseBackupCommands(backupCommandsEntry.Text); err == nil {
+				editSettings.BackupCommands = v
+			} else {
+				parseErrors = append(parseErrors, fmt.Sprintf("Backup Commands %v", err))
+			}
+
+			if v, err := strconv.Atoi(backupIntervalEntry.Text); err == nil && v >= 0 {
+				editSettings.BackupIntervalHours = v
+			} else {
+				parseErrors = append(parseErrors, "Backup schedule must be a non-negative number of hours")
+			}
+
+			newKeymapFile := keymapFileFromEditor(keymapStyleSelect.Selected, keyBindingsEntry.Text, &parseErrors)
+
 			// Validate color hex values
 			allColorEntries := make(map[string]*widget.Entry)
 			for k, v := range darkEntries {
@@ -860,7 +1120,15 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 			}
 
 			// Get remaining values
+			editSettings.CursorShape = cursorLabelToShape(cursorShapeSelect.Selected)
+			editSettings.ColorScheme = colorSchemeLabelToName(colorSchemeSelect.Selected)
+			editSettings.FontFamily = fontPaths[fontFamilySelect.Selected]
+			editSettings.CursorBlink = cursorBlinkCheck.Checked
+			editSettings.ScrollbackSpill = scrollbackSpillCheck.Checked
 			editSettings.CopyOnSelect = copyOnSelectCheck.Checked
+			editSettings.ClipboardPolicy = clipboardLabelToPolicy(clipboardPolicySelect.Selected)
+			editSettings.SyntaxHighlighting = syntaxHighlightingCheck.Checked
+			editSettings.RunLoginScripts = loginScriptsCheck.Checked
 			editSettings.DarkTheme = darkThemeCheck.Checked
 			editSettings.RememberWindowSize = rememberSizeCheck.Checked
 			editSettings.DefaultKeyPath = defaultKeyEntry.Text
@@ -868,6 +1136,12 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 			editSettings.EnableLogging = enableLoggingCheck.Checked
 			editSettings.LogDirectory = logDirEntry.Text
 			editSettings.TimestampLogs = timestampCheck.Checked
+			editSettings.BackupFolders = nil
+			for _, folder := range strings.Split(backupFoldersEntry.Text, ",") {
+				if folder = strings.TrimSpace(folder); folder != "" {
+					editSettings.BackupFolders = append(editSettings.BackupFolders, folder)
+				}
+			}
 
 			// Get color overrides from entries
 			editSettings.DarkThemeColors = editDarkColors
@@ -882,6 +1156,11 @@ func (sm *SettingsManager) ShowSettingsDialog(window fyne.Window) {
 				return
 			}
 
+			if err := saveKeymapFile(newKeymapFile); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+
 			// Notify listeners
 			if sm.onSave != nil {
 				sm.onSave(sm.settings)
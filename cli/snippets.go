@@ -0,0 +1,235 @@
+// snippets.go - Named text snippets with variables, sent to terminals
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Snippets live in snippets.yaml in the app home directory:
+//
+//	snippets:
+//	  - name: Interface status
+//	    folder: Cisco
+//	    shortcut: Ctrl+Alt+1
+//	    text: |
+//	      show interface {{interface}} status
+//
+// {{host}}, {{username}}, {{name}}, {{port}}, {{folder}} and {{device_type}}
+// come from the session; any other {{var}} is asked for before sending.
+
+// defaultSnippetLineDelay is the pause between lines sent one at a time
+const defaultSnippetLineDelay = 500 * time.Millisecond
+
+// snippetVariable matches a {{var}} placeholder
+var snippetVariable = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Snippet is a named block of text to type into terminals
+type Snippet struct {
+	Name      string `yaml:"name"`
+	Folder    string `yaml:"folder,omitempty"`
+	Text      string `yaml:"text"`
+	Shortcut  string `yaml:"shortcut,omitempty"`      // e.g. Ctrl+Alt+1
+	LineDelay int    `yaml:"line_delay_ms,omitempty"` // Line by line pause (default: 500)
+}
+
+// snippetsFile is the layout of snippets.yaml
+type snippetsFile struct {
+	Snippets []Snippet `yaml:"snippets"`
+}
+
+// SnippetStore loads and saves the snippet library
+type SnippetStore struct {
+	path     string
+	snippets []Snippet
+	mutex    sync.RWMutex
+}
+
+// NewSnippetStore creates a store for a snippets file
+func NewSnippetStore(path string) *SnippetStore {
+	return &SnippetStore{path: path}
+}
+
+// Load reads the snippets file; a missing file is an empty library
+func (s *SnippetStore) Load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read snippets: %w", err)
+	}
+
+	var file snippetsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse snippets: %w", err)
+	}
+	for i, snippet := range file.Snippets {
+		if snippet.Shortcut == "" {
+			continue
+		}
+		if _, err := parseKeyChord(snippet.Shortcut); err != nil {
+			log.Printf("Snippets: %s: %v", snippet.Name, err)
+			file.Snippets[i].Shortcut = ""
+		}
+	}
+
+	s.mutex.Lock()
+	s.snippets = file.Snippets
+	s.sortLocked()
+	s.mutex.Unlock()
+	log.Printf("Loaded %d snippets from %s", len(file.Snippets), s.path)
+	return nil
+}
+
+// Save writes the snippets file
+func (s *SnippetStore) Save() error {
+	s.mutex.RLock()
+	data, err := yaml.Marshal(snippetsFile{Snippets: s.snippets})
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal snippets: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write snippets: %w", err)
+	}
+	return nil
+}
+
+// GetSnippets returns a copy of the snippets, sorted by folder and name
+func (s *SnippetStore) GetSnippets() []Snippet {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]Snippet(nil), s.snippets...)
+}
+
+// Put adds a snippet, replacing the one at old's folder and name if set
+func (s *SnippetStore) Put(old *Snippet, snippet Snippet) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if old != nil {
+		s.removeLocked(*old)
+	}
+	s.removeLocked(snippet)
+	s.snippets = append(s.snippets, snippet)
+	s.sortLocked()
+}
+
+// Delete removes a snippet by folder and name
+func (s *SnippetStore) Delete(snippet Snippet) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removeLocked(snippet)
+}
+
+// ForShortcut returns the snippet bound to a key chord
+func (s *SnippetStore) ForShortcut(modifier fyne.KeyModifier, key fyne.KeyName) (Snippet, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, snippet := range s.snippets {
+		if snippet.Shortcut == "" {
+			continue
+		}
+		if chord, err := parseKeyChord(snippet.Shortcut); err == nil && chord.Modifier == modifier && chord.Key == key {
+			return snippet, true
+		}
+	}
+	return Snippet{}, false
+}
+
+func (s *SnippetStore) removeLocked(snippet Snippet) {
+	kept := s.snippets[:0]
+	for _, existing := range s.snippets {
+		if existing.Folder != snippet.Folder || existing.Name != snippet.Name {
+			kept = append(kept, existing)
+		}
+	}
+	s.snippets = kept
+}
+
+func (s *SnippetStore) sortLocked() {
+	sort.SliceStable(s.snippets, func(i, j int) bool {
+		if s.snippets[i].Folder != s.snippets[j].Folder {
+			return s.snippets[i].Folder < s.snippets[j].Folder
+		}
+		return s.snippets[i].Name < s.snippets[j].Name
+	})
+}
+
+// snippetPromptVariables returns the {{var}} names not filled from the
+// session, in order of first use
+func snippetPromptVariables(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range snippetVariable.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := sessionVariable(SessionInfo{}, name); ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// sessionVariable returns a placeholder's value taken from the session
+func sessionVariable(session SessionInfo, name string) (string, bool) {
+	switch name {
+	case "host":
+		return session.Host, true
+	case "username":
+		return session.Username, true
+	case "name":
+		return session.Name, true
+	case "port":
+		return strconv.Itoa(session.Port), true
+	case "folder":
+		return session.Group, true
+	case "device_type":
+		return session.DeviceType, true
+	}
+	return "", false
+}
+
+// expandSnippet fills a snippet's placeholders for a session; prompted
+// values are keyed by lowercase name
+func expandSnippet(text string, session SessionInfo, values map[string]string) string {
+	return snippetVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := strings.ToLower(snippetVariable.FindStringSubmatch(placeholder)[1])
+		if value, ok := sessionVariable(session, name); ok {
+			return value
+		}
+		return values[name]
+	})
+}
+
+// snippetInput turns snippet text into terminal input, with newlines typed
+// as Enter
+func snippetInput(text string) []byte {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return []byte(strings.ReplaceAll(text, "\n", "\r"))
+}
+
+// snippetLines splits snippet text into lines for sending one at a time
+func snippetLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineDelay returns the pause between lines sent one at a time
+func (s Snippet) lineDelay() time.Duration {
+	if s.LineDelay > 0 {
+		return time.Duration(s.LineDelay) * time.Millisecond
+	}
+	return defaultSnippetLineDelay
+}
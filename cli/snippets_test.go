@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandSnippet(t *testing.T) {
+	session := SessionInfo{
+		Name:       "core-1",
+		Host:       "10.0.0.1",
+		Port:       22,
+		Username:   "netops",
+		Group:      "Lab",
+		DeviceType: "cisco_ios",
+	}
+
+	tests := []struct {
+		name       string
+		text       string
+		values     map[string]string
+		wantPrompt []string
+		want       string
+		wantInput  string
+		wantLines  []string
+	}{
+		{
+			name:      "Session variables",
+			text:      "ssh {{username}}@{{host}} -p {{port}} # {{name}} {{folder}} {{device_type}}",
+			want:      "ssh netops@10.0.0.1 -p 22 # core-1 Lab cisco_ios",
+			wantInput: "ssh netops@10.0.0.1 -p 22 # core-1 Lab cisco_ios",
+			wantLines: []string{"ssh netops@10.0.0.1 -p 22 # core-1 Lab cisco_ios"},
+		},
+		{
+			name:       "Prompted variables",
+			text:       "interface {{ Interface }}\n description {{desc}} on {{HOST}}\n shutdown {{interface}}\n",
+			values:     map[string]string{"interface": "Gi0/1", "desc": "uplink"},
+			wantPrompt: []string{"interface", "desc"},
+			want:       "interface Gi0/1\n description uplink on 10.0.0.1\n shutdown Gi0/1\n",
+			wantInput:  "interface Gi0/1\r description uplink on 10.0.0.1\r shutdown Gi0/1\r",
+			wantLines:  []string{"interface Gi0/1", " description uplink on 10.0.0.1", " shutdown Gi0/1"},
+		},
+		{
+			name:       "Missing value",
+			text:       "ping {{target}}",
+			wantPrompt: []string{"target"},
+			want:       "ping ",
+			wantInput:  "ping ",
+			wantLines:  []string{"ping "},
+		},
+		{
+			name:      "CRLF lines",
+			text:      "show clock\r\nshow version\r\n",
+			want:      "show clock\r\nshow version\r\n",
+			wantInput: "show clock\rshow version\r",
+			wantLines: []string{"show clock", "show version"},
+		},
+		{
+			name:      "Blank lines kept",
+			text:      "conf t\n\nend",
+			want:      "conf t\n\nend",
+			wantInput: "conf t\r\rend",
+			wantLines: []string{"conf t", "", "end"},
+		},
+		{
+			name:      "Not a placeholder",
+			text:      "echo {{ }} {host}",
+			want:      "echo {{ }} {host}",
+			wantInput: "echo {{ }} {host}",
+			wantLines: []string{"echo {{ }} {host}"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := snippetPromptVariables(tc.text); !reflect.DeepEqual(got, tc.wantPrompt) {
+				t.Errorf("snippetPromptVariables = %q, want %q", got, tc.wantPrompt)
+			}
+			got := expandSnippet(tc.text, session, tc.values)
+			if got != tc.want {
+				t.Errorf("expandSnippet = %q, want %q", got, tc.want)
+			}
+			if input := string(snippetInput(got)); input != tc.wantInput {
+				t.Errorf("snippetInput = %q, want %q", input, tc.wantInput)
+			}
+			if lines := snippetLines(got); !reflect.DeepEqual(lines, tc.wantLines) {
+				t.Errorf("snippetLines = %q, want %q", lines, tc.wantLines)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+// snippets_view.go - Snippet panel under the session tree
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Ways of sending a snippet
+const (
+	snippetSendActive    = iota // Whole text to the active tab
+	snippetSendBroadcast        // Whole text to every broadcast target
+	snippetSendLines            // Line by line to the active tab
+)
+
+// snippetNodeSeparator joins folder and name in snippet tree node IDs
+const snippetNodeSeparator = "\x00"
+
+// loadSnippets opens the snippet library
+func (sm *SessionManager) loadSnippets() {
+	sm.snippetStore = NewSnippetStore(GetSnippetsPath())
+	if err := sm.snippetStore.Load(); err != nil {
+		log.Printf("Warning: Could not load snippets: %v", err)
+	}
+}
+
+// buildSnippetPanel creates the snippet tree with its toolbar and send
+// buttons
+func (sm *SessionManager) buildSnippetPanel() fyne.CanvasObject {
+	sm.snippetTree = widget.NewTree(
+		sm.snippetChildren,
+		func(uid widget.TreeNodeID) bool {
+			return uid == "" || strings.HasPrefix(uid, "folder:")
+		},
+		func(branch bool) fyne.CanvasObject {
+			icon := widget.NewIcon(theme.DocumentIcon())
+			if branch {
+				icon.SetResource(theme.FolderIcon())
+			}
+			return container.NewHBox(icon, widget.NewLabel("Snippet Name"), widget.NewLabel(""))
+		},
+		func(uid widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+			row := obj.(*fyne.Container)
+			name := row.Objects[1].(*widget.Label)
+			shortcut := row.Objects[2].(*widget.Label)
+			if branch {
+				name.SetText(strings.TrimPrefix(uid, "folder:"))
+				shortcut.SetText("")
+				return
+			}
+			if snippet, ok := sm.snippetByNode(uid); ok {
+				name.SetText(snippet.Name)
+				shortcut.SetText(snippet.Shortcut)
+			}
+		},
+	)
+	sm.snippetTree.OnSelected = func(uid widget.TreeNodeID) {
+		sm.selectedSnippetID = uid
+	}
+	sm.snippetTree.OnUnselected = func(widget.TreeNodeID) {
+		sm.selectedSnippetID = ""
+	}
+
+	title := widget.NewLabelWithStyle("Snippets", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	addBtn := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		sm.showSnippetEditor(nil)
+	})
+	addBtn.Importance = widget.LowImportance
+	editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+		if snippet, ok := sm.selectedSnippet(); ok {
+			sm.showSnippetEditor(&snippet)
+		}
+	})
+	editBtn.Importance = widget.LowImportance
+	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		if snippet, ok := sm.selectedSnippet(); ok {
+			sm.confirmDeleteSnippet(snippet)
+		}
+	})
+	deleteBtn.Importance = widget.LowImportance
+	targetsBtn := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), func() {
+		sm.showBroadcastTargetsDialog()
+	})
+	targetsBtn.Importance = widget.LowImportance
+	header := container.NewBorder(nil, nil, title,
+		container.NewHBox(addBtn, editBtn, deleteBtn, targetsBtn))
+
+	send := func(mode int) func() {
+		return func() {
+			if snippet, ok := sm.selectedSnippet(); ok {
+				sm.sendSnippet(snippet, mode)
+			}
+		}
+	}
+	footer := container.NewGridWithColumns(3,
+		widget.NewButton("Send", send(snippetSendActive)),
+		widget.NewButton("Broadcast", send(snippetSendBroadcast)),
+		widget.NewButton("Line by Line", send(snippetSendLines)),
+	)
+
+	return container.NewBorder(header, footer, nil, nil, container.NewVScroll(sm.snippetTree))
+}
+
+// snippetChildren lists folders at the root and snippets in each folder;
+// snippets without a folder sit at the root
+func (sm *SessionManager) snippetChildren(uid widget.TreeNodeID) []widget.TreeNodeID {
+	var children []widget.TreeNodeID
+	for _, snippet := range sm.snippetStore.GetSnippets() {
+		switch {
+		case uid == "" && snippet.Folder != "":
+			if folder := "folder:" + snippet.Folder; !slices.Contains(children, folder) {
+				children = append(children, folder)
+			}
+		case uid == "folder:"+snippet.Folder || (uid == "" && snippet.Folder == ""):
+			children = append(children, snippet.Folder+snippetNodeSeparator+snippet.Name)
+		}
+	}
+	return children
+}
+
+// snippetByNode returns the snippet of a tree node ID
+func (sm *SessionManager) snippetByNode(uid widget.TreeNodeID) (Snippet, bool) {
+	folder, name, ok := strings.Cut(uid, snippetNodeSeparator)
+	if !ok {
+		return Snippet{}, false
+	}
+	for _, snippet := range sm.snippetStore.GetSnippets() {
+		if snippet.Folder == folder && snippet.Name == name {
+			return snippet, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// selectedSnippet returns the snippet selected in the panel
+func (sm *SessionManager) selectedSnippet() (Snippet, bool) {
+	snippet, ok := sm.snippetByNode(sm.selectedSnippetID)
+	if !ok {
+		dialog.ShowInformation("Snippets", "Select a snippet first.", sm.window)
+	}
+	return snippet, ok
+}
+
+// showSnippetEditor adds a snippet, or edits one when snippet is set
+func (sm *SessionManager) showSnippetEditor(snippet *Snippet) {
+	nameEntry := widget.NewEntry()
+	folderEntry := widget.NewEntry()
+	folderEntry.SetPlaceHolder("(none)")
+	textEntry := widget.NewMultiLineEntry()
+	textEntry.SetPlaceHolder("show interface {{interface}} status")
+	textEntry.SetMinRowsVisible(6)
+	shortcutEntry := widget.NewEntry()
+	shortcutEntry.SetPlaceHolder("e.g. Ctrl+Alt+1")
+	delayEntry := widget.NewEntry()
+	delayEntry.SetPlaceHolder(strconv.Itoa(int(defaultSnippetLineDelay / time.Millisecond)))
+
+	title := "New Snippet"
+	if snippet != nil {
+		title = "Edit Snippet"
+		nameEntry.SetText(snippet.Name)
+		folderEntry.SetText(snippet.Folder)
+		textEntry.SetText(snippet.Text)
+		shortcutEntry.SetText(snippet.Shortcut)
+		if snippet.LineDelay > 0 {
+			delayEntry.SetText(strconv.Itoa(snippet.LineDelay))
+		}
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Folder", folderEntry),
+		widget.NewFormItem("Text", textEntry),
+		widget.NewFormItem("Shortcut", shortcutEntry),
+		widget.NewFormItem("Line Delay (ms)", delayEntry),
+		widget.NewFormItem("", widget.NewLabel("{{host}}, {{username}}, {{name}}, {{port}}, {{folder}} and\n"+
+			"{{device_type}} come from the session; other {{var}} are asked for.")),
+	}
+
+	d := dialog.NewForm(title, "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		updated := Snippet{
+			Name:     strings.TrimSpace(nameEntry.Text),
+			Folder:   strings.TrimSpace(folderEntry.Text),
+			Text:     textEntry.Text,
+			Shortcut: strings.TrimSpace(shortcutEntry.Text),
+		}
+		if updated.Name == "" {
+			dialog.ShowError(fmt.Errorf("name is required"), sm.window)
+			return
+		}
+		if updated.Shortcut != "" {
+			chord, err := parseKeyChord(updated.Shortcut)
+			if err != nil {
+				dialog.ShowError(err, sm.window)
+				return
+			}
+			if binding, ok := CurrentKeymap().Lookup(chord.Modifier, chord.Key); ok {
+				dialog.ShowError(fmt.Errorf("%s is bound to %s in the keymap", updated.Shortcut, binding.Action), sm.window)
+				return
+			}
+			if other, ok := sm.snippetStore.ForShortcut(chord.Modifier, chord.Key); ok &&
+				(snippet == nil || other.Folder != snippet.Folder || other.Name != snippet.Name) {
+				dialog.ShowError(fmt.Errorf("%s is already bound to %q", updated.Shortcut, other.Name), sm.window)
+				return
+			}
+		}
+		if text := strings.TrimSpace(delayEntry.Text); text != "" {
+			delay, err := strconv.Atoi(text)
+			if err != nil || delay < 0 {
+				dialog.ShowError(fmt.Errorf("line delay must be a non-negative number"), sm.window)
+				return
+			}
+			updated.LineDelay = delay
+		}
+
+		sm.snippetStore.Put(snippet, updated)
+		if err := sm.snippetStore.Save(); err != nil {
+			dialog.ShowError(err, sm.window)
+		}
+		sm.snippetTree.Refresh()
+	}, sm.window)
+	d.Resize(fyne.NewSize(550, 500))
+	d.Show()
+}
+
+// confirmDeleteSnippet deletes a snippet after asking
+func (sm *SessionManager) confirmDeleteSnippet(snippet Snippet) {
+	dialog.ShowConfirm("Delete Snippet", fmt.Sprintf("Delete snippet %q?", snippet.Name), func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		sm.snippetStore.Delete(snippet)
+		if err := sm.snippetStore.Save(); err != nil {
+			dialog.ShowError(err, sm.window)
+		}
+		sm.selectedSnippetID = ""
+		sm.snippetTree.UnselectAll()
+		sm.snippetTree.Refresh()
+	}, sm.window)
+}
+
+// activeSessionTab returns the session in the selected tab. In a split tab
+// it is the pane whose terminal had focus last.
+func (sm *SessionManager) activeSessionTab() *SessionTab {
+	selected := sm.tabContainer.Selected()
+	if selected == nil {
+		return nil
+	}
+
+	sm.tabsMutex.RLock()
+	defer sm.tabsMutex.RUnlock()
+	var active *SessionTab
+	for _, sessionTab := range sm.activeTabs {
+		if sessionTab.Tab != selected {
+			continue
+		}
+		if active == nil || sessionTab.Terminal.focusedAt.After(active.Terminal.focusedAt) {
+			active = sessionTab
+		}
+	}
+	return active
+}
+
+// broadcastTabs returns the connected sessions marked as broadcast targets
+func (sm *SessionManager) broadcastTabs() []*SessionTab {
+	sm.tabsMutex.RLock()
+	defer sm.tabsMutex.RUnlock()
+
+	var tabs []*SessionTab
+	for _, sessionTab := range sm.activeTabs {
+		if sessionTab.Broadcast && sessionTab.Terminal.IsSSHConnected() {
+			tabs = append(tabs, sessionTab)
+		}
+	}
+	return tabs
+}
+
+// showBroadcastTargetsDialog picks the open sessions that receive
+// broadcast snippets
+func (sm *SessionManager) showBroadcastTargetsDialog() {
+	sm.tabsMutex.RLock()
+	var tabs []*SessionTab
+	for _, sessionTab := range sm.activeTabs {
+		tabs = append(tabs, sessionTab)
+	}
+	sm.tabsMutex.RUnlock()
+	if len(tabs) == 0 {
+		dialog.ShowInformation("Broadcast Targets", "There are no open sessions.", sm.window)
+		return
+	}
+	slices.SortFunc(tabs, func(a, b *SessionTab) int { return strings.Compare(a.Tab.Text, b.Tab.Text) })
+
+	var labels, selected []string
+	byLabel := make(map[string]*SessionTab)
+	for _, sessionTab := range tabs {
+		label := sessionTab.Tab.Text
+		for n := 2; byLabel[label] != nil; n++ {
+			label = fmt.Sprintf("%s [%d]", sessionTab.Tab.Text, n)
+		}
+		byLabel[label] = sessionTab
+		labels = append(labels, label)
+		if sessionTab.Broadcast {
+			selected = append(selected, label)
+		}
+	}
+	checks := widget.NewCheckGroup(labels, nil)
+	checks.SetSelected(selected)
+
+	dialog.ShowCustomConfirm("Broadcast Targets", "OK", "Cancel", container.NewVScroll(checks), func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		for label, sessionTab := range byLabel {
+			sessionTab.Broadcast = slices.Contains(checks.Selected, label)
+		}
+	}, sm.window)
+}
+
+// handleSnippetShortcut sends the snippet bound to a key chord to the
+// active tab, reporting whether one was bound
+func (sm *SessionManager) handleSnippetShortcut(shortcut fyne.KeyboardShortcut) bool {
+	snippet, ok := sm.snippetStore.ForShortcut(shortcut.Mod(), shortcut.Key())
+	if !ok {
+		return false
+	}
+	sm.sendSnippet(snippet, snippetSendActive)
+	return true
+}
+
+// sendSnippet asks for any prompted variables, then types the snippet
+// into the target sessions
+func (sm *SessionManager) sendSnippet(snippet Snippet, mode int) {
+	var targets []*SessionTab
+	if mode == snippetSendBroadcast {
+		targets = sm.broadcastTabs()
+		if len(targets) == 0 {
+			dialog.ShowInformation("Broadcast", "No connected session is a broadcast target.", sm.window)
+			return
+		}
+	} else if tab := sm.activeSessionTab(); tab != nil && tab.Terminal.IsSSHConnected() {
+		targets = []*SessionTab{tab}
+	} else {
+		dialog.ShowInformation("Snippets", "The active tab is not a connected session.", sm.window)
+		return
+	}
+
+	send := func(values map[string]string) {
+		for _, target := range targets {
+			text := expandSnippet(snippet.Text, target.Info, values)
+			if mode == snippetSendLines {
+				go typeSnippetLines(target.Terminal, snippetLines(text), snippet.lineDelay())
+			} else if err := target.Terminal.WriteToPTY(snippetInput(text)); err != nil {
+				log.Printf("Snippets: sending %q to %s: %v", snippet.Name, target.Info.Name, err)
+			}
+		}
+		log.Printf("Snippets: sent %q to %d sessions", snippet.Name, len(targets))
+	}
+
+	names := snippetPromptVariables(snippet.Text)
+	if len(names) == 0 {
+		send(nil)
+		return
+	}
+
+	entries := make([]*widget.Entry, len(names))
+	items := make([]*widget.FormItem, len(names))
+	for i, name := range names {
+		entries[i] = widget.NewEntry()
+		items[i] = widget.NewFormItem(name, entries[i])
+	}
+	d := dialog.NewForm(snippet.Name, "Send", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		values := make(map[string]string, len(names))
+		for i, name := range names {
+			values[name] = entries[i].Text
+		}
+		send(values)
+	}, sm.window)
+	d.Resize(fyne.NewSize(400, 0))
+	d.Show()
+	sm.window.Canvas().Focus(entries[0])
+}
+
+// typeSnippetLines types lines one at a time with a pause between them,
+// stopping if the session drops
+func typeSnippetLines(terminal *SSHTerminalWidget, lines []string, delay time.Duration) {
+	for i, line := range lines {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+		if !terminal.IsSSHConnected() {
+			return
+		}
+		if err := terminal.WriteToPTY([]byte(line + "\r")); err != nil {
+			log.Printf("Snippets: sending line: %v", err)
+			return
+		}
+	}
+}
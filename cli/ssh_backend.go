@@ -11,6 +11,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"tetherssh/internal/gopyte"
@@ -116,6 +117,7 @@ type SSHConfig struct {
 	// Behavior
 	KeepAliveInterval time.Duration // 0 = disabled
 	KeepAliveMaxCount int           // Max missed keepalives before disconnect
+	NoShell           bool          // Connect without a shell session, for Exec
 }
 
 // DefaultSSHConfig returns a config with sensible defaults
@@ -277,7 +279,7 @@ func (s *SSHBackend) Connect() error {
 	}
 
 	// Connect to server
-	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
 	log.Printf("SSH: Connecting to %s as %s", addr, s.config.Username)
 
 	conn, err := net.DialTimeout("tcp", addr, s.config.Timeout)
@@ -315,12 +317,14 @@ func (s *SSHBackend) Connect() error {
 	s.client = ssh.NewClient(sshConn, chans, reqs)
 
 	// Create session
-	if err := s.createSession(); err != nil {
-		s.client.Close()
-		s.client = nil
-		s.lastError = err
-		s.setState(StateError)
-		return err
+	if !s.config.NoShell {
+		if err := s.createSession(); err != nil {
+			s.client.Close()
+			s.client = nil
+			s.lastError = err
+			s.setState(StateError)
+			return err
+		}
 	}
 
 	s.setState(StateConnected)
@@ -888,6 +892,19 @@ func (s *SSHBackend) GetHostKey() ssh.PublicKey {
 	return s.client.Conn.RemoteAddr().(interface{ PublicKey() ssh.PublicKey }).PublicKey()
 }
 
+// Exec runs a command in its own session and returns its combined output
+func (s *SSHBackend) Exec(command string) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("not connected")
+	}
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+	return session.CombinedOutput(command)
+}
+
 // SendSignal sends a signal to the remote process
 func (s *SSHBackend) SendSignal(sig ssh.Signal) error {
 	if s.session == nil {
@@ -908,6 +925,10 @@ type SSHTerminalWidget struct {
 	sshBackend *SSHBackend
 	sshConfig  SSHConfig
 
+	// Login script output capture
+	loginOutput *expectBuffer
+	loginMutex  sync.Mutex
+
 	// State callbacks
 	onStateChange func(ConnectionState)
 	onError       func(error)
@@ -1018,6 +1039,7 @@ func (w *SSHTerminalWidget) ConnectSSH() error {
 		return fmt.Errorf("screen not initialized")
 	}
 	w.stream = gopyte.NewStream(w.screen, false) // false = parse ANSI
+	w.stream.SetClipboardHandler(w.NativeTerminalWidget)
 
 	// Start reading from SSH and feeding to gopyte
 	go w.sshReadLoop()
@@ -1125,8 +1147,12 @@ func (w *SSHTerminalWidget) sshReadLoop() {
 
 				// Feed to gopyte for terminal emulation
 				if w.stream != nil {
-					w.stream.Feed(string(data))
+					w.mutex.Lock()
+					w.stream.FeedBytes(data)
+					w.mutex.Unlock()
 				}
+				w.recordOutput(data)
+				w.loginScriptOutput(data)
 
 				// Trigger redraw + auto-scroll
 				w.updatePending = true
@@ -1145,6 +1171,8 @@ func (w *SSHTerminalWidget) sshReadLoop() {
 
 // DisconnectWithContext - for graceful app shutdown (used by SessionManager.DisconnectAll)
 func (w *SSHTerminalWidget) DisconnectWithContext(ctx context.Context) {
+	w.StopLoginScript()
+
 	// Capture backend FIRST, before anything can nil it
 	backend := w.sshBackend
 
@@ -1199,6 +1227,7 @@ func (w *SSHTerminalWidget) Close() {
 // WriteToPTY overrides the parent method to write to SSH instead
 func (w *SSHTerminalWidget) WriteToPTY(data []byte) error {
 	if w.sshBackend != nil && w.sshBackend.IsConnected() {
+		w.recordInput(data)
 		_, err := w.sshBackend.Write(data)
 		if err != nil {
 			log.Printf("SSH write error: %v", err)
@@ -1231,6 +1260,7 @@ func (w *SSHTerminalWidget) ResizeTerminal(cols, rows int) {
 			log.Printf("Gopyte screen resized to %dx%d", cols, rows)
 		}()
 	}
+	w.recordResize(cols, rows)
 
 	// Resize SSH session (sends WindowChange to remote)
 	if w.sshBackend != nil && w.sshBackend.IsConnected() {
@@ -39,6 +39,10 @@ type SessionInfo struct {
 	Vendor     string
 	Model      string
 	CredsID    string
+
+	ClipboardPolicy string // OSC 52 policy, "" = use settings
+	LoginScript     string // Login script name, "" = by DeviceType, "none" = off
+	ColorScheme     string // Terminal color scheme, "" = use settings
 }
 
 // SessionManager manages multiple terminal sessions
@@ -57,6 +61,16 @@ type SessionManager struct {
 	filterText       string
 	activeTabs       map[string]*SessionTab
 	tabsMutex        sync.RWMutex
+	toolTabs         map[*container.TabItem]toolView // Replay and runner tabs; UI thread only
+	backups          backupScheduler
+
+	// Snippet library (see snippets_view.go)
+	snippetStore      *SnippetStore
+	snippetTree       *widget.Tree
+	selectedSnippetID string
+
+	// Keymap window actions bound on the canvas (see keymap.go)
+	windowShortcuts []fyne.Shortcut
 	
 	// Tree data structures
 	treeData      map[string][]string // parent -> children mapping
@@ -74,6 +88,11 @@ type SessionManager struct {
 
 }
 
+// toolView is the content of a tab that is not a session
+type toolView interface {
+	Stop() // Called when the tab closes
+}
+
 // SessionTab represents an active terminal tab
 type SessionTab struct {
 	TabID    string
@@ -81,6 +100,15 @@ type SessionTab struct {
 	Terminal *SSHTerminalWidget
 	Tab      *container.TabItem
 	State    ConnectionState
+	Pane     *terminalPane  // The terminal with its find bar
+	Body     *fyne.Container // Tab content: the pane, or both panes when split
+
+	// A split tab has a second pane with its own connection. It shares the
+	// main pane's Tab and is in activeTabs as well. UI thread only.
+	Split   *SessionTab // The second pane of a main pane
+	SplitOf *SessionTab // The main pane, when this is the second pane
+
+	Broadcast bool // Receives broadcast snippets; UI thread only
 }
 
 // NewSessionManager creates a new session manager
@@ -88,13 +116,16 @@ func NewSessionManager(window fyne.Window) *SessionManager {
 	sm := &SessionManager{
 		window:      window,
 		activeTabs:  make(map[string]*SessionTab),
+		toolTabs:    make(map[*container.TabItem]toolView),
 		treeData:    make(map[string][]string),
 		sessionByID: make(map[string]*SessionInfo),
 	}
 	
 	sm.loadSessions()
+	sm.loadSnippets()
 	sm.buildUI()
 	sm.setupKeyboardCapture()
+	sm.applyWindowShortcuts()
 	return sm
 }
 
@@ -283,22 +314,12 @@ func (sm *SessionManager) setupKeyboardCapture() {
 	})
 }
 
-// getActiveTerminal returns the terminal widget in the currently selected tab
+// getActiveTerminal returns the terminal widget in the currently selected
+// tab, the last focused pane of a split tab
 func (sm *SessionManager) getActiveTerminal() *SSHTerminalWidget {
-	selected := sm.tabContainer.Selected()
-	if selected == nil {
-		return nil
+	if sessionTab := sm.activeSessionTab(); sessionTab != nil {
+		return sessionTab.Terminal
 	}
-	
-	sm.tabsMutex.RLock()
-	defer sm.tabsMutex.RUnlock()
-	
-	for _, sessionTab := range sm.activeTabs {
-		if sessionTab.Tab == selected {
-			return sessionTab.Terminal
-		}
-	}
-	
 	return nil
 }
 
@@ -312,6 +333,10 @@ func (sm *SessionManager) buildUI() {
 	sm.tabContainer = container.NewDocTabs()
 	sm.tabContainer.CloseIntercept = sm.handleTabClose
 	
+	// Snippets share the sidebar below the session tree
+	sessionsAndSnippets := container.NewVSplit(container.NewVScroll(sm.sessionTree), sm.buildSnippetPanel())
+	sessionsAndSnippets.SetOffset(0.7)
+	
 	// Create sidebar with search
 	sidebar := container.NewBorder(
 		container.NewVBox(
@@ -320,7 +345,7 @@ func (sm *SessionManager) buildUI() {
 		),
 		sm.buildSidebarFooter(),
 		nil, nil,
-		container.NewVScroll(sm.sessionTree),
+		sessionsAndSnippets,
 	)
 	
 	// Set sidebar width
@@ -588,6 +613,16 @@ func (sm *SessionManager) editSession(session SessionInfo, nodeID string) {
 	modelEntry := widget.NewEntry()
 	modelEntry.SetText(session.Model)
 	
+	clipboardSelect := widget.NewSelect(clipboardPolicyOptions(true), nil)
+	clipboardSelect.SetSelected(clipboardPolicyToLabel(session.ClipboardPolicy))
+	
+	loginScriptEntry := widget.NewEntry()
+	loginScriptEntry.SetText(session.LoginScript)
+	loginScriptEntry.SetPlaceHolder("By device type; none to skip")
+
+	colorSchemeSelect := widget.NewSelect(colorSchemeOptions(true), nil)
+	colorSchemeSelect.SetSelected(colorSchemeToLabel(session.ColorScheme, true))
+	
 	// Toggle key fields based on auth type
 	authSelect.OnChanged = func(s string) {
 		if s == "SSH Key" {
@@ -610,6 +645,9 @@ func (sm *SessionManager) editSession(session SessionInfo, nodeID string) {
 		widget.NewFormItem("Device Type", deviceTypeEntry),
 		widget.NewFormItem("Vendor", vendorEntry),
 		widget.NewFormItem("Model", modelEntry),
+		widget.NewFormItem("Remote Clipboard", clipboardSelect),
+		widget.NewFormItem("Login Script", loginScriptEntry),
+		widget.NewFormItem("Color Scheme", colorSchemeSelect),
 	}
 	
 	d := dialog.NewForm("Edit Session", "Save", "Cancel", items,
@@ -658,6 +696,10 @@ func (sm *SessionManager) editSession(session SessionInfo, nodeID string) {
 				Vendor:        vendorEntry.Text,
 				Model:         modelEntry.Text,
 				CredsID:       session.CredsID,
+				
+				ClipboardPolicy: clipboardLabelToPolicy(clipboardSelect.Selected),
+				LoginScript:     loginScriptEntry.Text,
+				ColorScheme:     colorSchemeLabelToName(colorSchemeSelect.Selected),
 			}
 			
 			// Default display name if empty
@@ -716,7 +758,22 @@ func (sm *SessionManager) buildSidebarHeader() fyne.CanvasObject {
 	})
 	settingsBtn.Importance = widget.LowImportance
 
-	buttons := container.NewHBox(quickBtn, editBtn, addBtn, settingsBtn)
+	replayBtn := widget.NewButtonWithIcon("", theme.MediaVideoIcon(), func() {
+		sm.showOpenRecordingDialog()
+	})
+	replayBtn.Importance = widget.LowImportance
+
+	runBtn := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		sm.showRunOnDevicesDialog()
+	})
+	runBtn.Importance = widget.LowImportance
+
+	backupBtn := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
+		sm.showBackupDialog()
+	})
+	backupBtn.Importance = widget.LowImportance
+
+	buttons := container.NewHBox(quickBtn, editBtn, addBtn, runBtn, backupBtn, replayBtn, settingsBtn)
 
 	return container.NewBorder(nil, nil, title, buttons)
 }
@@ -754,8 +811,15 @@ func getDefaultKeyPath() string {
 
 // showQuickConnectDialog shows a dialog for quick ad-hoc connections
 func (sm *SessionManager) showQuickConnectDialog() {
+	sm.showQuickConnectDialogForHost("")
+}
+
+// showQuickConnectDialogForHost shows the quick connect dialog with the
+// host pre-filled, e.g. from a Ctrl+clicked address in a terminal
+func (sm *SessionManager) showQuickConnectDialogForHost(host string) {
 	hostEntry := widget.NewEntry()
 	hostEntry.SetPlaceHolder("192.168.1.1 or hostname")
+	hostEntry.SetText(host)
 	
 	portEntry := widget.NewEntry()
 	portEntry.SetText("22")
@@ -958,40 +1022,14 @@ func (sm *SessionManager) promptPasswordAndConnect(session SessionInfo) {
 func (sm *SessionManager) doConnect(session SessionInfo, password string) {
 	tabID := uuid.New().String()
 	
-	terminal := NewSSHTerminalWidget(true)
-	
-	sshConfig := DefaultSSHConfig()
-	sshConfig.Host = session.Host
-	sshConfig.Port = session.Port
-	sshConfig.Username = session.Username
-	sshConfig.Password = password
-	
-	if terminal.cols > 0 && terminal.rows > 0 {
-		sshConfig.Cols = terminal.cols
-		sshConfig.Rows = terminal.rows
-	}
-	
-	switch session.AuthType {
-	case AuthPublicKey:
-		sshConfig.PrivateKeyPath = session.KeyPath
-		sshConfig.KeyPassphrase = session.KeyPassphrase
-		sshConfig.UseAgent = false
-		log.Printf("Configured SSH key auth: %s", session.KeyPath)
-	case AuthPassword:
-		sshConfig.UseAgent = false
-	}
-	
-	terminal.SetSSHConfig(sshConfig)
-	
-	terminal.SetAuthUIHandler(func(prompt string, echo bool) (string, error) {
-		return sm.showAuthPrompt(prompt, echo)
-	})
+	terminal := sm.newSessionTerminal(session, sessionSSHConfig(session, session.Username, password))
 	
 	tabName := session.Name
 	sm.tabsMutex.RLock()
 	duplicateCount := 0
 	for _, tab := range sm.activeTabs {
-		if tab.Info.Host == session.Host && tab.Info.Port == session.Port {
+		// Split panes share their tab's name
+		if tab.SplitOf == nil && tab.Info.Host == session.Host && tab.Info.Port == session.Port {
 			duplicateCount++
 		}
 	}
@@ -1000,7 +1038,9 @@ func (sm *SessionManager) doConnect(session SessionInfo, password string) {
 		tabName = fmt.Sprintf("%s (%d)", session.Name, duplicateCount+1)
 	}
 	
-	tabItem := container.NewTabItem(tabName, terminal)
+	pane := newTerminalPane(terminal)
+	body := container.NewStack(pane.content)
+	tabItem := container.NewTabItem(tabName, body)
 	
 	sessionTab := &SessionTab{
 		TabID:    tabID,
@@ -1008,6 +1048,8 @@ func (sm *SessionManager) doConnect(session SessionInfo, password string) {
 		Terminal: terminal,
 		Tab:      tabItem,
 		State:    StateDisconnected,
+		Pane:     pane,
+		Body:     body,
 	}
 	
 terminal.SetStateChangeHandler(func(state ConnectionState) {
@@ -1045,13 +1087,98 @@ terminal.SetStateChangeHandler(func(state ConnectionState) {
 	sm.tabContainer.Append(tabItem)
 	sm.tabContainer.Select(tabItem)
 	
+	sm.connectSessionTab(sessionTab, password, func(status string) {
+		fyne.Do(func() {
+			if sessionTab.State != StateConnected {
+				return
+			}
+			if status == "" {
+				tabItem.Text = tabName
+			} else {
+				tabItem.Text = fmt.Sprintf("%s (%s)", tabName, status)
+			}
+			sm.tabContainer.Refresh()
+		})
+	})
+}
+
+// newSessionTerminal creates a terminal for a saved session with the
+// manager's handlers set
+func (sm *SessionManager) newSessionTerminal(session SessionInfo, sshConfig SSHConfig) *SSHTerminalWidget {
+	terminal := NewSSHTerminalWidget(true)
+	
+	if terminal.cols > 0 && terminal.rows > 0 {
+		sshConfig.Cols = terminal.cols
+		sshConfig.Rows = terminal.rows
+	}
+	
+	terminal.SetSSHConfig(sshConfig)
+	terminal.SetClipboardPolicy(session.ClipboardPolicy, session.Name)
+	terminal.SetColorScheme(session.ColorScheme)
+	terminal.SetDeviceType(session.DeviceType)
+	terminal.SetTriggerSession(session.Name, session.Group, session.DeviceType)
+	terminal.SetQuickConnectHandler(func(host string) {
+		fyne.Do(func() {
+			sm.showQuickConnectDialogForHost(host)
+		})
+	})
+	
+	terminal.SetShortcutHandler(sm.handleSnippetShortcut)
+	terminal.SetKeyActionHandler(sm.handleKeyAction)
+	
+	terminal.SetAuthUIHandler(func(prompt string, echo bool) (string, error) {
+		return sm.showAuthPrompt(prompt, echo)
+	})
+	return terminal
+}
+
+// connectSessionTab connects a session's terminal in the background and
+// runs its login script, reporting the script's progress to onStatus
+func (sm *SessionManager) connectSessionTab(sessionTab *SessionTab, password string, onStatus func(string)) {
+	session := sessionTab.Info
+	terminal := sessionTab.Terminal
+	
+	var script *LoginScript
+	if settings := GetSettings(); settings != nil && settings.Get().RunLoginScripts {
+		script = loginScriptFor(session)
+	}
+	
 	go func() {
+		if script != nil {
+			terminal.StartLoginCapture()
+		}
 		if err := terminal.ConnectSSH(); err != nil {
-			log.Printf("Failed to connect to %s [%s]: %v", session.Name, tabID, err)
+			log.Printf("Failed to connect to %s [%s]: %v", session.Name, sessionTab.TabID, err)
+			terminal.StopLoginScript()
+			return
+		}
+		if script != nil {
+			terminal.RunLoginScript(script, loginSecrets(session, password), onStatus)
 		}
 	}()
 }
 
+// sessionSSHConfig builds the SSH configuration for a saved session,
+// logging in with the given username and password
+func sessionSSHConfig(session SessionInfo, username, password string) SSHConfig {
+	sshConfig := DefaultSSHConfig()
+	sshConfig.Host = session.Host
+	sshConfig.Port = session.Port
+	sshConfig.Username = username
+	sshConfig.Password = password
+	
+	switch session.AuthType {
+	case AuthPublicKey:
+		sshConfig.PrivateKeyPath = session.KeyPath
+		sshConfig.KeyPassphrase = session.KeyPassphrase
+		sshConfig.UseAgent = false
+		log.Printf("Configured SSH key auth: %s", session.KeyPath)
+	case AuthPassword:
+		sshConfig.UseAgent = false
+	}
+	return sshConfig
+}
+
 // showAuthPrompt shows a dialog for authentication prompts
 func (sm *SessionManager) showAuthPrompt(prompt string, echo bool) (string, error) {
 	resultChan := make(chan string, 1)
@@ -1094,27 +1221,44 @@ func (sm *SessionManager) showAuthPrompt(prompt string, echo bool) (string, erro
 func (sm *SessionManager) handleTabClose(tab *container.TabItem) {
 	sm.tabsMutex.Lock()
 	var sessionTab *SessionTab
-	var tabID string
-	for id, st := range sm.activeTabs {
-		if st.Tab == tab {
+	for _, st := range sm.activeTabs {
+		if st.Tab == tab && st.SplitOf == nil {
 			sessionTab = st
-			tabID = id
 			break
 		}
 	}
 	sm.tabsMutex.Unlock()
 
 	if sessionTab == nil {
+		if view, ok := sm.toolTabs[tab]; ok {
+			view.Stop()
+			delete(sm.toolTabs, tab)
+		}
 		sm.tabContainer.Remove(tab)
 		return
 	}
 
-	if sessionTab.State != StateConnected {
+	// Closing a split tab closes both panes
+	panes := []*SessionTab{sessionTab}
+	connected := sessionTab.State == StateConnected
+	if split := sessionTab.Split; split != nil {
+		panes = append(panes, split)
+		connected = connected || split.State == StateConnected
+	}
+
+	if !connected {
 		go func() {
-			sessionTab.Terminal.Disconnect()
+			for _, pane := range panes {
+				pane.Terminal.Disconnect()
+				pane.Terminal.CloseScrollback()
+				pane.Terminal.StopRecording()
+				pane.Terminal.StopSessionLog()
+			}
 			fyne.Do(func() {
 				sm.tabsMutex.Lock()
-				delete(sm.activeTabs, tabID)
+				for _, pane := range panes {
+					delete(sm.activeTabs, pane.TabID)
+				}
 				sm.tabsMutex.Unlock()
 				sm.tabContainer.Remove(tab)
 				sm.sessionTree.Refresh()
@@ -1139,11 +1283,18 @@ func (sm *SessionManager) handleTabClose(tab *container.TabItem) {
 			defer cancel()
 
 			go func() {
-				sessionTab.Terminal.DisconnectWithContext(ctx)
+				for _, pane := range panes {
+					pane.Terminal.DisconnectWithContext(ctx)
+					pane.Terminal.CloseScrollback()
+					pane.Terminal.StopRecording()
+					pane.Terminal.StopSessionLog()
+				}
 
 				fyne.Do(func() {
 					sm.tabsMutex.Lock()
-					delete(sm.activeTabs, tabID)
+					for _, pane := range panes {
+						delete(sm.activeTabs, pane.TabID)
+					}
 					sm.tabsMutex.Unlock()
 
 					sm.tabContainer.Remove(tab)
@@ -1259,6 +1410,25 @@ func (sm *SessionManager) showAddSessionDialog() {
 	d.Show()
 }
 
+// ApplySettings applies saved settings to the open sessions
+func (sm *SessionManager) ApplySettings(settings *AppSettings) {
+	ReloadHighlightProfiles()
+	ReloadKeymap()
+	ReloadColorSchemes()
+	sm.applyWindowShortcuts()
+	sm.ApplyBackupSchedule(settings)
+
+	sm.tabsMutex.RLock()
+	defer sm.tabsMutex.RUnlock()
+
+	for _, tab := range sm.activeTabs {
+		tab.Terminal.ApplyScrollbackSettings(settings)
+		tab.Terminal.ApplyFontSettings()
+		tab.Terminal.ApplyColorScheme()
+		tab.Terminal.ApplyTriggerSettings(settings)
+	}
+}
+
 // DisconnectAll closes all active sessions
 func (sm *SessionManager) DisconnectAll() {
 	// Stop status bar updates
@@ -1283,6 +1453,9 @@ func (sm *SessionManager) DisconnectAll() {
 		go func(t *SessionTab) {
 			defer wg.Done()
 			t.Terminal.DisconnectWithContext(ctx)
+			t.Terminal.CloseScrollback()
+			t.Terminal.StopRecording()
+			t.Terminal.StopSessionLog()
 		}(tab)
 	}
 
@@ -0,0 +1,141 @@
+// terminal_clipboard.go - OSC 52 clipboard integration with per-session policy
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// Clipboard policies for OSC 52 requests from the remote host
+const (
+	ClipboardPolicyDeny      = "deny"      // Ignore all clipboard requests
+	ClipboardPolicyWrite     = "write"     // Host may set the local clipboard
+	ClipboardPolicyReadWrite = "readwrite" // Host may also read it, after confirmation
+)
+
+// clipboardPolicyLabels maps policies to the labels shown in Select widgets
+var clipboardPolicyLabels = []struct {
+	policy string
+	label  string
+}{
+	{ClipboardPolicyDeny, "Deny"},
+	{ClipboardPolicyWrite, "Allow write"},
+	{ClipboardPolicyReadWrite, "Allow read + write (confirm reads)"},
+}
+
+// clipboardPolicyOptions returns the Select options, optionally with a
+// leading "use global setting" entry for per-session editors
+func clipboardPolicyOptions(withDefault bool) []string {
+	var options []string
+	if withDefault {
+		options = append(options, clipboardPolicyDefaultLabel)
+	}
+	for _, p := range clipboardPolicyLabels {
+		options = append(options, p.label)
+	}
+	return options
+}
+
+const clipboardPolicyDefaultLabel = "Default (from settings)"
+
+// clipboardPolicyToLabel converts a policy value to its display label
+func clipboardPolicyToLabel(policy string) string {
+	for _, p := range clipboardPolicyLabels {
+		if p.policy == policy {
+			return p.label
+		}
+	}
+	return clipboardPolicyDefaultLabel
+}
+
+// clipboardLabelToPolicy converts a display label back to a policy value.
+// The default label maps to "" (inherit from settings).
+func clipboardLabelToPolicy(label string) string {
+	for _, p := range clipboardPolicyLabels {
+		if p.label == label {
+			return p.policy
+		}
+	}
+	return ""
+}
+
+// SetClipboardPolicy sets the per-session OSC 52 policy. Empty uses the
+// global setting, which is looked up on every request so changes apply live.
+func (t *NativeTerminalWidget) SetClipboardPolicy(policy string, host string) {
+	t.clipboardPolicy = policy
+	t.clipboardHost = host
+}
+
+// effectiveClipboardPolicy resolves the session policy against settings
+func (t *NativeTerminalWidget) effectiveClipboardPolicy() string {
+	policy := t.clipboardPolicy
+	if policy == "" {
+		policy = GetSettings().Get().ClipboardPolicy
+	}
+	switch policy {
+	case ClipboardPolicyWrite, ClipboardPolicyReadWrite:
+		return policy
+	default:
+		return ClipboardPolicyDeny
+	}
+}
+
+// ClipboardSet implements gopyte.ClipboardHandler (OSC 52 set)
+func (t *NativeTerminalWidget) ClipboardSet(selection string, text string) {
+	if t.effectiveClipboardPolicy() == ClipboardPolicyDeny {
+		log.Printf("OSC 52: denied clipboard write of %d bytes", len(text))
+		return
+	}
+
+	log.Printf("OSC 52: setting clipboard (%s) to %d bytes", selection, len(text))
+	fyne.Do(func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		window.Clipboard().SetContent(text)
+	})
+}
+
+// ClipboardQuery implements gopyte.ClipboardHandler (OSC 52 query).
+// Reads always need the user's confirmation, even when allowed by policy.
+// Only one confirmation is shown at a time; queries arriving while it is
+// open are dropped, so a host cannot flood the user with dialogs.
+func (t *NativeTerminalWidget) ClipboardQuery(selection string) {
+	if t.effectiveClipboardPolicy() != ClipboardPolicyReadWrite {
+		log.Printf("OSC 52: denied clipboard read")
+		return
+	}
+	if !t.clipboardQueryPending.CompareAndSwap(false, true) {
+		log.Printf("OSC 52: dropped clipboard read, one is already waiting for the user")
+		return
+	}
+
+	host := t.clipboardHost
+	if host == "" {
+		host = "The remote host"
+	}
+
+	fyne.Do(func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		dialog.ShowConfirm(
+			"Clipboard Access",
+			fmt.Sprintf("%s is requesting the contents of your clipboard.\n\nAllow it to read the clipboard?", host),
+			func(allow bool) {
+				t.clipboardQueryPending.Store(false)
+				if !allow {
+					log.Printf("OSC 52: user refused clipboard read")
+					return
+				}
+				content := window.Clipboard().Content()
+				reply := gopyte.EncodeClipboardReply(selection, content)
+				if err := t.WriteToPTY([]byte(reply)); err != nil {
+					log.Printf("OSC 52: failed to send clipboard reply: %v", err)
+				}
+			},
+			window,
+		)
+	})
+}
@@ -0,0 +1,122 @@
+// terminal_colors.go - Applying color schemes to a terminal
+package main
+
+import (
+	"image/color"
+	"log"
+)
+
+// initColors resolves the palette and layers the host's OSC color changes
+// over it; the constructor calls it after initFont
+func (t *NativeTerminalWidget) initColors() {
+	t.schemeColors = colorSchemePalette(t.colorScheme)
+
+	var overrides map[int]color.RGBA
+	if t.screen != nil {
+		t.mutex.RLock()
+		overrides, t.colorVersion = t.screen.ColorOverrides()
+		t.mutex.RUnlock()
+	}
+	t.colors = t.schemeColors.withOverrides(overrides)
+	t.textTheme.colors = t.colors
+}
+
+// syncColorOverrides picks up colors the host set or reset since the last
+// redraw (OSC 4/10/11/12, RIS)
+func (t *NativeTerminalWidget) syncColorOverrides() {
+	if t.screen == nil {
+		return
+	}
+	t.mutex.RLock()
+	overrides, version := t.screen.ColorOverrides()
+	t.mutex.RUnlock()
+	if version == t.colorVersion {
+		return
+	}
+
+	t.colorVersion = version
+	t.colors = t.schemeColors.withOverrides(overrides)
+	t.textTheme.colors = t.colors
+	t.refreshColors()
+}
+
+// schemeColor answers OSC color queries for slots the host has not set
+func (t *NativeTerminalWidget) schemeColor(slot int) (color.RGBA, bool) {
+	if t.schemeColors == nil {
+		return color.RGBA{}, false
+	}
+	return t.schemeColors.slotColor(slot)
+}
+
+// deviceResponseQueue is how many replies to host queries can wait for
+// the writer before the parser blocks
+const deviceResponseQueue = 256
+
+// sendDeviceResponse queues a reply to a host query for
+// deviceResponseWriter, so a slow connection can't hold up the parser and
+// replies reach the host in the order it asked
+func (t *NativeTerminalWidget) sendDeviceResponse(data string) {
+	select {
+	case t.responses <- data:
+	case <-t.ctx.Done():
+	}
+}
+
+// deviceResponseWriter writes the queued replies to the host one at a time
+// until the terminal closes
+func (t *NativeTerminalWidget) deviceResponseWriter() {
+	for {
+		select {
+		case data := <-t.responses:
+			if err := t.WriteToPTY([]byte(data)); err != nil {
+				log.Printf("Device response: %v", err)
+			}
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// SetColorScheme sets the per-session color scheme. Empty uses settings.
+func (t *NativeTerminalWidget) SetColorScheme(name string) {
+	t.colorScheme = name
+	t.ApplyColorScheme()
+}
+
+// ApplyColorScheme picks up a changed scheme, theme or scheme file and
+// redraws with it
+func (t *NativeTerminalWidget) ApplyColorScheme() {
+	t.initColors()
+	if t.colorScheme != "" {
+		log.Printf("Color scheme: %s", t.colorScheme)
+	}
+
+	t.refreshColors()
+
+	if t.screen != nil {
+		t.screen.InvalidateCache()
+	}
+	t.updatePending = true
+	t.triggerImmediateRedraw()
+}
+
+// refreshColors redraws what the palette colors outside the cells
+func (t *NativeTerminalWidget) refreshColors() {
+	// Default-colored text comes from the grid's theme
+	t.textOverride.Refresh()
+	if t.background != nil {
+		t.background.FillColor = t.colors.Background()
+		t.background.Refresh()
+	}
+	t.updateCursorOverlay()
+}
+
+// schemeSwatches returns a palette's ANSI colors in index order, for
+// previews
+func (p *terminalPalette) schemeSwatches() []color.Color {
+	swatches := make([]color.Color, len(ansiColorNames))
+	for i, name := range ansiColorNames {
+		swatches[i] = p.ansi(name)
+	}
+	return swatches
+}
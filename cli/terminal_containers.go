@@ -17,7 +17,7 @@ type HybridScrollContainer struct {
 
 // NewHybridScrollContainer creates a new hybrid scroll container
 func NewHybridScrollContainer(terminal *NativeTerminalWidget) *HybridScrollContainer {
-	baseScroll := container.NewScroll(terminal.textGrid)
+	baseScroll := container.NewScroll(terminal.gridContent)
 	baseScroll.SetMinSize(fyne.NewSize(600, 400))
 	baseScroll.Direction = container.ScrollVerticalOnly
 
@@ -34,6 +34,13 @@ func NewHybridScrollContainer(terminal *NativeTerminalWidget) *HybridScrollConta
 func (h *HybridScrollContainer) MouseDown(event *desktop.MouseEvent) {
 	fmt.Printf("HybridScrollContainer.MouseDown: Forwarding to terminal\n")
 	if h.terminal != nil {
+		// Ctrl+click (Cmd+click on macOS) follows links instead of selecting
+		if event.Button == desktop.MouseButtonPrimary &&
+			event.Modifier&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0 &&
+			h.terminal.handleLinkClick(event) {
+			return
+		}
+
 		// Forward directly to terminal's selection manager
 		h.terminal.isSelecting = true
 		if h.terminal.selection != nil {
@@ -0,0 +1,190 @@
+// terminal_cursor.go - Cursor overlay: DECSCUSR shapes, blinking, DECTCEM visibility
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+)
+
+// Cursor shapes selectable in settings
+const (
+	CursorShapeBlock     = "block"
+	CursorShapeUnderline = "underline"
+	CursorShapeBar       = "bar"
+)
+
+// cursorShapeLabels maps shapes to the labels shown in the settings Select
+var cursorShapeLabels = []struct {
+	shape string
+	label string
+}{
+	{CursorShapeBlock, "Block"},
+	{CursorShapeUnderline, "Underline"},
+	{CursorShapeBar, "Bar"},
+}
+
+const (
+	cursorBlinkInterval = 530 * time.Millisecond // Same rate as xterm
+	cursorLineWidth     = 2                      // Thickness of underline and bar cursors
+)
+
+// cursorShapeOptions returns the Select options for the cursor shape
+func cursorShapeOptions() []string {
+	var options []string
+	for _, s := range cursorShapeLabels {
+		options = append(options, s.label)
+	}
+	return options
+}
+
+// cursorShapeToLabel converts a shape to its label, unknown shapes show as Block
+func cursorShapeToLabel(shape string) string {
+	for _, s := range cursorShapeLabels {
+		if s.shape == shape {
+			return s.label
+		}
+	}
+	return cursorShapeLabels[0].label
+}
+
+// cursorLabelToShape converts a Select label back to a shape
+func cursorLabelToShape(label string) string {
+	for _, s := range cursorShapeLabels {
+		if s.label == label {
+			return s.shape
+		}
+	}
+	return CursorShapeBlock
+}
+
+// cursorStyle resolves the cursor shape and blinking. A DECSCUSR style set
+// by the host wins over the settings until the host resets it with CSI 0 SP q.
+func (t *NativeTerminalWidget) cursorStyle() (shape string, blink bool) {
+	shape = CursorShapeBlock
+	if settings := GetSettings(); settings != nil {
+		shape = settings.Get().CursorShape
+		blink = settings.Get().CursorBlink
+	}
+	if t.screen == nil {
+		return shape, blink
+	}
+
+	t.mutex.RLock()
+	style := t.screen.GetCursorStyle()
+	t.mutex.RUnlock()
+	switch style {
+	case gopyte.CursorStyleBlinkBlock, gopyte.CursorStyleSteadyBlock:
+		shape = CursorShapeBlock
+	case gopyte.CursorStyleBlinkUnderline, gopyte.CursorStyleSteadyUnderline:
+		shape = CursorShapeUnderline
+	case gopyte.CursorStyleBlinkBar, gopyte.CursorStyleSteadyBar:
+		shape = CursorShapeBar
+	}
+	if style != gopyte.CursorStyleDefault {
+		// Odd styles blink, even styles are steady
+		blink = style%2 == 1
+	}
+	return shape, blink
+}
+
+// setCursorCell records the visible grid cell the cursor is on, row -1 when
+// it is off screen, and redraws the overlay
+func (t *NativeTerminalWidget) setCursorCell(row, col int) {
+	if row != t.cursorRow || col != t.cursorCol {
+		// Keep the cursor solid while it moves
+		t.cursorBlinkOn = true
+	}
+	t.cursorRow, t.cursorCol = row, col
+	t.updateCursorOverlay()
+}
+
+// updateCursorOverlay positions the cursor rectangle over its cell. The text
+// underneath is left untouched; a block cursor is translucent so it stays
+// readable, and becomes a hollow box while the terminal is unfocused.
+func (t *NativeTerminalWidget) updateCursorOverlay() {
+	rect := t.cursorOverlay
+	if rect == nil {
+		return
+	}
+
+	if t.cursorRow < 0 || t.cursorCol < 0 || t.screen == nil {
+		rect.Hide()
+		return
+	}
+	t.mutex.RLock()
+	hidden := t.screen.IsCursorHidden()
+	t.mutex.RUnlock()
+	if hidden {
+		rect.Hide()
+		return
+	}
+
+	shape, blink := t.cursorStyle()
+	if blink && t.hasFocus && !t.cursorBlinkOn {
+		rect.Hide()
+		return
+	}
+
+	fg := t.colors.Cursor()
+	pos := fyne.NewPos(float32(t.cursorCol)*t.charWidth, float32(t.cursorRow)*t.charHeight)
+	size := fyne.NewSize(t.charWidth, t.charHeight)
+
+	rect.StrokeWidth = 0
+	switch {
+	case !t.hasFocus:
+		rect.FillColor = color.Transparent
+		rect.StrokeColor = fg
+		rect.StrokeWidth = 1
+	case shape == CursorShapeUnderline:
+		rect.FillColor = fg
+		pos.Y += t.charHeight - cursorLineWidth
+		size.Height = cursorLineWidth
+	case shape == CursorShapeBar:
+		rect.FillColor = fg
+		size.Width = cursorLineWidth
+	default:
+		rect.FillColor = withAlpha(fg, 0x99)
+	}
+
+	rect.Move(pos)
+	rect.Resize(size)
+	rect.Show()
+	rect.Refresh()
+}
+
+// withAlpha returns c with its opacity replaced
+func withAlpha(c color.Color, alpha uint8) color.NRGBA {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = alpha
+	return nrgba
+}
+
+// cursorBlinker toggles blinking cursors until the widget is closed
+func (t *NativeTerminalWidget) cursorBlinker() {
+	ticker := time.NewTicker(cursorBlinkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fyne.Do(t.blinkCursor)
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// blinkCursor toggles a blinking cursor. It runs on the UI thread, where
+// the focus and the rest of the cursor state are changed.
+func (t *NativeTerminalWidget) blinkCursor() {
+	if _, blink := t.cursorStyle(); !blink || !t.hasFocus {
+		t.cursorBlinkOn = true
+		return
+	}
+	t.cursorBlinkOn = !t.cursorBlinkOn
+	t.updateCursorOverlay()
+}
@@ -0,0 +1,181 @@
+// terminal_decorations.go - Faint and concealed text, styled and colored underlines, overlines
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Thickness of drawn underlines and overlines
+const decorationStroke = 1
+
+// applyTextAttributes applies the attributes a TextGrid style cannot hold
+// by itself to a cell whose colors are already set: faint text is blended
+// toward the background, concealed text is blanked and a plain underline
+// uses the grid's own. Other underlines and overlines are drawn by
+// updateTextDecorations.
+func (t *NativeTerminalWidget) applyTextAttributes(cell *widget.TextGridCell, style *widget.CustomTextGridStyle, attr gopyte.Attributes) {
+	if attr.Faint {
+		style.FGColor = blendColors(t.cellForeground(style), t.cellBackground(style), 0.5)
+	}
+	if attr.Conceal {
+		cell.Rune = ' '
+	}
+	if attr.Underscore && !needsDrawnUnderline(attr) {
+		style.TextStyle.Underline = true
+	}
+}
+
+// needsDrawnUnderline reports whether an underline is one the TextGrid
+// cannot draw: a style other than single, or a color of its own
+func needsDrawnUnderline(attr gopyte.Attributes) bool {
+	if !attr.Underscore {
+		return false
+	}
+	single := attr.UnderlineStyle == gopyte.UnderlineSingle || attr.UnderlineStyle == gopyte.UnderlineNone
+	return !single || attr.UnderlineColor != ""
+}
+
+// cellForeground returns the text color of a styled cell
+func (t *NativeTerminalWidget) cellForeground(style *widget.CustomTextGridStyle) color.Color {
+	if style.FGColor != nil {
+		return style.FGColor
+	}
+	return t.colors.Foreground()
+}
+
+// cellBackground returns the background color of a styled cell
+func (t *NativeTerminalWidget) cellBackground(style *widget.CustomTextGridStyle) color.Color {
+	if style.BGColor != nil {
+		return style.BGColor
+	}
+	return t.colors.Background()
+}
+
+// blendColors mixes a toward b by amount, 0 giving a and 1 giving b
+func blendColors(a, b color.Color, amount float64) color.Color {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	mix := func(x, y uint32) uint8 {
+		return uint8(math.Round((float64(x)*(1-amount) + float64(y)*amount) / 0x101))
+	}
+	return color.NRGBA{mix(ar, br), mix(ag, bg), mix(ab, bb), 0xff}
+}
+
+// decorationRun is a span of cells on one row drawn with the same lines
+type decorationRun struct {
+	row, start, end int
+	underline       int // gopyte underline style, UnderlineNone for none
+	overline        bool
+	color           color.Color
+}
+
+// updateTextDecorations draws the underlines and overlines of the visible
+// rows that the TextGrid cannot. attrs are the rows' attributes; the cell
+// styles of the grid give the text colors.
+func (t *NativeTerminalWidget) updateTextDecorations(attrs [][]gopyte.Attributes) {
+	if t.decorations == nil {
+		return
+	}
+
+	var runs []decorationRun
+	for row := 0; row < len(attrs) && row < len(t.textGrid.Rows); row++ {
+		cells := t.textGrid.Rows[row].Cells
+		for col := 0; col < len(attrs[row]) && col < len(cells); col++ {
+			attr := attrs[row][col]
+			run := decorationRun{row: row, start: col, end: col + 1, overline: attr.Overline}
+			if needsDrawnUnderline(attr) {
+				run.underline = max(attr.UnderlineStyle, gopyte.UnderlineSingle)
+			}
+			if run.underline == gopyte.UnderlineNone && !run.overline {
+				continue
+			}
+
+			style, _ := cells[col].Style.(*widget.CustomTextGridStyle)
+			if style == nil {
+				style = &widget.CustomTextGridStyle{}
+			}
+			run.color = t.cellForeground(style)
+			if run.underline != gopyte.UnderlineNone && attr.UnderlineColor != "" {
+				if c := t.colors.ansi(attr.UnderlineColor); c != nil {
+					run.color = c
+				}
+			}
+
+			if n := len(runs); n > 0 {
+				last := &runs[n-1]
+				if last.row == row && last.end == col && last.underline == run.underline &&
+					last.overline == run.overline && last.color == run.color {
+					last.end++
+					continue
+				}
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	var objects []fyne.CanvasObject
+	for _, run := range runs {
+		objects = append(objects, t.decorationLines(run)...)
+	}
+	t.decorations.Objects = objects
+	t.decorations.Refresh()
+}
+
+// decorationLines returns the lines drawing one run
+func (t *NativeTerminalWidget) decorationLines(run decorationRun) []fyne.CanvasObject {
+	x0 := float32(run.start) * t.charWidth
+	x1 := float32(run.end) * t.charWidth
+	top := float32(run.row) * t.charHeight
+	bottom := top + t.charHeight - 2
+
+	var lines []fyne.CanvasObject
+	line := func(ax, ay, bx, by float32) {
+		l := canvas.NewLine(run.color)
+		l.StrokeWidth = decorationStroke
+		l.Position1 = fyne.NewPos(ax, ay)
+		l.Position2 = fyne.NewPos(bx, by)
+		lines = append(lines, l)
+	}
+	// segments draws dashes of length on, spaced every period
+	segments := func(on, period float32) {
+		for x := x0; x < x1; x += period {
+			line(x, bottom, float32(math.Min(float64(x+on), float64(x1))), bottom)
+		}
+	}
+
+	if run.overline {
+		line(x0, top+1, x1, top+1)
+	}
+	switch run.underline {
+	case gopyte.UnderlineSingle:
+		line(x0, bottom, x1, bottom)
+	case gopyte.UnderlineDouble:
+		line(x0, bottom, x1, bottom)
+		line(x0, bottom-2, x1, bottom-2)
+	case gopyte.UnderlineCurly:
+		// A zigzag of two strokes per half cell
+		step := t.charWidth / 4
+		up := true
+		for x := x0; x < x1; x += step {
+			end := float32(math.Min(float64(x+step), float64(x1)))
+			if up {
+				line(x, bottom, end, bottom-2)
+			} else {
+				line(x, bottom-2, end, bottom)
+			}
+			up = !up
+		}
+	case gopyte.UnderlineDotted:
+		segments(1, 3)
+	case gopyte.UnderlineDashed:
+		segments(t.charWidth/2, t.charWidth*3/4)
+	}
+	return lines
+}
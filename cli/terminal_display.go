@@ -68,6 +68,11 @@ func (t *NativeTerminalWidget) logBufferState(context string) {
 
 // Main redraw function with enhanced debugging
 func (t *NativeTerminalWidget) performRedrawDirect() {
+	// Hold back half-drawn frames of a synchronized update
+	if t.deferRedrawForSync() {
+		return
+	}
+
 	// Log buffer state before processing
 	t.logBufferState("BEFORE_REDRAW")
 
@@ -89,6 +94,7 @@ func (t *NativeTerminalWidget) performRedrawDirect() {
 		shouldAutoScroll := !t.IsInHistoryMode()
 		t.renderNormalMode(allLines, allAttrs, shouldAutoScroll)
 	}
+	t.syncLineRows()
 
 	// Log buffer state after processing
 	t.logBufferState("AFTER_REDRAW")
@@ -127,13 +133,6 @@ func (t *NativeTerminalWidget) renderAlternateScreen(allLines []string, allAttrs
 		}
 	}
 
-	// Place cursor
-	cursorX, cursorY := t.screen.GetCursor()
-	if cursorY >= 0 && cursorY < len(displayLines) && cursorX >= 0 && cursorX < t.cols {
-		t.placeCursorInLine(&displayLines[cursorY], cursorX)
-		log.Printf("ALTERNATE: Cursor at (%d,%d)", cursorX, cursorY)
-	}
-
 	// Set content
 	fullText := strings.Join(displayLines, "\n")
 	t.textGrid.SetText(fullText)
@@ -144,6 +143,16 @@ func (t *NativeTerminalWidget) renderAlternateScreen(allLines []string, allAttrs
 	} else {
 		t.textGrid.Refresh()
 	}
+	t.updatePromptGutter(nil, VirtualScrollState{}) // No prompts on the alternate screen
+
+	// Place cursor
+	cursorX, cursorY := t.screen.GetCursor()
+	if cursorY >= 0 && cursorY < len(displayLines) && cursorX >= 0 && cursorX < t.cols {
+		t.setCursorCell(cursorY, cursorX)
+		log.Printf("ALTERNATE: Cursor at (%d,%d)", cursorX, cursorY)
+	} else {
+		t.setCursorCell(-1, -1)
+	}
 
 	log.Printf("ALTERNATE: Rendered %d lines", len(displayLines))
 }
@@ -174,15 +183,6 @@ func (t *NativeTerminalWidget) renderNormalMode(allLines []string, allAttrs [][]
 	// Extract visible content
 	visibleLines := t.extractVisibleContent(allLines, viewport)
 
-	// Place cursor if visible
-	cursorX, cursorY := t.screen.GetCursor()
-	adjustedCursorY := t.adjustCursorForViewport(cursorX, cursorY, viewport, len(allLines))
-
-	if adjustedCursorY >= 0 && adjustedCursorY < len(visibleLines) && cursorX >= 0 && cursorX < t.cols && !t.IsInHistoryMode() {
-		t.placeCursorInLine(&visibleLines[adjustedCursorY], cursorX)
-		log.Printf("NORMAL: Cursor at (%d,%d) in viewport", cursorX, adjustedCursorY)
-	}
-
 	// Set visible content
 	fullText := strings.Join(visibleLines, "\n")
 	t.textGrid.SetText(fullText)
@@ -194,6 +194,19 @@ func (t *NativeTerminalWidget) renderNormalMode(allLines []string, allAttrs [][]
 	} else {
 		t.textGrid.Refresh()
 	}
+	t.updatePromptGutter(allLines, viewport)
+	t.decorateFindMatch(viewport)
+
+	// Place cursor if visible
+	cursorX, cursorY := t.screen.GetCursor()
+	adjustedCursorY := t.adjustCursorForViewport(cursorX, cursorY, viewport, len(allLines))
+
+	if adjustedCursorY >= 0 && adjustedCursorY < len(visibleLines) && cursorX >= 0 && cursorX < t.cols && !t.IsInHistoryMode() {
+		t.setCursorCell(adjustedCursorY, cursorX)
+		log.Printf("NORMAL: Cursor at (%d,%d) in viewport", cursorX, adjustedCursorY)
+	} else {
+		t.setCursorCell(-1, -1)
+	}
 
 	log.Printf("NORMAL: Rendered viewport lines %d-%d of %d total",
 		viewport.scrollOffset, viewport.scrollOffset+viewport.visibleLines-1, len(allLines))
@@ -389,33 +402,10 @@ func (t *NativeTerminalWidget) adjustCursorForViewport(cursorX, cursorY int, vie
 	}
 }
 
-// Place cursor in line
-func (t *NativeTerminalWidget) placeCursorInLine(line *string, cursorX int) {
-	if line == nil || cursorX < 0 {
-		return
-	}
-
-	currentLine := *line
-	runes := []rune(currentLine)
-
-	if cursorX < len(runes) {
-		// Replace character at cursor position with block cursor
-		runes[cursorX] = '█'
-		*line = string(runes)
-	} else if cursorX < t.cols {
-		// Extend line with spaces and place cursor
-		padLen := cursorX - len(runes)
-		if padLen > 0 {
-			padding := strings.Repeat(" ", padLen)
-			*line = currentLine + padding + "█"
-		} else {
-			*line = currentLine + "█"
-		}
-	}
-}
-
 // Apply colors (simplified)
 func (t *NativeTerminalWidget) applyColors(lines []string, attrs [][]gopyte.Attributes) {
+	t.syncColorOverrides()
+
 	if len(t.textGrid.Rows) == 0 || len(attrs) == 0 {
 		t.textGrid.Refresh()
 		return
@@ -428,7 +418,7 @@ func (t *NativeTerminalWidget) applyColors(lines []string, attrs [][]gopyte.Attr
 
 		row := t.textGrid.Rows[rowIdx]
 		runes := []rune(line)
-		lineAttrs := attrs[rowIdx]
+		lineAttrs := t.highlightLine(line, attrs[rowIdx])
 
 		for charIdx, char := range runes {
 			if charIdx >= len(row.Cells) || charIdx >= len(lineAttrs) {
@@ -452,26 +442,24 @@ func (t *NativeTerminalWidget) applyColors(lines []string, attrs [][]gopyte.Attr
 			}
 
 			row.Cells[charIdx].Rune = char
+			t.applyTextAttributes(&row.Cells[charIdx], style, attr)
 		}
 	}
 
+	t.updateTextDecorations(attrs)
+	t.decorateHoveredLink(lines, attrs)
 	t.textGrid.Refresh()
 }
 
 // Map gopyte color to Fyne color - now theme-aware
 func (t *NativeTerminalWidget) mapColor(colorName string) color.Color {
-	if colorName == "" || colorName == "default" {
-		return nil
-	}
-
-	// Get theme-aware color mappings
-	mappings := GetTerminalColorMappings()
-
-	if fyneColor, exists := mappings[colorName]; exists {
-		return fyneColor
-	}
+	return t.colors.ansi(colorName)
+}
 
-	return mappings["white"]
+// mapTerminalColor maps a gopyte color name with the color scheme from
+// settings, nil for the default color
+func mapTerminalColor(colorName string) color.Color {
+	return currentPalette().ansi(colorName)
 }
 
 // New debug method to force expansion of viewport
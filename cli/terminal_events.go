@@ -29,19 +29,11 @@ func (t *NativeTerminalWidget) TypedKey(key *fyne.KeyEvent) {
 		return
 	}
 
-	var data []byte
+	// Keys that type text arrive again through TypedRune
+	data := t.encodeSpecialKey(key.Name, currentKeyModifiers())
 
-	// Handle keys - Page Up/Down always go to applications
 	switch key.Name {
-	case fyne.KeyPageUp:
-		// Always send to application (vim, less, etc.)
-		data = []byte("\x1b[5~")
-
-	case fyne.KeyPageDown:
-		// Always send to application (vim, less, etc.)
-		data = []byte("\x1b[6~")
 	case fyne.KeyBackspace:
-		data = []byte("\x7f")
 		// CRITICAL: Force cache invalidation for backspace
 		if t.screen != nil {
 			t.screen.InvalidateCache()
@@ -60,59 +52,6 @@ func (t *NativeTerminalWidget) TypedKey(key *fyne.KeyEvent) {
 			fmt.Printf("TypedKey: Enter pressed, exiting history mode\n")
 			t.exitHistoryMode()
 		}
-		data = []byte("\r")
-
-	case fyne.KeyTab:
-		data = []byte("\t")
-
-	case fyne.KeyDelete:
-		data = []byte("\x1b[3~")
-
-	case fyne.KeyUp:
-		data = []byte("\x1b[A")
-
-	case fyne.KeyDown:
-		data = []byte("\x1b[B")
-
-	case fyne.KeyLeft:
-		data = []byte("\x1b[D")
-
-	case fyne.KeyRight:
-		data = []byte("\x1b[C")
-
-	case fyne.KeyHome:
-		data = []byte("\x1b[H")
-
-	case fyne.KeyEnd:
-		data = []byte("\x1b[F")
-
-	case fyne.KeyEscape:
-		data = []byte("\x1b")
-
-	case fyne.KeyF1:
-		data = []byte("\x1b[11~")
-	case fyne.KeyF2:
-		data = []byte("\x1b[12~")
-	case fyne.KeyF3:
-		data = []byte("\x1b[13~")
-	case fyne.KeyF4:
-		data = []byte("\x1b[14~")
-	case fyne.KeyF5:
-		data = []byte("\x1b[15~")
-	case fyne.KeyF6:
-		data = []byte("\x1b[17~")
-	case fyne.KeyF7:
-		data = []byte("\x1b[18~")
-	case fyne.KeyF8:
-		data = []byte("\x1b[19~")
-	case fyne.KeyF9:
-		data = []byte("\x1b[20~")
-	case fyne.KeyF10:
-		data = []byte("\x1b[21~")
-	case fyne.KeyF11:
-		data = []byte("\x1b[23~")
-	case fyne.KeyF12:
-		data = []byte("\x1b[24~")
 	}
 
 	if len(data) > 0 {
@@ -170,7 +109,7 @@ func (t *NativeTerminalWidget) TypedRune(r rune) {
 		data = []byte{byte(r)}
 	} else {
 		// Regular printable character
-		data = []byte(string(r))
+		data = t.encodeRune(r)
 	}
 
 	fmt.Printf("TypedRune: Calling WriteToPTY with %d bytes: %v (%q)\n", len(data), data, string(data))
@@ -225,6 +164,9 @@ func (t *NativeTerminalWidget) debugRuneEvent(r rune) {
 func (t *NativeTerminalWidget) FocusGained() {
 	fmt.Printf("FocusGained: Terminal widget gained focus\n")
 	t.hasFocus = true
+	t.focusedAt = time.Now()
+	t.cursorBlinkOn = true
+	t.updateCursorOverlay()
 
 	// Ensure we can receive all key events
 	if canvas := fyne.CurrentApp().Driver().CanvasForObject(t); canvas != nil {
@@ -303,6 +245,14 @@ func (t *NativeTerminalWidget) enhancedUpdateProcessor() {
 func (t *NativeTerminalWidget) MouseDown(event *desktop.MouseEvent) {
 	fmt.Printf("MouseDown: position=%v, button=%v\n", event.Position, event.Button)
 
+	// Ctrl+click (Cmd+click on macOS) follows links
+	if event.Button == desktop.MouseButtonPrimary &&
+		event.Modifier&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0 {
+		if t.handleLinkClick(event) {
+			return
+		}
+	}
+
 	// Request focus on click
 	if canvas := fyne.CurrentApp().Driver().CanvasForObject(t); canvas != nil {
 		canvas.Focus(t)
@@ -483,6 +433,7 @@ func (t *NativeTerminalWidget) performResize(width, height float32) {
 			}()
 			t.screen.Resize(newCols, newRows)
 		}()
+		t.recordResize(newCols, newRows)
 	}
 	t.mutex.Unlock()
 
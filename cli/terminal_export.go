@@ -0,0 +1,200 @@
+// terminal_export.go - Terminal context menu and saving the buffer as text, ANSI or HTML
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Export formats for "Save buffer as..."
+const (
+	ExportFormatText = "text"
+	ExportFormatANSI = "ansi"
+	ExportFormatHTML = "html"
+)
+
+// exportFormats maps formats to the labels shown in the save dialog and the
+// file extension they are saved with
+var exportFormats = []struct {
+	format    string
+	label     string
+	extension string
+}{
+	{ExportFormatText, "Plain text", ".txt"},
+	{ExportFormatANSI, "ANSI text (keeps colors)", ".ansi"},
+	{ExportFormatHTML, "HTML page", ".html"},
+}
+
+// unsafeFileChars are replaced when a window title becomes a file name
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// TappedSecondary shows the terminal context menu on right click
+func (t *NativeTerminalWidget) TappedSecondary(event *fyne.PointEvent) {
+	t.showTerminalMenu(event.AbsolutePosition)
+}
+
+// showTerminalMenu offers copy/paste, saving the buffer and recording
+func (t *NativeTerminalWidget) showTerminalMenu(pos fyne.Position) {
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(t)
+	if canvas == nil {
+		// The tab holds the SSH widget wrapping this one
+		canvas = fyne.CurrentApp().Driver().AllWindows()[0].Canvas()
+	}
+
+	copyItem := fyne.NewMenuItem("Copy", func() {
+		t.selection.CopyToClipboard()
+	})
+	copyItem.Disabled = t.selection == nil || !t.selection.HasSelection()
+
+	pasteItem := fyne.NewMenuItem("Paste", func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		if content := window.Clipboard().Content(); content != "" {
+			t.WriteToPTY([]byte(content))
+		}
+	})
+
+	saveItem := fyne.NewMenuItem("Save buffer as...", t.showSaveBufferDialog)
+
+	menu := fyne.NewMenu("", copyItem, pasteItem, fyne.NewMenuItemSeparator(),
+		saveItem, t.recordingMenuItem())
+	widget.ShowPopUpMenuAtPosition(menu, canvas, pos)
+}
+
+// showSaveBufferDialog asks for the format and scope, then for the file
+func (t *NativeTerminalWidget) showSaveBufferDialog() {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+
+	var labels []string
+	for _, f := range exportFormats {
+		labels = append(labels, f.label)
+	}
+	formatSelect := widget.NewSelect(labels, nil)
+	formatSelect.SetSelectedIndex(0)
+
+	selectionCheck := widget.NewCheck("Selection only", nil)
+	if t.selection == nil || !t.selection.HasSelection() {
+		selectionCheck.Disable()
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Format", formatSelect),
+		widget.NewFormItem("", selectionCheck),
+	}
+
+	d := dialog.NewForm("Save Buffer", "Save...", "Cancel", items,
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			format := exportFormats[formatSelect.SelectedIndex()]
+			content := t.exportBuffer(format.format, selectionCheck.Checked)
+
+			save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				if writer == nil {
+					return
+				}
+				defer writer.Close()
+
+				if _, err := writer.Write([]byte(content)); err != nil {
+					dialog.ShowError(fmt.Errorf("failed to save buffer: %w", err), window)
+					return
+				}
+				log.Printf("Export: saved %d bytes to %s", len(content), writer.URI())
+			}, window)
+			save.SetFileName(t.exportFileName(format.extension))
+			save.SetFilter(storage.NewExtensionFileFilter([]string{format.extension}))
+			save.Show()
+		}, window)
+	d.Show()
+}
+
+// exportFileName suggests a file name from the window title and the time
+func (t *NativeTerminalWidget) exportFileName(extension string) string {
+	name := unsafeFileChars.ReplaceAllString(t.GetTitle(), "_")
+	if name == "" || name == "_" {
+		name = "terminal"
+	}
+	return fmt.Sprintf("%s-%s%s", name, time.Now().Format("20060102-150405"), extension)
+}
+
+// exportBuffer renders History plus the current screen, or only the
+// selection, in the given format
+func (t *NativeTerminalWidget) exportBuffer(format string, selectionOnly bool) string {
+	t.mutex.RLock()
+	var lines [][]gopyte.Cell
+	if selectionOnly {
+		lines = t.selectedCells()
+	} else {
+		lines = t.screen.GetLineCells(0, t.screen.GetHistorySize()+t.rows)
+	}
+	t.mutex.RUnlock()
+
+	switch format {
+	case ExportFormatANSI:
+		return gopyte.FormatANSI(lines)
+	case ExportFormatHTML:
+		return gopyte.FormatHTML(lines, t.GetTitle(), t.exportPalette())
+	default:
+		return gopyte.FormatText(lines)
+	}
+}
+
+// exportPalette uses the terminal colors of the active theme
+func (t *NativeTerminalWidget) exportPalette() gopyte.HTMLPalette {
+	fg := t.colors.colors["default"]
+	if fg == nil {
+		fg = t.colors.Foreground()
+	}
+	return gopyte.HTMLPalette{
+		Colors:     t.colors.colors,
+		Foreground: fg,
+		Background: t.colors.Background(),
+	}
+}
+
+// selectedCells returns the cells under the selection, cut to the selected
+// columns on its first and last line
+func (t *NativeTerminalWidget) selectedCells() [][]gopyte.Cell {
+	sm := t.selection
+	if sm == nil || !sm.HasSelection() {
+		return nil
+	}
+
+	startCol, startRow := sm.positionToCell(sm.startPos)
+	endCol, endRow := sm.positionToCell(sm.endPos)
+	if startRow > endRow || (startRow == endRow && startCol > endCol) {
+		startRow, endRow = endRow, startRow
+		startCol, endCol = endCol, startCol
+	}
+
+	// Selection rows are on screen; map them to content lines like
+	// GetSelectedText does
+	allLines := t.screen.GetDisplay()
+	viewport := t.calculateUnifiedViewport(allLines)
+	first := t.screen.GetDisplayStart() + viewport.scrollOffset + startRow
+	last := t.screen.GetDisplayStart() + viewport.scrollOffset + endRow
+
+	lines := t.screen.GetLineCells(first, last+1)
+	if len(lines) == 0 {
+		return nil
+	}
+	n := len(lines) - 1
+	if len(lines) == last-first+1 {
+		lines[n] = lines[n][:min(endCol, len(lines[n]))]
+	}
+	lines[0] = lines[0][min(startCol, len(lines[0])):]
+	return lines
+}
@@ -0,0 +1,158 @@
+// terminal_find.go - Searching the scrollback
+package main
+
+import (
+	"log"
+	"unicode"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// findMatch is one hit of a scrollback search: a content line (GetLines
+// index) and the rune columns [start, end) on it
+type findMatch struct {
+	line  int
+	start int
+	end   int
+}
+
+// before reports whether m comes before other in reading order
+func (m findMatch) before(other findMatch) bool {
+	if m.line != other.line {
+		return m.line < other.line
+	}
+	return m.start < other.start
+}
+
+// findMatches returns every case-insensitive occurrence of query, in order
+func findMatches(lines []string, query string) []findMatch {
+	needle := []rune(query)
+	for i, r := range needle {
+		needle[i] = unicode.ToLower(r)
+	}
+	if len(needle) == 0 {
+		return nil
+	}
+
+	var matches []findMatch
+	for lineIdx, line := range lines {
+		runes := []rune(line)
+		for i := range runes {
+			runes[i] = unicode.ToLower(runes[i])
+		}
+		for start := 0; start+len(needle) <= len(runes); {
+			if !hasRunePrefix(runes[start:], needle) {
+				start++
+				continue
+			}
+			matches = append(matches, findMatch{line: lineIdx, start: start, end: start + len(needle)})
+			start += len(needle)
+		}
+	}
+	return matches
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// nextFindMatch picks the match after current (direction > 0) or before it,
+// wrapping around at either end. Returns false when there are no matches.
+func nextFindMatch(matches []findMatch, current findMatch, direction int) (int, bool) {
+	if len(matches) == 0 {
+		return 0, false
+	}
+	if direction > 0 {
+		for i, m := range matches {
+			if current.before(m) {
+				return i, true
+			}
+		}
+		return 0, true
+	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i].before(current) {
+			return i, true
+		}
+	}
+	return len(matches) - 1, true
+}
+
+// Find moves to the next (direction > 0) or previous match of query in the
+// scrollback and scrolls it into view. A new query starts from the bottom
+// and searches upward. Returns the match's index and the number of matches,
+// index -1 when nothing matched.
+func (t *NativeTerminalWidget) Find(query string, direction int) (index, count int) {
+	if t.screen == nil || t.screen.IsUsingAlternate() {
+		return -1, 0
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := t.screen.GetLines(0, t.screen.GetHistorySize()+t.rows)
+	matches := findMatches(lines, query)
+
+	current := t.findCurrent
+	if !t.findActive || query != t.findQuery {
+		current = findMatch{line: len(lines)}
+		direction = -1
+	}
+	t.findQuery = query
+	t.findActive = true
+	t.updatePending = true
+
+	index, ok := nextFindMatch(matches, current, direction)
+	if !ok {
+		t.findCurrent = findMatch{line: -1}
+		return -1, 0
+	}
+	t.findCurrent = matches[index]
+
+	// Scroll only when the match is off screen, keeping it near the middle
+	top := t.screen.GetHistorySize() - t.screen.GetHistoryPos()
+	if line := t.findCurrent.line; line < top || line >= top+t.rows {
+		t.scrollToContentLine(max(0, line-t.rows/2))
+	}
+	log.Printf("Find: %q match %d of %d at line %d", query, index+1, len(matches), t.findCurrent.line)
+	return index, len(matches)
+}
+
+// ClearFind removes the search highlight
+func (t *NativeTerminalWidget) ClearFind() {
+	t.mutex.Lock()
+	t.findActive = false
+	t.findQuery = ""
+	t.mutex.Unlock()
+	t.updatePending = true
+}
+
+// decorateFindMatch highlights the current search match if it is visible.
+// Called by the normal-screen renderers after the cell colors are set.
+func (t *NativeTerminalWidget) decorateFindMatch(viewport VirtualScrollState) {
+	if !t.findActive || t.findCurrent.line < 0 || t.screen == nil {
+		return
+	}
+
+	row := t.findCurrent.line - t.screen.GetDisplayStart() - viewport.scrollOffset
+	if row < 0 || row >= viewport.visibleLines || row >= len(t.textGrid.Rows) {
+		return
+	}
+
+	cells := t.textGrid.Rows[row].Cells
+	for x := t.findCurrent.start; x < t.findCurrent.end && x < len(cells); x++ {
+		if cells[x].Style == nil {
+			cells[x].Style = &widget.CustomTextGridStyle{}
+		}
+		if style, ok := cells[x].Style.(*widget.CustomTextGridStyle); ok {
+			style.FGColor = t.mapColor("black")
+			style.BGColor = t.mapColor("yellow")
+		}
+	}
+	t.textGrid.Refresh()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindMatches(t *testing.T) {
+	lines := []string{
+		"Router#show interfaces",
+		"GigabitEthernet0/1 is up",
+		"",
+		"gigabitethernet0/2 is down; GIGABITETHERNET0/3",
+		"Überprüfung über",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []findMatch
+	}{
+		{"Empty query", "", nil},
+		{"No match", "vlan", nil},
+		{"Case-insensitive", "gigabitethernet", []findMatch{{1, 0, 15}, {3, 0, 15}, {3, 28, 43}}},
+		{"Rune columns", "ÜBER", []findMatch{{4, 0, 4}, {4, 12, 16}}},
+		{"Whole line", "Router#show interfaces", []findMatch{{0, 0, 22}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := findMatches(lines, tc.query); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("findMatches(%q) = %v, want %v", tc.query, got, tc.want)
+			}
+		})
+	}
+
+	if got := findMatches([]string{"aaaa"}, "aa"); !reflect.DeepEqual(got, []findMatch{{0, 0, 2}, {0, 2, 4}}) {
+		t.Errorf("overlapping matches = %v", got)
+	}
+}
+
+func TestNextFindMatch(t *testing.T) {
+	matches := []findMatch{{1, 0, 3}, {3, 0, 3}, {3, 10, 13}, {7, 4, 7}}
+
+	tests := []struct {
+		name      string
+		current   findMatch
+		direction int
+		want      int
+	}{
+		{"New search from the bottom", findMatch{line: 20}, -1, 3},
+		{"Older", matches[2], -1, 1},
+		{"Older on the same line", findMatch{3, 10, 13}, -1, 1},
+		{"Newer", matches[1], 1, 2},
+		{"Older wraps to the bottom", matches[0], -1, 3},
+		{"Newer wraps to the top", matches[3], 1, 0},
+		{"From between matches", findMatch{line: 5}, 1, 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := nextFindMatch(matches, tc.current, tc.direction)
+			if !ok || got != tc.want {
+				t.Errorf("nextFindMatch = %d, %v; want %d", got, ok, tc.want)
+			}
+		})
+	}
+
+	if _, ok := nextFindMatch(nil, findMatch{}, 1); ok {
+		t.Errorf("nextFindMatch found a match in none")
+	}
+}
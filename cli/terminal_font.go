@@ -0,0 +1,365 @@
+// terminal_font.go - Terminal font family, size, line height and per-tab zoom
+package main
+
+import (
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// The font family is app wide: NativeTheme returns it for monospace text,
+// which is also what TextGrid measures its cells with. The size is per
+// terminal, set on the grid with a theme override so each tab can zoom.
+//
+// TextGrid makes each row as tall as the measured text. For a line height
+// above 1 the rows are shown by lineRows instead, one single-row grid per
+// terminal row placed charHeight apart; textGrid still holds the content and
+// syncLineRows copies its rows over after each redraw. Cell backgrounds
+// cover the text height only, leaving the spacing in the scheme background.
+
+// Terminal font size limits, in points
+const (
+	defaultFontSize = 12
+	minFontSize     = 6
+	maxFontSize     = 72
+)
+
+// Line height limits, as multiples of the font's own line height. Closer
+// rows would clip the glyphs.
+const (
+	defaultLineHeight = 1.0
+	minLineHeight     = 1.0
+	maxLineHeight     = 2.0
+)
+
+// fontFamilyBundledLabel is the settings choice for the bundled font
+const fontFamilyBundledLabel = "Bundled (default)"
+
+// fontDirs returns the directories searched for monospace fonts, the app's
+// own fonts directory first
+func fontDirs() []string {
+	home, _ := os.UserHomeDir()
+	dirs := []string{GetFontsDir()}
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = append(dirs, "/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts"))
+	case "windows":
+		dirs = append(dirs, filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"))
+	default:
+		dirs = append(dirs, "/usr/share/fonts", "/usr/local/share/fonts",
+			filepath.Join(home, ".local", "share", "fonts"), filepath.Join(home, ".fonts"))
+	}
+	return dirs
+}
+
+// MonospaceFont is an installed font usable for the terminal
+type MonospaceFont struct {
+	Name string // Family and style, e.g. "JetBrainsMono Nerd Font Regular"
+	Path string
+}
+
+var (
+	monospaceFontsOnce sync.Once
+	monospaceFonts     []MonospaceFont
+)
+
+// installedMonospaceFonts lists the TTF and OTF fonts whose letters all
+// have the same advance, sorted by name. The scan runs once.
+func installedMonospaceFonts() []MonospaceFont {
+	monospaceFontsOnce.Do(func() {
+		seen := make(map[string]bool)
+		for _, dir := range fontDirs() {
+			filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				ext := strings.ToLower(filepath.Ext(path))
+				if ext != ".ttf" && ext != ".otf" {
+					return nil
+				}
+				if name, ok := monospaceFontName(path); ok && !seen[name] {
+					seen[name] = true
+					monospaceFonts = append(monospaceFonts, MonospaceFont{Name: name, Path: path})
+				}
+				return nil
+			})
+		}
+		sort.Slice(monospaceFonts, func(i, j int) bool { return monospaceFonts[i].Name < monospaceFonts[j].Name })
+		log.Printf("Fonts: found %d monospace fonts", len(monospaceFonts))
+	})
+	return monospaceFonts
+}
+
+// loadMonospaceFonts scans for fonts off the UI thread, as parsing them
+// takes a while, and passes them to done on the UI thread
+func loadMonospaceFonts(done func([]MonospaceFont)) {
+	go func() {
+		fonts := installedMonospaceFonts()
+		fyne.Do(func() { done(fonts) })
+	}()
+}
+
+// monospaceFontName returns a font file's full name if it is monospace
+func monospaceFontName(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	parsed, err := sfnt.ParseReaderAt(f)
+	if err != nil {
+		return "", false
+	}
+	var buf sfnt.Buffer
+	var advance fixed.Int26_6
+	for i, r := range "iMW.0" {
+		glyph, err := parsed.GlyphIndex(&buf, r)
+		if err != nil || glyph == 0 {
+			return "", false
+		}
+		a, err := parsed.GlyphAdvance(&buf, glyph, fixed.I(1000), font.HintingNone)
+		if err != nil || (i > 0 && a != advance) {
+			return "", false
+		}
+		advance = a
+	}
+
+	name, err := parsed.Name(&buf, sfnt.NameIDFull)
+	if err != nil || name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return name, true
+}
+
+var (
+	terminalFontMutex sync.Mutex
+	terminalFontPath  string
+	terminalFont      fyne.Resource
+)
+
+// currentTerminalFont returns the font chosen in settings, nil for the
+// bundled one. A font that fails to load is logged once and skipped.
+func currentTerminalFont() fyne.Resource {
+	path := ""
+	if settings := GetSettings(); settings != nil {
+		path = settings.Get().FontFamily
+	}
+
+	terminalFontMutex.Lock()
+	defer terminalFontMutex.Unlock()
+	if path == terminalFontPath {
+		return terminalFont
+	}
+	terminalFontPath = path
+	terminalFont = nil
+	if path == "" {
+		return nil
+	}
+	resource, err := fyne.LoadResourceFromPath(path)
+	if err != nil {
+		log.Printf("Fonts: %v; using the bundled font", err)
+		return nil
+	}
+	terminalFont = resource
+	log.Printf("Fonts: terminal font %s", path)
+	return terminalFont
+}
+
+// terminalTextTheme is the app theme with the terminal's text size and the
+// default text color of its color scheme
+type terminalTextTheme struct {
+	size   float32
+	colors *terminalPalette
+}
+
+func (t *terminalTextTheme) current() fyne.Theme {
+	return fyne.CurrentApp().Settings().Theme()
+}
+
+func (t *terminalTextTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if name == theme.ColorNameForeground && t.colors != nil && t.colors.foreground != nil {
+		return t.colors.foreground
+	}
+	return t.current().Color(name, variant)
+}
+
+func (t *terminalTextTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return t.current().Font(style)
+}
+
+func (t *terminalTextTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return t.current().Icon(name)
+}
+
+func (t *terminalTextTheme) Size(name fyne.ThemeSizeName) float32 {
+	if name == theme.SizeNameText {
+		return t.size
+	}
+	return t.current().Size(name)
+}
+
+// baseFontSize returns the font size from settings
+func baseFontSize() float32 {
+	if settings := GetSettings(); settings != nil && settings.Get().FontSize > 0 {
+		return float32(settings.Get().FontSize)
+	}
+	return defaultFontSize
+}
+
+// currentLineHeight returns the line height from settings
+func currentLineHeight() float32 {
+	if settings := GetSettings(); settings != nil && settings.Get().LineHeight >= minLineHeight {
+		return float32(math.Min(settings.Get().LineHeight, maxLineHeight))
+	}
+	return defaultLineHeight
+}
+
+// initFont sizes the grid text from settings; the constructor calls it
+// before the grid is shown
+func (t *NativeTerminalWidget) initFont() {
+	t.fontSize = baseFontSize()
+	t.lineHeight = currentLineHeight()
+	t.textTheme = &terminalTextTheme{size: t.fontSize}
+	t.lineRows = container.New(&lineRowsLayout{terminal: t})
+	t.gridContent = container.NewStack(t.textGrid, t.lineRows)
+	t.textOverride = container.NewThemeOverride(t.gridContent, t.textTheme)
+	t.showLineRows()
+	t.calculateCharDimensions()
+}
+
+// showLineRows shows the grid's own rows or the line-spaced ones, whichever
+// the line height needs
+func (t *NativeTerminalWidget) showLineRows() {
+	if t.lineHeight > 1 {
+		t.textGrid.Hide()
+		t.lineRows.Show()
+		return
+	}
+	t.lineRows.Hide()
+	t.lineRows.Objects = nil
+	t.textGrid.Show()
+}
+
+// syncLineRows copies the grid's rows to the line-spaced rows; the cells
+// are shared, so this only hands each row grid its row
+func (t *NativeTerminalWidget) syncLineRows() {
+	if t.lineRows == nil || !t.lineRows.Visible() {
+		return
+	}
+
+	rows := t.textGrid.Rows
+	added := false
+	for len(t.lineRows.Objects) < len(rows) {
+		grid := widget.NewTextGrid()
+		t.lineRows.Objects = append(t.lineRows.Objects, grid)
+		added = true
+	}
+	t.lineRows.Objects = t.lineRows.Objects[:len(rows)]
+	for i, obj := range t.lineRows.Objects {
+		obj.(*widget.TextGrid).Rows = rows[i : i+1]
+	}
+
+	if added {
+		// New grids take the terminal's text size from the override
+		t.textOverride.Refresh()
+		return
+	}
+	t.lineRows.Refresh()
+}
+
+// lineRowsLayout places the row grids charHeight apart, each row's text
+// centered in its line
+type lineRowsLayout struct {
+	terminal *NativeTerminalWidget
+}
+
+func (l *lineRowsLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	t := l.terminal
+	offset := float32(math.Floor(float64(t.charHeight-t.cellHeight) / 2))
+	for i, obj := range objects {
+		obj.Resize(fyne.NewSize(size.Width, t.cellHeight))
+		obj.Move(fyne.NewPos(0, float32(i)*t.charHeight+offset))
+	}
+}
+
+func (l *lineRowsLayout) MinSize([]fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+// clampFontSize keeps a font size within the limits
+func clampFontSize(size float32) float32 {
+	return float32(math.Max(minFontSize, math.Min(maxFontSize, float64(size))))
+}
+
+// setFontSize changes the grid text size, then recomputes the grid
+// dimensions and resizes the host to match
+func (t *NativeTerminalWidget) setFontSize(size float32) {
+	size = clampFontSize(size)
+	if size == t.fontSize && size == t.textTheme.size {
+		return
+	}
+
+	t.mutex.Lock()
+	t.fontSize = size
+	t.mutex.Unlock()
+	t.textTheme.size = size
+	t.textOverride.Refresh()
+	t.calculateCharDimensions()
+
+	if widgetSize := t.Size(); widgetSize.Width > 0 && widgetSize.Height > 0 {
+		t.performResize(widgetSize.Width, widgetSize.Height)
+	}
+	if t.screen != nil {
+		t.screen.InvalidateCache()
+	}
+	t.updatePending = true
+	t.triggerImmediateRedraw()
+}
+
+// setZoom zooms this terminal by offset points from the settings size. The
+// offset is clamped along with the size, so zooming past a limit and back
+// takes effect at once.
+func (t *NativeTerminalWidget) setZoom(offset float32) {
+	base := baseFontSize()
+	size := clampFontSize(base + offset)
+	t.zoomOffset = size - base
+	t.setFontSize(size)
+}
+
+// zoom steps the font size of this terminal up or down by a point
+func (t *NativeTerminalWidget) zoom(step float32) {
+	t.setZoom(t.zoomOffset + step)
+}
+
+// resetZoom returns this terminal to the font size from settings
+func (t *NativeTerminalWidget) resetZoom() {
+	t.setZoom(0)
+}
+
+// ApplyFontSettings picks up a new font family, size or line height,
+// keeping the terminal's zoom
+func (t *NativeTerminalWidget) ApplyFontSettings() {
+	t.lineHeight = currentLineHeight()
+	t.showLineRows()
+
+	// A new family or line height changes the cell size even at the same
+	// point size
+	t.textTheme.size = 0
+	t.setZoom(t.zoomOffset)
+}
@@ -0,0 +1,143 @@
+// terminal_keyboard.go - Key encoding: xterm, modifyOtherKeys and the kitty keyboard protocol
+package main
+
+import (
+	"unicode"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// fyneSpecialKeys are the keys that do not type text. Fyne sends them
+// only to TypedKey, or to TypedShortcut with Ctrl, Alt or Super held.
+var fyneSpecialKeys = map[fyne.KeyName]gopyte.Key{
+	fyne.KeyReturn:    gopyte.KeyEnter,
+	fyne.KeyEnter:     gopyte.KeyEnter,
+	fyne.KeyTab:       gopyte.KeyTab,
+	fyne.KeyEscape:    gopyte.KeyEscape,
+	fyne.KeyBackspace: gopyte.KeyBackspace,
+	fyne.KeyUp:        gopyte.KeyUp,
+	fyne.KeyDown:      gopyte.KeyDown,
+	fyne.KeyLeft:      gopyte.KeyLeft,
+	fyne.KeyRight:     gopyte.KeyRight,
+	fyne.KeyHome:      gopyte.KeyHome,
+	fyne.KeyEnd:       gopyte.KeyEnd,
+	fyne.KeyInsert:    gopyte.KeyInsert,
+	fyne.KeyDelete:    gopyte.KeyDelete,
+	fyne.KeyPageUp:    gopyte.KeyPageUp,
+	fyne.KeyPageDown:  gopyte.KeyPageDown,
+	fyne.KeyF1:        gopyte.KeyF1,
+	fyne.KeyF2:        gopyte.KeyF2,
+	fyne.KeyF3:        gopyte.KeyF3,
+	fyne.KeyF4:        gopyte.KeyF4,
+	fyne.KeyF5:        gopyte.KeyF5,
+	fyne.KeyF6:        gopyte.KeyF6,
+	fyne.KeyF7:        gopyte.KeyF7,
+	fyne.KeyF8:        gopyte.KeyF8,
+	fyne.KeyF9:        gopyte.KeyF9,
+	fyne.KeyF10:       gopyte.KeyF10,
+	fyne.KeyF11:       gopyte.KeyF11,
+	fyne.KeyF12:       gopyte.KeyF12,
+}
+
+// fyneSymbolKeys are the text keys other than letters and digits, by the
+// character they type unshifted on a US layout
+var fyneSymbolKeys = map[fyne.KeyName]gopyte.Key{
+	fyne.KeySpace:        ' ',
+	fyne.KeyApostrophe:   '\'',
+	fyne.KeyComma:        ',',
+	fyne.KeyMinus:        '-',
+	fyne.KeyPeriod:       '.',
+	fyne.KeySlash:        '/',
+	fyne.KeySemicolon:    ';',
+	fyne.KeyEqual:        '=',
+	fyne.KeyLeftBracket:  '[',
+	fyne.KeyBackslash:    '\\',
+	fyne.KeyRightBracket: ']',
+	fyne.KeyBackTick:     '`',
+}
+
+// keyModifiers converts fyne modifiers for the encoder
+func keyModifiers(modifier fyne.KeyModifier) gopyte.KeyModifiers {
+	var mods gopyte.KeyModifiers
+	if modifier&fyne.KeyModifierShift != 0 {
+		mods |= gopyte.ModShift
+	}
+	if modifier&fyne.KeyModifierAlt != 0 {
+		mods |= gopyte.ModAlt
+	}
+	if modifier&fyne.KeyModifierControl != 0 {
+		mods |= gopyte.ModCtrl
+	}
+	if modifier&fyne.KeyModifierSuper != 0 {
+		mods |= gopyte.ModSuper
+	}
+	return mods
+}
+
+// currentKeyModifiers returns the modifiers held now, which TypedKey
+// events do not carry (Shift+Tab, Shift+arrows)
+func currentKeyModifiers() fyne.KeyModifier {
+	if app := fyne.CurrentApp(); app != nil {
+		if driver, ok := app.Driver().(desktop.Driver); ok {
+			return driver.CurrentKeyModifiers()
+		}
+	}
+	return 0
+}
+
+// keyboardMode returns the key encoding the host asked for
+func (t *NativeTerminalWidget) keyboardMode() gopyte.KeyboardMode {
+	if t.screen == nil {
+		return gopyte.KeyboardMode{}
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.screen.KeyboardMode()
+}
+
+// encodeSpecialKey returns what a key that types no text sends, nil for
+// text keys, which arrive again through TypedRune
+func (t *NativeTerminalWidget) encodeSpecialKey(name fyne.KeyName, modifier fyne.KeyModifier) []byte {
+	key, ok := fyneSpecialKeys[name]
+	if !ok {
+		return nil
+	}
+	return []byte(gopyte.EncodeKey(key, keyModifiers(modifier), t.keyboardMode()))
+}
+
+// encodeChord returns what a Ctrl, Alt or Super chord sends, nil when the
+// key or chord has no encoding
+func (t *NativeTerminalWidget) encodeChord(name fyne.KeyName, modifier fyne.KeyModifier) []byte {
+	if data := t.encodeSpecialKey(name, modifier); data != nil {
+		return data
+	}
+
+	key, ok := fyneSymbolKeys[name]
+	if !ok {
+		char := t.keyNameToChar(name)
+		if char == 0 {
+			return nil
+		}
+		key = gopyte.Key(char)
+	}
+	return []byte(gopyte.EncodeKey(key, keyModifiers(modifier), t.keyboardMode()))
+}
+
+// encodeRune returns what a typed character sends: the character itself,
+// unless the host asked for every key as an escape code. The key is then
+// taken to be the lowercase character, with Shift for uppercase ones.
+func (t *NativeTerminalWidget) encodeRune(r rune) []byte {
+	mode := t.keyboardMode()
+	if mode.KittyFlags&gopyte.KittyReportAllKeys == 0 {
+		return []byte(string(r))
+	}
+
+	key, mods := gopyte.Key(unicode.ToLower(r)), gopyte.KeyModifiers(0)
+	if rune(key) != r {
+		mods = gopyte.ModShift
+	}
+	return []byte(gopyte.EncodeKey(key, mods, mode))
+}
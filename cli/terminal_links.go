@@ -0,0 +1,308 @@
+// terminal_links.go - OSC 8 hyperlinks and URL/IP/hostname detection
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// linkKind tells what happens when a link is Ctrl+clicked
+type linkKind int
+
+const (
+	linkURL  linkKind = iota // Opened in the browser
+	linkHost                 // IP address or hostname, offers quick connect
+)
+
+// terminalLink is a clickable span on one line, in cell (rune) columns [start, end)
+type terminalLink struct {
+	start  int
+	end    int
+	target string
+	kind   linkKind
+}
+
+var (
+	urlPattern  = regexp.MustCompile(`\b(?:https?|ftp|ssh|sftp|telnet)://[^\s<>"'` + "`" + `]+`)
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`)
+	// Hostnames need at least two dots so file names like main.go don't match
+	hostPattern = regexp.MustCompile(`\b(?:[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.){2,}[A-Za-z]{2,63}\b`)
+)
+
+// detectLinks finds the links on a rendered line. OSC 8 hyperlinks from the
+// cell attributes win over anything detected in the text.
+func detectLinks(line string, lineAttrs []gopyte.Attributes) []terminalLink {
+	var links []terminalLink
+
+	// OSC 8 runs first
+	for col := 0; col < len(lineAttrs); {
+		target := lineAttrs[col].Link
+		if target == "" {
+			col++
+			continue
+		}
+		end := col + 1
+		for end < len(lineAttrs) && lineAttrs[end].Link == target {
+			end++
+		}
+		links = append(links, hyperlinkToLink(col, end, target))
+		col = end
+	}
+
+	addMatches := func(pattern *regexp.Regexp, kind linkKind, clean func(string) string) {
+		for _, m := range pattern.FindAllStringIndex(line, -1) {
+			// Skip matches glued to a word, e.g. std::vector
+			if (m[0] > 0 && isWordByte(line[m[0]-1])) || (m[1] < len(line) && isWordByte(line[m[1]])) {
+				continue
+			}
+			text := clean(line[m[0]:m[1]])
+			if text == "" {
+				continue
+			}
+			start := utf8.RuneCountInString(line[:m[0]])
+			link := terminalLink{
+				start:  start,
+				end:    start + utf8.RuneCountInString(text),
+				target: text,
+				kind:   kind,
+			}
+			if !overlapsAny(links, link) {
+				links = append(links, link)
+			}
+		}
+	}
+
+	addMatches(urlPattern, linkURL, trimURLPunctuation)
+	addMatches(ipv6Pattern, linkHost, func(s string) string {
+		// Require a real address, not a time, MAC or a bare "::"
+		ip := net.ParseIP(s)
+		if strings.Count(s, ":") < 2 || ip == nil || ip.IsUnspecified() || !strings.ContainsAny(s, "0123456789") {
+			return ""
+		}
+		return s
+	})
+	addMatches(ipv4Pattern, linkHost, func(s string) string {
+		if net.ParseIP(s) == nil {
+			return ""
+		}
+		return s
+	})
+	addMatches(hostPattern, linkHost, func(s string) string { return s })
+
+	return links
+}
+
+// hyperlinkToLink classifies an OSC 8 target. ssh:// links become host links.
+func hyperlinkToLink(start, end int, target string) terminalLink {
+	link := terminalLink{start: start, end: end, target: target, kind: linkURL}
+	if u, err := url.Parse(target); err == nil && u.Scheme == "ssh" && u.Hostname() != "" {
+		link.kind = linkHost
+		link.target = u.Hostname()
+	}
+	return link
+}
+
+// trimURLPunctuation drops sentence punctuation and unbalanced closing
+// brackets that the URL pattern swallows
+func trimURLPunctuation(s string) string {
+	for len(s) > 0 {
+		last := s[len(s)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"", last) >= 0:
+			s = s[:len(s)-1]
+		case last == ')' && strings.Count(s, "(") < strings.Count(s, ")"):
+			s = s[:len(s)-1]
+		case last == ']' && strings.Count(s, "[") < strings.Count(s, "]"):
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 ||
+		(b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func overlapsAny(links []terminalLink, link terminalLink) bool {
+	for _, l := range links {
+		if link.start < l.end && l.start < link.end {
+			return true
+		}
+	}
+	return false
+}
+
+// linkAtColumn returns the link covering col, if any
+func linkAtColumn(links []terminalLink, col int) *terminalLink {
+	for i := range links {
+		if col >= links[i].start && col < links[i].end {
+			return &links[i]
+		}
+	}
+	return nil
+}
+
+// linkAtPosition finds the link under a widget position
+func (t *NativeTerminalWidget) linkAtPosition(pos fyne.Position) *terminalLink {
+	if t.screen == nil || t.charWidth <= 0 || t.charHeight <= 0 {
+		return nil
+	}
+
+	col := int(pos.X / t.charWidth)
+	row := int(pos.Y / t.charHeight)
+
+	t.mutex.RLock()
+	allLines := t.screen.GetDisplay()
+	allAttrs := t.screen.GetAttributes()
+	t.mutex.RUnlock()
+
+	viewport := t.calculateUnifiedViewport(allLines)
+	actualRow := viewport.scrollOffset + row
+	if actualRow < 0 || actualRow >= len(allLines) {
+		return nil
+	}
+
+	var lineAttrs []gopyte.Attributes
+	if actualRow < len(allAttrs) {
+		lineAttrs = allAttrs[actualRow]
+	}
+	return linkAtColumn(detectLinks(allLines[actualRow], lineAttrs), col)
+}
+
+// decorateHoveredLink underlines the link under the mouse. Called by both
+// color passes after the cell styles for the frame have been set.
+func (t *NativeTerminalWidget) decorateHoveredLink(lines []string, attrs [][]gopyte.Attributes) {
+	row, col := t.hoverRow, t.hoverCol
+	if row < 0 || row >= len(lines) || row >= len(t.textGrid.Rows) {
+		return
+	}
+
+	var lineAttrs []gopyte.Attributes
+	if row < len(attrs) {
+		lineAttrs = attrs[row]
+	}
+	link := linkAtColumn(detectLinks(lines[row], lineAttrs), col)
+	if link == nil {
+		return
+	}
+
+	cells := t.textGrid.Rows[row].Cells
+	for x := link.start; x < link.end && x < len(cells); x++ {
+		if cells[x].Style == nil {
+			cells[x].Style = &widget.CustomTextGridStyle{}
+		}
+		if style, ok := cells[x].Style.(*widget.CustomTextGridStyle); ok {
+			style.TextStyle.Underline = true
+		}
+	}
+}
+
+// updateHover tracks the cell under the mouse and redraws when it changes
+func (t *NativeTerminalWidget) updateHover(pos fyne.Position) {
+	if t.charWidth <= 0 || t.charHeight <= 0 {
+		return
+	}
+
+	col := int(pos.X / t.charWidth)
+	row := int(pos.Y / t.charHeight)
+	if row == t.hoverRow && col == t.hoverCol {
+		return
+	}
+
+	t.hoverRow, t.hoverCol = row, col
+	wasOnLink := t.hoverLink != nil
+	t.hoverLink = t.linkAtPosition(pos)
+	if wasOnLink || t.hoverLink != nil {
+		t.updatePending = true
+	}
+}
+
+// clearHover forgets the hovered cell when the mouse leaves the terminal
+func (t *NativeTerminalWidget) clearHover() {
+	wasOnLink := t.hoverLink != nil
+	t.hoverRow, t.hoverCol = -1, -1
+	t.hoverLink = nil
+	if wasOnLink {
+		t.updatePending = true
+	}
+}
+
+// Cursor implements desktop.Cursorable - a hand over links
+func (t *NativeTerminalWidget) Cursor() desktop.Cursor {
+	if t.hoverLink != nil {
+		return desktop.PointerCursor
+	}
+	return desktop.TextCursor
+}
+
+// SetQuickConnectHandler sets the callback used by "Quick connect to this host"
+func (t *NativeTerminalWidget) SetQuickConnectHandler(handler func(host string)) {
+	t.onQuickConnect = handler
+}
+
+// handleLinkClick opens the link under a Ctrl+click. Returns true if there
+// was a link at the position.
+func (t *NativeTerminalWidget) handleLinkClick(event *desktop.MouseEvent) bool {
+	link := t.linkAtPosition(event.Position)
+	if link == nil {
+		return false
+	}
+
+	switch link.kind {
+	case linkURL:
+		t.openURL(link.target)
+	case linkHost:
+		t.showHostLinkMenu(link.target, event.AbsolutePosition)
+	}
+	return true
+}
+
+// openURL hands a URL to the OS browser, refusing unexpected schemes
+func (t *NativeTerminalWidget) openURL(target string) {
+	u, err := url.Parse(target)
+	if err != nil || !gopyte.IsSafeLink(target) {
+		log.Printf("Links: refusing to open %q", target)
+		return
+	}
+
+	log.Printf("Links: opening %s", u)
+	if err := fyne.CurrentApp().OpenURL(u); err != nil {
+		log.Printf("Links: failed to open %s: %v", u, err)
+	}
+}
+
+// showHostLinkMenu offers quick connect / copy for a detected host
+func (t *NativeTerminalWidget) showHostLinkMenu(host string, pos fyne.Position) {
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(t)
+	if canvas == nil {
+		return
+	}
+
+	var items []*fyne.MenuItem
+	if t.onQuickConnect != nil {
+		items = append(items, fyne.NewMenuItem("Quick connect to this host", func() {
+			t.onQuickConnect(host)
+		}))
+	}
+	items = append(items, fyne.NewMenuItem(fmt.Sprintf("Copy %s", host), func() {
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		window.Clipboard().SetContent(host)
+	}))
+
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), canvas, pos)
+}
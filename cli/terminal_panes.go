@@ -0,0 +1,200 @@
+// terminal_panes.go - Session panes with a find bar, and split tabs
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/google/uuid"
+)
+
+// terminalPane is a session terminal with its scrollback find bar below it
+type terminalPane struct {
+	content  *fyne.Container
+	terminal *SSHTerminalWidget
+
+	findBar    *fyne.Container
+	findEntry  *findEntry
+	findStatus *widget.Label
+}
+
+// findEntry is the find bar's search field; Escape closes the bar
+type findEntry struct {
+	widget.Entry
+	onEscape func()
+}
+
+func newFindEntry() *findEntry {
+	e := &findEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// TypedKey closes the find bar on Escape
+func (e *findEntry) TypedKey(key *fyne.KeyEvent) {
+	if key.Name == fyne.KeyEscape && e.onEscape != nil {
+		e.onEscape()
+		return
+	}
+	e.Entry.TypedKey(key)
+}
+
+func newTerminalPane(terminal *SSHTerminalWidget) *terminalPane {
+	p := &terminalPane{
+		terminal:   terminal,
+		findEntry:  newFindEntry(),
+		findStatus: widget.NewLabel(""),
+	}
+
+	p.findEntry.SetPlaceHolder("Find in scrollback")
+	p.findEntry.OnChanged = func(string) { p.find(-1) }
+	p.findEntry.OnSubmitted = func(string) { p.find(-1) }
+	p.findEntry.onEscape = p.hideFind
+
+	// Up finds older output, down newer
+	buttons := container.NewHBox(
+		p.findStatus,
+		widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() { p.find(-1) }),
+		widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() { p.find(1) }),
+		widget.NewButtonWithIcon("", theme.CancelIcon(), p.hideFind),
+	)
+	p.findBar = container.NewBorder(nil, nil, nil, buttons, p.findEntry)
+	p.findBar.Hide()
+
+	p.content = container.NewBorder(nil, p.findBar, nil, nil, terminal)
+	return p
+}
+
+// showFind opens the find bar and focuses the search field
+func (p *terminalPane) showFind() {
+	p.findBar.Show()
+	p.content.Refresh()
+	if canvas := fyne.CurrentApp().Driver().CanvasForObject(p.terminal); canvas != nil {
+		canvas.Focus(p.findEntry)
+	}
+	if p.findEntry.Text != "" {
+		p.find(-1)
+	}
+}
+
+// hideFind closes the find bar and gives the terminal the focus back
+func (p *terminalPane) hideFind() {
+	p.findBar.Hide()
+	p.findStatus.SetText("")
+	p.terminal.ClearFind()
+	p.content.Refresh()
+	if canvas := fyne.CurrentApp().Driver().CanvasForObject(p.terminal); canvas != nil {
+		canvas.Focus(p.terminal)
+	}
+}
+
+// find moves to the next match in the given direction and shows the count
+func (p *terminalPane) find(direction int) {
+	query := p.findEntry.Text
+	if query == "" {
+		p.terminal.ClearFind()
+		p.findStatus.SetText("")
+		return
+	}
+
+	index, count := p.terminal.Find(query, direction)
+	if count == 0 {
+		p.findStatus.SetText("No matches")
+	} else {
+		p.findStatus.SetText(fmt.Sprintf("%d of %d", index+1, count))
+	}
+}
+
+// showFindBar opens the find bar of the active pane
+func (sm *SessionManager) showFindBar() {
+	if sessionTab := sm.activeSessionTab(); sessionTab != nil && sessionTab.Pane != nil {
+		sessionTab.Pane.showFind()
+	}
+}
+
+// toggleSplit opens a second connection to the active session beside it,
+// or closes the second pane if the tab is split already
+func (sm *SessionManager) toggleSplit() {
+	sessionTab := sm.activeSessionTab()
+	if sessionTab == nil {
+		return
+	}
+	if sessionTab.SplitOf != nil {
+		sessionTab = sessionTab.SplitOf
+	}
+	if sessionTab.Split != nil {
+		sm.closeSplit(sessionTab)
+	} else {
+		sm.openSplit(sessionTab)
+	}
+}
+
+// openSplit connects a second pane with the main pane's login
+func (sm *SessionManager) openSplit(main *SessionTab) {
+	session := main.Info
+	sshConfig := main.Terminal.sshConfig
+	terminal := sm.newSessionTerminal(session, sshConfig)
+
+	split := &SessionTab{
+		TabID:    uuid.New().String(),
+		Info:     session,
+		Terminal: terminal,
+		Tab:      main.Tab,
+		State:    StateDisconnected,
+		Pane:     newTerminalPane(terminal),
+		SplitOf:  main,
+	}
+
+	terminal.SetStateChangeHandler(func(state ConnectionState) {
+		split.State = state
+		fyne.Do(func() {
+			sm.sessionTree.Refresh()
+			if state == StateConnected {
+				sm.window.Canvas().Focus(terminal)
+			}
+		})
+	})
+	terminal.SetErrorHandler(func(err error) {
+		log.Printf("SSH error for %s [%s]: %v", session.Name, split.TabID, err)
+		dialog.ShowError(err, sm.window)
+	})
+
+	sm.tabsMutex.Lock()
+	sm.activeTabs[split.TabID] = split
+	sm.tabsMutex.Unlock()
+
+	main.Split = split
+	main.Body.Objects = []fyne.CanvasObject{container.NewHSplit(main.Pane.content, split.Pane.content)}
+	main.Body.Refresh()
+
+	log.Printf("Split: opening a second pane for %s [%s]", session.Name, main.TabID)
+	sm.connectSessionTab(split, sshConfig.Password, func(string) {})
+}
+
+// closeSplit disconnects the second pane of a split tab and removes it
+func (sm *SessionManager) closeSplit(main *SessionTab) {
+	split := main.Split
+	main.Split = nil
+	main.Body.Objects = []fyne.CanvasObject{main.Pane.content}
+	main.Body.Refresh()
+	sm.window.Canvas().Focus(main.Terminal)
+
+	log.Printf("Split: closing the second pane of %s [%s]", main.Info.Name, main.TabID)
+	go func() {
+		split.Terminal.Disconnect()
+		split.Terminal.CloseScrollback()
+		split.Terminal.StopRecording()
+		split.Terminal.StopSessionLog()
+		fyne.Do(func() {
+			sm.tabsMutex.Lock()
+			delete(sm.activeTabs, split.TabID)
+			sm.tabsMutex.Unlock()
+			sm.sessionTree.Refresh()
+		})
+	}()
+}
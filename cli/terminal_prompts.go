@@ -0,0 +1,274 @@
+// terminal_prompts.go - Shell prompt marks: prompt jumping, command output copy, gutter
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+// Width of the exit status marker drawn at the left edge of prompt lines
+const promptGutterWidth = 3
+
+// defaultPromptPatterns are used to find prompts on devices that cannot emit
+// OSC 133 marks, keyed by session DeviceType
+var defaultPromptPatterns = map[string]string{
+	"cisco_ios":     `^[\w.\-]+(\([\w\-]+\))?[>#]`,
+	"cisco_xe":      `^[\w.\-]+(\([\w\-]+\))?[>#]`,
+	"cisco_nxos":    `^[\w.\-]+(\([\w\-]+\))?#`,
+	"arista_eos":    `^[\w.\-]+(\([\w\-]+\))?[>#]`,
+	"juniper_junos": `^[\w.\-]+@[\w.\-]+[>#%]`,
+}
+
+// copyPromptPatterns returns a copy so defaults are never modified
+func copyPromptPatterns(patterns map[string]string) map[string]string {
+	c := make(map[string]string, len(patterns))
+	for k, v := range patterns {
+		c[k] = v
+	}
+	return c
+}
+
+// formatPromptPatterns renders patterns as "device_type = regex" lines
+func formatPromptPatterns(patterns map[string]string) string {
+	keys := make([]string, 0, len(patterns))
+	for k := range patterns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", k, patterns[k])
+	}
+	return b.String()
+}
+
+// parsePromptPatterns parses "device_type = regex" lines, checking each regex
+func parsePromptPatterns(text string) (map[string]string, error) {
+	patterns := make(map[string]string)
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		deviceType, pattern, ok := strings.Cut(line, "=")
+		deviceType = strings.TrimSpace(deviceType)
+		pattern = strings.TrimSpace(pattern)
+		if !ok || deviceType == "" || pattern == "" {
+			return nil, fmt.Errorf("line %d: expected device_type = regex", n+1)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		patterns[strings.ToLower(deviceType)] = pattern
+	}
+	return patterns, nil
+}
+
+// SetDeviceType selects the prompt regex used when the host sends no OSC 133 marks
+func (t *NativeTerminalWidget) SetDeviceType(deviceType string) {
+	t.deviceType = strings.ToLower(strings.TrimSpace(deviceType))
+}
+
+// promptRegex returns the fallback prompt regex for the session's device
+// type, looked up on each use so settings changes apply to open tabs
+func (t *NativeTerminalWidget) promptRegex() *regexp.Regexp {
+	if t.deviceType == "" {
+		return nil
+	}
+	pattern := GetSettings().Get().PromptPatterns[t.deviceType]
+	if pattern == "" {
+		return nil
+	}
+	if t.promptPattern == nil || t.promptPattern.String() != pattern {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Prompts: invalid pattern for %s: %v", t.deviceType, err)
+			return nil
+		}
+		t.promptPattern = re
+	}
+	return t.promptPattern
+}
+
+// commandMarks returns the commands in the scrollback. OSC 133 marks from
+// the shell are preferred; otherwise prompts are found with the device
+// type's regex, without exit status.
+func (t *NativeTerminalWidget) commandMarks() []gopyte.CommandMark {
+	if t.screen == nil || t.screen.IsUsingAlternate() {
+		return nil
+	}
+	if marks := t.screen.GetCommandMarks(); len(marks) > 0 {
+		return marks
+	}
+
+	re := t.promptRegex()
+	if re == nil {
+		return nil
+	}
+
+	lines := t.screen.GetLines(0, t.screen.GetHistorySize()+t.rows)
+	var marks []gopyte.CommandMark
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		if n := len(marks); n > 0 {
+			marks[n-1].EndLine = i
+		}
+		marks = append(marks, gopyte.CommandMark{
+			PromptLine: i,
+			OutputLine: i + 1,
+			EndLine:    -1,
+		})
+	}
+	return marks
+}
+
+// jumpToPrompt scrolls so the previous (direction < 0) or next prompt is
+// at the top of the view
+func (t *NativeTerminalWidget) jumpToPrompt(direction int) {
+	marks := t.commandMarks()
+	if len(marks) == 0 {
+		log.Printf("Prompts: no prompt marks to jump to")
+		return
+	}
+
+	// History position p shows content line (historySize - p) at the top
+	historySize := t.screen.GetHistorySize()
+	currentPos := t.screen.GetHistoryPos()
+	top := historySize - currentPos
+
+	target := -1
+	if direction < 0 {
+		for i := len(marks) - 1; i >= 0; i-- {
+			if marks[i].PromptLine < top {
+				target = marks[i].PromptLine
+				break
+			}
+		}
+	} else {
+		for _, m := range marks {
+			if m.PromptLine > top {
+				target = m.PromptLine
+				break
+			}
+		}
+	}
+
+	if target < 0 {
+		if direction > 0 {
+			t.ScrollToBottom()
+		}
+		return
+	}
+
+	t.scrollToContentLine(target)
+	log.Printf("Prompts: jumped to prompt at line %d", target)
+}
+
+// scrollToContentLine scrolls so a content line (a GetLines index) is at the
+// top of the view, or to the bottom when the line is on the screen
+func (t *NativeTerminalWidget) scrollToContentLine(line int) {
+	// History position p shows content line (historySize - p) at the top
+	historySize := t.screen.GetHistorySize()
+	currentPos := t.screen.GetHistoryPos()
+	newPos := historySize - line
+	if newPos <= 0 {
+		t.ScrollToBottom()
+		return
+	}
+	if newPos > currentPos {
+		t.screen.ScrollUp(newPos - currentPos)
+	} else if newPos < currentPos {
+		t.screen.ScrollDown(currentPos - newPos)
+	}
+	t.updatePending = true
+}
+
+// copyLastCommandOutput copies the output of the most recent finished command
+func (t *NativeTerminalWidget) copyLastCommandOutput() {
+	marks := t.commandMarks()
+
+	for i := len(marks) - 1; i >= 0; i-- {
+		m := marks[i]
+		if !m.Finished() || m.OutputLine < 0 {
+			continue
+		}
+
+		lines := t.screen.GetLines(m.OutputLine, m.EndLine)
+		for j := range lines {
+			lines[j] = strings.TrimRight(lines[j], " ")
+		}
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		text := strings.Join(lines, "\n")
+
+		window := fyne.CurrentApp().Driver().AllWindows()[0]
+		window.Clipboard().SetContent(text)
+		log.Printf("Prompts: copied %d lines of command output", len(lines))
+		return
+	}
+
+	log.Printf("Prompts: no finished command to copy output from")
+}
+
+// promptMarkerColor picks the gutter color for a command's exit status
+func (t *NativeTerminalWidget) promptMarkerColor(m gopyte.CommandMark) string {
+	switch {
+	case !m.HasExitCode:
+		return "bright_black"
+	case m.ExitCode == 0:
+		return "bright_green"
+	default:
+		return "bright_red"
+	}
+}
+
+// updatePromptGutter draws a marker next to each visible prompt, coloured by
+// the exit status of its command. allLines is what GetDisplay returned.
+func (t *NativeTerminalWidget) updatePromptGutter(allLines []string, viewport VirtualScrollState) {
+	if t.promptGutter == nil {
+		return
+	}
+
+	var markers []fyne.CanvasObject
+	addMarker := func(row int, colorName string) {
+		rect := canvas.NewRectangle(t.mapColor(colorName))
+		rect.Move(fyne.NewPos(0, float32(row)*t.charHeight))
+		rect.Resize(fyne.NewSize(promptGutterWidth, t.charHeight))
+		markers = append(markers, rect)
+	}
+
+	if t.screen != nil && !t.screen.IsUsingAlternate() {
+		if marks := t.screen.GetCommandMarks(); len(marks) > 0 {
+			displayStart := t.screen.GetDisplayStart()
+			for _, m := range marks {
+				row := m.PromptLine - displayStart - viewport.scrollOffset
+				if row >= 0 && row < viewport.visibleLines {
+					addMarker(row, t.promptMarkerColor(m))
+				}
+			}
+		} else if re := t.promptRegex(); re != nil {
+			// Only scan what is on screen; there is no exit status to show
+			for row := 0; row < viewport.visibleLines; row++ {
+				idx := viewport.scrollOffset + row
+				if idx < len(allLines) && re.MatchString(allLines[idx]) {
+					addMarker(row, "bright_black")
+				}
+			}
+		}
+	}
+
+	t.promptGutter.Objects = markers
+	t.promptGutter.Refresh()
+}
@@ -176,8 +176,9 @@ func (t *NativeTerminalWidget) processTerminalDataUnified(data []byte) {
 				log.Printf("Error feeding data to stream: %v", r)
 			}
 		}()
-		t.stream.Feed(string(data))
+		t.stream.FeedBytes(data)
 	}()
+	t.recordOutput(data)
 
 	// CRITICAL FIX: Handle alternate screen mode completely differently
 	if t.screen != nil && t.screen.IsUsingAlternate() {
@@ -354,6 +355,8 @@ func (t *NativeTerminalWidget) ScrollDownInHistory(lines int) {
 }
 
 func (t *NativeTerminalWidget) WriteToPTY(data []byte) error {
+	t.recordInput(data)
+
 	// Check for write override (SSH connections use this)
 	if t.writeOverride != nil {
 		t.writeOverride(data)
@@ -528,7 +531,7 @@ func (t *NativeTerminalWidget) handleEscapeSequences(data string) {
 	// Icon name changes
 	t.handleIconNameSequences(data)
 
-	// Cursor visibility changes
+	// Cursor visibility changes (DECTCEM is applied by gopyte, logged here)
 	if strings.Contains(data, "\x1b[?25l") {
 		log.Printf("TERMINAL: Cursor hidden")
 	}
@@ -621,11 +624,8 @@ func (t *NativeTerminalWidget) handleIconNameSequences(data string) {
 
 // Detect shell state and command patterns
 func (t *NativeTerminalWidget) detectShellState(data string) {
-	// Common shell prompt indicators
-	if strings.Contains(data, "$ ") || strings.Contains(data, "# ") ||
-		strings.Contains(data, "> ") || strings.Contains(data, "% ") {
-		// Likely shell prompt - could be used for command history parsing
-	}
+	// Prompts are tracked by gopyte from OSC 133 marks, with a per-device
+	// regex fallback (see terminal_prompts.go)
 
 	// Command completion sequences (bash/zsh)
 	if strings.Contains(data, "\x1b[?1004h") {
@@ -0,0 +1,151 @@
+// terminal_recording.go - Recording sessions as asciicast v2 files
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// castExtension is the file extension asciinema uses for recordings
+const castExtension = ".cast"
+
+// recordOutput records data from the host, called where it is fed to the
+// stream
+func (t *NativeTerminalWidget) recordOutput(data []byte) {
+	t.recordMutex.Lock()
+	defer t.recordMutex.Unlock()
+	if t.recorder != nil {
+		t.recorder.Output(data)
+	}
+}
+
+// recordInput records data sent to the host
+func (t *NativeTerminalWidget) recordInput(data []byte) {
+	t.recordMutex.Lock()
+	defer t.recordMutex.Unlock()
+	if t.recorder != nil {
+		t.recorder.Input(data)
+	}
+}
+
+// recordResize records a new terminal size
+func (t *NativeTerminalWidget) recordResize(cols, rows int) {
+	t.recordMutex.Lock()
+	defer t.recordMutex.Unlock()
+	if t.recorder != nil {
+		t.recorder.Resize(cols, rows)
+	}
+}
+
+// IsRecording reports whether the session is being recorded
+func (t *NativeTerminalWidget) IsRecording() bool {
+	t.recordMutex.Lock()
+	defer t.recordMutex.Unlock()
+	return t.recorder != nil
+}
+
+// StartRecording records the session to w from now on. The recording
+// starts from the current screen size; what is already on screen is not
+// included.
+func (t *NativeTerminalWidget) StartRecording(w fyne.URIWriteCloser, recordInput bool) error {
+	t.mutex.RLock()
+	header := gopyte.CastHeader{
+		Width:  t.cols,
+		Height: t.rows,
+		Title:  t.title,
+		Env:    map[string]string{"TERM": "xterm-256color"},
+	}
+	t.mutex.RUnlock()
+
+	recorder, err := gopyte.NewCastRecorder(w, header, recordInput)
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	t.recordMutex.Lock()
+	previous := t.recorder
+	t.recorder = recorder
+	t.recordingURI = w.URI()
+	t.recordMutex.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	log.Printf("Recording: started %s (input %v)", w.URI(), recordInput)
+	return nil
+}
+
+// StopRecording finishes the recording, reporting any write error that
+// cut it short
+func (t *NativeTerminalWidget) StopRecording() error {
+	t.recordMutex.Lock()
+	recorder, uri := t.recorder, t.recordingURI
+	t.recorder, t.recordingURI = nil, nil
+	t.recordMutex.Unlock()
+
+	if recorder == nil {
+		return nil
+	}
+	err := recorder.Close()
+	log.Printf("Recording: stopped %s after %s", uri, recorder.Elapsed().Round(time.Second))
+	if err != nil {
+		return fmt.Errorf("recording %s: %w", uri, err)
+	}
+	return nil
+}
+
+// recordingMenuItem starts or stops recording from the terminal menu
+func (t *NativeTerminalWidget) recordingMenuItem() *fyne.MenuItem {
+	if t.IsRecording() {
+		return fyne.NewMenuItem("Stop recording", func() {
+			if err := t.StopRecording(); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			}
+		})
+	}
+	return fyne.NewMenuItem("Start recording...", t.showStartRecordingDialog)
+}
+
+// showStartRecordingDialog asks whether to include input, then for the file
+func (t *NativeTerminalWidget) showStartRecordingDialog() {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+
+	inputCheck := widget.NewCheck("Include keyboard input", nil)
+	items := []*widget.FormItem{
+		widget.NewFormItem("", inputCheck),
+		widget.NewFormItem("", widget.NewLabel("Keyboard input includes anything typed\nat password prompts.")),
+	}
+
+	d := dialog.NewForm("Record Session", "Save as...", "Cancel", items,
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+
+			save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				if writer == nil {
+					return
+				}
+				if err := t.StartRecording(writer, inputCheck.Checked); err != nil {
+					dialog.ShowError(fmt.Errorf("failed to start recording: %w", err), window)
+				}
+			}, window)
+			save.SetFileName(t.exportFileName(castExtension))
+			save.SetFilter(storage.NewExtensionFileFilter([]string{castExtension}))
+			save.Show()
+		}, window)
+	d.Show()
+}
@@ -0,0 +1,244 @@
+// terminal_replay.go - Replaying asciicast recordings in a tab
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// replayFrameInterval is how often a playing recording is redrawn
+const replayFrameInterval = 33 * time.Millisecond
+
+// replaySpeeds are the playback speeds offered, labels and factors
+var replaySpeeds = []struct {
+	label  string
+	factor float64
+}{
+	{"0.5x", 0.5},
+	{"1x", 1},
+	{"2x", 2},
+	{"4x", 4},
+	{"8x", 8},
+}
+
+// CastReplayView plays a recording through a fresh screen with play/pause,
+// speed and seek controls. The recording keeps its recorded size.
+type CastReplayView struct {
+	player *gopyte.CastPlayer
+	mutex  sync.Mutex
+
+	grid        *widget.TextGrid
+	playButton  *widget.Button
+	speedSelect *widget.Select
+	seekSlider  *widget.Slider
+	timeLabel   *widget.Label
+	content     fyne.CanvasObject
+
+	playing bool
+	speed   float64
+	seeking bool // Slider moved by playback, not by the user
+	stop    chan struct{}
+}
+
+// NewCastReplayView creates a paused player at the start of the recording
+func NewCastReplayView(cast *gopyte.Cast) *CastReplayView {
+	v := &CastReplayView{
+		player: gopyte.NewCastPlayer(cast, gopyte.DefaultScrollbackLines),
+		speed:  1,
+		stop:   make(chan struct{}),
+	}
+
+	v.grid = widget.NewTextGrid()
+	v.grid.ShowLineNumbers = false
+	v.grid.ShowWhitespace = false
+
+	v.playButton = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), v.TogglePlay)
+
+	var labels []string
+	for _, s := range replaySpeeds {
+		labels = append(labels, s.label)
+	}
+	v.speedSelect = widget.NewSelect(labels, func(label string) {
+		for _, s := range replaySpeeds {
+			if s.label == label {
+				v.mutex.Lock()
+				v.speed = s.factor
+				v.mutex.Unlock()
+			}
+		}
+	})
+	v.speedSelect.SetSelected("1x")
+
+	v.seekSlider = widget.NewSlider(0, math.Max(cast.Duration(), 0.001))
+	v.seekSlider.Step = 0.01
+	v.seekSlider.OnChanged = func(value float64) {
+		if !v.seeking {
+			v.Seek(value)
+		}
+	}
+
+	v.timeLabel = widget.NewLabel("")
+
+	controls := container.NewBorder(nil, nil,
+		container.NewHBox(v.playButton, v.speedSelect),
+		v.timeLabel,
+		v.seekSlider)
+	v.content = container.NewBorder(nil, controls, nil, nil, container.NewScroll(v.grid))
+
+	v.render()
+	go v.playLoop()
+	return v
+}
+
+// Content returns the tab content
+func (v *CastReplayView) Content() fyne.CanvasObject {
+	return v.content
+}
+
+// TogglePlay starts or pauses playback; playing at the end starts over
+func (v *CastReplayView) TogglePlay() {
+	v.mutex.Lock()
+	v.playing = !v.playing
+	if v.playing && v.player.Done() {
+		v.player.Seek(0)
+	}
+	v.mutex.Unlock()
+	v.render()
+}
+
+// Seek jumps to a time in the recording
+func (v *CastReplayView) Seek(to float64) {
+	v.mutex.Lock()
+	v.player.Seek(to)
+	v.mutex.Unlock()
+	v.render()
+}
+
+// Stop ends playback when the tab is closed
+func (v *CastReplayView) Stop() {
+	close(v.stop)
+}
+
+// playLoop advances the player while playing
+func (v *CastReplayView) playLoop() {
+	ticker := time.NewTicker(replayFrameInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-v.stop:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last).Seconds()
+			last = now
+
+			v.mutex.Lock()
+			if !v.playing {
+				v.mutex.Unlock()
+				continue
+			}
+			v.player.Seek(v.player.Position() + elapsed*v.speed)
+			if v.player.Done() {
+				v.playing = false
+			}
+			v.mutex.Unlock()
+
+			fyne.Do(v.render)
+		}
+	}
+}
+
+// render draws the current screen and updates the controls
+func (v *CastReplayView) render() {
+	v.mutex.Lock()
+	screen := v.player.Screen
+	historySize := screen.GetHistorySize()
+	lines := screen.GetLineCells(historySize, historySize+len(screen.GetBuffer()))
+	position, duration := v.player.Position(), v.player.Cast.Duration()
+	playing := v.playing
+	v.mutex.Unlock()
+
+	rows := make([]widget.TextGridRow, len(lines))
+	for y, cells := range lines {
+		row := make([]widget.TextGridCell, len(cells))
+		for x, c := range cells {
+			style := &widget.CustomTextGridStyle{
+				FGColor: mapTerminalColor(c.Attrs.Fg),
+				BGColor: mapTerminalColor(c.Attrs.Bg),
+			}
+			row[x] = widget.TextGridCell{Rune: c.Char, Style: style}
+		}
+		rows[y] = widget.TextGridRow{Cells: row}
+	}
+	v.grid.Rows = rows
+	v.grid.Refresh()
+
+	if playing {
+		v.playButton.SetIcon(theme.MediaPauseIcon())
+	} else {
+		v.playButton.SetIcon(theme.MediaPlayIcon())
+	}
+	v.seeking = true
+	v.seekSlider.SetValue(math.Min(position, duration))
+	v.seeking = false
+	v.timeLabel.SetText(fmt.Sprintf("%s / %s", formatReplayTime(position), formatReplayTime(duration)))
+}
+
+// formatReplayTime formats seconds as m:ss
+func formatReplayTime(seconds float64) string {
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// showOpenRecordingDialog picks a recording and opens it in a replay tab
+func (sm *SessionManager) showOpenRecordingDialog() {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, sm.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		cast, err := gopyte.ReadCast(reader)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to open recording: %w", err), sm.window)
+			return
+		}
+		sm.openReplayTab(cast, reader.URI().Name())
+	}, sm.window)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{castExtension}))
+	open.Show()
+}
+
+// openReplayTab adds a tab playing a recording
+func (sm *SessionManager) openReplayTab(cast *gopyte.Cast, name string) {
+	view := NewCastReplayView(cast)
+	title := cast.Header.Title
+	if title == "" {
+		title = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	tabItem := container.NewTabItemWithIcon(title, theme.MediaVideoIcon(), view.Content())
+	sm.toolTabs[tabItem] = view
+	sm.tabContainer.Append(tabItem)
+	sm.tabContainer.Select(tabItem)
+	log.Printf("Replay: opened %s (%d events, %.1fs)", name, len(cast.Events), cast.Duration())
+}
@@ -261,7 +261,7 @@ func (sm *SelectionManager) ApplyHighlight(rows []widget.TextGridRow, viewport V
 					row.Cells[colIdx].Style = &widget.CustomTextGridStyle{}
 				}
 				style := row.Cells[colIdx].Style.(*widget.CustomTextGridStyle)
-				style.BGColor = color.RGBA{0x0C, 0x7A, 0xCC, 0xFF}
+				style.BGColor = sm.terminal.colors.Selection()
 				style.FGColor = color.White
 			}
 		}
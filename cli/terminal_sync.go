@@ -0,0 +1,35 @@
+// terminal_sync.go - Synchronized output (mode 2026): hold redraws until the host finishes a frame
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// deferRedrawForSync reports whether a redraw should be skipped because the
+// host is in the middle of a synchronized update. The frame is then drawn
+// when the end marker arrives and triggers the next redraw, or when the
+// update times out, whichever comes first.
+func (t *NativeTerminalWidget) deferRedrawForSync() bool {
+	now := time.Now()
+
+	t.mutex.RLock()
+	until, deferred := t.screen.RenderDeferred(now)
+	t.mutex.RUnlock()
+
+	if !deferred {
+		return false
+	}
+
+	t.syncMutex.Lock()
+	defer t.syncMutex.Unlock()
+
+	if t.syncTimer != nil {
+		t.syncTimer.Stop()
+	}
+	t.syncTimer = time.AfterFunc(until.Sub(now), func() {
+		fyne.Do(t.performRedrawDirect)
+	})
+	return true
+}
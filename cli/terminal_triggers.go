@@ -0,0 +1,341 @@
+// terminal_triggers.go - Trigger rules: highlight, notify, bell, send text or log on output
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"tetherssh/internal/gopyte"
+
+	"fyne.io/fyne/v2"
+)
+
+// Trigger scopes; rules for a folder or device type name it after the colon
+const (
+	TriggerScopeFolder = "folder:"
+	TriggerScopeDevice = "device:"
+)
+
+// How long the visual bell shows
+const bellFlashDuration = 120 * time.Millisecond
+
+// TriggerRule is a trigger as stored in settings
+type TriggerRule struct {
+	Scope    string `json:"scope"`    // "" for all sessions, "folder:<group>" or "device:<type>"
+	Action   string `json:"action"`   // highlight, notify, bell, send, start_log, stop_log
+	Argument string `json:"argument"` // Highlight style, message, text to send or log name
+	Instant  bool   `json:"instant"`  // Also match unfinished lines such as prompts
+	Pattern  string `json:"pattern"`
+}
+
+// triggerActions are the actions a rule can name
+var triggerActions = []gopyte.TriggerAction{
+	gopyte.TriggerHighlight, gopyte.TriggerNotify, gopyte.TriggerBell,
+	gopyte.TriggerSend, gopyte.TriggerStartLog, gopyte.TriggerStopLog,
+}
+
+// sendEscapes turns the escapes allowed in send text into control characters
+var sendEscapes = strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t", `\e`, "\x1b", `\\`, `\`)
+
+// formatTriggerRules renders rules as "[scope] action[!] argument : regex"
+// lines, "!" marking instant rules
+func formatTriggerRules(rules []TriggerRule) string {
+	var b strings.Builder
+	for _, r := range rules {
+		if r.Scope != "" {
+			fmt.Fprintf(&b, "[%s] ", r.Scope)
+		}
+		b.WriteString(r.Action)
+		if r.Instant {
+			b.WriteString("!")
+		}
+		if r.Argument != "" {
+			b.WriteString(" " + r.Argument)
+		}
+		fmt.Fprintf(&b, " : %s\n", r.Pattern)
+	}
+	return b.String()
+}
+
+// parseTriggerRules parses "[scope] action[!] argument : regex" lines,
+// checking each regex and highlight style
+func parseTriggerRules(text string) ([]TriggerRule, error) {
+	var rules []TriggerRule
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseTriggerRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseTriggerRule parses one rule line
+func parseTriggerRule(line string) (TriggerRule, error) {
+	var rule TriggerRule
+	spec, pattern, ok := strings.Cut(line, " : ")
+	rule.Pattern = strings.TrimSpace(pattern)
+	if !ok || rule.Pattern == "" {
+		return rule, fmt.Errorf("expected [scope] action argument : regex")
+	}
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return rule, err
+	}
+
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "[") {
+		scope, rest, ok := strings.Cut(spec[1:], "]")
+		if !ok {
+			return rule, fmt.Errorf("missing ] after scope")
+		}
+		rule.Scope = strings.TrimSpace(scope)
+		if !strings.HasPrefix(rule.Scope, TriggerScopeFolder) && !strings.HasPrefix(rule.Scope, TriggerScopeDevice) {
+			return rule, fmt.Errorf("scope must be folder:<name> or device:<type>")
+		}
+		spec = strings.TrimSpace(rest)
+	}
+
+	action, argument, _ := strings.Cut(spec, " ")
+	rule.Instant = strings.HasSuffix(action, "!")
+	rule.Action = strings.ToLower(strings.TrimSuffix(action, "!"))
+	rule.Argument = strings.TrimSpace(argument)
+
+	known := false
+	for _, a := range triggerActions {
+		known = known || string(a) == rule.Action
+	}
+	if !known {
+		return rule, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	if rule.Action == string(gopyte.TriggerHighlight) {
+		if _, err := parseHighlightStyle(rule.Argument); err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+// parseHighlightStyle parses styles such as "red", "bold white on red"
+func parseHighlightStyle(text string) (gopyte.Attributes, error) {
+	var style gopyte.Attributes
+	words := strings.Fields(strings.ToLower(text))
+	for i := 0; i < len(words); i++ {
+		switch word := words[i]; word {
+		case "bold":
+			style.Bold = true
+		case "italic":
+			style.Italics = true
+		case "underline":
+			style.Underscore = true
+		case "reverse":
+			style.Reverse = true
+		case "on":
+			if i+1 >= len(words) || !isTerminalColor(words[i+1]) {
+				return style, fmt.Errorf("expected a color after \"on\"")
+			}
+			i++
+			style.Bg = words[i]
+		default:
+			if !isTerminalColor(word) {
+				return style, fmt.Errorf("unknown highlight style %q", word)
+			}
+			style.Fg = word
+		}
+	}
+	if style == (gopyte.Attributes{}) {
+		return style, fmt.Errorf("highlight needs a style, such as red or bold on yellow")
+	}
+	return style, nil
+}
+
+// isTerminalColor reports whether the theme maps a color name
+func isTerminalColor(name string) bool {
+	_, ok := darkColorMappings[name]
+	return ok && name != "default"
+}
+
+// ruleApplies reports whether a rule's scope covers a session
+func (r TriggerRule) ruleApplies(group, deviceType string) bool {
+	switch {
+	case r.Scope == "":
+		return true
+	case strings.HasPrefix(r.Scope, TriggerScopeFolder):
+		return strings.EqualFold(strings.TrimPrefix(r.Scope, TriggerScopeFolder), group)
+	case strings.HasPrefix(r.Scope, TriggerScopeDevice):
+		return strings.EqualFold(strings.TrimPrefix(r.Scope, TriggerScopeDevice), deviceType)
+	}
+	return false
+}
+
+// compileTriggers returns the gopyte triggers for a session's folder and
+// device type; rules that fail to compile are logged and skipped
+func compileTriggers(rules []TriggerRule, group, deviceType string) []*gopyte.Trigger {
+	var triggers []*gopyte.Trigger
+	for _, r := range rules {
+		if !r.ruleApplies(group, deviceType) {
+			continue
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			log.Printf("Triggers: skipping %q: %v", r.Pattern, err)
+			continue
+		}
+		trigger := &gopyte.Trigger{
+			Pattern: re,
+			Action:  gopyte.TriggerAction(r.Action),
+			Text:    r.Argument,
+			Instant: r.Instant,
+		}
+		switch trigger.Action {
+		case gopyte.TriggerHighlight:
+			if trigger.Style, err = parseHighlightStyle(r.Argument); err != nil {
+				log.Printf("Triggers: skipping %q: %v", r.Pattern, err)
+				continue
+			}
+		case gopyte.TriggerSend:
+			trigger.Text = sendEscapes.Replace(r.Argument)
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers
+}
+
+// SetTriggerSession sets the session name, folder and device type that
+// select trigger rules, and applies them
+func (t *NativeTerminalWidget) SetTriggerSession(name, group, deviceType string) {
+	t.triggerSession = name
+	t.triggerGroup = group
+	t.triggerDevice = deviceType
+	if settings := GetSettings(); settings != nil {
+		t.ApplyTriggerSettings(settings.Get())
+	}
+}
+
+// ApplyTriggerSettings recompiles the trigger rules for this session
+func (t *NativeTerminalWidget) ApplyTriggerSettings(settings *AppSettings) {
+	triggers := compileTriggers(settings.Triggers, t.triggerGroup, t.triggerDevice)
+
+	t.mutex.Lock()
+	t.screen.SetTriggers(triggers, t)
+	t.mutex.Unlock()
+	log.Printf("Triggers: %d rules for %q (folder %q, device %q)",
+		len(triggers), t.triggerSession, t.triggerGroup, t.triggerDevice)
+}
+
+// TriggerFired runs a trigger action. It is called while output is fed,
+// so anything slow or touching the UI runs later.
+func (t *NativeTerminalWidget) TriggerFired(match gopyte.TriggerMatch) {
+	switch match.Trigger.Action {
+	case gopyte.TriggerNotify:
+		message := match.Text
+		if message == "" {
+			message = match.Line
+		}
+		fyne.Do(func() {
+			fyne.CurrentApp().SendNotification(fyne.NewNotification(t.triggerTitle(), message))
+		})
+	case gopyte.TriggerBell:
+		fyne.Do(t.visualBell)
+	case gopyte.TriggerSend:
+		data := []byte(match.Text)
+		go t.WriteToPTY(data)
+	case gopyte.TriggerStartLog:
+		t.startSessionLog(match.Text)
+	case gopyte.TriggerStopLog:
+		t.StopSessionLog()
+	}
+}
+
+// LineCompleted writes completed lines to the session log while one is open
+func (t *NativeTerminalWidget) LineCompleted(line string) {
+	t.sessionLogMutex.Lock()
+	defer t.sessionLogMutex.Unlock()
+	if t.sessionLog == nil {
+		return
+	}
+	if settings := GetSettings(); settings != nil && settings.Get().TimestampLogs {
+		line = time.Now().Format("2006-01-02 15:04:05 ") + line
+	}
+	if _, err := t.sessionLog.WriteString(line + "\n"); err != nil {
+		log.Printf("Session log: %v", err)
+		t.sessionLog.Close()
+		t.sessionLog = nil
+	}
+}
+
+// triggerTitle names the session in notifications
+func (t *NativeTerminalWidget) triggerTitle() string {
+	if t.triggerSession != "" {
+		return t.triggerSession
+	}
+	return t.GetTitle()
+}
+
+// startSessionLog opens {name}_{timestamp}.log in the log directory; a log
+// already open is kept
+func (t *NativeTerminalWidget) startSessionLog(name string) {
+	t.sessionLogMutex.Lock()
+	defer t.sessionLogMutex.Unlock()
+	if t.sessionLog != nil {
+		return
+	}
+
+	dir := GetLogsDir()
+	if settings := GetSettings(); settings != nil && settings.Get().LogDirectory != "" {
+		dir = settings.Get().LogDirectory
+	}
+	if name == "" {
+		name = t.triggerTitle()
+	}
+	name = unsafeFileChars.ReplaceAllString(name, "_")
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.log", name, time.Now().Format("20060102-150405")))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Session log: %v", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("Session log: %v", err)
+		return
+	}
+	t.sessionLog = f
+	log.Printf("Session log: started %s", path)
+}
+
+// StopSessionLog closes the session log
+func (t *NativeTerminalWidget) StopSessionLog() {
+	t.sessionLogMutex.Lock()
+	defer t.sessionLogMutex.Unlock()
+	if t.sessionLog == nil {
+		return
+	}
+	log.Printf("Session log: stopped %s", t.sessionLog.Name())
+	t.sessionLog.Close()
+	t.sessionLog = nil
+}
+
+// visualBell briefly flashes the terminal
+func (t *NativeTerminalWidget) visualBell() {
+	if t.bellFlash == nil {
+		return
+	}
+	r, g, b, _ := GetTerminalColorMappings()["default"].RGBA()
+	t.bellFlash.FillColor = color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0x30}
+	t.bellFlash.Show()
+	t.bellFlash.Refresh()
+	time.AfterFunc(bellFlashDuration, func() {
+		fyne.Do(t.bellFlash.Hide)
+	})
+}
@@ -5,14 +5,20 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"math"
+	"os"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"tetherssh/internal/gopyte"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
 )
@@ -48,10 +54,17 @@ type NativeTerminalWidget struct {
 	// State management
 	title string
 
-	// Font and sizing
-	fontSize   float32
-	charWidth  float32
-	charHeight float32
+	// Font and sizing (see terminal_font.go)
+	fontSize     float32
+	zoomOffset   float32 // Points added to the settings size by zooming
+	textTheme    *terminalTextTheme
+	textOverride *container.ThemeOverride
+	gridContent  *fyne.Container // The grid and its line-spaced rows
+	lineRows     *fyne.Container // One grid per row, for a line height above 1
+	lineHeight   float32         // Row height as a multiple of cellHeight
+	charWidth    float32
+	charHeight   float32 // Row height, with the line height applied
+	cellHeight   float32 // Height of a TextGrid row
 	cols       int
 	rows       int
 
@@ -62,6 +75,7 @@ type NativeTerminalWidget struct {
 	mutex         sync.RWMutex
 	updateChannel chan []byte
 	updatePending bool
+	responses     chan string // Replies to host queries, see sendDeviceResponse
 
 	// Context for cancellation
 	ctx    context.Context
@@ -73,6 +87,10 @@ type NativeTerminalWidget struct {
 	resizeTimer *time.Timer
 	resizeMutex sync.Mutex
 
+	// Synchronized output timeout (see terminal_sync.go)
+	syncTimer *time.Timer
+	syncMutex sync.Mutex
+
 	// Selection support
 	selectionStart fyne.Position
 	selectionEnd   fyne.Position
@@ -102,6 +120,69 @@ type NativeTerminalWidget struct {
 
 	// Resize callback - allows SSH sessions to receive resize events
 	onResizeCallback func(cols, rows int)
+
+	// Shortcut callbacks - let the session manager claim chords the keymap
+	// leaves unbound (snippets) and run window actions (tabs)
+	onShortcut  func(shortcut fyne.KeyboardShortcut) bool
+	onKeyAction func(action string) bool
+
+	// OSC 52 clipboard policy for this session ("" = use settings)
+	clipboardPolicy string
+	clipboardHost   string
+	// An OSC 52 read is waiting for the user's answer
+	clipboardQueryPending atomic.Bool
+
+	// Link hover state (visible grid cell under the mouse, -1 when outside)
+	hoverRow       int
+	hoverCol       int
+	hoverLink      *terminalLink
+	onQuickConnect func(host string)
+
+	// Prompt marks: gutter overlay and the regex fallback for devices
+	// without OSC 133 (see terminal_prompts.go)
+	promptGutter  *fyne.Container
+	deviceType    string
+	promptPattern *regexp.Regexp
+
+	// Underlines and overlines drawn over the text (see terminal_decorations.go)
+	decorations *fyne.Container
+
+	// Cursor overlay (see terminal_cursor.go). Row/col are the visible grid
+	// cell, -1 when the cursor is off screen.
+	cursorOverlay *canvas.Rectangle
+	cursorRow     int
+	cursorCol     int
+	cursorBlinkOn bool
+
+	// Session recording (see terminal_recording.go)
+	recorder     *gopyte.CastRecorder
+	recordingURI fyne.URI
+	recordMutex  sync.Mutex
+
+	// Trigger rules and the session log they start (see terminal_triggers.go)
+	triggerSession  string
+	triggerGroup    string
+	triggerDevice   string
+	sessionLog      *os.File
+	sessionLogMutex sync.Mutex
+	bellFlash       *canvas.Rectangle
+
+	// Color scheme for this session ("" = use settings) and its palette
+	// (see terminal_colors.go)
+	colorScheme  string
+	schemeColors *terminalPalette // The scheme alone
+	colors       *terminalPalette // With the host's OSC changes
+	colorVersion int
+	background   *canvas.Rectangle
+
+	// Scrollback search (see terminal_find.go)
+	findQuery   string
+	findCurrent findMatch
+	findActive  bool
+
+	// When the terminal last gained focus, to tell which pane of a split
+	// tab is active (see terminal_panes.go)
+	focusedAt time.Time
 }
 
 // NewNativeTerminalWidget creates a new cross-platform terminal with history support
@@ -110,9 +191,9 @@ func NewNativeTerminalWidget(darkMode bool) *NativeTerminalWidget {
 
 	t := &NativeTerminalWidget{
 		updateChannel: make(chan []byte, 1000),
+		responses:     make(chan string, deviceResponseQueue),
 		ctx:           ctx,
 		cancel:        cancel,
-		fontSize:      13.0,
 		cols:          80,
 		rows:          24,
 		title:         "Terminal",
@@ -130,6 +211,15 @@ func NewNativeTerminalWidget(darkMode bool) *NativeTerminalWidget {
 		debugEvents:    true,
 		lastScrollTime: time.Now(),
 
+		// No link hovered yet
+		hoverRow: -1,
+		hoverCol: -1,
+
+		// Cursor not drawn until the first render
+		cursorRow:     -1,
+		cursorCol:     -1,
+		cursorBlinkOn: true,
+
 		// Initialize virtual scroll state
 		virtualScroll: VirtualScrollState{
 			visibleLines: 24,
@@ -138,23 +228,33 @@ func NewNativeTerminalWidget(darkMode bool) *NativeTerminalWidget {
 
 	t.selection = NewSelectionManager(t)
 
-	t.calculateCharDimensions()
-
-	// Enhanced WideCharScreen with more history for better scrolling
-	historyLines := 1000
-	if runtime.GOOS == "windows" {
-		// ConPTY can handle more history efficiently
-		historyLines = 2000
+	// WideCharScreen with the scrollback size from settings
+	historyLines := gopyte.DefaultScrollbackLines
+	if settings := GetSettings(); settings != nil && settings.Get().ScrollbackLines > 0 {
+		historyLines = settings.Get().ScrollbackLines
 	}
 
 	log.Printf("Creating WideCharScreen with enhanced history support (%d lines)", historyLines)
 	t.screen = gopyte.NewWideCharScreen(t.cols, t.rows, historyLines)
 	t.stream = gopyte.NewStream(t.screen, false)
+	t.stream.SetClipboardHandler(t)
+	t.screen.SetResponseHandler(t.sendDeviceResponse)
+	t.screen.SetColorProvider(t.schemeColor)
+	if settings := GetSettings(); settings != nil {
+		t.screen.SetTriggers(compileTriggers(settings.Get().Triggers, "", ""), t)
+	}
+	if settings := GetSettings(); settings != nil && settings.Get().ScrollbackSpill {
+		if err := t.screen.SetScrollbackSpill(true); err != nil {
+			log.Printf("Scrollback: %v", err)
+		}
+	}
 
 	// Create TextGrid
 	t.textGrid = widget.NewTextGrid()
 	t.textGrid.ShowLineNumbers = false
 	t.textGrid.ShowWhitespace = false
+	t.initFont()
+	t.initColors()
 
 	// Initialize TextGrid size
 	t.initializeTextGridSize()
@@ -162,6 +262,8 @@ func NewNativeTerminalWidget(darkMode bool) *NativeTerminalWidget {
 	// Start background processing
 	go t.dataProcessor()
 	go t.updateProcessor()
+	go t.cursorBlinker()
+	go t.deviceResponseWriter()
 
 	t.ExtendBaseWidget(t)
 	log.Printf("NewNativeTerminalWidget: Created %s terminal widget", runtime.GOOS)
@@ -192,118 +294,61 @@ func (t *NativeTerminalWidget) Focusable() bool {
 func (t *NativeTerminalWidget) FocusLost() {
 	fmt.Printf("FocusLost: Unified terminal widget lost focus (%s)\n", runtime.GOOS)
 	t.hasFocus = false
+	t.updateCursorOverlay()
+}
+
+// SetShortcutHandler sets a callback offered each chord the keymap does not
+// bind; it returns true when it handled the chord
+func (t *NativeTerminalWidget) SetShortcutHandler(handler func(shortcut fyne.KeyboardShortcut) bool) {
+	t.onShortcut = handler
+}
+
+// SetKeyActionHandler sets the callback for keymap actions outside the
+// terminal, such as switching tabs; it returns true when it ran the action
+func (t *NativeTerminalWidget) SetKeyActionHandler(handler func(action string) bool) {
+	t.onKeyAction = handler
 }
 
 func (t *NativeTerminalWidget) TypedShortcut(shortcut fyne.Shortcut) {
 	fmt.Printf("TypedShortcut received: %T\n", shortcut)
 
+	// Keymap bindings (copy, paste, tabs...) and snippets come first
+	if keyboardShortcut, ok := shortcut.(fyne.KeyboardShortcut); ok && t.handleKeyChord(keyboardShortcut) {
+		t.updatePending = true
+		return
+	}
+
 	if !t.isPTYAvailable() {
 		fmt.Printf("TypedShortcut: No PTY available, ignoring\n")
 		return
 	}
 
-	// Handle desktop custom shortcuts (Ctrl+key and Alt+key combinations)
+	// Ctrl, Alt and Super chords, encoded the way the host asked for
 	if customShortcut, ok := shortcut.(*desktop.CustomShortcut); ok {
 		fmt.Printf("Custom shortcut detected: Key=%s, Modifier=%d\n",
 			customShortcut.KeyName, customShortcut.Modifier)
-		// In TypedShortcut method:
-		if customShortcut.Modifier&fyne.KeyModifierControl != 0 {
-			if customShortcut.KeyName == fyne.KeyC {
-				if t.selection != nil && t.selection.HasSelection() {
-					// Copy selection and clear
-					t.selection.CopyToClipboard()
-					t.selection.Clear()
-					fmt.Printf("Copied selection and cleared\n")
-					return
-				} else {
-					// No selection - send interrupt
-					t.WriteToPTY([]byte{0x03})
-					return
-				}
-			}
-		}
-		// Handle Alt modifier combinations FIRST (before Control)
-		if customShortcut.Modifier&fyne.KeyModifierAlt != 0 {
-			// Alt+key sends ESC followed by the key
-			var sequence []byte
-
-			// Check if it's Alt+Control combo (handle differently)
-			if customShortcut.Modifier&fyne.KeyModifierControl != 0 {
-				// Alt+Ctrl combinations - less common but some apps use them
-				fmt.Printf("Alt+Ctrl combo detected\n")
-				// You can implement specific handling here if needed
-				return
-			}
-
-			// Pure Alt+key combinations
-			if keyChar := t.keyNameToChar(customShortcut.KeyName); keyChar != 0 {
-				sequence = []byte{0x1B, keyChar} // ESC + character
-				fmt.Printf("Sending Alt+%c sequence: ESC+%c (0x1B 0x%02X)\n",
-					keyChar, keyChar, keyChar)
-			} else {
-				// Handle special keys with Alt
-				switch customShortcut.KeyName {
-				case fyne.KeyLeft:
-					sequence = []byte{0x1B, 0x5B, 0x44} // ESC[D
-				case fyne.KeyRight:
-					sequence = []byte{0x1B, 0x5B, 0x43} // ESC[C
-				case fyne.KeyUp:
-					sequence = []byte{0x1B, 0x5B, 0x41} // ESC[A
-				case fyne.KeyDown:
-					sequence = []byte{0x1B, 0x5B, 0x42} // ESC[B
-				case fyne.KeyBackspace:
-					sequence = []byte{0x1B, 0x7F} // ESC + DEL
-				case fyne.KeyReturn:
-					sequence = []byte{0x1B, 0x0D} // ESC + CR
-				}
-			}
-
-			if len(sequence) > 0 {
-				t.WriteToPTY(sequence)
-				t.updatePending = true
-				if t.screen != nil {
-					t.screen.InvalidateCache()
-				}
-				t.triggerImmediateRedraw()
-				return
-			}
-		}
-
-		// Handle Control modifier combinations (existing code)
-		if customShortcut.Modifier&fyne.KeyModifierControl != 0 {
-			controlByte := t.keyNameToControlByte(customShortcut.KeyName)
-			if controlByte != 0 {
-				fmt.Printf("Sending control sequence: Ctrl+%c (0x%02X)\n",
-					controlByte+64, controlByte)
-
-				t.WriteToPTY([]byte{controlByte})
-				t.updatePending = true
-
-				if t.screen != nil {
-					t.screen.InvalidateCache()
-				}
-
-				t.triggerImmediateRedraw()
-				return
+		if data := t.encodeChord(customShortcut.KeyName, customShortcut.Modifier); len(data) > 0 {
+			fmt.Printf("Sending chord sequence: %q\n", data)
+			t.WriteToPTY(data)
+			t.updatePending = true
+			if t.screen != nil {
+				t.screen.InvalidateCache()
 			}
+			t.triggerImmediateRedraw()
+			return
 		}
 	}
 
-	// Handle standard shortcuts (Copy, Paste, Cut, etc.)
-	switch shortcut := shortcut.(type) {
+	// Standard shortcuts the keymap leaves unbound go to the host as
+	// control keys; copy and paste are keymap actions
+	switch shortcut.(type) {
 	case *fyne.ShortcutCopy:
 		fmt.Printf("Copy shortcut (Ctrl+C) - sending interrupt\n")
 		t.WriteToPTY([]byte{0x03}) // Ctrl+C
 
 	case *fyne.ShortcutPaste:
-		fmt.Printf("Paste shortcut (Ctrl+V) detected\n")
-		if shortcut.Clipboard != nil {
-			content := shortcut.Clipboard.Content()
-			if content != "" {
-				fmt.Printf("Pasting content: %q\n", content)
-				t.WriteToPTY([]byte(content))
-			}
-		}
+		fmt.Printf("Paste shortcut (Ctrl+V) - sending literal next\n")
+		t.WriteToPTY([]byte{0x16}) // Ctrl+V
 
 	case *fyne.ShortcutCut:
 		fmt.Printf("Cut shortcut (Ctrl+X) detected\n")
@@ -398,66 +443,6 @@ func (t *NativeTerminalWidget) keyNameToChar(keyName fyne.KeyName) byte {
 	}
 }
 
-// ADD: keyNameToControlByte method
-func (t *NativeTerminalWidget) keyNameToControlByte(keyName fyne.KeyName) byte {
-	switch keyName {
-	case fyne.KeyA:
-		return 0x01 // Ctrl+A
-	case fyne.KeyB:
-		return 0x02 // Ctrl+B
-	case fyne.KeyC:
-		return 0x03 // Ctrl+C
-	case fyne.KeyD:
-		return 0x04 // Ctrl+D
-	case fyne.KeyE:
-		return 0x05 // Ctrl+E
-	case fyne.KeyF:
-		return 0x06 // Ctrl+F
-	case fyne.KeyG:
-		return 0x07 // Ctrl+G
-	case fyne.KeyH:
-		return 0x08 // Ctrl+H
-	case fyne.KeyI:
-		return 0x09 // Ctrl+I
-	case fyne.KeyJ:
-		return 0x0A // Ctrl+J
-	case fyne.KeyK:
-		return 0x0B // Ctrl+K
-	case fyne.KeyL:
-		return 0x0C // Ctrl+L
-	case fyne.KeyM:
-		return 0x0D // Ctrl+M
-	case fyne.KeyN:
-		return 0x0E // Ctrl+N
-	case fyne.KeyO:
-		return 0x0F // Ctrl+O
-	case fyne.KeyP:
-		return 0x10 // Ctrl+P
-	case fyne.KeyQ:
-		return 0x11 // Ctrl+Q
-	case fyne.KeyR:
-		return 0x12 // Ctrl+R
-	case fyne.KeyS:
-		return 0x13 // Ctrl+S
-	case fyne.KeyT:
-		return 0x14 // Ctrl+T
-	case fyne.KeyU:
-		return 0x15 // Ctrl+U
-	case fyne.KeyV:
-		return 0x16 // Ctrl+V
-	case fyne.KeyW:
-		return 0x17 // Ctrl+W
-	case fyne.KeyX:
-		return 0x18 // Ctrl+X
-	case fyne.KeyY:
-		return 0x19 // Ctrl+Y
-	case fyne.KeyZ:
-		return 0x1A // Ctrl+Z
-	default:
-		return 0
-	}
-}
-
 // ADD: triggerImmediateRedraw method (removed - already exists in terminal_events.go)
 
 // ADD: Missing unified history methods (removed - already exist in other files)
@@ -470,22 +455,61 @@ func (t *NativeTerminalWidget) CreateRenderer() fyne.WidgetRenderer {
 	t.scroll = NewHybridScrollContainer(t)
 	t.scroll.OptimizeForVirtualScrolling()
 
+	// The scheme background sits behind the text
+	t.background = canvas.NewRectangle(t.colors.Background())
+
+	// Prompt markers are drawn over the left edge of the text
+	t.promptGutter = container.NewWithoutLayout()
+
+	// Underlines and overlines the TextGrid cannot draw go over the text
+	t.decorations = container.NewWithoutLayout()
+
+	// The cursor is drawn on top of the text, positioned by the renderers
+	t.cursorOverlay = canvas.NewRectangle(color.Transparent)
+	t.cursorOverlay.Hide()
+
+	// The visual bell covers everything while it flashes
+	t.bellFlash = canvas.NewRectangle(color.Transparent)
+	t.bellFlash.Hide()
+
 	return &unifiedTerminalRenderer{
-		widget:  t,
-		scroll:  t.scroll,
-		content: t.scroll,
+		widget:      t,
+		scroll:      t.scroll,
+		content:     t.scroll,
+		background:  t.background,
+		decorations: t.decorations,
+		gutter:      t.promptGutter,
+		cursor:      t.cursorOverlay,
+		bell:        t.bellFlash,
 	}
 }
 
 type unifiedTerminalRenderer struct {
-	widget  *NativeTerminalWidget
-	scroll  *HybridScrollContainer
-	content fyne.CanvasObject
+	widget      *NativeTerminalWidget
+	scroll      *HybridScrollContainer
+	content     fyne.CanvasObject
+	background  *canvas.Rectangle
+	decorations *fyne.Container
+	gutter      *fyne.Container
+	cursor      *canvas.Rectangle
+	bell        *canvas.Rectangle
 }
 
 // Ensure we implement all required fyne.WidgetRenderer methods
 func (r *unifiedTerminalRenderer) Layout(size fyne.Size) {
 	r.content.Resize(size)
+	if r.background != nil {
+		r.background.Resize(size)
+	}
+	if r.decorations != nil {
+		r.decorations.Resize(size)
+	}
+	if r.gutter != nil {
+		r.gutter.Resize(size)
+	}
+	if r.bell != nil {
+		r.bell.Resize(size)
+	}
 
 	widget := r.widget
 	cols, rows := widget.CalculateTerminalSize(size.Width, size.Height)
@@ -516,7 +540,24 @@ func (r *unifiedTerminalRenderer) Refresh() {
 
 func (r *unifiedTerminalRenderer) Objects() []fyne.CanvasObject {
 	if r.content != nil {
-		return []fyne.CanvasObject{r.content}
+		var objects []fyne.CanvasObject
+		if r.background != nil {
+			objects = append(objects, r.background)
+		}
+		objects = append(objects, r.content)
+		if r.decorations != nil {
+			objects = append(objects, r.decorations)
+		}
+		if r.gutter != nil {
+			objects = append(objects, r.gutter)
+		}
+		if r.cursor != nil {
+			objects = append(objects, r.cursor)
+		}
+		if r.bell != nil {
+			objects = append(objects, r.bell)
+		}
+		return objects
 	}
 	return []fyne.CanvasObject{}
 }
@@ -543,6 +584,7 @@ func (t *NativeTerminalWidget) performRedrawUnified() {
 		go func() {
 			fyne.Do(func() {
 				t.renderAlternateScreenUnified(allLines, allAttrs)
+				t.syncLineRows()
 			})
 		}()
 	} else {
@@ -551,6 +593,7 @@ func (t *NativeTerminalWidget) performRedrawUnified() {
 		go func() {
 			fyne.Do(func() {
 				t.renderNormalModeUnified(allLines, allAttrs, shouldAutoScroll)
+				t.syncLineRows()
 			})
 		}()
 	}
@@ -588,13 +631,8 @@ func (t *NativeTerminalWidget) renderNormalModeUnified(allLines []string, allAtt
 	// Handle cursor positioning
 	cursorX, cursorY := t.screen.GetCursor()
 	adjustedCursorY := t.adjustUnifiedCursor(cursorX, cursorY, viewport, len(allLines))
-
-	// Place cursor if visible and not in history mode
-	if adjustedCursorY >= 0 && adjustedCursorY < len(visibleLines) &&
-		cursorX >= 0 && cursorX < t.cols && !t.IsInHistoryModeUnified() {
-		t.placeCursorInLineFast(&visibleLines[adjustedCursorY], cursorX)
-		log.Printf("NORMAL (%s): Cursor at (%d,%d) in viewport", runtime.GOOS, cursorX, adjustedCursorY)
-	}
+	cursorVisible := adjustedCursorY >= 0 && adjustedCursorY < len(visibleLines) &&
+		cursorX >= 0 && cursorX < t.cols && !t.IsInHistoryModeUnified()
 
 	// Set visible content
 	fullText := strings.Join(visibleLines, "\n")
@@ -611,6 +649,17 @@ func (t *NativeTerminalWidget) renderNormalModeUnified(allLines []string, allAtt
 		t.selection.ApplyHighlight(t.textGrid.Rows, viewport)
 		t.textGrid.Refresh() // Ensure refresh after highlight
 	}
+	t.updatePromptGutter(allLines, viewport)
+	t.decorateFindMatch(viewport)
+
+	// Draw the cursor overlay if visible and not in history mode
+	if cursorVisible {
+		t.setCursorCell(adjustedCursorY, cursorX)
+		log.Printf("NORMAL (%s): Cursor at (%d,%d) in viewport", runtime.GOOS, cursorX, adjustedCursorY)
+	} else {
+		t.setCursorCell(-1, -1)
+	}
+
 	// Update scroll bar position
 	t.updateUnifiedScrollBar(viewport)
 
@@ -834,11 +883,32 @@ func (t *NativeTerminalWidget) ScrollToBottom() {
 func (t *NativeTerminalWidget) SetMaxHistoryLines(maxLines int) {
 	// ONLY set WideCharScreen history - remove PTYManager part entirely
 	if t.screen != nil {
+		t.mutex.Lock()
 		t.screen.SetMaxHistoryLines(maxLines)
+		t.mutex.Unlock()
+		t.updatePending = true
 		log.Printf("Set max history lines to %d", maxLines)
 	}
 }
 
+// ApplyScrollbackSettings resizes the scrollback and turns spilling to disk
+// on or off after the settings were saved
+func (t *NativeTerminalWidget) ApplyScrollbackSettings(settings *AppSettings) {
+	if t.screen == nil {
+		return
+	}
+	if settings.ScrollbackLines > 0 {
+		t.SetMaxHistoryLines(settings.ScrollbackLines)
+	}
+
+	t.mutex.Lock()
+	err := t.screen.SetScrollbackSpill(settings.ScrollbackSpill)
+	t.mutex.Unlock()
+	if err != nil {
+		log.Printf("Scrollback: %v", err)
+	}
+}
+
 func (t *NativeTerminalWidget) TestColors() {
 	// Enhanced color test that works on both Windows and Unix
 	var colorTest string
@@ -872,8 +942,28 @@ func (t *NativeTerminalWidget) Close() {
 	}
 	t.resizeMutex.Unlock()
 
+	// Stop synchronized output timer
+	t.syncMutex.Lock()
+	if t.syncTimer != nil {
+		t.syncTimer.Stop()
+	}
+	t.syncMutex.Unlock()
+
 	// Close unified PTY
 	t.CloseUnified()
+
+	t.CloseScrollback()
+	t.StopRecording()
+	t.StopSessionLog()
+}
+
+// CloseScrollback removes the scrollback spill file of a closed session
+func (t *NativeTerminalWidget) CloseScrollback() {
+	if t.screen != nil {
+		t.mutex.Lock()
+		t.screen.Close()
+		t.mutex.Unlock()
+	}
 }
 
 // ENHANCED DEBUG METHODS
@@ -1034,21 +1124,11 @@ func (t *NativeTerminalWidget) updateProcessor() {
 
 // HELPER METHODS
 func (t *NativeTerminalWidget) calculateCharDimensions() {
-	// Platform-specific character dimension calculations
-	switch runtime.GOOS {
-	case "windows":
-		// Windows tends to have slightly different font rendering
-		t.charWidth = t.fontSize * 0.58
-		t.charHeight = t.fontSize * 1.25
-	case "darwin":
-		// macOS font rendering
-		t.charWidth = t.fontSize * 0.55
-		t.charHeight = t.fontSize * 1.15
-	default:
-		// Linux and other Unix systems
-		t.charWidth = t.fontSize * 0.56
-		t.charHeight = t.fontSize * 1.22
-	}
+	// Measured and rounded the way TextGrid sizes its cells
+	size := fyne.MeasureText("M", t.fontSize, fyne.TextStyle{Monospace: true})
+	t.charWidth = float32(math.Round(float64(size.Width)))
+	t.cellHeight = float32(math.Round(float64(size.Height)))
+	t.charHeight = float32(math.Round(float64(t.cellHeight * t.lineHeight)))
 
 	log.Printf("Character dimensions (%s): %.2fx%.2f for fontSize %.1f",
 		runtime.GOOS, t.charWidth, t.charHeight, t.fontSize)
@@ -1065,30 +1145,6 @@ func (t *NativeTerminalWidget) initializeTextGridSize() {
 		runtime.GOOS, initialSize.Width, initialSize.Height, t.cols, t.rows)
 }
 
-func (t *NativeTerminalWidget) placeCursorInLineFast(line *string, cursorX int) {
-	if line == nil || cursorX < 0 {
-		return
-	}
-
-	currentLine := *line
-	runes := []rune(currentLine)
-
-	if cursorX < len(runes) {
-		// Replace character at cursor position with block cursor
-		runes[cursorX] = '█' // Block cursor character
-		*line = string(runes)
-	} else if cursorX < t.cols {
-		// Extend line with spaces and place cursor
-		padLen := cursorX - len(runes)
-		if padLen > 0 {
-			padding := strings.Repeat(" ", padLen)
-			*line = currentLine + padding + "█"
-		} else {
-			*line = currentLine + "█"
-		}
-	}
-}
-
 // Utility function for minimum
 func min(a, b int) int {
 	if a < b {
@@ -1148,6 +1204,8 @@ func (t *NativeTerminalWidget) applyColorsToTextGrid(lines []string, attrs [][]g
 		t.applyLineColorsFromAttributes(rowIdx, line, attrs[rowIdx])
 	}
 
+	t.updateTextDecorations(attrs)
+	t.decorateHoveredLink(lines, attrs)
 	t.textGrid.Refresh()
 }
 
@@ -1159,6 +1217,7 @@ func (t *NativeTerminalWidget) applyLineColorsFromAttributes(rowIdx int, line st
 
 	row := t.textGrid.Rows[rowIdx]
 	runes := []rune(line)
+	lineAttrs = t.highlightLine(line, lineAttrs)
 
 	for charIdx, char := range runes {
 		if charIdx >= len(row.Cells) || charIdx >= len(lineAttrs) {
@@ -1188,6 +1247,7 @@ func (t *NativeTerminalWidget) applyLineColorsFromAttributes(rowIdx int, line st
 		}
 
 		row.Cells[charIdx].Rune = char
+		t.applyTextAttributes(&row.Cells[charIdx], style, attr)
 	}
 }
 
@@ -1197,20 +1257,10 @@ func (t *NativeTerminalWidget) mapGopyteColorToFyne(colorName string) color.Colo
 		return nil
 	}
 
-	if fyneColor, exists := colorMappings[colorName]; exists {
-		return fyneColor
-	}
-
-	if strings.HasPrefix(colorName, "color") {
-		return colorMappings["white"]
-	}
-
-	switch colorName {
-	case "brown":
-		return colorMappings["yellow"]
-	default:
-		return colorMappings["white"]
+	if colorName == "brown" {
+		colorName = "yellow"
 	}
+	return t.colors.ansi(colorName)
 }
 
 // HELPER METHOD: makeBrighter
@@ -1311,11 +1361,6 @@ func (t *NativeTerminalWidget) renderAlternateScreenUnified(allLines []string, a
 	// Get cursor position
 	cursorX, cursorY := t.screen.GetCursor()
 
-	// Place cursor exactly where app says it should be
-	if cursorY >= 0 && cursorY < len(displayLines) && cursorX >= 0 && cursorX < t.cols {
-		t.placeCursorInLineFast(&displayLines[cursorY], cursorX)
-	}
-
 	// Set content exactly as app wants it
 	fullText := strings.Join(displayLines, "\n")
 	t.textGrid.SetText(fullText)
@@ -1326,6 +1371,14 @@ func (t *NativeTerminalWidget) renderAlternateScreenUnified(allLines []string, a
 	} else {
 		t.textGrid.Refresh()
 	}
+	t.updatePromptGutter(nil, VirtualScrollState{}) // No prompts on the alternate screen
+
+	// Place cursor exactly where app says it should be
+	if cursorY >= 0 && cursorY < len(displayLines) && cursorX >= 0 && cursorX < t.cols {
+		t.setCursorCell(cursorY, cursorX)
+	} else {
+		t.setCursorCell(-1, -1)
+	}
 
 	log.Printf("ALTERNATE: Rendered %d lines", len(displayLines))
 }
@@ -1482,15 +1535,15 @@ func (t *NativeTerminalWidget) TripleTapped(event *fyne.PointEvent) {
 
 // MouseIn implements desktop.Hoverable
 func (t *NativeTerminalWidget) MouseIn(event *desktop.MouseEvent) {
-	// Optional: handle mouse enter - could show cursor or change state
+	t.updateHover(event.Position)
 }
 
 // MouseOut implements desktop.Hoverable
 func (t *NativeTerminalWidget) MouseOut() {
-	// Optional: handle mouse leave
+	t.clearHover()
 }
 
 // MouseMoved implements desktop.Hoverable
 func (t *NativeTerminalWidget) MouseMoved(event *desktop.MouseEvent) {
-	// Optional: handle mouse movement for hover effects
+	t.updateHover(event.Position)
 }
@@ -452,6 +452,11 @@ func (t *NativeTheme) lightColor(name fyne.ThemeColorName, variant fyne.ThemeVar
 }
 
 func (t *NativeTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if style.Monospace {
+		if font := currentTerminalFont(); font != nil {
+			return font
+		}
+	}
 	return theme.DefaultTheme().Font(style)
 }
 
@@ -1,9 +1,5 @@
 package gopyte
 
-import (
-	"container/list"
-)
-
 // AlternateScreen adds alternative screen buffer support to HistoryScreen
 // This is used by applications like vim, less, etc.
 type AlternateScreen struct {
@@ -14,7 +10,7 @@ type AlternateScreen struct {
 	mainAttrs    [][]Attributes
 	mainCursor   Cursor
 	mainTabStops map[int]bool
-	mainHistory  *list.List
+	mainHistory  *Scrollback
 
 	altBuffer   [][]rune
 	altAttrs    [][]Attributes
@@ -120,8 +116,8 @@ func (a *AlternateScreen) switchToAlternate() {
 	a.cursor = Cursor{X: 0, Y: 0, Attrs: DefaultAttributes()}
 	a.tabStops = a.altTabStops
 
-	// Alternate screen doesn't use History, use empty list
-	a.History = list.New()
+	// Alternate screen doesn't use History, use an empty one
+	a.History = NewScrollback(a.mainHistory.MaxLines())
 	a.usingAlternate = true
 
 	// If we were viewing History, exit that mode
@@ -351,7 +347,7 @@ func (a *AlternateScreen) Resize(newCols, newLines int) {
 		// Resize the alt buffer “in place” by temporarily making it active,
 		// delegating to base, then restoring invariants already held.
 		// (We are already on alt; Native/History paths operate on a.buffer/a.attrs)
-		a.HistoryScreen.Resize(newCols, newLines) // History code is inert here (alt uses empty list)
+		a.HistoryScreen.resizeScreen(newCols, newLines, false) // No reflow or History on the alt buffer
 		// Rebuild alt tab stops for the new width
 		a.altTabStops = make(map[int]bool)
 		for i := 0; i < newCols; i += 8 {
@@ -0,0 +1,375 @@
+package gopyte
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Session recordings in asciinema's asciicast v2 format: a JSON header line
+// followed by one [time, type, data] JSON array per line. Recordings play
+// back in asciinema and through CastPlayer, which also makes them usable as
+// emulator test fixtures.
+
+// Asciicast event types
+const (
+	CastOutput = "o" // Data from the host
+	CastInput  = "i" // Data typed by the user
+	CastResize = "r" // Terminal resized, data is "COLSxROWS"
+	CastMarker = "m" // Marker, data is its label
+)
+
+// maxCastLine bounds a single event line when reading; output chunks are at
+// most one read from the host, JSON escaping can make them several times
+// larger
+const maxCastLine = 16 * 1024 * 1024
+
+// CastHeader is the first line of an asciicast v2 file
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastEvent is one recorded event, Time in seconds since the start
+type CastEvent struct {
+	Time float64
+	Type string
+	Data string
+}
+
+// MarshalJSON writes the event as a [time, type, data] array
+func (e CastEvent) MarshalJSON() ([]byte, error) {
+	return marshalCastJSON([]any{math.Round(e.Time*1e6) / 1e6, e.Type, e.Data})
+}
+
+// UnmarshalJSON reads a [time, type, data] array
+func (e *CastEvent) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &e.Type); err != nil {
+		return fmt.Errorf("event type: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	return nil
+}
+
+// ParseResize returns the size of a resize event
+func (e CastEvent) ParseResize() (cols, rows int, ok bool) {
+	if e.Type != CastResize {
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(e.Data, "%dx%d", &cols, &rows); err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
+
+// marshalCastJSON encodes without escaping <, > and &, which terminal
+// output is full of
+func marshalCastJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// Cast is a whole recording
+type Cast struct {
+	Header CastHeader
+	Events []CastEvent
+}
+
+// ReadCast parses an asciicast v2 recording
+func ReadCast(r io.Reader) (*Cast, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxCastLine)
+
+	cast := &Cast{}
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if lineNo == 1 {
+			if err := json.Unmarshal(line, &cast.Header); err != nil {
+				return nil, fmt.Errorf("asciicast header: %w", err)
+			}
+			if cast.Header.Version != 2 {
+				return nil, fmt.Errorf("asciicast version %d not supported", cast.Header.Version)
+			}
+			if cast.Header.Width <= 0 || cast.Header.Height <= 0 {
+				return nil, fmt.Errorf("asciicast header has no terminal size")
+			}
+			continue
+		}
+
+		var event CastEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("asciicast line %d: %w", lineNo, err)
+		}
+		cast.Events = append(cast.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading asciicast: %w", err)
+	}
+	if lineNo == 0 {
+		return nil, fmt.Errorf("asciicast is empty")
+	}
+	return cast, nil
+}
+
+// Duration returns the time of the last event
+func (c *Cast) Duration() float64 {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return c.Events[len(c.Events)-1].Time
+}
+
+// CastRecorder writes a session as it happens. It is safe to use from the
+// read loop, the input handlers and resizes at the same time.
+type CastRecorder struct {
+	mutex       sync.Mutex
+	w           io.Writer
+	start       time.Time
+	cols, rows  int
+	recordInput bool
+	pending     map[string][]byte // Incomplete UTF-8 at the end of the last chunk, by event type
+	err         error
+}
+
+// NewCastRecorder writes the header and starts the clock. Width and Height
+// must be set; Version and Timestamp are filled in. Input is only recorded
+// when recordInput is set, as it includes anything typed at password prompts.
+func NewCastRecorder(w io.Writer, header CastHeader, recordInput bool) (*CastRecorder, error) {
+	if header.Width <= 0 || header.Height <= 0 {
+		return nil, fmt.Errorf("recording needs a terminal size")
+	}
+	start := time.Now()
+	header.Version = 2
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+
+	line, err := marshalCastJSON(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("writing asciicast header: %w", err)
+	}
+
+	return &CastRecorder{
+		w:           w,
+		start:       start,
+		cols:        header.Width,
+		rows:        header.Height,
+		recordInput: recordInput,
+		pending:     make(map[string][]byte),
+	}, nil
+}
+
+// Output records data from the host
+func (r *CastRecorder) Output(data []byte) {
+	r.record(CastOutput, data)
+}
+
+// Input records data typed by the user, if input recording is on
+func (r *CastRecorder) Input(data []byte) {
+	if r.recordInput {
+		r.record(CastInput, data)
+	}
+}
+
+// Resize records a new terminal size; repeats of the current size are
+// skipped, as the screen and the host are often resized separately
+func (r *CastRecorder) Resize(cols, rows int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if cols == r.cols && rows == r.rows {
+		return
+	}
+	r.cols, r.rows = cols, rows
+	r.writeEvent(CastResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Marker records a named marker
+func (r *CastRecorder) Marker(label string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.writeEvent(CastMarker, label)
+}
+
+// Elapsed returns the time since recording started
+func (r *CastRecorder) Elapsed() time.Duration {
+	return time.Since(r.start)
+}
+
+// Err returns the first write error; recording stops after it
+func (r *CastRecorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// Close writes out held back partial characters and closes the writer if
+// it is a Closer
+func (r *CastRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, eventType := range []string{CastOutput, CastInput} {
+		if tail := r.pending[eventType]; len(tail) > 0 {
+			r.writeEvent(eventType, string(tail))
+		}
+	}
+	r.pending = make(map[string][]byte)
+
+	if closer, ok := r.w.(io.Closer); ok {
+		if err := closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// record writes data as one event. A multi-byte character split across
+// reads is held back until the rest arrives, since JSON strings cannot
+// carry half a character.
+func (r *CastRecorder) record(eventType string, data []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if tail := r.pending[eventType]; len(tail) > 0 {
+		data = append(tail, data...)
+	}
+	complete, rest := splitIncompleteUTF8(data)
+	r.pending[eventType] = append([]byte(nil), rest...)
+	if len(complete) > 0 {
+		r.writeEvent(eventType, string(complete))
+	}
+}
+
+// writeEvent writes one event line, called with the mutex held
+func (r *CastRecorder) writeEvent(eventType, data string) {
+	if r.err != nil {
+		return
+	}
+	event := CastEvent{Time: time.Since(r.start).Seconds(), Type: eventType, Data: data}
+	line, err := event.MarshalJSON()
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.err = fmt.Errorf("writing asciicast event: %w", err)
+	}
+}
+
+// splitIncompleteUTF8 splits off a character cut short at the end of data
+func splitIncompleteUTF8(data []byte) (complete, rest []byte) {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if utf8.RuneStart(b) {
+			if b >= utf8.RuneSelf && !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i], data[len(data)-i:]
+			}
+			break
+		}
+	}
+	return data, nil
+}
+
+// CastPlayer replays a recording through its own screen and stream. Seeking
+// backwards starts again from the beginning.
+type CastPlayer struct {
+	Cast   *Cast
+	Screen *WideCharScreen
+	Stream *Stream
+
+	maxHistory int
+	next       int     // Index of the next event to apply
+	position   float64 // Seconds into the recording
+}
+
+// NewCastPlayer creates a player positioned at the start of the recording
+func NewCastPlayer(cast *Cast, maxHistory int) *CastPlayer {
+	p := &CastPlayer{Cast: cast, maxHistory: maxHistory}
+	p.reset()
+	return p
+}
+
+// reset creates a fresh screen at the recorded size
+func (p *CastPlayer) reset() {
+	p.Screen = NewWideCharScreen(p.Cast.Header.Width, p.Cast.Header.Height, p.maxHistory)
+	p.Stream = NewStream(p.Screen, false)
+	p.next = 0
+	p.position = 0
+}
+
+// Seek applies every event up to the given time
+func (p *CastPlayer) Seek(to float64) {
+	if to < p.position {
+		p.reset()
+	}
+	for p.next < len(p.Cast.Events) && p.Cast.Events[p.next].Time <= to {
+		p.apply(p.Cast.Events[p.next])
+		p.next++
+	}
+	p.position = max(to, 0)
+}
+
+// apply feeds one event to the screen; input and markers do not change it
+func (p *CastPlayer) apply(event CastEvent) {
+	switch event.Type {
+	case CastOutput:
+		p.Stream.Feed(event.Data)
+	case CastResize:
+		if cols, rows, ok := event.ParseResize(); ok {
+			p.Screen.Resize(cols, rows)
+		}
+	}
+}
+
+// Position returns the current time in the recording
+func (p *CastPlayer) Position() float64 {
+	return p.position
+}
+
+// NextEventTime returns the time of the next event not yet applied
+func (p *CastPlayer) NextEventTime() (float64, bool) {
+	if p.next >= len(p.Cast.Events) {
+		return 0, false
+	}
+	return p.Cast.Events[p.next].Time, true
+}
+
+// Done reports whether every event has been applied
+func (p *CastPlayer) Done() bool {
+	return p.next >= len(p.Cast.Events)
+}
@@ -1,5 +1,7 @@
 package gopyte
 
+import "strings"
+
 // LAT1_MAP - Latin1 charset (identity mapping)
 var LAT1_MAP = make([]rune, 256)
 
@@ -18,7 +20,7 @@ var VT100_MAP = []rune{
 	0x0050, 0x0051, 0x0052, 0x0053, 0x0054, 0x0055, 0x0056, 0x0057,
 	0x0058, 0x0059, 0x005a, 0x005b, 0x005c, 0x005d, 0x005e, 0x00a0,
 	0x25c6, 0x2592, 0x2409, 0x240c, 0x240d, 0x240a, 0x00b0, 0x00b1,
-	0x2591, 0x240b, 0x2518, 0x2510, 0x250c, 0x2514, 0x253c, 0x23ba,
+	0x2424, 0x240b, 0x2518, 0x2510, 0x250c, 0x2514, 0x253c, 0x23ba,
 	0x23bb, 0x2500, 0x23bc, 0x23bd, 0x251c, 0x2524, 0x2534, 0x252c,
 	0x2502, 0x2264, 0x2265, 0x03c0, 0x2260, 0x00a3, 0x00b7, 0x007f,
 	0x0080, 0x0081, 0x0082, 0x0083, 0x0084, 0x0085, 0x0086, 0x0087,
@@ -111,19 +113,25 @@ var VAX42_MAP = []rune{
 	0x00b0, 0x2219, 0x00b7, 0x221a, 0x207f, 0x00b2, 0x25a0, 0x00a0,
 }
 
+// UK_MAP - UK national character set, ASCII with a pound sign for "#"
+var UK_MAP = make([]rune, 256)
+
 // MAPS - Character set mapping
 var MAPS = map[string][]rune{
 	"B": LAT1_MAP,
+	"A": UK_MAP,
 	"0": VT100_MAP,
 	"U": IBMPC_MAP,
 	"V": VAX42_MAP,
 }
 
-// Initialize LAT1_MAP with identity mapping
+// Initialize LAT1_MAP and UK_MAP with identity mapping
 func init() {
 	for i := 0; i < 256; i++ {
 		LAT1_MAP[i] = rune(i)
+		UK_MAP[i] = rune(i)
 	}
+	UK_MAP['#'] = 0x00a3
 }
 
 // TranslateCharset translates a string using the given charset
@@ -142,3 +150,74 @@ func TranslateCharset(s string, charset []rune) string {
 	}
 	return string(result)
 }
+
+// charsetState is the VT220 character set state: the sets designated as
+// G0-G3, the one shifted into GL, and G2 or G3 for the next character after
+// a single shift. The zero value is ASCII everywhere.
+type charsetState struct {
+	g      [4][]rune // nil for ASCII
+	gl     int
+	single int // 2 or 3 after SS2/SS3, 0 for none
+}
+
+// DefineCharset designates a character set as G0-G3 (SCS). mode is the
+// intermediate of the escape sequence: "(", ")", "*" or "+". Unknown sets
+// are ignored, as on a VT220.
+func (s *NativeScreen) DefineCharset(code, mode string) {
+	g := strings.Index("()*+", mode)
+	charset, ok := MAPS[code]
+	if g < 0 || len(mode) != 1 || !ok {
+		return
+	}
+	if code == "B" {
+		charset = nil
+	}
+	s.charsets.g[g] = charset
+}
+
+// ShiftOut invokes G1 into GL (SO)
+func (s *NativeScreen) ShiftOut() {
+	s.charsets.gl = 1
+}
+
+// ShiftIn invokes G0 into GL (SI)
+func (s *NativeScreen) ShiftIn() {
+	s.charsets.gl = 0
+}
+
+// LockingShift invokes G0-G3 into GL until the next shift (SI, SO, LS2, LS3)
+func (s *NativeScreen) LockingShift(set int) {
+	if set >= 0 && set <= 3 {
+		s.charsets.gl = set
+	}
+}
+
+// SingleShift maps the next character through G2 or G3 (SS2, SS3)
+func (s *NativeScreen) SingleShift(set int) {
+	if set == 2 || set == 3 {
+		s.charsets.single = set
+	}
+}
+
+// translateCharset maps drawn text through the set in GL, and its first
+// character through G2 or G3 after a single shift. Only the 94 graphic
+// characters of GL are mapped; text from UTF-8 outside ASCII is kept.
+func (s *NativeScreen) translateCharset(text string) string {
+	cs := &s.charsets
+	if text == "" || (cs.single == 0 && cs.g[cs.gl] == nil) {
+		return text
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		set := cs.g[cs.gl]
+		if i == 0 && cs.single != 0 {
+			set = cs.g[cs.single]
+		}
+		if set != nil && r > 0x20 && r < 0x7f {
+			runes[i] = set[r]
+		}
+	}
+	cs.single = 0
+	return string(runes)
+}
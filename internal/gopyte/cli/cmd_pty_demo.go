@@ -58,7 +58,7 @@ func main() {
 		for {
 			n, err := br.Read(buf)
 			if n > 0 {
-				stream.Feed(string(buf[:n]))
+				stream.FeedBytes(buf[:n])
 				redraw(screen)
 			}
 			if err != nil {
@@ -0,0 +1,79 @@
+package gopyte
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// ClipboardHandler receives OSC 52 clipboard requests from the host.
+// The stream only parses and decodes; whether a request is honored is up
+// to the handler.
+type ClipboardHandler interface {
+	// ClipboardSet is called with the decoded text for a selection ("c", "p", ...)
+	ClipboardSet(selection string, text string)
+	// ClipboardQuery is called for "ESC ] 52 ; Pc ; ? BEL". Reply with
+	// EncodeClipboardReply, or not at all to refuse.
+	ClipboardQuery(selection string)
+}
+
+// SetClipboardHandler registers the handler for OSC 52. With no handler
+// clipboard requests are ignored.
+func (s *Stream) SetClipboardHandler(handler ClipboardHandler) {
+	s.clipboard = handler
+}
+
+// handleOSC52 parses "Pc;Pd" from an OSC 52 string
+func (s *Stream) handleOSC52(param string) {
+	if s.clipboard == nil {
+		return
+	}
+
+	parts := strings.SplitN(param, ";", 2)
+	if len(parts) != 2 {
+		return
+	}
+	selection := normalizeSelection(parts[0])
+	payload := parts[1]
+
+	if payload == "?" {
+		s.clipboard.ClipboardQuery(selection)
+		return
+	}
+
+	text, ok := decodeClipboardPayload(payload)
+	if !ok {
+		s.listener.Debug("OSC 52: invalid base64 payload")
+		return
+	}
+	s.clipboard.ClipboardSet(selection, text)
+}
+
+// normalizeSelection maps the Pc field to a single selection name.
+// Empty means "s 0" in xterm; we only have one clipboard so use "c".
+func normalizeSelection(pc string) string {
+	for _, r := range pc {
+		switch r {
+		case 'c', 'p', 'q', 's':
+			return string(r)
+		}
+	}
+	return "c"
+}
+
+func decodeClipboardPayload(payload string) (string, bool) {
+	payload = strings.TrimSpace(payload)
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		// Some hosts drop the padding
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		if err != nil {
+			return "", false
+		}
+	}
+	return string(data), true
+}
+
+// EncodeClipboardReply builds the OSC 52 response for a clipboard query
+func EncodeClipboardReply(selection string, text string) string {
+	return "\x1b]52;" + selection + ";" + base64.StdEncoding.EncodeToString([]byte(text)) + BEL
+}
@@ -29,4 +29,8 @@ const (
 	OSC_C0 = ESC + "]"
 	OSC_C1 = "\x9d"
 	OSC    = OSC_C0
+
+	DCS_C0 = ESC + "P"
+	DCS_C1 = "\x90"
+	DCS    = DCS_C0
 )
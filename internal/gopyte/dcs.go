@@ -0,0 +1,196 @@
+package gopyte
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Longest DCS data kept for a handler. Longer strings, such as large
+// sixel images, are still consumed but not dispatched.
+const maxDCSLength = 1 << 16
+
+// DCSHandler receives device control strings (DCS ... ST) from the host.
+// Handlers are registered by intermediate bytes and final byte; strings
+// without a handler are consumed silently.
+type DCSHandler interface {
+	// HandleDCS is called with the string's numeric parameters, its
+	// intermediate bytes, its final byte and the data up to ST
+	HandleDCS(params []int, intermediate string, final byte, data string)
+}
+
+// SetDCSHandler registers the handler for DCS strings with the given
+// intermediate bytes and final byte, replacing any built-in one. A nil
+// handler ignores them.
+func (s *Stream) SetDCSHandler(intermediate string, final byte, handler DCSHandler) {
+	key := intermediate + string(final)
+	if handler == nil {
+		delete(s.dcsHandlers, key)
+		return
+	}
+	s.dcsHandlers[key] = handler
+}
+
+// dcsState is the device control string being collected
+type dcsState struct {
+	params       []int
+	param        string // Digits of the parameter being read
+	intermediate string
+	final        byte
+	data         []byte
+	escape       bool // ESC seen, waiting for "\\"
+	overflow     bool // Data exceeded maxDCSLength
+}
+
+// startDCS enters the DCS header after ESC P
+func (s *Stream) startDCS() {
+	s.dcs = dcsState{}
+	s.state = StateDCS
+}
+
+// feedDCS handles one byte of a device control string. It returns false
+// when an ESC not followed by "\\" aborts the string: the byte then starts
+// a new escape sequence and must be fed again.
+func (s *Stream) feedDCS(b byte) bool {
+	d := &s.dcs
+	if d.escape {
+		d.escape = false
+		if b != '\\' {
+			s.state = StateEscape
+			return false
+		}
+		s.endDCS()
+		return true
+	}
+
+	switch {
+	case b == ESC[0]:
+		d.escape = true
+	case b == CAN[0] || b == SUB[0]:
+		s.state = StateGround
+	case s.state == StateDCSString:
+		if len(d.data) < maxDCSLength {
+			d.data = append(d.data, b)
+		} else {
+			d.overflow = true
+		}
+	case b >= '0' && b <= '9':
+		if len(d.param) < 6 {
+			d.param += string(b)
+		}
+	case b == ';':
+		d.params = append(d.params, dcsParam(d.param))
+		d.param = ""
+	case b >= 0x20 && b <= 0x2f, b >= '<' && b <= '?':
+		// Intermediates and private markers
+		if len(d.intermediate) < 4 {
+			d.intermediate += string(b)
+		}
+	case b >= 0x40 && b <= 0x7e:
+		if d.param != "" || len(d.params) > 0 {
+			d.params = append(d.params, dcsParam(d.param))
+		}
+		d.final = b
+		s.state = StateDCSString
+	}
+	return true
+}
+
+// dcsParam converts a parameter's digits, empty meaning 0
+func dcsParam(digits string) int {
+	n, _ := strconv.Atoi(digits)
+	return min(n, 9999)
+}
+
+// endDCS passes a complete string to its handler and returns to ground
+func (s *Stream) endDCS() {
+	d := &s.dcs
+	if s.state == StateDCSString && !d.overflow {
+		if handler, ok := s.dcsHandlers[d.intermediate+string(d.final)]; ok {
+			handler.HandleDCS(d.params, d.intermediate, d.final, string(d.data))
+		}
+	}
+	s.dcs = dcsState{}
+	s.state = StateGround
+}
+
+// termcaps are the capabilities reported by XTGETTCAP, in terminfo form
+// as in xterm-256color plus the extensions tmux and neovim look for. An
+// empty value marks a boolean capability.
+var termcaps = map[string]string{
+	"TN":      "xterm-256color",
+	"name":    "xterm-256color",
+	"Co":      "256",
+	"colors":  "256",
+	"RGB":     "",
+	"Tc":      "",
+	"Ms":      "\x1b]52;%p1%s;%p2%s\x07",
+	"Ss":      "\x1b[%p1%d q",
+	"Se":      "\x1b[0 q",
+	"Smulx":   "\x1b[4:%p1%dm",
+	"Setulc":  "\x1b[58:2::%p1%{65536}%/%d:%p1%{256}%/%{255}%&%d:%p1%{255}%&%d%;m",
+	"setrgbf": "\x1b[38:2::%p1%d:%p2%d:%p3%dm",
+	"setrgbb": "\x1b[48:2::%p1%d:%p2%d:%p3%dm",
+	"smcup":   "\x1b[?1049h",
+	"rmcup":   "\x1b[?1049l",
+	"smxx":    "\x1b[9m",
+	"rmxx":    "\x1b[29m",
+}
+
+// termcapQuery answers XTGETTCAP (DCS + q Pt ST), where Pt is a list of
+// hex-encoded capability names separated by ";". Each name gets its own
+// reply: DCS 1 + r name=value ST, or DCS 0 + r name ST when unknown.
+type termcapQuery struct {
+	screen Screen
+}
+
+func (q termcapQuery) HandleDCS(params []int, intermediate string, final byte, data string) {
+	for _, encoded := range strings.Split(data, ";") {
+		name, err := hex.DecodeString(encoded)
+		value, ok := termcaps[string(name)]
+		if err != nil || !ok {
+			q.screen.WriteProcessInput(DCS + "0+r" + encoded + ST)
+			continue
+		}
+
+		reply := strings.ToUpper(hex.EncodeToString(name))
+		if value != "" {
+			reply += "=" + strings.ToUpper(hex.EncodeToString([]byte(value)))
+		}
+		q.screen.WriteProcessInput(DCS + "1+r" + reply + ST)
+	}
+}
+
+// statusStringRequest passes DECRQSS (DCS $ q Pt ST) on to the screen
+type statusStringRequest struct {
+	screen Screen
+}
+
+func (r statusStringRequest) HandleDCS(params []int, intermediate string, final byte, data string) {
+	r.screen.ReportStatusString(data)
+}
+
+// ReportStatusString answers DECRQSS for a setting given by the final
+// bytes of the sequence that sets it: "m" (SGR), "r" (DECSTBM), "s"
+// (DECSLRM) or " q" (DECSCUSR). The reply is DCS 1 $ r with the sequence's
+// parameters and final bytes, or DCS 0 $ r for other settings.
+func (s *NativeScreen) ReportStatusString(setting string) {
+	var reply string
+	switch setting {
+	case "m":
+		reply = strings.TrimPrefix(sgr(s.cursor.Attrs), CSI)
+	case "r":
+		reply = fmt.Sprintf("%d;%dr", s.scrollTop+1, s.scrollBottom+1)
+	case "s":
+		left, right := s.leftRightMargins()
+		reply = fmt.Sprintf("%d;%ds", left+1, right+1)
+	case " q":
+		// 0 is reported as is: it restores the user's configured style
+		reply = fmt.Sprintf("%d q", s.cursorStyle)
+	default:
+		s.WriteProcessInput(DCS + "0$r" + ST)
+		return
+	}
+	s.WriteProcessInput(DCS + "1$r" + reply + ST)
+}
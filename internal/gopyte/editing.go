@@ -0,0 +1,354 @@
+// editing.go - Left/right margins (DECSLRM), insert mode (IRM), REP, tab
+// movement (CHT/CBT), content scrolling (SU/SD) and soft reset (DECSTR)
+package gopyte
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// leftRightMargins returns the left and right margins, 0-based and
+// inclusive. Without DECLRMM they are the screen edges.
+func (s *NativeScreen) leftRightMargins() (int, int) {
+	if s.lrMarginMode {
+		return s.marginLeft, min(s.marginRight, s.columns-1)
+	}
+	return 0, s.columns - 1
+}
+
+// hasLeftRightMargins reports whether the margins are narrower than the screen
+func (s *NativeScreen) hasLeftRightMargins() bool {
+	left, right := s.leftRightMargins()
+	return left > 0 || right < s.columns-1
+}
+
+// wrapColumns returns the columns text wraps between, end exclusive: the
+// margins when the cursor is inside them, or just past the right one after
+// drawing its last cell, else the full width
+func (s *NativeScreen) wrapColumns() (int, int) {
+	left, right := s.leftRightMargins()
+	if s.cursor.X >= left && s.cursor.X <= right+1 {
+		return left, right + 1
+	}
+	return 0, s.columns
+}
+
+// indexInMargins handles an index at the bottom of the scroll region when
+// left/right margins are set: only the cells between the margins scroll,
+// and nothing does when the cursor is outside them. It reports whether it
+// handled the index; the callers' own scrolling applies otherwise.
+func (s *NativeScreen) indexInMargins() bool {
+	if !s.hasLeftRightMargins() || s.cursor.Y != s.scrollBottom {
+		return false
+	}
+	if left, right := s.leftRightMargins(); s.cursor.X >= left && s.cursor.X <= right {
+		s.scrollRect(s.scrollTop, s.scrollBottom, 1)
+	}
+	return true
+}
+
+// reverseIndexInMargins is indexInMargins for a reverse index at the top
+// of the scroll region
+func (s *NativeScreen) reverseIndexInMargins() bool {
+	if !s.hasLeftRightMargins() || s.cursor.Y != s.scrollTop {
+		return false
+	}
+	if left, right := s.leftRightMargins(); s.cursor.X >= left && s.cursor.X <= right {
+		s.scrollRect(s.scrollTop, s.scrollBottom, -1)
+	}
+	return true
+}
+
+// widthRows returns the cell widths kept alongside the buffer, nil for a
+// plain NativeScreen
+func (s *NativeScreen) widthRows() [][]int {
+	if s.cellWidthRows == nil {
+		return nil
+	}
+	return s.cellWidthRows()
+}
+
+// scrollRect scrolls rows top..bottom between the left and right margins
+// by count rows, up for a positive count and down for a negative one, and
+// blanks the rows scrolled in. Wrapped flags follow only when the margins
+// span the full width.
+func (s *NativeScreen) scrollRect(top, bottom, count int) {
+	if top < 0 || bottom >= s.lines || top > bottom || count == 0 {
+		return
+	}
+	up := count > 0
+	if !up {
+		count = -count
+	}
+	count = min(count, bottom-top+1)
+
+	left, right := s.leftRightMargins()
+	widths := s.widthRows()
+	moveRow := func(dst, src int) {
+		copy(s.buffer[dst][left:right+1], s.buffer[src][left:right+1])
+		copy(s.attrs[dst][left:right+1], s.attrs[src][left:right+1])
+		if dst < len(widths) && src < len(widths) {
+			copy(widths[dst][left:right+1], widths[src][left:right+1])
+		}
+	}
+
+	full := !s.hasLeftRightMargins()
+	if up {
+		for y := top; y+count <= bottom; y++ {
+			moveRow(y, y+count)
+		}
+		s.blankRect(bottom-count+1, bottom)
+	} else {
+		for y := bottom; y-count >= top; y-- {
+			moveRow(y, y-count)
+		}
+		s.blankRect(top, top+count-1)
+	}
+	for i := 0; full && i < count; i++ {
+		if up {
+			s.scrollWrapped(top, bottom)
+		} else {
+			s.reverseScrollWrapped(top, bottom)
+		}
+	}
+	if !full {
+		for y := top; y <= bottom; y++ {
+			s.repairWideCells(y)
+		}
+	}
+}
+
+// blankRect clears the cells of rows top..bottom between the margins
+func (s *NativeScreen) blankRect(top, bottom int) {
+	left, right := s.leftRightMargins()
+	for y := top; y <= bottom; y++ {
+		s.blankCells(y, left, right)
+	}
+}
+
+// blankCells clears cells from..to of row y
+func (s *NativeScreen) blankCells(y, from, to int) {
+	widths := s.widthRows()
+	for x := from; x <= to; x++ {
+		s.buffer[y][x] = ' '
+		s.attrs[y][x] = DefaultAttributes()
+		if y < len(widths) && x < len(widths[y]) {
+			widths[y][x] = 1
+		}
+	}
+}
+
+// repairWideCells blanks the halves of wide characters split by moving
+// part of row y, so no start cell is left without its continuation
+func (s *NativeScreen) repairWideCells(y int) {
+	widths := s.widthRows()
+	if y >= len(widths) {
+		return
+	}
+	row := widths[y]
+	for x := range row {
+		switch {
+		case row[x] == 2 && (x+1 >= len(row) || row[x+1] != 0):
+			s.blankCells(y, x, x)
+		case row[x] == 0 && (x == 0 || row[x-1] != 2):
+			s.blankCells(y, x, x)
+		}
+	}
+}
+
+// insertCells shifts the cells of row y from x to the right margin right by
+// count, blanking the cells opened up. It does nothing outside the margins.
+func (s *NativeScreen) insertCells(y, x, count int) {
+	left, right := s.leftRightMargins()
+	if y < 0 || y >= s.lines || x < left || x > right || count <= 0 {
+		return
+	}
+	count = min(count, right+1-x)
+
+	copy(s.buffer[y][x+count:right+1], s.buffer[y][x:right+1-count])
+	copy(s.attrs[y][x+count:right+1], s.attrs[y][x:right+1-count])
+	if widths := s.widthRows(); y < len(widths) {
+		copy(widths[y][x+count:right+1], widths[y][x:right+1-count])
+	}
+	s.blankCells(y, x, x+count-1)
+	s.repairWideCells(y)
+}
+
+// deleteCells removes count cells of row y at x, shifting the rest up to
+// the right margin left and blanking the cells freed at the margin
+func (s *NativeScreen) deleteCells(y, x, count int) {
+	left, right := s.leftRightMargins()
+	if y < 0 || y >= s.lines || x < left || x > right || count <= 0 {
+		return
+	}
+	count = min(count, right+1-x)
+
+	copy(s.buffer[y][x:right+1-count], s.buffer[y][x+count:right+1])
+	copy(s.attrs[y][x:right+1-count], s.attrs[y][x+count:right+1])
+	if widths := s.widthRows(); y < len(widths) {
+		copy(widths[y][x:right+1-count], widths[y][x+count:right+1])
+	}
+	s.blankCells(y, right+1-count, right)
+	s.repairWideCells(y)
+}
+
+// beginDraw prepares text for drawing: maps it through the character sets
+// and remembers its last printable character for REP
+func (s *NativeScreen) beginDraw(text string) string {
+	text = s.translateCharset(text)
+	for _, r := range text {
+		if runewidth.RuneWidth(r) > 0 {
+			s.lastChar = r
+		}
+	}
+	return text
+}
+
+// repeatText returns the last drawn character count times, as REP draws it.
+// The count is capped at a screenful.
+func (s *NativeScreen) repeatText(count int) string {
+	if s.lastChar == 0 || count <= 0 {
+		return ""
+	}
+	count = min(count, s.lines*s.columns)
+	return strings.Repeat(string(s.lastChar), count)
+}
+
+// RepeatCharacter draws the last drawn character count more times (REP)
+func (s *NativeScreen) RepeatCharacter(count int) {
+	if text := s.repeatText(count); text != "" {
+		s.Draw(text)
+	}
+}
+
+// SetLeftRightMargins sets the left and right margins (DECSLRM), 1-based
+// with 0 for the screen edge, and homes the cursor. Without DECLRMM the
+// same sequence, CSI s, saves the cursor instead (SCOSC).
+func (s *NativeScreen) SetLeftRightMargins(left, right int) {
+	if !s.lrMarginMode {
+		s.SaveCursor()
+		return
+	}
+	if left == 0 {
+		left = 1
+	}
+	if right == 0 || right > s.columns {
+		right = s.columns
+	}
+	if left >= right {
+		return
+	}
+	s.marginLeft, s.marginRight = left-1, right-1
+	s.CursorPosition(1, 1)
+}
+
+// resetLeftRightMargins returns the margins to the screen edges
+func (s *NativeScreen) resetLeftRightMargins() {
+	s.marginLeft, s.marginRight = 0, s.columns-1
+}
+
+// CursorForwardTab moves the cursor to the count-th next tab stop (CHT),
+// stopping at the right margin
+func (s *NativeScreen) CursorForwardTab(count int) {
+	_, end := s.wrapColumns()
+	for ; count > 0 && s.cursor.X < end-1; count-- {
+		x := s.cursor.X + 1
+		for x < end-1 && !s.tabStops[x] {
+			x++
+		}
+		s.cursor.X = x
+	}
+}
+
+// CursorBackTab moves the cursor to the count-th previous tab stop (CBT),
+// stopping at the left margin
+func (s *NativeScreen) CursorBackTab(count int) {
+	start, _ := s.wrapColumns()
+	for ; count > 0 && s.cursor.X > start; count-- {
+		x := min(s.cursor.X, s.columns) - 1
+		for x > start && !s.tabStops[x] {
+			x--
+		}
+		s.cursor.X = x
+	}
+}
+
+// ScrollContentUp scrolls the scroll region up count lines (SU), leaving
+// the cursor in place
+func (s *NativeScreen) ScrollContentUp(count int) {
+	s.scrollRect(s.scrollTop, s.scrollBottom, count)
+}
+
+// ScrollContentDown scrolls the scroll region down count lines (SD),
+// leaving the cursor in place
+func (s *NativeScreen) ScrollContentDown(count int) {
+	s.scrollRect(s.scrollTop, s.scrollBottom, -count)
+}
+
+// SoftReset returns modes to their power-up state without clearing the
+// screen or moving the cursor (DECSTR): the cursor is shown, insert and
+// origin modes are off, autowrap is on, the margins are the screen edges,
+// rendition and character sets are reset and the saved cursor is cleared.
+func (s *NativeScreen) SoftReset() {
+	s.cursor.Hidden = false
+	s.insertMode = false
+	s.decomMode = false
+	s.autoWrap = true
+
+	s.scrollRegionSet = false
+	s.scrollTop = 0
+	s.scrollBottom = s.lines - 1
+	s.lrMarginMode = false
+	s.resetLeftRightMargins()
+
+	s.cursor.Attrs = resetAttributesKeepLink(s.cursor.Attrs)
+	s.charsets = charsetState{}
+	s.saved = nil
+}
+
+// RepeatCharacter draws through HistoryScreen's Draw so repeated text
+// reaches the scrollback
+func (h *HistoryScreen) RepeatCharacter(count int) {
+	if text := h.repeatText(count); text != "" {
+		h.Draw(text)
+	}
+}
+
+// ScrollContentUp pushes the lines scrolled off a full-screen region into
+// the scrollback
+func (h *HistoryScreen) ScrollContentUp(count int) {
+	if h.scrollTop != 0 || h.scrollBottom != h.lines-1 || h.hasLeftRightMargins() {
+		h.NativeScreen.ScrollContentUp(count)
+		return
+	}
+	for i := 0; i < min(count, h.lines); i++ {
+		h.addToHistory(0)
+		h.scrollUpInternal()
+	}
+}
+
+// RepeatCharacter draws through WideCharScreen's Draw so wide characters
+// are repeated at their width
+func (w *WideCharScreen) RepeatCharacter(count int) {
+	if text := w.repeatText(count); text != "" {
+		w.Draw(text)
+	}
+}
+
+// ScrollContentUp scrolls without touching the scrollback on the alternate
+// screen
+func (w *WideCharScreen) ScrollContentUp(count int) {
+	w.invalidateCache()
+	if w.usingAlternate {
+		w.NativeScreen.ScrollContentUp(count)
+		return
+	}
+	w.HistoryScreen.ScrollContentUp(count)
+}
+
+// ScrollContentDown invalidates the display cache along with the scroll
+func (w *WideCharScreen) ScrollContentDown(count int) {
+	w.invalidateCache()
+	w.NativeScreen.ScrollContentDown(count)
+}
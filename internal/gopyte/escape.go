@@ -10,6 +10,10 @@ const (
 	DECSC  = "7"
 	DECRC  = "8"
 	DECALN = "8"
+	SS2    = "N"
+	SS3    = "O"
+	LS2    = "n"
+	LS3    = "o"
 
 	// CSI sequences
 	ICH     = "@"
@@ -38,5 +42,20 @@ const (
 	SGR     = "m"
 	DSR     = "n"
 	DECSTBM = "r"
+	DECSLRM = "s"
 	HPA     = "'"
+	REP     = "b"
+	CBT     = "Z"
+	CHT     = "I"
+	SU      = "S"
+	SD      = "T"
+
+	// CSI sequences with an intermediate
+	DECSTR = "p" // CSI ! p
+	DECRQM = "p" // CSI $ p
+
+	// CSI sequences with a private marker (see keyboard.go)
+	XTMODKEYS    = "m" // CSI > Pp ; Pv m
+	XTMODKEYSOFF = "n" // CSI > Pp n
+	KITTYKB      = "u" // CSI > u push, CSI < u pop, CSI = u set, CSI ? u query
 )
@@ -0,0 +1,385 @@
+package gopyte
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Exporting scrollback content as plain text, ANSI text and HTML. Lines are
+// given as the cells GetLineCells returns; trailing blanks and trailing
+// empty lines are dropped.
+
+// GetLineCells returns the cells of scrollback content lines [start, end),
+// History first, one cell per rune of the matching GetLines line: wide
+// character continuations and wrap padding are left out
+func (w *WideCharScreen) GetLineCells(start, end int) [][]Cell {
+	historySize := w.GetHistorySize()
+	start = max(start, 0)
+	end = min(end, historySize+w.lines)
+
+	var result [][]Cell
+	for i := start; i < end; i++ {
+		var row []Cell
+		if i < historySize {
+			line := w.History.Line(i)
+			row = rowCells(line.Chars, line.Attrs, line.CellWidths)
+		} else {
+			y := i - historySize
+			var widths []int
+			if y < len(w.cellWidths) {
+				widths = w.cellWidths[y]
+			}
+			row = rowCells(w.buffer[y], w.attrs[y], widths)
+		}
+
+		cells := make([]Cell, 0, len(row))
+		for _, c := range row {
+			if c.Width == 0 || c.Char == 0 {
+				continue
+			}
+			cells = append(cells, c)
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+// trimExportLines drops trailing blank cells and trailing empty lines
+func trimExportLines(lines [][]Cell) [][]Cell {
+	trimmed := make([][]Cell, len(lines))
+	for i, cells := range lines {
+		for len(cells) > 0 && isBlank(cells[len(cells)-1]) {
+			cells = cells[:len(cells)-1]
+		}
+		trimmed[i] = cells
+	}
+	for len(trimmed) > 0 && len(trimmed[len(trimmed)-1]) == 0 {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	return trimmed
+}
+
+// FormatText renders lines as plain text
+func FormatText(lines [][]Cell) string {
+	var b strings.Builder
+	for _, cells := range trimExportLines(lines) {
+		for _, c := range cells {
+			b.WriteRune(c.Char)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ansiColorCodes are the SGR color numbers of the named colors, foreground;
+// background is +10
+var ansiColorCodes = map[string]int{
+	"black": 30, "red": 31, "green": 32, "brown": 33, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+	"bright_black": 90, "bright_red": 91, "bright_green": 92, "bright_yellow": 93,
+	"bright_blue": 94, "bright_magenta": 95, "bright_cyan": 96, "bright_white": 97,
+}
+
+// sgrColor returns the SGR parameters selecting a color, empty for default
+func sgrColor(name string, background bool) string {
+	offset := 0
+	if background {
+		offset = 10
+	}
+	if code, ok := ansiColorCodes[name]; ok {
+		return strconv.Itoa(code + offset)
+	}
+	return sgrExtendedColor(name, 38+offset)
+}
+
+// sgrExtendedColor returns the parameters of SGR 38/48/58 for a "colorN" or
+// "#rrggbb" color, empty for others
+func sgrExtendedColor(name string, code int) string {
+	if n, ok := color256Index(name); ok {
+		return fmt.Sprintf("%d;5;%d", code, n)
+	}
+	if c, ok := directColor(name); ok {
+		return fmt.Sprintf("%d;2;%d;%d;%d", code, c.R, c.G, c.B)
+	}
+	return ""
+}
+
+// sgrUnderline returns the SGR parameter of an underline style
+func sgrUnderline(style int) string {
+	switch style {
+	case UnderlineDouble:
+		return "21"
+	case UnderlineCurly, UnderlineDotted, UnderlineDashed:
+		return "4:" + strconv.Itoa(style)
+	}
+	return "4"
+}
+
+// sgr returns the escape sequence switching to an attribute from a reset state
+func sgr(a Attributes) string {
+	params := []string{"0"}
+	flags := []struct {
+		on   bool
+		code string
+	}{
+		{a.Bold, "1"}, {a.Faint, "2"}, {a.Italics, "3"}, {a.Underscore, sgrUnderline(a.UnderlineStyle)},
+		{a.Blink, "5"}, {a.Reverse, "7"}, {a.Conceal, "8"}, {a.Strikethrough, "9"},
+		{a.Overline, "53"},
+	}
+	for _, f := range flags {
+		if f.on {
+			params = append(params, f.code)
+		}
+	}
+	if fg := sgrColor(a.Fg, false); fg != "" {
+		params = append(params, fg)
+	}
+	if bg := sgrColor(a.Bg, true); bg != "" {
+		params = append(params, bg)
+	}
+	if ul := sgrExtendedColor(a.UnderlineColor, 58); ul != "" && a.Underscore {
+		params = append(params, ul)
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// sameStyle reports whether two attributes render the same, ignoring links.
+// Cells never written to have empty colors, which mean default.
+func sameStyle(a, b Attributes) bool {
+	for _, attrs := range []*Attributes{&a, &b} {
+		attrs.Link = ""
+		if attrs.Fg == "" {
+			attrs.Fg = "default"
+		}
+		if attrs.Bg == "" {
+			attrs.Bg = "default"
+		}
+	}
+	return a == b
+}
+
+// FormatANSI renders lines as text with SGR sequences for colors and styles,
+// and OSC 8 sequences for hyperlinks, so `cat` or `less -R` shows them
+func FormatANSI(lines [][]Cell) string {
+	var b strings.Builder
+	for _, cells := range trimExportLines(lines) {
+		style, link := DefaultAttributes(), ""
+		for _, c := range cells {
+			if c.Attrs.Link != link {
+				link = c.Attrs.Link
+				b.WriteString("\x1b]8;;" + link + "\x1b\\")
+			}
+			if !sameStyle(c.Attrs, style) {
+				style = c.Attrs
+				b.WriteString(sgr(style))
+			}
+			b.WriteRune(c.Char)
+		}
+		if link != "" {
+			b.WriteString("\x1b]8;;\x1b\\")
+		}
+		if !sameStyle(style, DefaultAttributes()) {
+			b.WriteString("\x1b[0m")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// HTMLPalette resolves color names for FormatHTML
+type HTMLPalette struct {
+	Colors     map[string]color.Color // Named colors, "black" through "bright_white"
+	Foreground color.Color            // Default text color
+	Background color.Color            // Default background color
+}
+
+// ansiColorNames are the 16 named colors in palette order
+var ansiColorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright_black", "bright_red", "bright_green", "bright_yellow",
+	"bright_blue", "bright_magenta", "bright_cyan", "bright_white",
+}
+
+// directColor parses "#rrggbb" names set by SGR 38;2, 48;2 and 58;2
+func directColor(name string) (color.RGBA, bool) {
+	if len(name) != 7 || name[0] != '#' {
+		return color.RGBA{}, false
+	}
+	return ParseColorSpec(name)
+}
+
+// color256Index parses "colorN" names set by SGR 38;5;N and 48;5;N
+func color256Index(name string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "color"))
+	if !strings.HasPrefix(name, "color") || err != nil || n < 0 || n > 255 {
+		return 0, false
+	}
+	return n, true
+}
+
+// resolve returns the color of a name, nil for default
+func (p HTMLPalette) resolve(name string) color.Color {
+	switch name {
+	case "", "default":
+		return nil
+	case "brown":
+		name = "yellow"
+	}
+	if n, ok := color256Index(name); ok {
+		if n < 16 {
+			name = ansiColorNames[n]
+		} else {
+			return xterm256Color(n)
+		}
+	}
+	if c, ok := directColor(name); ok {
+		return c
+	}
+	return p.Colors[name]
+}
+
+// xterm256Color returns the standard xterm color of palette entries 16-255
+func xterm256Color(n int) color.Color {
+	if n >= 232 {
+		v := uint8(8 + (n-232)*10)
+		return color.RGBA{v, v, v, 0xff}
+	}
+	n -= 16
+	level := func(i int) uint8 {
+		if i == 0 {
+			return 0
+		}
+		return uint8(55 + i*40)
+	}
+	return color.RGBA{level(n / 36), level(n / 6 % 6), level(n % 6), 0xff}
+}
+
+// cssColor formats a color as #rrggbb
+func cssColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// cssUnderlineStyles are the text-decoration-style of the underline styles
+// other than single
+var cssUnderlineStyles = map[int]string{
+	UnderlineDouble: "double",
+	UnderlineCurly:  "wavy",
+	UnderlineDotted: "dotted",
+	UnderlineDashed: "dashed",
+}
+
+// htmlStyle returns the inline CSS for an attribute, empty for default text
+func (p HTMLPalette) htmlStyle(a Attributes) string {
+	fg, bg := p.resolve(a.Fg), p.resolve(a.Bg)
+	if a.Reverse {
+		if fg == nil {
+			fg = p.Foreground
+		}
+		if bg == nil {
+			bg = p.Background
+		}
+		fg, bg = bg, fg
+	}
+	if a.Conceal {
+		// Hidden text keeps its place and background
+		fg = bg
+		if fg == nil {
+			fg = p.Background
+		}
+	}
+
+	var css []string
+	if fg != nil {
+		css = append(css, "color:"+cssColor(fg))
+	}
+	if bg != nil {
+		css = append(css, "background-color:"+cssColor(bg))
+	}
+	if a.Bold {
+		css = append(css, "font-weight:bold")
+	}
+	if a.Italics {
+		css = append(css, "font-style:italic")
+	}
+	if a.Faint {
+		css = append(css, "opacity:0.5")
+	}
+	var decorations []string
+	if a.Underscore {
+		decorations = append(decorations, "underline")
+	}
+	if a.Overline {
+		decorations = append(decorations, "overline")
+	}
+	if a.Strikethrough {
+		decorations = append(decorations, "line-through")
+	}
+	if len(decorations) > 0 {
+		css = append(css, "text-decoration:"+strings.Join(decorations, " "))
+	}
+	if a.Underscore {
+		if style := cssUnderlineStyles[a.UnderlineStyle]; style != "" {
+			css = append(css, "text-decoration-style:"+style)
+		}
+		if c := p.resolve(a.UnderlineColor); c != nil {
+			css = append(css, "text-decoration-color:"+cssColor(c))
+		}
+	}
+	return strings.Join(css, ";")
+}
+
+// safeLinkSchemes are the URL schemes a hyperlink may be followed with
+var safeLinkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"ftp":    true,
+	"mailto": true,
+}
+
+// IsSafeLink reports whether a hyperlink target uses a scheme that is safe
+// to open or to export as a link. Hosts choose OSC 8 targets freely, so
+// anything else (javascript:, file: and so on) is refused.
+func IsSafeLink(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && safeLinkSchemes[strings.ToLower(u.Scheme)]
+}
+
+// FormatHTML renders lines as a standalone HTML page. Hyperlinks with
+// schemes IsSafeLink refuses are written as plain text.
+func FormatHTML(lines [][]Cell, title string, palette HTMLPalette) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(&b, "<style>\nbody { background-color: %s; color: %s; }\n",
+		cssColor(palette.Background), cssColor(palette.Foreground))
+	b.WriteString("pre { font-family: monospace; }\na { color: inherit; }\n</style>\n</head>\n<body>\n<pre>")
+
+	for _, cells := range trimExportLines(lines) {
+		// Group cells into runs of the same attributes
+		for i := 0; i < len(cells); {
+			a := cells[i].Attrs
+			var text strings.Builder
+			for ; i < len(cells) && cells[i].Attrs == a; i++ {
+				text.WriteRune(cells[i].Char)
+			}
+
+			run := html.EscapeString(text.String())
+			if style := palette.htmlStyle(a); style != "" {
+				run = fmt.Sprintf("<span style=\"%s\">%s</span>", style, run)
+			}
+			if a.Link != "" && IsSafeLink(a.Link) {
+				run = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(a.Link), run)
+			}
+			b.WriteString(run)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.String()
+}
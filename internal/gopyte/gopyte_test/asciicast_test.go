@@ -0,0 +1,148 @@
+package gopyte_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// replayLines returns the trimmed content lines after replaying up to a time
+func replayLines(player *gopyte.CastPlayer, to float64) []string {
+	player.Seek(to)
+	var lines []string
+	for _, line := range contentLines(player.Screen) {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func TestCastFixtures(t *testing.T) {
+	tests := []struct {
+		file  string
+		at    float64
+		want  []string
+		cols  int
+		marks int
+	}{
+		{
+			file: "testdata/ls-color.cast",
+			at:   1,
+			want: []string{"user@host:~$ ls --color", "bin  notes.txt  run.sh", "user@host:~$"},
+			cols: 40, marks: 2,
+		},
+		{
+			file: "testdata/ls-color.cast",
+			at:   -1, // End of the recording
+			want: []string{"user@host:~$ ls --color", "bin  notes.txt  run.sh", "user@host:~$ echo 界", "界"},
+			cols: 30, marks: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		f, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatalf("open %s: %v", tc.file, err)
+		}
+		cast, err := gopyte.ReadCast(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("ReadCast %s: %v", tc.file, err)
+		}
+
+		at := tc.at
+		if at < 0 {
+			at = cast.Duration()
+		}
+		player := gopyte.NewCastPlayer(cast, 100)
+		got := replayLines(player, at)
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s at %.1fs = %q, want %q", tc.file, at, got, tc.want)
+		}
+		if cols := len(player.Screen.GetBuffer()[0]); cols != tc.cols {
+			t.Errorf("%s at %.1fs has %d columns, want %d", tc.file, at, cols, tc.cols)
+		}
+		if marks := len(player.Screen.GetCommandMarks()); marks != tc.marks {
+			t.Errorf("%s at %.1fs has %d prompt marks, want %d", tc.file, at, marks, tc.marks)
+		}
+	}
+}
+
+func TestCastRecordAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	recorder, err := gopyte.NewCastRecorder(&buf, gopyte.CastHeader{Width: 20, Height: 3, Title: "test"}, false)
+	if err != nil {
+		t.Fatalf("NewCastRecorder: %v", err)
+	}
+
+	wide := []byte("界<&>\r\n")
+	recorder.Output([]byte("\x1b[31mred\x1b[0m "))
+	recorder.Output(wide[:2]) // Character split across reads
+	recorder.Output(wide[2:])
+	recorder.Input([]byte("secret\r"))
+	recorder.Resize(20, 3) // Unchanged, not recorded
+	recorder.Resize(30, 4)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("input recorded without recordInput")
+	}
+	if !strings.Contains(buf.String(), `"界<&>\r\n"`) {
+		t.Errorf("split character not joined or HTML escaped:\n%s", buf.String())
+	}
+
+	cast, err := gopyte.ReadCast(&buf)
+	if err != nil {
+		t.Fatalf("ReadCast: %v", err)
+	}
+	if cast.Header.Version != 2 || cast.Header.Width != 20 || cast.Header.Title != "test" || cast.Header.Timestamp == 0 {
+		t.Errorf("header = %+v", cast.Header)
+	}
+	var types []string
+	for _, event := range cast.Events {
+		types = append(types, event.Type)
+	}
+	if got := strings.Join(types, ","); got != "o,o,r" {
+		t.Errorf("event types = %s, want o,o,r", got)
+	}
+
+	player := gopyte.NewCastPlayer(cast, 100)
+	if got := replayLines(player, cast.Duration()); len(got) != 1 || got[0] != "red 界<&>" {
+		t.Errorf("replay = %q", got)
+	}
+	if rows := len(player.Screen.GetBuffer()); rows != 4 {
+		t.Errorf("replay has %d rows after resize, want 4", rows)
+	}
+	if attrs := player.Screen.GetAttributes(); len(attrs) == 0 || attrs[0][0].Fg != "red" {
+		t.Errorf("replay lost colors")
+	}
+
+	// Seeking back to before the first event starts from a fresh screen
+	if got := replayLines(player, -1); len(got) != 0 || len(player.Screen.GetBuffer()) != 3 {
+		t.Errorf("after seeking back = %q", got)
+	}
+}
+
+func TestReadCastErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Empty", ""},
+		{"Version 1", `{"version": 1, "width": 80, "height": 24}`},
+		{"No size", `{"version": 2}`},
+		{"Bad event", "{\"version\": 2, \"width\": 80, \"height\": 24}\n[0.1, \"o\"]"},
+	}
+	for _, tc := range tests {
+		if _, err := gopyte.ReadCast(strings.NewReader(tc.input)); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// charsetLines feeds input into a fresh screen and returns its lines with
+// trailing blanks removed
+func charsetLines(input string, lines int) []string {
+	screen := gopyte.NewWideCharScreen(20, 6, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed(input)
+
+	display := screen.GetDisplay()
+	result := make([]string, lines)
+	for i := range result {
+		result[i] = strings.TrimRight(display[i], " ")
+	}
+	return result
+}
+
+func TestCharsets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			// The box vttest draws in its character set screen
+			"DEC special graphics box",
+			"\x1b(0lqqqk\r\nx   x\r\nmqqqj\x1b(B",
+			[]string{"┌───┐", "│   │", "└───┘"},
+		},
+		{
+			"All DEC special graphics",
+			"\x1b(0`abcdefghijklmnopqrstuvwxyz{|}~\x1b(B",
+			[]string{"◆▒␉␌␍␊°±␤␋┘┐┌└┼⎺⎻─⎼⎽", "├┤┴┬│≤≥π≠£·"},
+		},
+		{
+			"ASCII after designation back",
+			"\x1b(0q\x1b(Bq",
+			[]string{"─q"},
+		},
+		{
+			"UK set",
+			"\x1b(A#1 \x1b(B#2",
+			[]string{"£1 #2"},
+		},
+		{
+			"G1 with shift out and in",
+			"\x1b)0a\x0eqqq\x0fb",
+			[]string{"a───b"},
+		},
+		{
+			"Shift out without a designation is ASCII",
+			"\x0eqq\x0f",
+			[]string{"qq"},
+		},
+		{
+			"Single shift 2 maps one character",
+			"\x1b*0\x1bNqq",
+			[]string{"─q"},
+		},
+		{
+			"Single shift 3 maps one character",
+			"\x1b+A\x1bO##",
+			[]string{"£#"},
+		},
+		{
+			"Locking shift 2",
+			"\x1b*0\x1bnxx\x0fxx",
+			[]string{"││xx"},
+		},
+		{
+			"Locking shift 3",
+			"\x1b+0\x1bomm\x1b(B\x0fm",
+			[]string{"└└m"},
+		},
+		{
+			"UTF-8 text passes through graphics",
+			"\x1b(0é→q\x1b(B",
+			[]string{"é→─"},
+		},
+		{
+			"Unknown set keeps the designation",
+			"\x1b(0\x1b(Zq\x1b(B",
+			[]string{"─"},
+		},
+		{
+			"Restore cursor restores charsets",
+			"\x1b(0\x1b7\x1b(Bq\x1b8q\x1b(B",
+			[]string{"─"},
+		},
+		{
+			"RIS resets charsets",
+			"\x1b(0\x0e\x1bcq",
+			[]string{"q"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := charsetLines(tc.input, len(tc.want))
+			for i := range tc.want {
+				if got[i] != tc.want[i] {
+					t.Errorf("line %d = %q, want %q", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCharsetDesignationMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b(0\x1b)A\x1b*B\x1b+0\x0e\x0f\x1bn\x1bo\x1bN\x1bO")
+
+	want := []string{
+		"DefineCharset[0 (]", "DefineCharset[A )]", "DefineCharset[B *]", "DefineCharset[0 +]",
+		"ShiftOut[]", "ShiftIn[]", "LockingShift[2]", "LockingShift[3]",
+		"SingleShift[2]", "SingleShift[3]",
+	}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
@@ -0,0 +1,63 @@
+package gopyte_test
+
+import (
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+func TestDECSCUSR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"Blinking block", "\x1b[1 q", gopyte.CursorStyleBlinkBlock},
+		{"Steady underline", "\x1b[4 q", gopyte.CursorStyleSteadyUnderline},
+		{"Blinking bar", "\x1b[5 q", gopyte.CursorStyleBlinkBar},
+		{"Steady bar", "\x1b[6 q", gopyte.CursorStyleSteadyBar},
+		{"No parameter resets", "\x1b[6 q\x1b[ q", gopyte.CursorStyleDefault},
+		{"Unknown style ignored", "\x1b[2 q\x1b[9 q", gopyte.CursorStyleSteadyBlock},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewWideCharScreen(20, 4, 10)
+			stream := gopyte.NewStream(screen, false)
+			stream.Feed(tc.input + "x")
+
+			if got := screen.GetCursorStyle(); got != tc.want {
+				t.Errorf("cursor style = %d, want %d", got, tc.want)
+			}
+			// The sequence must not print anything
+			if display := screen.GetDisplay(); display[0][:2] != "x " {
+				t.Errorf("line 0 = %q, want it to start with %q", display[0], "x ")
+			}
+		})
+	}
+}
+
+func TestDECTCEM(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 4, 10)
+	stream := gopyte.NewStream(screen, false)
+
+	if screen.IsCursorHidden() {
+		t.Fatalf("cursor hidden on a new screen")
+	}
+
+	stream.Feed("\x1b[?25l")
+	if !screen.IsCursorHidden() {
+		t.Errorf("cursor visible after ?25l")
+	}
+
+	// Visibility is not part of the saved cursor
+	stream.Feed("\x1b7\x1b[?25h\x1b8")
+	if screen.IsCursorHidden() {
+		t.Errorf("restoring the cursor hid it again")
+	}
+
+	stream.Feed("\x1b[?25l\x1bc")
+	if screen.IsCursorHidden() {
+		t.Errorf("cursor still hidden after reset")
+	}
+}
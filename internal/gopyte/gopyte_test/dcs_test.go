@@ -0,0 +1,191 @@
+package gopyte_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// newDCSScreen returns a screen whose replies are collected
+func newDCSScreen() (*gopyte.NativeScreen, *gopyte.Stream, *[]string) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+	var replies []string
+	screen.SetResponseHandler(func(data string) { replies = append(replies, data) })
+	return screen, stream, &replies
+}
+
+// hexUpper encodes s as XTGETTCAP does
+func hexUpper(s string) string {
+	return strings.ToUpper(hex.EncodeToString([]byte(s)))
+}
+
+func TestXTGETTCAP(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"Terminal name", "\x1bP+q544E\x1b\\", []string{"\x1bP1+r544E=" + hexUpper("xterm-256color") + "\x1b\\"}},
+		{"Lowercase hex", "\x1bP+q" + hex.EncodeToString([]byte("colors")) + "\x1b\\", []string{
+			"\x1bP1+r" + hexUpper("colors") + "=" + hexUpper("256") + "\x1b\\",
+		}},
+		{"Boolean capability", "\x1bP+q" + hexUpper("RGB") + "\x1b\\", []string{"\x1bP1+r" + hexUpper("RGB") + "\x1b\\"}},
+		{"Escape sequences in values", "\x1bP+q" + hexUpper("Smulx") + "\x1b\\", []string{
+			"\x1bP1+r" + hexUpper("Smulx") + "=" + hexUpper("\x1b[4:%p1%dm") + "\x1b\\",
+		}},
+		{"Several names", "\x1bP+q" + hexUpper("TN") + ";" + hexUpper("nope") + ";" + hexUpper("Co") + "\x1b\\", []string{
+			"\x1bP1+r" + hexUpper("TN") + "=" + hexUpper("xterm-256color") + "\x1b\\",
+			"\x1bP0+r" + hexUpper("nope") + "\x1b\\",
+			"\x1bP1+r" + hexUpper("Co") + "=" + hexUpper("256") + "\x1b\\",
+		}},
+		{"Unknown name", "\x1bP+q7A7A\x1b\\", []string{"\x1bP0+r7A7A\x1b\\"}},
+		{"Invalid hex", "\x1bP+qxyz\x1b\\", []string{"\x1bP0+rxyz\x1b\\"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stream, replies := newDCSScreen()
+			stream.Feed(tc.input)
+			if strings.Join(*replies, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("replies = %q, want %q", *replies, tc.want)
+			}
+		})
+	}
+}
+
+func TestDECRQSS(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"SGR default", "\x1bP$qm\x1b\\", "\x1bP1$r0m\x1b\\"},
+		{"SGR", "\x1b[1;4;31;48;5;100m\x1bP$qm\x1b\\", "\x1bP1$r0;1;4;31;48;5;100m\x1b\\"},
+		{"SGR direct color", "\x1b[38;2;1;2;3m\x1bP$qm\x1b\\", "\x1bP1$r0;38;2;1;2;3m\x1b\\"},
+		{"DECSTBM default", "\x1bP$qr\x1b\\", "\x1bP1$r1;4r\x1b\\"},
+		{"DECSTBM", "\x1b[2;3r\x1bP$qr\x1b\\", "\x1bP1$r2;3r\x1b\\"},
+		{"DECSLRM", "\x1b[?69h\x1b[5;10s\x1bP$qs\x1b\\", "\x1bP1$r5;10s\x1b\\"},
+		{"DECSCUSR default", "\x1bP$q q\x1b\\", "\x1bP1$r0 q\x1b\\"},
+		{"DECSCUSR", "\x1b[5 q\x1bP$q q\x1b\\", "\x1bP1$r5 q\x1b\\"},
+		{"Unknown setting", "\x1bP$qx\x1b\\", "\x1bP0$r\x1b\\"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stream, replies := newDCSScreen()
+			stream.Feed(tc.input)
+			if len(*replies) != 1 || (*replies)[0] != tc.want {
+				t.Errorf("replies = %q, want %q", *replies, tc.want)
+			}
+		})
+	}
+}
+
+func TestDCSConsumed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Sixel", "a\x1bPq#0;2;0;0;0#0~~@@vv\x1b\\b"},
+		{"Unknown with parameters", "a\x1bP1000p some data\x1b\\b"},
+		{"Private marker", "a\x1bP>|version\x1b\\b"},
+		{"Empty", "a\x1bP\x1b\\b"},
+		{"Cancelled", "a\x1bPqdata\x18b"},
+		{"Control characters in data", "a\x1bPqx\ny\rz\x1b\\b"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen, stream, replies := newDCSScreen()
+			stream.Feed(tc.input)
+			if line := screen.GetDisplay()[0]; line != "ab" {
+				t.Errorf("line = %q, want %q", line, "ab")
+			}
+			if len(*replies) != 0 {
+				t.Errorf("replies = %q, want none", *replies)
+			}
+		})
+	}
+}
+
+func TestDCSSplitAcrossFeeds(t *testing.T) {
+	_, stream, replies := newDCSScreen()
+	for _, chunk := range []string{"\x1bP", "$", "qm\x1b", "\\"} {
+		stream.Feed(chunk)
+	}
+	want := "\x1bP1$r0m\x1b\\"
+	if len(*replies) != 1 || (*replies)[0] != want {
+		t.Errorf("replies = %q, want %q", *replies, want)
+	}
+}
+
+func TestDCSAbortedByEscape(t *testing.T) {
+	screen, stream, replies := newDCSScreen()
+	stream.Feed("\x1bP$qm\x1b[1mx")
+
+	if len(*replies) != 0 {
+		t.Errorf("replies = %q, want none", *replies)
+	}
+	if line := screen.GetDisplay()[0]; line != "x" {
+		t.Errorf("line = %q, want %q", line, "x")
+	}
+
+	// The SGR after the aborted string was applied
+	stream.Feed("\x1bP$qm\x1b\\")
+	if want := "\x1bP1$r0;1m\x1b\\"; len(*replies) != 1 || (*replies)[0] != want {
+		t.Errorf("replies = %q, want %q", *replies, want)
+	}
+}
+
+// recordingDCSHandler keeps the last string it was given
+type recordingDCSHandler struct {
+	params       []int
+	intermediate string
+	final        byte
+	data         string
+	calls        int
+}
+
+func (h *recordingDCSHandler) HandleDCS(params []int, intermediate string, final byte, data string) {
+	h.params, h.intermediate, h.final, h.data = params, intermediate, final, data
+	h.calls++
+}
+
+func TestSetDCSHandler(t *testing.T) {
+	_, stream, replies := newDCSScreen()
+	handler := &recordingDCSHandler{}
+	stream.SetDCSHandler("$", 't', handler)
+
+	stream.Feed("\x1bP1;;2$tsome;data\x1b\\")
+	if handler.calls != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.calls)
+	}
+	if got := handler.params; len(got) != 3 || got[0] != 1 || got[1] != 0 || got[2] != 2 {
+		t.Errorf("params = %v, want [1 0 2]", got)
+	}
+	if handler.intermediate != "$" || handler.final != 't' || handler.data != "some;data" {
+		t.Errorf("got %q %q %q, want %q %q %q", handler.intermediate, handler.final, handler.data, "$", 't', "some;data")
+	}
+
+	// A nil handler turns off a built-in one
+	stream.SetDCSHandler("+", 'q', nil)
+	stream.Feed("\x1bP+q544E\x1b\\")
+	if len(*replies) != 0 {
+		t.Errorf("replies = %q, want none", *replies)
+	}
+}
+
+func TestDECRQSSMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1bP$q q\x1b\\\x1bP$qm\x1b\\")
+
+	want := []string{"ReportStatusString[ q]", "ReportStatusString[m]"}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
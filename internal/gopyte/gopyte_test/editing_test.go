@@ -0,0 +1,174 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// editingScreen feeds input into a fresh 20x5 screen
+func editingScreen(input string) *gopyte.WideCharScreen {
+	screen := gopyte.NewWideCharScreen(20, 5, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed(input)
+	return screen
+}
+
+// screenLines returns the first lines of a screen's buffer, without the
+// scrollback, with wide character continuations and trailing blanks removed
+func screenLines(screen *gopyte.WideCharScreen, lines int) []string {
+	buffer := screen.GetBuffer()
+	result := make([]string, lines)
+	for i := range result {
+		line := strings.ReplaceAll(string(buffer[i]), "\x00", "")
+		result[i] = strings.TrimRight(line, " ")
+	}
+	return result
+}
+
+func TestEditingFunctions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"Insert mode", "abcdef\r\x1b[4hXY", []string{"XYabcdef"}},
+		{"Insert mode reset", "abcdef\r\x1b[4h\x1b[4lXY", []string{"XYcdef"}},
+		{"Insert mode stops at the right margin", "0123456789\x1b[?69h\x1b[1;6s\x1b[1;2H\x1b[4hX", []string{"0X12346789"}},
+		{"ICH", "abcdef\x1b[1;2H\x1b[2@", []string{"a  bcdef"}},
+		{"ICH within margins", "0123456789\x1b[?69h\x1b[2;6s\x1b[1;3H\x1b[2@", []string{"01  236789"}},
+		{"DCH", "abcdef\x1b[1;2H\x1b[2P", []string{"adef"}},
+		{"DCH within margins", "0123456789\x1b[?69h\x1b[2;6s\x1b[1;3H\x1b[2P", []string{"0145  6789"}},
+		{"DCH outside margins", "0123456789\x1b[?69h\x1b[2;6s\x1b[1;8H\x1b[2P", []string{"0123456789"}},
+		{"REP", "ab\x1b[3b", []string{"abbbb"}},
+		{"REP wide character", "界\x1b[2b", []string{"界界界"}},
+		{"REP with nothing drawn", "\x1b[3bx", []string{"x"}},
+		{"REP of a DEC graphic", "\x1b(0q\x1b[2b\x1b(B", []string{"───"}},
+		{"CHT", "\x1b[2Ix", []string{"                x"}},
+		{"CHT stops at the last column", "\x1b[9Ix", []string{"                   x"}},
+		{"CBT", "\x1b[1;19H\x1b[2Zx", []string{"        x"}},
+		{"CBT stops at the first column", "\x1b[1;5H\x1b[3Zx", []string{"x"}},
+		{"SU", "a\r\nb\r\nc\x1b[S", []string{"b", "c", ""}},
+		{"SU keeps the cursor", "a\r\nb\x1b[Sx", []string{"b", " x"}},
+		{"SD", "a\r\nb\x1b[T", []string{"", "a", "b"}},
+		{"SD count", "a\r\nb\x1b[2T", []string{"", "", "a", "b"}},
+		{"SU in scroll region", "a\r\nb\r\nc\r\nd\x1b[2;3r\x1b[S", []string{"a", "c", "", "d"}},
+		{"SD in scroll region", "a\r\nb\r\nc\r\nd\x1b[2;3r\x1b[T", []string{"a", "", "b", "d"}},
+		{"SU within left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[S", []string{"afgd", "e  h"}},
+		{"IND within left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;2r\x1b[2;2H\x1bD", []string{"afgd", "e  h", ""}},
+		{"RI within left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;2r\x1b[1;2H\x1bM", []string{"a  d", "ebch", ""}},
+		{"IND outside left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;2r\x1b[2;4H\x1bD", []string{"abcd", "efgh", ""}},
+		{"IL within left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;2H\x1b[L", []string{"a  d", "ebch", " fg"}},
+		{"DL within left/right margins", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;2H\x1b[M", []string{"afgd", "e  h"}},
+		{"Wrap at the right margin", "\x1b[?69h\x1b[1;4sabcdef", []string{"abcd", "ef"}},
+		{"Wrap at the right margin from the left margin", "\x1b[?69h\x1b[3;5s\x1b[1;3Habcdef", []string{"  abc", "  def"}},
+		{"Carriage return to the left margin", "\x1b[?69h\x1b[3;10s\x1b[1;5Habc\rX", []string{"  X abc"}},
+		{"Origin mode with left/right margins", "\x1b[?69h\x1b[3;10s\x1b[?6h\x1b[1;2HX", []string{"   X"}},
+		{"Margins reset with DECLRMM", "\x1b[?69h\x1b[1;4s\x1b[?69labcdef", []string{"abcdef"}},
+		{"CSI s without DECLRMM saves the cursor", "ab\x1b[sxyz\x1b8c", []string{"abcyz"}},
+		{"Invalid margins are ignored", "\x1b[?69h\x1b[5;3sabcdef", []string{"abcdef"}},
+		{"DECSTR ends insert mode", "abc\r\x1b[4h\x1b[!pX", []string{"Xbc"}},
+		{"DECSTR turns autowrap back on", "\x1b[?7l\x1b[!p01234567890123456789ab", []string{"01234567890123456789", "ab"}},
+		{"DECSTR clears margins", "\x1b[?69h\x1b[1;4s\x1b[!pabcdef", []string{"abcdef"}},
+		{"DECSTR resets charsets", "\x1b(0\x1b[!pq", []string{"q"}},
+		{"DECSTR keeps the screen and cursor", "abc\x1b[!pd", []string{"abcd"}},
+		{"DECRC restores origin mode", "\x1b[2;4r\x1b[?6h\x1b7\x1b[?6l\x1b8\x1b[1;1Hx", []string{"", "x"}},
+		{"DECRC restores autowrap", "\x1b[?7l\x1b7\x1b[?7h\x1b801234567890123456789ab", []string{"01234567890123456789", ""}},
+		{"DECRC without DECSC homes", "abc\x1b8x", []string{"xbc"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := screenLines(editingScreen(tc.input), len(tc.want))
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("lines = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditingCursor(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		x, y  int
+	}{
+		{"SU keeps the cursor", "a\r\nb\r\nc\x1b[2S", 1, 2},
+		{"SD keeps the cursor", "ab\x1b[3T", 2, 0},
+		{"DECSLRM homes the cursor", "abc\r\ndef\x1b[?69h\x1b[3;6s", 0, 0},
+		{"DECSLRM homes to the margin in origin mode", "\x1b[?6h\x1b[?69h\x1b[3;6s", 2, 0},
+		{"DECSTR keeps the cursor", "abc\r\nde\x1b[!p", 2, 1},
+		{"CHT within margins", "\x1b[?69h\x1b[1;12s\x1b[3I", 11, 0},
+		{"CBT within margins", "\x1b[?69h\x1b[4;20s\x1b[1;18H\x1b[3Z", 3, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y := editingScreen(tc.input).GetCursor()
+			if x != tc.x || y != tc.y {
+				t.Errorf("cursor = (%d, %d), want (%d, %d)", x, y, tc.x, tc.y)
+			}
+		})
+	}
+}
+
+func TestEditingAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		bold  bool
+	}{
+		{"DECRC restores attributes", "\x1b[1m\x1b7\x1b[0m\x1b8", true},
+		{"DECRC restores attributes after a change", "\x1b7\x1b[1m\x1b8", false},
+		{"DECSTR resets attributes", "\x1b[1m\x1b[!p", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := firstCellAttrs(tc.input).Bold; got != tc.bold {
+				t.Errorf("bold = %v, want %v", got, tc.bold)
+			}
+		})
+	}
+}
+
+func TestEditingScrollUpHistory(t *testing.T) {
+	screen := editingScreen("a\r\nb\x1b[2S")
+	if got := screen.GetHistorySize(); got != 2 {
+		t.Errorf("history size = %d, want 2", got)
+	}
+
+	// Only a full-screen region reaches the scrollback
+	screen = editingScreen("a\r\nb\x1b[1;3r\x1b[S")
+	if got := screen.GetHistorySize(); got != 0 {
+		t.Errorf("history size with a scroll region = %d, want 0", got)
+	}
+
+	// And never from the alternate screen
+	screen = editingScreen("\x1b[?1049ha\r\nb\x1b[S")
+	if got := screen.GetHistorySize(); got != 0 {
+		t.Errorf("history size on the alternate screen = %d, want 0", got)
+	}
+}
+
+func TestEditingMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b[2;5s\x1b[s\x1b[?s\x1b[3b\x1b[I\x1b[2Z\x1b[S\x1b[2T\x1b[!p")
+
+	want := []string{
+		"SetLeftRightMargins[2 5]",
+		"SetLeftRightMargins[0 0]",
+		"RepeatCharacter[3]",
+		"CursorForwardTab[1]",
+		"CursorBackTab[2]",
+		"ScrollContentUp[1]",
+		"ScrollContentDown[2]",
+		"SoftReset[]",
+	}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
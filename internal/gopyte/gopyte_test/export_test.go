@@ -0,0 +1,113 @@
+package gopyte_test
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+func TestExportFormats(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 3, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	// Enough lines that the first ones are in History
+	stream.Feed("plain\r\n")
+	stream.Feed("\x1b[1;31merror\x1b[0m ok\r\n")
+	stream.Feed("界 \x1b[38;5;196m<x>\x1b[0m\r\n")
+	stream.Feed("\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\")
+
+	lines := screen.GetLineCells(0, screen.GetHistorySize()+3)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "Text",
+			got:  gopyte.FormatText(lines),
+			want: "plain\nerror ok\n界 <x>\nlink\n",
+		},
+		{
+			name: "ANSI",
+			got:  gopyte.FormatANSI(lines),
+			want: "plain\n" +
+				"\x1b[0;1;31merror\x1b[0m ok\n" +
+				"界 \x1b[0;38;5;196m<x>\x1b[0m\n" +
+				"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\\n",
+		},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("%s export = %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+
+	palette := gopyte.HTMLPalette{
+		Colors: map[string]color.Color{
+			"red":     color.RGBA{0xff, 0, 0, 0xff},
+			"default": color.White, // Theme mappings carry the default foreground too
+		},
+		Foreground: color.White,
+		Background: color.Black,
+	}
+	page := gopyte.FormatHTML(lines, "router <1>", palette)
+	for _, want := range []string{
+		"<title>router &lt;1&gt;</title>",
+		"background-color: #000000; color: #ffffff;",
+		"plain\n",
+		`<span style="color:#ff0000;font-weight:bold">error</span> ok`,
+		`<span style="color:#ff0000">&lt;x&gt;</span>`,
+		`<a href="https://example.com">link</a>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML export missing %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestHTMLExportLinkSchemes(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"HTTPS", "https://example.com", `<a href="https://example.com">x</a>`},
+		{"Mail", "mailto:noc@example.com", `<a href="mailto:noc@example.com">x</a>`},
+		{"Upper case scheme", "HTTP://example.com", `<a href="HTTP://example.com">x</a>`},
+		{"JavaScript", "javascript:alert(1)", "<pre>x\n"},
+		{"File", "file:///etc/passwd", "<pre>x\n"},
+		{"Data", "data:text/html,<b>", "<pre>x\n"},
+		{"No scheme", "example.com", "<pre>x\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewWideCharScreen(20, 3, 100)
+			stream := gopyte.NewStream(screen, false)
+			stream.Feed("\x1b]8;;" + tc.link + "\x1b\\x\x1b]8;;\x1b\\")
+
+			page := gopyte.FormatHTML(screen.GetLineCells(0, 3), "", gopyte.HTMLPalette{Foreground: color.White, Background: color.Black})
+			if !strings.Contains(page, tc.want) {
+				t.Errorf("HTML export missing %q:\n%s", tc.want, page)
+			}
+		})
+	}
+}
+
+func TestGetLineCellsSkipsContinuations(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(10, 2, 100)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed("a界b")
+
+	cells := screen.GetLineCells(0, 1)[0]
+	line := screen.GetLines(0, 1)[0]
+	if len(cells) != len([]rune(line)) {
+		t.Fatalf("got %d cells for %d runes", len(cells), len([]rune(line)))
+	}
+	if cells[1].Char != '界' || cells[1].Width != 2 || cells[2].Char != 'b' {
+		t.Errorf("cells = %+v", cells[:3])
+	}
+}
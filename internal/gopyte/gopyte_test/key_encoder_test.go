@@ -0,0 +1,141 @@
+package gopyte_test
+
+import (
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+func TestEncodeKey(t *testing.T) {
+	const (
+		shift = gopyte.ModShift
+		alt   = gopyte.ModAlt
+		ctrl  = gopyte.ModCtrl
+		super = gopyte.ModSuper
+	)
+	legacy := gopyte.KeyboardMode{}
+	mok1 := gopyte.KeyboardMode{ModifyOtherKeys: 1}
+	mok2 := gopyte.KeyboardMode{ModifyOtherKeys: 2}
+	kitty := func(flags int) gopyte.KeyboardMode { return gopyte.KeyboardMode{KittyFlags: flags} }
+	disambiguate := kitty(gopyte.KittyDisambiguate)
+	allKeys := kitty(gopyte.KittyDisambiguate | gopyte.KittyReportAllKeys)
+
+	tests := []struct {
+		name string
+		key  gopyte.Key
+		mods gopyte.KeyModifiers
+		mode gopyte.KeyboardMode
+		want string
+	}{
+		// Traditional encoding
+		{"Letter", 'a', 0, legacy, "a"},
+		{"Shift+letter", 'a', shift, legacy, "A"},
+		{"Ctrl+letter", 'c', ctrl, legacy, "\x03"},
+		{"Ctrl+Shift+letter drops Shift", 'c', ctrl | shift, legacy, "\x03"},
+		{"Ctrl+space", ' ', ctrl, legacy, "\x00"},
+		{"Ctrl+bracket", '[', ctrl, legacy, "\x1b"},
+		{"Ctrl+digit", '3', ctrl, legacy, "\x1b"},
+		{"Ctrl+key without a control code", '.', ctrl, legacy, "."},
+		{"Alt+letter", 'x', alt, legacy, "\x1bx"},
+		{"Alt+Shift+letter", 'x', alt | shift, legacy, "\x1bX"},
+		{"Alt+Ctrl+letter", 'x', alt | ctrl, legacy, "\x1b\x18"},
+		{"Super+letter", 'c', super, legacy, ""},
+		{"Enter", gopyte.KeyEnter, 0, legacy, "\r"},
+		{"Alt+Enter", gopyte.KeyEnter, alt, legacy, "\x1b\r"},
+		{"Ctrl+Enter drops Ctrl", gopyte.KeyEnter, ctrl, legacy, "\r"},
+		{"Tab", gopyte.KeyTab, 0, legacy, "\t"},
+		{"Shift+Tab", gopyte.KeyTab, shift, legacy, "\x1b[Z"},
+		{"Backspace", gopyte.KeyBackspace, 0, legacy, "\x7f"},
+		{"Ctrl+Backspace", gopyte.KeyBackspace, ctrl, legacy, "\b"},
+		{"Alt+Backspace", gopyte.KeyBackspace, alt, legacy, "\x1b\x7f"},
+		{"Escape", gopyte.KeyEscape, 0, legacy, "\x1b"},
+		{"Up", gopyte.KeyUp, 0, legacy, "\x1b[A"},
+		{"Ctrl+Right", gopyte.KeyRight, ctrl, legacy, "\x1b[1;5C"},
+		{"Shift+Alt+Left", gopyte.KeyLeft, shift | alt, legacy, "\x1b[1;4D"},
+		{"Home", gopyte.KeyHome, 0, legacy, "\x1b[H"},
+		{"Shift+End", gopyte.KeyEnd, shift, legacy, "\x1b[1;2F"},
+		{"Insert", gopyte.KeyInsert, 0, legacy, "\x1b[2~"},
+		{"Delete", gopyte.KeyDelete, 0, legacy, "\x1b[3~"},
+		{"Ctrl+Page Up", gopyte.KeyPageUp, ctrl, legacy, "\x1b[5;5~"},
+		{"F1", gopyte.KeyF1, 0, legacy, "\x1b[11~"},
+		{"F5", gopyte.KeyF5, 0, legacy, "\x1b[15~"},
+		{"Ctrl+F1", gopyte.KeyF1, ctrl, legacy, "\x1b[1;5P"},
+		{"Alt+F2", gopyte.KeyF2, alt, legacy, "\x1b[1;3Q"},
+		{"Ctrl+Shift+F3", gopyte.KeyF3, ctrl | shift, legacy, "\x1b[1;6R"},
+		{"Shift+F4", gopyte.KeyF4, shift, legacy, "\x1b[1;2S"},
+		{"F4", gopyte.KeyF4, 0, legacy, "\x1b[14~"},
+		{"Shift+F12", gopyte.KeyF12, shift, legacy, "\x1b[24;2~"},
+
+		// modifyOtherKeys level 1: only chords the traditional encoding loses
+		{"mok1 letter", 'a', 0, mok1, "a"},
+		{"mok1 Ctrl+letter", 'a', ctrl, mok1, "\x01"},
+		{"mok1 Alt+letter", 'a', alt, mok1, "\x1ba"},
+		{"mok1 Ctrl+Shift+letter", 'a', ctrl | shift, mok1, "\x1b[27;6;65~"},
+		{"mok1 Ctrl+Enter", gopyte.KeyEnter, ctrl, mok1, "\x1b[27;5;13~"},
+		{"mok1 Shift+Enter", gopyte.KeyEnter, shift, mok1, "\x1b[27;2;13~"},
+		{"mok1 Ctrl+Tab", gopyte.KeyTab, ctrl, mok1, "\x1b[27;5;9~"},
+		{"mok1 Shift+Tab", gopyte.KeyTab, shift, mok1, "\x1b[Z"},
+		{"mok1 Ctrl+digit", '1', ctrl, mok1, "\x1b[27;5;49~"},
+		{"mok1 Ctrl+period", '.', ctrl, mok1, "\x1b[27;5;46~"},
+		{"mok1 Super+letter", 'c', super, mok1, "\x1b[27;9;99~"},
+		{"mok1 Ctrl+Up", gopyte.KeyUp, ctrl, mok1, "\x1b[1;5A"},
+
+		// modifyOtherKeys level 2: every Ctrl, Alt or Super chord
+		{"mok2 letter", 'a', 0, mok2, "a"},
+		{"mok2 Shift+letter", 'a', shift, mok2, "A"},
+		{"mok2 Ctrl+letter", 'a', ctrl, mok2, "\x1b[27;5;97~"},
+		{"mok2 Alt+letter", 'a', alt, mok2, "\x1b[27;3;97~"},
+		{"mok2 Alt+Ctrl+letter", 'a', alt | ctrl, mok2, "\x1b[27;7;97~"},
+		{"mok2 Enter", gopyte.KeyEnter, 0, mok2, "\r"},
+		{"mok2 Alt+Enter", gopyte.KeyEnter, alt, mok2, "\x1b[27;3;13~"},
+		{"mok2 Ctrl+Down", gopyte.KeyDown, ctrl, mok2, "\x1b[1;5B"},
+
+		// Kitty, disambiguate escape codes
+		{"Kitty letter", 'a', 0, disambiguate, "a"},
+		{"Kitty Shift+letter", 'a', shift, disambiguate, "A"},
+		{"Kitty Ctrl+letter", 'a', ctrl, disambiguate, "\x1b[97;5u"},
+		{"Kitty Ctrl+Shift+letter", 'a', ctrl | shift, disambiguate, "\x1b[97;6u"},
+		{"Kitty Alt+letter", 'a', alt, disambiguate, "\x1b[97;3u"},
+		{"Kitty Alt+Ctrl+letter", 'a', alt | ctrl, disambiguate, "\x1b[97;7u"},
+		{"Kitty Super+letter", 'a', super, disambiguate, "\x1b[97;9u"},
+		{"Kitty Escape", gopyte.KeyEscape, 0, disambiguate, "\x1b[27u"},
+		{"Kitty Enter", gopyte.KeyEnter, 0, disambiguate, "\r"},
+		{"Kitty Ctrl+Enter", gopyte.KeyEnter, ctrl, disambiguate, "\x1b[13;5u"},
+		{"Kitty Shift+Tab", gopyte.KeyTab, shift, disambiguate, "\x1b[9;2u"},
+		{"Kitty Backspace", gopyte.KeyBackspace, 0, disambiguate, "\x7f"},
+		{"Kitty Alt+Backspace", gopyte.KeyBackspace, alt, disambiguate, "\x1b[127;3u"},
+		{"Kitty Up", gopyte.KeyUp, 0, disambiguate, "\x1b[A"},
+		{"Kitty Ctrl+Left", gopyte.KeyLeft, ctrl, disambiguate, "\x1b[1;5D"},
+		{"Kitty F1", gopyte.KeyF1, 0, disambiguate, "\x1b[P"},
+		{"Kitty Shift+F1", gopyte.KeyF1, shift, disambiguate, "\x1b[1;2P"},
+		{"Kitty F3", gopyte.KeyF3, 0, disambiguate, "\x1b[13~"},
+		{"Kitty F5", gopyte.KeyF5, ctrl, disambiguate, "\x1b[15;5~"},
+
+		// Kitty, report all keys as escape codes
+		{"Kitty all letter", 'a', 0, allKeys, "\x1b[97u"},
+		{"Kitty all Shift+letter", 'a', shift, allKeys, "\x1b[97;2u"},
+		{"Kitty all Enter", gopyte.KeyEnter, 0, allKeys, "\x1b[13u"},
+		{"Kitty all Tab", gopyte.KeyTab, 0, allKeys, "\x1b[9u"},
+		{"Kitty all Backspace", gopyte.KeyBackspace, 0, allKeys, "\x1b[127u"},
+		{"Kitty all space", ' ', 0, allKeys, "\x1b[32u"},
+		{"Kitty all Home", gopyte.KeyHome, 0, allKeys, "\x1b[H"},
+
+		// Kitty, alternate keys and associated text
+		{"Kitty alternates Shift+letter", 'a', shift, kitty(gopyte.KittyReportAllKeys | gopyte.KittyReportAlternates), "\x1b[97:65;2u"},
+		{"Kitty alternates Ctrl+Shift+letter", 'a', ctrl | shift, kitty(gopyte.KittyDisambiguate | gopyte.KittyReportAlternates), "\x1b[97:65;6u"},
+		{"Kitty alternates without Shift", 'a', ctrl, kitty(gopyte.KittyDisambiguate | gopyte.KittyReportAlternates), "\x1b[97;5u"},
+		{"Kitty text", 'a', 0, kitty(gopyte.KittyReportAllKeys | gopyte.KittyReportText), "\x1b[97;1;97u"},
+		{"Kitty text Shift+letter", 'a', shift, kitty(gopyte.KittyReportAllKeys | gopyte.KittyReportText), "\x1b[97;2;65u"},
+		{"Kitty text Ctrl+letter", 'a', ctrl, kitty(gopyte.KittyReportAllKeys | gopyte.KittyReportText), "\x1b[97;5u"},
+		{"Kitty text needs all keys", 'a', 0, kitty(gopyte.KittyDisambiguate | gopyte.KittyReportText), "a"},
+		{"Kitty event types", 'a', ctrl, kitty(gopyte.KittyDisambiguate | gopyte.KittyReportEvents), "\x1b[97;5u"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := gopyte.EncodeKey(tc.key, tc.mods, tc.mode); got != tc.want {
+				t.Errorf("EncodeKey = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+func TestKeyboardMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  gopyte.KeyboardMode
+	}{
+		{"Default", "", gopyte.KeyboardMode{}},
+		{"modifyOtherKeys", "\x1b[>4;2m", gopyte.KeyboardMode{ModifyOtherKeys: 2}},
+		{"modifyOtherKeys level 1", "\x1b[>4;1m", gopyte.KeyboardMode{ModifyOtherKeys: 1}},
+		{"modifyOtherKeys reset", "\x1b[>4;2m\x1b[>4m", gopyte.KeyboardMode{}},
+		{"modifyOtherKeys reset with no resource", "\x1b[>4;2m\x1b[>m", gopyte.KeyboardMode{}},
+		{"modifyOtherKeys disabled", "\x1b[>4;2m\x1b[>4n", gopyte.KeyboardMode{}},
+		{"Other resources ignored", "\x1b[>1;2m", gopyte.KeyboardMode{}},
+		{"modifyOtherKeys level clamped", "\x1b[>4;9m", gopyte.KeyboardMode{ModifyOtherKeys: 2}},
+		{"Kitty push", "\x1b[>1u", gopyte.KeyboardMode{KittyFlags: 1}},
+		{"Kitty push twice", "\x1b[>1u\x1b[>31u", gopyte.KeyboardMode{KittyFlags: 31}},
+		{"Kitty pop", "\x1b[>1u\x1b[>31u\x1b[<u", gopyte.KeyboardMode{KittyFlags: 1}},
+		{"Kitty pop count", "\x1b[>1u\x1b[>3u\x1b[>31u\x1b[<2u", gopyte.KeyboardMode{KittyFlags: 1}},
+		{"Kitty pop everything", "\x1b[>1u\x1b[<5u", gopyte.KeyboardMode{}},
+		{"Kitty pop empty", "\x1b[<u", gopyte.KeyboardMode{}},
+		{"Kitty unknown flags dropped", "\x1b[>255u", gopyte.KeyboardMode{KittyFlags: 31}},
+		{"Kitty set", "\x1b[>1u\x1b[=8u", gopyte.KeyboardMode{KittyFlags: 8}},
+		{"Kitty set without push", "\x1b[=9u", gopyte.KeyboardMode{KittyFlags: 9}},
+		{"Kitty add flags", "\x1b[>1u\x1b[=8;2u", gopyte.KeyboardMode{KittyFlags: 9}},
+		{"Kitty remove flags", "\x1b[>9u\x1b[=1;3u", gopyte.KeyboardMode{KittyFlags: 8}},
+		{"Kitty set keeps the stack", "\x1b[>1u\x1b[>2u\x1b[=8u\x1b[<u", gopyte.KeyboardMode{KittyFlags: 1}},
+		{"Kitty alternate screen has its own stack", "\x1b[>1u\x1b[?1049h", gopyte.KeyboardMode{}},
+		{"Kitty alternate screen stack", "\x1b[>1u\x1b[?1049h\x1b[>8u", gopyte.KeyboardMode{KittyFlags: 8}},
+		{"Kitty main screen stack restored", "\x1b[>1u\x1b[?1049h\x1b[>8u\x1b[?1049l", gopyte.KeyboardMode{KittyFlags: 1}},
+		{"RIS resets", "\x1b[>4;2m\x1b[>1u\x1bc", gopyte.KeyboardMode{}},
+		{"Not SGR", "\x1b[>4;2mx", gopyte.KeyboardMode{ModifyOtherKeys: 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewWideCharScreen(20, 4, 10)
+			stream := gopyte.NewStream(screen, false)
+			stream.Feed(tc.input)
+			if got := screen.KeyboardMode(); got != tc.want {
+				t.Errorf("KeyboardMode = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyboardStackLimit(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+
+	// The oldest of too many entries is dropped
+	stream.Feed("\x1b[>1u" + strings.Repeat("\x1b[>2u", 8))
+	stream.Feed("\x1b[<7u")
+	if got := screen.KeyboardMode().KittyFlags; got != 2 {
+		t.Errorf("flags = %d, want 2", got)
+	}
+	stream.Feed("\x1b[<u")
+	if got := screen.KeyboardMode().KittyFlags; got != 0 {
+		t.Errorf("flags = %d, want 0", got)
+	}
+}
+
+func TestKeyboardMarkersNotDrawn(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("a\x1b[>4;2mb\x1b[>1uc\x1b[<ud\x1b[=1;2ue\x1b[>cf")
+	if line := screen.GetDisplay()[0]; line != "abcdef" {
+		t.Errorf("line = %q, want %q", line, "abcdef")
+	}
+}
+
+func TestKittyKeyboardQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Default", "\x1b[?u", "\x1b[?0u"},
+		{"Pushed", "\x1b[>5u\x1b[?u", "\x1b[?5u"},
+		{"Popped", "\x1b[>5u\x1b[<u\x1b[?u", "\x1b[?0u"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stream, replies := newDCSScreen()
+			stream.Feed(tc.input)
+			if len(*replies) != 1 || (*replies)[0] != tc.want {
+				t.Errorf("replies = %q, want %q", *replies, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyboardMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b[>4;2m\x1b[>4n\x1b[>3u\x1b[<u\x1b[<2u\x1b[=5u\x1b[=1;3u\x1b[?u\x1b[>0c")
+
+	want := []string{
+		"SetModifyOtherKeys[2]",
+		"SetModifyOtherKeys[0]",
+		"PushKeyboardFlags[3]",
+		"PopKeyboardFlags[1]",
+		"PopKeyboardFlags[2]",
+		"SetKeyboardFlags[5 1]",
+		"SetKeyboardFlags[1 3]",
+		"ReportKeyboardFlags[]",
+		"ReportDeviceAttributes[0 false]",
+	}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
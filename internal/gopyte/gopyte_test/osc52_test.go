@@ -0,0 +1,131 @@
+package gopyte_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+type recordingClipboard struct {
+	sets    []string
+	queries []string
+}
+
+func (c *recordingClipboard) ClipboardSet(selection string, text string) {
+	c.sets = append(c.sets, selection+":"+text)
+}
+
+func (c *recordingClipboard) ClipboardQuery(selection string) {
+	c.queries = append(c.queries, selection)
+}
+
+func TestOSC52Set(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"BEL terminated", "\x1b]52;c;aGVsbG8=\x07", "c:hello"},
+		{"ST terminated", "\x1b]52;c;aGVsbG8=\x1b\\", "c:hello"},
+		{"Primary selection", "\x1b]52;p;d29ybGQ=\x07", "p:world"},
+		{"Empty selection", "\x1b]52;;aGk=\x07", "c:hi"},
+		{"No padding", "\x1b]52;c;aGk\x07", "c:hi"},
+		{"UTF-8 payload", "\x1b]52;c;w6lsw6h2ZQ==\x07", "c:élève"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewNativeScreen(20, 4)
+			stream := gopyte.NewStream(screen, false)
+			clip := &recordingClipboard{}
+			stream.SetClipboardHandler(clip)
+
+			stream.Feed(tc.input)
+
+			if len(clip.sets) != 1 || clip.sets[0] != tc.want {
+				t.Errorf("sets = %q, want [%q]", clip.sets, tc.want)
+			}
+			if display := screen.GetDisplay(); display[0] != "" {
+				t.Errorf("OSC 52 leaked onto screen: %q", display[0])
+			}
+		})
+	}
+}
+
+func TestOSC52QueryAndInvalid(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+	clip := &recordingClipboard{}
+	stream.SetClipboardHandler(clip)
+
+	stream.Feed("\x1b]52;c;?\x07")
+	stream.Feed("\x1b]52;c;!!not base64!!\x07")
+
+	if len(clip.queries) != 1 || clip.queries[0] != "c" {
+		t.Errorf("queries = %q, want [\"c\"]", clip.queries)
+	}
+	if len(clip.sets) != 0 {
+		t.Errorf("invalid payload should be ignored, got %q", clip.sets)
+	}
+}
+
+func TestOSC52WithoutHandler(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b]52;c;aGVsbG8=\x07ok")
+
+	if display := screen.GetDisplay(); display[0] != "ok" {
+		t.Errorf("line 0 = %q, want %q", display[0], "ok")
+	}
+}
+
+func TestEncodeClipboardReply(t *testing.T) {
+	got := gopyte.EncodeClipboardReply("c", "hello")
+	want := "\x1b]52;c;aGVsbG8=\x07"
+	if got != want {
+		t.Errorf("EncodeClipboardReply = %q, want %q", got, want)
+	}
+}
+
+func TestOSC52Large(t *testing.T) {
+	tests := []struct {
+		name string
+		size int // Bytes of text copied
+		sets int
+	}{
+		{"Under the limit", 512 << 10, 1},
+		{"Over the limit", 1 << 20, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewNativeScreen(20, 4)
+			stream := gopyte.NewStream(screen, false)
+			clip := &recordingClipboard{}
+			stream.SetClipboardHandler(clip)
+
+			text := strings.Repeat("x", tc.size)
+			payload := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+			// Fed in small reads, as from the SSH channel
+			for len(payload) > 0 {
+				n := min(len(payload), 4096)
+				stream.Feed(payload[:n])
+				payload = payload[n:]
+			}
+			stream.Feed("after")
+
+			if len(clip.sets) != tc.sets {
+				t.Fatalf("got %d sets, want %d", len(clip.sets), tc.sets)
+			}
+			if tc.sets == 1 && clip.sets[0] != "c:"+text {
+				t.Errorf("copied text has %d bytes, want %d", len(clip.sets[0])-2, len(text))
+			}
+			if line := screen.GetDisplay()[0]; line != "after" {
+				t.Errorf("line = %q, want %q", line, "after")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package gopyte_test
+
+import (
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// linksOnFirstLine feeds input into a fresh screen and returns the link of each cell
+// on the first line
+func linksOnFirstLine(input string) []string {
+	screen := gopyte.NewWideCharScreen(20, 4, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed(input)
+
+	attrs := screen.GetAttributes()
+	links := make([]string, len(attrs[0]))
+	for x, a := range attrs[0] {
+		links[x] = a.Link
+	}
+	return links
+}
+
+func TestOSC8Hyperlink(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[int]string // column -> expected link, others must be empty
+	}{
+		{
+			"BEL terminated",
+			"a\x1b]8;;https://example.com\x07ex\x1b]8;;\x07b",
+			map[int]string{1: "https://example.com", 2: "https://example.com"},
+		},
+		{
+			"ST terminated with params",
+			"\x1b]8;id=42;http://host/\x1b\\go\x1b]8;;\x1b\\!",
+			map[int]string{0: "http://host/", 1: "http://host/"},
+		},
+		{
+			"Survives SGR reset",
+			"\x1b]8;;http://x/\x07\x1b[1ma\x1b[0mb\x1b]8;;\x07c",
+			map[int]string{0: "http://x/", 1: "http://x/"},
+		},
+		{
+			"URI containing semicolon",
+			"\x1b]8;;http://x/?a=1;b=2\x07z\x1b]8;;\x07",
+			map[int]string{0: "http://x/?a=1;b=2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			links := linksOnFirstLine(tc.input)
+			for x, got := range links {
+				if want := tc.want[x]; got != want {
+					t.Errorf("column %d link = %q, want %q", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOSC8NotDisplayed(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b]8;;https://example.com\x07link\x1b]8;;\x07")
+
+	if display := screen.GetDisplay(); display[0] != "link" {
+		t.Errorf("line 0 = %q, want %q", display[0], "link")
+	}
+}
@@ -0,0 +1,161 @@
+package gopyte_test
+
+import (
+	"image/color"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// newColorScreen returns a screen whose replies are collected
+func newColorScreen() (*gopyte.NativeScreen, *gopyte.Stream, *[]string) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+	var replies []string
+	screen.SetResponseHandler(func(data string) { replies = append(replies, data) })
+	return screen, stream, &replies
+}
+
+func TestParseColorSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want color.RGBA
+		ok   bool
+	}{
+		{"rgb:ff/80/00", color.RGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"rgb:ffff/8080/0000", color.RGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"rgb:f/8/0", color.RGBA{0xff, 0x88, 0x00, 0xff}, true},
+		{"#ff8000", color.RGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"#f80", color.RGBA{0xf0, 0x80, 0x00, 0xff}, true},
+		{"#ffff80800000", color.RGBA{0xff, 0x80, 0x00, 0xff}, true},
+		{"rgb:ff/80", color.RGBA{}, false},
+		{"#ff80", color.RGBA{}, false},
+		{"red", color.RGBA{}, false},
+	}
+
+	for _, tc := range tests {
+		got, ok := gopyte.ParseColorSpec(tc.spec)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("ParseColorSpec(%q) = %v, %v; want %v, %v", tc.spec, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestOSCColorSet(t *testing.T) {
+	screen, stream, _ := newColorScreen()
+
+	stream.Feed("\x1b]4;1;rgb:11/22/33;200;#445566\x07")
+	stream.Feed("\x1b]10;#aabbcc;#010203\x1b\\")
+	stream.Feed("\x1b]12;rgb:ff/00/00\x07")
+
+	overrides, _ := screen.ColorOverrides()
+	want := map[int]color.RGBA{
+		1:                      {0x11, 0x22, 0x33, 0xff},
+		200:                    {0x44, 0x55, 0x66, 0xff},
+		gopyte.ColorForeground: {0xaa, 0xbb, 0xcc, 0xff},
+		gopyte.ColorBackground: {0x01, 0x02, 0x03, 0xff},
+		gopyte.ColorCursor:     {0xff, 0x00, 0x00, 0xff},
+	}
+	if len(overrides) != len(want) {
+		t.Fatalf("overrides = %v, want %v", overrides, want)
+	}
+	for slot, c := range want {
+		if overrides[slot] != c {
+			t.Errorf("slot %d = %v, want %v", slot, overrides[slot], c)
+		}
+	}
+	if display := screen.GetDisplay(); display[0] != "" {
+		t.Errorf("OSC leaked onto screen: %q", display[0])
+	}
+}
+
+func TestOSCColorQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Background BEL", "\x1b]11;?\x07", "\x1b]11;rgb:0000/0000/0000\x07"},
+		{"Background ST", "\x1b]11;?\x1b\\", "\x1b]11;rgb:0000/0000/0000\x1b\\"},
+		{"Foreground", "\x1b]10;?\x07", "\x1b]10;rgb:ffff/ffff/ffff\x07"},
+		{"Palette", "\x1b]4;1;?\x07", "\x1b]4;1;rgb:cdcd/0000/0000\x07"},
+		{"Cube", "\x1b]4;196;?\x07", "\x1b]4;196;rgb:ffff/0000/0000\x07"},
+		{"After set", "\x1b]11;#102030\x07\x1b]11;?\x07", "\x1b]11;rgb:1010/2020/3030\x07"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, stream, replies := newColorScreen()
+			stream.Feed(tc.input)
+			if len(*replies) != 1 || (*replies)[0] != tc.want {
+				t.Errorf("replies = %q, want [%q]", *replies, tc.want)
+			}
+		})
+	}
+}
+
+func TestOSCColorQueryUsesProvider(t *testing.T) {
+	screen, stream, replies := newColorScreen()
+	screen.SetColorProvider(func(slot int) (color.RGBA, bool) {
+		if slot == gopyte.ColorBackground {
+			return color.RGBA{0xfd, 0xf6, 0xe3, 0xff}, true
+		}
+		return color.RGBA{}, false
+	})
+
+	stream.Feed("\x1b]11;?\x07\x1b]4;2;?\x07")
+
+	want := []string{"\x1b]11;rgb:fdfd/f6f6/e3e3\x07", "\x1b]4;2;rgb:0000/cdcd/0000\x07"}
+	if len(*replies) != len(want) || (*replies)[0] != want[0] || (*replies)[1] != want[1] {
+		t.Errorf("replies = %q, want %q", *replies, want)
+	}
+}
+
+func TestOSCColorReset(t *testing.T) {
+	screen, stream, _ := newColorScreen()
+	stream.Feed("\x1b]4;1;#111111;2;#222222;3;#333333\x07\x1b]11;#444444\x07")
+
+	stream.Feed("\x1b]104;2\x07")
+	if overrides, _ := screen.ColorOverrides(); len(overrides) != 3 {
+		t.Errorf("after OSC 104;2 overrides = %v, want 3", overrides)
+	}
+
+	stream.Feed("\x1b]104\x07")
+	overrides, _ := screen.ColorOverrides()
+	if len(overrides) != 1 || overrides[gopyte.ColorBackground] != (color.RGBA{0x44, 0x44, 0x44, 0xff}) {
+		t.Errorf("after OSC 104 overrides = %v, want only the background", overrides)
+	}
+
+	stream.Feed("\x1b]111\x07")
+	if overrides, _ := screen.ColorOverrides(); len(overrides) != 0 {
+		t.Errorf("after OSC 111 overrides = %v, want none", overrides)
+	}
+}
+
+func TestOSCColorResetOnRIS(t *testing.T) {
+	screen, stream, _ := newColorScreen()
+	stream.Feed("\x1b]4;5;#123456\x07\x1b]10;#abcdef\x07")
+	_, before := screen.ColorOverrides()
+
+	stream.Feed("\x1bc")
+
+	overrides, after := screen.ColorOverrides()
+	if len(overrides) != 0 {
+		t.Errorf("overrides after RIS = %v, want none", overrides)
+	}
+	if after == before {
+		t.Error("RIS should change the color version")
+	}
+}
+
+func TestOSCColorMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b]12;?\x07\x1b]112\x07")
+
+	want := []string{"QueryColor[258 \x07]", "ResetColor[258]"}
+	if len(screen.Calls) != len(want) || screen.Calls[0] != want[0] || screen.Calls[1] != want[1] {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
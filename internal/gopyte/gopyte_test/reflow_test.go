@@ -0,0 +1,221 @@
+package gopyte_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// contentLines returns History and screen lines with trailing spaces and
+// trailing empty lines removed
+func contentLines(screen *gopyte.WideCharScreen) []string {
+	lines := screen.GetLines(0, screen.GetHistorySize()+len(screen.GetBuffer()))
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func TestReflowOnResize(t *testing.T) {
+	tests := []struct {
+		name       string
+		cols, rows int
+		input      string
+		newCols    int
+		want       []string
+		wantCursor [2]int // x, y after the resize
+	}{
+		{
+			name: "Widen joins wrapped line",
+			cols: 10, rows: 5,
+			input:      "0123456789ABCDE\r\n$ ",
+			newCols:    20,
+			want:       []string{"0123456789ABCDE", "$"},
+			wantCursor: [2]int{2, 1},
+		},
+		{
+			name: "Narrow wraps without losing text",
+			cols: 20, rows: 5,
+			input:      "hello wide world\r\n$ ",
+			newCols:    6,
+			want:       []string{"hello", "wide w", "orld", "$"},
+			wantCursor: [2]int{2, 3},
+		},
+		{
+			name: "Hard line breaks are kept",
+			cols: 5, rows: 5,
+			input:      "abcde\r\nfg",
+			newCols:    10,
+			want:       []string{"abcde", "fg"},
+			wantCursor: [2]int{2, 1},
+		},
+		{
+			name: "Cursor stays on its character",
+			cols: 10, rows: 5,
+			input:      "$ abcdefghijkl\x1b[3D",
+			newCols:    4,
+			want:       []string{"$ ab", "cdef", "ghij", "kl"},
+			wantCursor: [2]int{3, 2},
+		},
+		{
+			name: "Wide character moves to the next row",
+			cols: 6, rows: 5,
+			input:      "ab界界\r\n",
+			newCols:    5,
+			want:       []string{"ab界", "界"},
+			wantCursor: [2]int{0, 2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewWideCharScreen(tc.cols, tc.rows, 100)
+			stream := gopyte.NewStream(screen, false)
+			stream.Feed(tc.input)
+
+			screen.Resize(tc.newCols, tc.rows)
+
+			got := contentLines(screen)
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("lines = %q, want %q", got, tc.want)
+			}
+			if x, y := screen.GetCursor(); x != tc.wantCursor[0] || y != tc.wantCursor[1] {
+				t.Errorf("cursor = (%d,%d), want (%d,%d)", x, y, tc.wantCursor[0], tc.wantCursor[1])
+			}
+		})
+	}
+}
+
+func TestReflowScrollback(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(10, 3, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	// Three 15 character lines on a 10x3 screen push four rows into History
+	stream.Feed("aaaaaaaaaaAAAAA\r\nbbbbbbbbbbBBBBB\r\ncccccccccc")
+	if screen.GetHistorySize() == 0 {
+		t.Fatalf("expected lines in History before resizing")
+	}
+
+	screen.Resize(20, 3)
+	want := []string{"aaaaaaaaaaAAAAA", "bbbbbbbbbbBBBBB", "cccccccccc"}
+	if got := contentLines(screen); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("after widening lines = %q, want %q", got, want)
+	}
+	if x, y := screen.GetCursor(); x != 10 || y != 2 {
+		t.Errorf("cursor = (%d,%d), want (10,2)", x, y)
+	}
+
+	// Back to the original width restores the original rows
+	screen.Resize(10, 3)
+	want = []string{"aaaaaaaaaa", "AAAAA", "bbbbbbbbbb", "BBBBB", "cccccccccc"}
+	if got := contentLines(screen); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("after narrowing lines = %q, want %q", got, want)
+	}
+
+	// Writing continues on the cursor's line
+	stream.Feed("!")
+	lines := contentLines(screen)
+	if last := lines[len(lines)-1]; last != "!" {
+		t.Errorf("line after the cursor's = %q, want %q", last, "!")
+	}
+}
+
+func TestReflowAlternateScreen(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(10, 4, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("0123456789ABC\r\n$ ")
+	stream.Feed("\x1b[?1049h\x1b[HALTERNATE!")
+
+	screen.Resize(5, 4)
+
+	// The alternate screen is only truncated; its program redraws it
+	if got := screen.GetLines(0, 1)[0]; got != "ALTER" {
+		t.Errorf("alternate line 0 = %q, want %q", got, "ALTER")
+	}
+	if screen.GetHistorySize() != 0 {
+		t.Errorf("alternate screen content went to History")
+	}
+
+	// The main screen was reflowed underneath
+	stream.Feed("\x1b[?1049l")
+	want := []string{"01234", "56789", "ABC", "$"}
+	if got := contentLines(screen); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("main screen lines = %q, want %q", got, want)
+	}
+	if x, y := screen.GetCursor(); x != 2 || y != 3 {
+		t.Errorf("cursor = (%d,%d), want (2,3)", x, y)
+	}
+}
+
+func TestReflowKeepsPromptMarks(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(10, 6, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	runCommand(stream, "echo", []string{"0123456789ABCDE"}, 0)
+	stream.Feed(promptStart + "$ ")
+
+	screen.Resize(20, 6)
+
+	marks := screen.GetCommandMarks()
+	if len(marks) != 2 {
+		t.Fatalf("got %d marks, want 2: %+v", len(marks), marks)
+	}
+	want := gopyte.CommandMark{PromptLine: 0, OutputLine: 1, EndLine: 2, ExitCode: 0, HasExitCode: true}
+	if marks[0] != want {
+		t.Errorf("mark 0 = %+v, want %+v", marks[0], want)
+	}
+	if marks[1].PromptLine != 2 {
+		t.Errorf("second prompt on line %d, want 2", marks[1].PromptLine)
+	}
+}
+
+// longScrollback returns a 10 column screen with n 15 character lines, each
+// wrapped over two rows
+func longScrollback(n int) *gopyte.WideCharScreen {
+	screen := gopyte.NewWideCharScreen(10, 5, 2*n)
+	stream := gopyte.NewStream(screen, false)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%05d-----abcd\r\n", i)
+	}
+	stream.Feed(b.String())
+	return screen
+}
+
+func TestReflowLongScrollback(t *testing.T) {
+	screen := longScrollback(50000)
+	screen.Resize(20, 5)
+
+	lines := contentLines(screen)
+	// The newest lines are rewrapped at the new width
+	if got, want := lines[len(lines)-1], "49999-----abcd"; got != want {
+		t.Errorf("last line = %q, want %q", got, want)
+	}
+	// The oldest keep their old rows, and nothing is lost or reordered
+	if got := strings.Join(lines[:2], "|"); got != "00000-----|abcd" {
+		t.Errorf("first rows = %q, want %q", got, "00000-----|abcd")
+	}
+	joined := strings.Join(lines, "")
+	last := -1
+	for i := 0; i < 50000; i += 997 {
+		at := strings.Index(joined, fmt.Sprintf("%05d-----abcd", i))
+		if at <= last {
+			t.Fatalf("line %d missing or out of order", i)
+		}
+		last = at
+	}
+}
+
+func BenchmarkReflowLongScrollback(b *testing.B) {
+	screen := longScrollback(50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		screen.Resize(20+i%2, 5)
+	}
+}
@@ -0,0 +1,197 @@
+package gopyte_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// textLine makes a History line of cols cells holding text
+func textLine(text string, cols int) gopyte.HistoryLine {
+	line := gopyte.HistoryLine{
+		Chars:      make([]rune, cols),
+		Attrs:      make([]gopyte.Attributes, cols),
+		CellWidths: make([]int, cols),
+	}
+	runes := []rune(text)
+	for x := 0; x < cols; x++ {
+		line.Chars[x] = ' '
+		if x < len(runes) {
+			line.Chars[x] = runes[x]
+		}
+		line.Attrs[x] = gopyte.DefaultAttributes()
+		line.CellWidths[x] = 1
+	}
+	return line
+}
+
+func lineText(line gopyte.HistoryLine) string {
+	return strings.TrimRight(string(line.Chars), " ")
+}
+
+func TestScrollbackRoundTrip(t *testing.T) {
+	sb := gopyte.NewScrollback(10)
+
+	red := gopyte.Attributes{Fg: "red", Bg: "default", Bold: true}
+	link := gopyte.Attributes{Fg: "blue", Bg: "default", Link: "https://example.com"}
+	line := textLine("ab界", 8)
+	line.Attrs[0], line.Attrs[1] = red, red
+	line.Attrs[2], line.Attrs[3] = link, link
+	line.Chars[3] = 0
+	line.CellWidths[2], line.CellWidths[3] = 2, 0
+	line.Attrs[7] = gopyte.Attributes{Fg: "default", Bg: "green"} // Coloured trailing blank
+	line.Wrapped = true
+
+	sb.Push(line)
+	got := sb.Line(0)
+
+	if len(got.Chars) != 8 || len(got.Attrs) != 8 || len(got.CellWidths) != 8 {
+		t.Fatalf("line has %d/%d/%d cells, want 8", len(got.Chars), len(got.Attrs), len(got.CellWidths))
+	}
+	for x := 0; x < 8; x++ {
+		if got.Chars[x] != line.Chars[x] || got.Attrs[x] != line.Attrs[x] || got.CellWidths[x] != line.CellWidths[x] {
+			t.Errorf("cell %d = %q %+v %d, want %q %+v %d", x,
+				got.Chars[x], got.Attrs[x], got.CellWidths[x],
+				line.Chars[x], line.Attrs[x], line.CellWidths[x])
+		}
+	}
+	if !got.Wrapped {
+		t.Errorf("Wrapped flag lost")
+	}
+}
+
+func TestScrollbackLimits(t *testing.T) {
+	sb := gopyte.NewScrollback(5)
+	for i := 0; i < 12; i++ {
+		sb.Push(textLine(fmt.Sprintf("line %d", i), 10))
+	}
+	if sb.Len() != 5 {
+		t.Fatalf("Len = %d, want 5", sb.Len())
+	}
+	if got := lineText(sb.Line(0)); got != "line 7" {
+		t.Errorf("oldest line = %q, want %q", got, "line 7")
+	}
+
+	sb.SetMaxLines(3)
+	if got := lineText(sb.Line(0)); sb.Len() != 3 || got != "line 9" {
+		t.Errorf("after shrinking Len = %d, oldest = %q", sb.Len(), got)
+	}
+
+	sb.SetMaxLines(100)
+	for i := 12; i < 20; i++ {
+		sb.Push(textLine(fmt.Sprintf("line %d", i), 10))
+	}
+	if got := lineText(sb.Line(sb.Len() - 1)); sb.Len() != 11 || got != "line 19" {
+		t.Errorf("after growing Len = %d, newest = %q", sb.Len(), got)
+	}
+}
+
+func TestScrollbackSpill(t *testing.T) {
+	dir := t.TempDir()
+	total := gopyte.ScrollbackMemoryLines * 3
+	sb := gopyte.NewScrollback(total - 1000)
+	if err := sb.EnableSpill(dir); err != nil {
+		t.Fatalf("EnableSpill: %v", err)
+	}
+
+	for i := 0; i < total; i++ {
+		sb.Push(textLine(fmt.Sprintf("line %d", i), 20))
+	}
+	if sb.Len() != total-1000 {
+		t.Fatalf("Len = %d, want %d", sb.Len(), total-1000)
+	}
+	for _, i := range []int{0, 1, 5000, 15000, sb.Len() - 1} {
+		want := fmt.Sprintf("line %d", i+1000)
+		if got := lineText(sb.Line(i)); got != want {
+			t.Errorf("line %d = %q, want %q", i, got, want)
+		}
+	}
+
+	// Turning spilling off brings every line back into memory
+	sb.DisableSpill()
+	if got := lineText(sb.Line(0)); sb.Len() != total-1000 || got != "line 1000" {
+		t.Errorf("after DisableSpill Len = %d, oldest = %q", sb.Len(), got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spill file left behind: %v", entries)
+	}
+
+	// Close removes the spill file
+	if err := sb.EnableSpill(dir); err != nil {
+		t.Fatalf("EnableSpill: %v", err)
+	}
+	sb.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spill file left after Close: %v", entries)
+	}
+}
+
+func TestSetMaxHistoryLines(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 3, 100)
+	stream := gopyte.NewStream(screen, false)
+	for i := 0; i < 20; i++ {
+		stream.Feed(fmt.Sprintf("line %d\r\n", i))
+	}
+	if screen.GetHistorySize() != 18 {
+		t.Fatalf("History size = %d, want 18", screen.GetHistorySize())
+	}
+
+	screen.SetMaxHistoryLines(5)
+	if screen.GetHistorySize() != 5 {
+		t.Errorf("History size = %d, want 5", screen.GetHistorySize())
+	}
+	if got := strings.TrimRight(screen.GetLines(0, 1)[0], " "); got != "line 13" {
+		t.Errorf("oldest History line = %q, want %q", got, "line 13")
+	}
+}
+
+func TestScrollbackPaletteCompaction(t *testing.T) {
+	// colorLine makes a line whose first cell has a color unique to i
+	colorLine := func(i int) gopyte.HistoryLine {
+		line := textLine(fmt.Sprintf("line %d", i), 20)
+		line.Attrs[0].Fg = fmt.Sprintf("#%06x", i)
+		return line
+	}
+
+	tests := []struct {
+		name     string
+		maxLines int
+		spill    bool
+	}{
+		{"Memory", 100, false},
+		{"Spill", gopyte.ScrollbackMemoryLines + 3000, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sb := gopyte.NewScrollback(tc.maxLines)
+			if tc.spill {
+				if err := sb.EnableSpill(t.TempDir()); err != nil {
+					t.Fatalf("EnableSpill: %v", err)
+				}
+				defer sb.Close()
+			}
+
+			total := max(tc.maxLines*5, 5000)
+			for i := 0; i < total; i++ {
+				sb.Push(colorLine(i))
+			}
+
+			// Each line holds two attributes; the palette may double before
+			// it is compacted
+			if got, limit := sb.PaletteLen(), max(4*(tc.maxLines+1), 2048); got > limit {
+				t.Errorf("PaletteLen = %d, want at most %d", got, limit)
+			}
+			for _, i := range []int{0, 1, tc.maxLines / 2, tc.maxLines - 1} {
+				want := colorLine(total - tc.maxLines + i)
+				got := sb.Line(i)
+				if lineText(got) != lineText(want) || got.Attrs[0] != want.Attrs[0] || got.Attrs[1] != want.Attrs[1] {
+					t.Errorf("line %d = %q %+v, want %q %+v", i, lineText(got), got.Attrs[0], lineText(want), want.Attrs[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package gopyte_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+const (
+	promptStart  = "\x1b]133;A\x07"
+	commandStart = "\x1b]133;B\x07"
+	outputStart  = "\x1b]133;C\x07"
+)
+
+func commandEnd(code int) string {
+	return fmt.Sprintf("\x1b]133;D;%d\x07", code)
+}
+
+// runCommand feeds one prompt/command/output cycle the way bash or zsh
+// shell integration emits it
+func runCommand(stream *gopyte.Stream, command string, output []string, code int) {
+	stream.Feed(promptStart + "$ " + commandStart + command + "\r\n" + outputStart)
+	for _, line := range output {
+		stream.Feed(line + "\r\n")
+	}
+	stream.Feed(commandEnd(code))
+}
+
+func TestPromptMarks(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(40, 10, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	runCommand(stream, "ls", []string{"a.txt", "b.txt"}, 0)
+	runCommand(stream, "false", nil, 1)
+	stream.Feed(promptStart + "$ " + commandStart)
+
+	marks := screen.GetCommandMarks()
+	want := []gopyte.CommandMark{
+		{PromptLine: 0, OutputLine: 1, EndLine: 3, ExitCode: 0, HasExitCode: true},
+		{PromptLine: 3, OutputLine: 4, EndLine: 4, ExitCode: 1, HasExitCode: true},
+		{PromptLine: 4, OutputLine: -1, EndLine: -1},
+	}
+	if len(marks) != len(want) {
+		t.Fatalf("got %d marks, want %d: %+v", len(marks), len(want), marks)
+	}
+	for i := range want {
+		if marks[i] != want[i] {
+			t.Errorf("mark %d = %+v, want %+v", i, marks[i], want[i])
+		}
+	}
+
+	output := screen.GetLines(marks[0].OutputLine, marks[0].EndLine)
+	if got := strings.TrimRight(output[0], " ") + "," + strings.TrimRight(output[1], " "); got != "a.txt,b.txt" {
+		t.Errorf("output of first command = %q", got)
+	}
+	if marks[2].Finished() {
+		t.Errorf("running command reported as finished")
+	}
+}
+
+func TestPromptMarksFollowHistory(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(40, 5, 8)
+	stream := gopyte.NewStream(screen, false)
+
+	runCommand(stream, "seq 3", []string{"1", "2", "3"}, 0)
+	runCommand(stream, "seq 4", []string{"1", "2", "3", "4"}, 0)
+	stream.Feed(promptStart + "$ ")
+
+	// 10 lines were written on a 5 line screen, so the first prompt has
+	// scrolled into History
+	marks := screen.GetCommandMarks()
+	if len(marks) != 3 {
+		t.Fatalf("got %d marks, want 3: %+v", len(marks), marks)
+	}
+	lines := screen.GetLines(marks[1].PromptLine, marks[1].PromptLine+1)
+	if got := strings.TrimRight(lines[0], " "); got != "$ seq 4" {
+		t.Errorf("second prompt line = %q, want %q", got, "$ seq 4")
+	}
+
+	// Push the first command out of the 8 line History
+	for i := 0; i < 6; i++ {
+		stream.Feed("\r\n")
+	}
+	marks = screen.GetCommandMarks()
+	if len(marks) != 2 {
+		t.Fatalf("after trimming got %d marks, want 2: %+v", len(marks), marks)
+	}
+	lines = screen.GetLines(marks[0].PromptLine, marks[0].PromptLine+1)
+	if got := strings.TrimRight(lines[0], " "); got != "$ seq 4" {
+		t.Errorf("oldest prompt line = %q, want %q", got, "$ seq 4")
+	}
+}
+
+func TestPromptMarksClearedAndIgnored(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(40, 5, 100)
+	stream := gopyte.NewStream(screen, false)
+
+	runCommand(stream, "ls", []string{"x"}, 0)
+	stream.Feed("\x1b[2J")
+	if marks := screen.GetCommandMarks(); len(marks) != 0 {
+		t.Errorf("marks survived clear: %+v", marks)
+	}
+
+	// A redrawn prompt replaces the previous mark
+	stream.Feed("\x1b[H" + promptStart + "$ \r" + promptStart + "$ ")
+	if marks := screen.GetCommandMarks(); len(marks) != 1 {
+		t.Errorf("redrawn prompt left %d marks, want 1", len(marks))
+	}
+
+	// Marks from full screen programs are not recorded
+	stream.Feed("\x1b[?1049h" + promptStart + "\x1b[?1049l")
+	if marks := screen.GetCommandMarks(); len(marks) != 1 {
+		t.Errorf("alternate screen mark recorded, got %d marks", len(marks))
+	}
+}
@@ -0,0 +1,143 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// firstCellAttrs feeds input and a character into a fresh screen and returns
+// the attributes the character was drawn with. Colors never set, which are
+// empty, are returned as "default".
+func firstCellAttrs(input string) gopyte.Attributes {
+	screen := gopyte.NewWideCharScreen(20, 4, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed(input + "x")
+
+	a := screen.GetAttributes()[0][0]
+	if a.Fg == "" {
+		a.Fg = "default"
+	}
+	if a.Bg == "" {
+		a.Bg = "default"
+	}
+	return a
+}
+
+func TestSGRAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  func(a *gopyte.Attributes) // Changes from the default attributes
+	}{
+		{"Faint", "\x1b[2m", func(a *gopyte.Attributes) { a.Faint = true }},
+		{"Bold and faint off", "\x1b[1;2;22m", func(a *gopyte.Attributes) {}},
+		{"Conceal", "\x1b[8m", func(a *gopyte.Attributes) { a.Conceal = true }},
+		{"Reveal", "\x1b[8;28m", func(a *gopyte.Attributes) {}},
+		{"Overline", "\x1b[53m", func(a *gopyte.Attributes) { a.Overline = true }},
+		{"Overline off", "\x1b[53;55m", func(a *gopyte.Attributes) {}},
+		{"Underline", "\x1b[4m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineSingle
+		}},
+		{"Double underline", "\x1b[21m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineDouble
+		}},
+		{"Curly underline", "\x1b[4:3m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineCurly
+		}},
+		{"Dotted underline", "\x1b[4:4m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineDotted
+		}},
+		{"Dashed underline", "\x1b[4:5m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineDashed
+		}},
+		{"Underline 4:0", "\x1b[4:3m\x1b[4:0m", func(a *gopyte.Attributes) {}},
+		{"Underline off", "\x1b[21;24m", func(a *gopyte.Attributes) {}},
+		{"Curly among others", "\x1b[1;4:3;31m", func(a *gopyte.Attributes) {
+			a.Bold, a.Fg = true, "red"
+			a.Underscore, a.UnderlineStyle = true, gopyte.UnderlineCurly
+		}},
+		{"Underline color 256", "\x1b[4;58;5;196m", func(a *gopyte.Attributes) {
+			a.Underscore, a.UnderlineStyle, a.UnderlineColor = true, gopyte.UnderlineSingle, "color196"
+		}},
+		{"Underline color direct", "\x1b[58;2;255;128;0m", func(a *gopyte.Attributes) {
+			a.UnderlineColor = "#ff8000"
+		}},
+		{"Underline color colon", "\x1b[58:2::255:128:0m", func(a *gopyte.Attributes) {
+			a.UnderlineColor = "#ff8000"
+		}},
+		{"Underline color colon no space", "\x1b[58:2:1:2:3m", func(a *gopyte.Attributes) {
+			a.UnderlineColor = "#010203"
+		}},
+		{"Underline color colon 256", "\x1b[58:5:33m", func(a *gopyte.Attributes) {
+			a.UnderlineColor = "color33"
+		}},
+		{"Underline color reset", "\x1b[58;5;1m\x1b[59m", func(a *gopyte.Attributes) {}},
+		{"Direct foreground", "\x1b[38;2;1;2;3m", func(a *gopyte.Attributes) { a.Fg = "#010203" }},
+		{"Direct background colon", "\x1b[48:2::10:20:30m", func(a *gopyte.Attributes) { a.Bg = "#0a141e" }},
+		{"Reset", "\x1b[2;8;53;21;58;5;1m\x1b[0m", func(a *gopyte.Attributes) {}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want := gopyte.DefaultAttributes()
+			tc.want(&want)
+			if got := firstCellAttrs(tc.input); got != want {
+				t.Errorf("attributes = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSGRBrightColors(t *testing.T) {
+	names := []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+	for i, name := range names {
+		want := "bright_" + name
+		if got := firstCellAttrs("\x1b[9" + string(rune('0'+i)) + "m").Fg; got != want {
+			t.Errorf("SGR 9%d foreground = %q, want %q", i, got, want)
+		}
+		if got := firstCellAttrs("\x1b[10" + string(rune('0'+i)) + "m").Bg; got != want {
+			t.Errorf("SGR 10%d background = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSGRColonSequenceConsumed(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 4, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed("a\x1b[4:3mb\x1b[58:2::1:2:3mc\x1b[4:0md")
+
+	if line := strings.TrimRight(screen.GetDisplay()[0], " "); line != "abcd" {
+		t.Errorf("line = %q, want %q", line, "abcd")
+	}
+}
+
+func TestSGRUnderlineStyleMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b[1;4:3;38:2::1:2:3;0m")
+
+	want := []string{
+		"SelectGraphicRendition[[1]]",
+		"SetUnderlineStyle[3]",
+		"SelectGraphicRendition[[38 2 1 2 3]]",
+		"SelectGraphicRendition[[0]]",
+	}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
+
+func TestSGRExportNewAttributes(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 4, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed("\x1b[2;53;4:3;58;2;255;0;0mx")
+
+	got := gopyte.FormatANSI(screen.GetLineCells(0, 1))
+	want := "\x1b[0;2;4:3;53;58;2;255;0;0mx\x1b[0m\n"
+	if got != want {
+		t.Errorf("FormatANSI = %q, want %q", got, want)
+	}
+}
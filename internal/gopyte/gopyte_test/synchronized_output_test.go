@@ -0,0 +1,130 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tetherssh/internal/gopyte"
+)
+
+func TestSynchronizedOutputNoIntermediateFrames(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 3, 10)
+	stream := gopyte.NewStream(screen, false)
+	stream.Feed("old 1\r\nold 2\r\nold 3")
+
+	oldFrame := strings.Join(screenLines(screen, 3), "\n")
+	newFrame := "new 1\nnew 2\nnew 3"
+
+	// A frame redrawn line by line, arriving in several reads the way a
+	// TUI's output does. The widget renders after each read unless the
+	// screen says to wait.
+	chunks := []string{
+		"\x1b[?2026h\x1b[H\x1b[2J",
+		"new 1\r\n",
+		"new 2\r\n",
+		"new 3",
+		"\x1b[?2026l",
+	}
+	var rendered []string
+	for _, chunk := range chunks {
+		stream.Feed(chunk)
+		if _, deferred := screen.RenderDeferred(time.Now()); !deferred {
+			rendered = append(rendered, strings.Join(screenLines(screen, 3), "\n"))
+		}
+	}
+
+	if len(rendered) != 1 || rendered[0] != newFrame {
+		t.Errorf("rendered frames = %q, want only %q", rendered, newFrame)
+	}
+	for _, frame := range rendered {
+		if frame != oldFrame && frame != newFrame {
+			t.Errorf("intermediate frame rendered: %q", frame)
+		}
+	}
+}
+
+func TestSynchronizedOutputTimeout(t *testing.T) {
+	screen := gopyte.NewNativeScreen(20, 4)
+	stream := gopyte.NewStream(screen, false)
+
+	start := time.Now()
+	stream.Feed("\x1b[?2026h")
+	until, deferred := screen.RenderDeferred(start)
+	if !deferred {
+		t.Fatal("render not deferred during a synchronized update")
+	}
+	if limit := time.Now().Add(gopyte.SyncUpdateTimeout); until.After(limit) {
+		t.Errorf("deferred until %v, more than the timeout away", until)
+	}
+
+	// Setting the mode again does not push the deadline back
+	stream.Feed("\x1b[?2026h")
+	if again, _ := screen.RenderDeferred(start); !again.Equal(until) {
+		t.Errorf("deadline moved from %v to %v", until, again)
+	}
+
+	if _, deferred := screen.RenderDeferred(until); deferred {
+		t.Error("render still deferred at the timeout")
+	}
+
+	stream.Feed("\x1b[?2026l")
+	if _, deferred := screen.RenderDeferred(start); deferred {
+		t.Error("render deferred after the update ended")
+	}
+
+	// RIS ends an update too
+	stream.Feed("\x1b[?2026h\x1bc")
+	if _, deferred := screen.RenderDeferred(time.Now()); deferred {
+		t.Error("render deferred after RIS")
+	}
+}
+
+func TestDECRQM(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Synchronized update reset", "\x1b[?2026$p", "\x1b[?2026;2$y"},
+		{"Synchronized update set", "\x1b[?2026h\x1b[?2026$p", "\x1b[?2026;1$y"},
+		{"Synchronized update ended", "\x1b[?2026h\x1b[?2026l\x1b[?2026$p", "\x1b[?2026;2$y"},
+		{"Autowrap", "\x1b[?7$p", "\x1b[?7;1$y"},
+		{"Autowrap reset", "\x1b[?7l\x1b[?7$p", "\x1b[?7;2$y"},
+		{"Cursor hidden", "\x1b[?25l\x1b[?25$p", "\x1b[?25;2$y"},
+		{"Origin mode", "\x1b[?6h\x1b[?6$p", "\x1b[?6;1$y"},
+		{"Left/right margin mode", "\x1b[?69$p", "\x1b[?69;2$y"},
+		{"Alternate screen", "\x1b[?1049$p", "\x1b[?1049;2$y"},
+		{"Alternate screen set", "\x1b[?1049h\x1b[?1049$p", "\x1b[?1049;1$y"},
+		{"Insert mode", "\x1b[4h\x1b[4$p", "\x1b[4;1$y"},
+		{"Newline mode", "\x1b[20$p", "\x1b[20;1$y"},
+		{"Unknown private mode", "\x1b[?9999$p", "\x1b[?9999;0$y"},
+		{"Unknown ANSI mode", "\x1b[2026$p", "\x1b[2026;0$y"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewWideCharScreen(20, 4, 10)
+			stream := gopyte.NewStream(screen, false)
+			var replies []string
+			screen.SetResponseHandler(func(data string) { replies = append(replies, data) })
+
+			stream.Feed(tc.input)
+			if len(replies) != 1 || replies[0] != tc.want {
+				t.Errorf("replies = %q, want %q", replies, tc.want)
+			}
+		})
+	}
+}
+
+func TestDECRQMMockScreen(t *testing.T) {
+	screen := gopyte.NewMockScreen()
+	stream := gopyte.NewStream(screen, false)
+
+	stream.Feed("\x1b[?2026$p\x1b[4$p")
+
+	want := []string{"ReportMode[2026 true]", "ReportMode[4 false]"}
+	if strings.Join(screen.Calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", screen.Calls, want)
+	}
+}
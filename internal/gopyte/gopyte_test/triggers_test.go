@@ -0,0 +1,76 @@
+package gopyte_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// triggerLog records what the screen passes to the handler
+type triggerLog struct {
+	fired     []string
+	completed []string
+}
+
+func (l *triggerLog) TriggerFired(match gopyte.TriggerMatch) {
+	l.fired = append(l.fired, string(match.Trigger.Action)+":"+match.Text)
+}
+
+func (l *triggerLog) LineCompleted(line string) {
+	l.completed = append(l.completed, line)
+}
+
+func TestTriggers(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 5, 100)
+	stream := gopyte.NewStream(screen, false)
+	handler := &triggerLog{}
+
+	red := gopyte.Attributes{Fg: "red", Bold: true}
+	screen.SetTriggers([]*gopyte.Trigger{
+		{Pattern: regexp.MustCompile(`down`), Action: gopyte.TriggerHighlight, Style: red},
+		{Pattern: regexp.MustCompile(`(\d+) bytes copied`), Action: gopyte.TriggerNotify, Text: "Copied $1 bytes"},
+		{Pattern: regexp.MustCompile(`\[confirm\]$`), Action: gopyte.TriggerSend, Text: "\r", Instant: true},
+	}, handler)
+
+	stream.Feed("Gi0/1 changed state to down\r\n") // Wraps, matched as a whole
+	stream.Feed("123 bytes copied\r\n")
+	stream.Feed("Proceed? [con")
+	stream.Feed("firm]") // Instant trigger fires once the prompt is complete
+	stream.Feed("\r\n")
+
+	if got := strings.Join(handler.completed, "|"); got != "Gi0/1 changed state to down|123 bytes copied|Proceed? [confirm]" {
+		t.Errorf("completed lines = %q", got)
+	}
+	if got := strings.Join(handler.fired, "|"); got != "notify:Copied 123 bytes|send:\r" {
+		t.Errorf("fired = %q", got)
+	}
+
+	// "down" wrapped onto the second row, columns 3-6
+	lines := screen.GetLineCells(0, 2)
+	for i, c := range lines[1] {
+		want := i >= 3 && i < 7
+		if isRed := c.Attrs.Fg == "red" && c.Attrs.Bold; isRed != want {
+			t.Errorf("row 1 cell %d %q highlighted = %v, want %v", i, c.Char, isRed, want)
+		}
+	}
+	if lines[0][0].Attrs.Fg == "red" {
+		t.Errorf("text before the match highlighted")
+	}
+}
+
+func TestTriggersSkipAlternateScreen(t *testing.T) {
+	screen := gopyte.NewWideCharScreen(20, 5, 100)
+	stream := gopyte.NewStream(screen, false)
+	handler := &triggerLog{}
+	screen.SetTriggers([]*gopyte.Trigger{
+		{Pattern: regexp.MustCompile(`error`), Action: gopyte.TriggerBell},
+	}, handler)
+
+	stream.Feed("\x1b[?1049herror in vim\r\n\x1b[?1049l")
+	stream.Feed("error\r\n")
+	if len(handler.fired) != 1 || len(handler.completed) != 1 {
+		t.Errorf("fired %q, completed %q; want one each from the main screen", handler.fired, handler.completed)
+	}
+}
@@ -0,0 +1,134 @@
+package gopyte_test
+
+import (
+	"strings"
+	"testing"
+
+	"tetherssh/internal/gopyte"
+)
+
+// feedSplit feeds data through FeedBytes in two reads split at offset
+func feedSplit(data []byte, offset int) []string {
+	screen := gopyte.NewNativeScreen(40, 6)
+	stream := gopyte.NewStream(screen, false)
+	stream.FeedBytes(data[:offset])
+	stream.FeedBytes(data[offset:])
+	return screen.GetDisplay()
+}
+
+func feedWhole(data []byte) []string {
+	screen := gopyte.NewNativeScreen(40, 6)
+	stream := gopyte.NewStream(screen, false)
+	stream.FeedBytes(data)
+	return screen.GetDisplay()
+}
+
+var utf8StreamCases = []struct {
+	name  string
+	input string
+	want  string // expected first line
+}{
+	{"Latin", "naïve café", "naïve café"},
+	{"Continuation 0x9b", "aěb", "aěb"},
+	{"CJK", "日本語", "日本語"},
+	{"Emoji", "ok 😀 ok", "ok 😀 ok"},
+	{"With SGR", "\x1b[31mрусский\x1b[0m текст", "русский текст"},
+	{"Invalid byte", "a\xffb", "a�b"},
+	{"Truncated sequence", "a\xe2\x82b", "a��b"},
+	{"Title", "\x1b]2;Überschrift\x07ü", "ü"},
+	{"Latin-1 mode", "\x1b%@\xe9t\xe9", "été"},
+	{"Back to UTF-8", "\x1b%@\xe9\x1b%G\xc3\xa9", "éé"},
+	{"Percent in text", "100%é", "100%é"},
+}
+
+func TestFeedBytesDecodesUTF8(t *testing.T) {
+	for _, tc := range utf8StreamCases {
+		t.Run(tc.name, func(t *testing.T) {
+			display := feedWhole([]byte(tc.input))
+			if got := strings.TrimRight(display[0], " "); got != tc.want {
+				t.Errorf("line 0 = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFeedBytesSplitAtEveryOffset(t *testing.T) {
+	for _, tc := range utf8StreamCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := []byte(tc.input)
+			want := feedWhole(data)
+			for offset := 0; offset <= len(data); offset++ {
+				got := feedSplit(data, offset)
+				if strings.Join(got, "\n") != strings.Join(want, "\n") {
+					t.Errorf("split at %d: got %q, want %q", offset, got[0], want[0])
+				}
+			}
+		})
+	}
+}
+
+func TestFeedBytesByteAtATime(t *testing.T) {
+	data := []byte("\x1b[1mΓειά\x1b[0m 世界 🌍\r\nline ě\x1b]2;título\x07")
+
+	screen := gopyte.NewNativeScreen(40, 6)
+	stream := gopyte.NewStream(screen, false)
+	for i := range data {
+		stream.FeedBytes(data[i : i+1])
+	}
+
+	want := feedWhole(data)
+	got := screen.GetDisplay()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+	if screen.GetTitle() != "título" {
+		t.Errorf("title = %q, want %q", screen.GetTitle(), "título")
+	}
+}
+
+func FuzzFeedBytesSplit(f *testing.F) {
+	for _, tc := range utf8StreamCases {
+		f.Add([]byte(tc.input), uint(len(tc.input)/2))
+	}
+	f.Add([]byte("\xf0\x9f\x98"), uint(2))
+	f.Add([]byte("%\xc3\xa9"), uint(2))
+
+	f.Fuzz(func(t *testing.T, data []byte, offset uint) {
+		if len(data) > 256 {
+			return
+		}
+		split := int(offset % uint(len(data)+1))
+		want := feedWhole(data)
+		got := feedSplit(data, split)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("split at %d line %d: got %q, want %q", split, i, got[i], want[i])
+			}
+		}
+	})
+}
+
+func TestCSIStateResetAfterCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Intermediate", "\x1b[1$\x18"},
+		{"Space intermediate", "\x1b[2 \x1a"},
+		{"Private marker", "\x1b[>4\x18"},
+		{"Private and parameters", "\x1b[?12;3\x18"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			screen := gopyte.NewNativeScreen(20, 6)
+			stream := gopyte.NewStream(screen, false)
+			stream.Feed(tc.input + "\x1b[3;5H")
+			if x, y := screen.GetCursor(); x != 4 || y != 2 {
+				t.Errorf("cursor = (%d, %d), want (4, 2)", x, y)
+			}
+		})
+	}
+}
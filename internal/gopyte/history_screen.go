@@ -1,7 +1,6 @@
 package gopyte
 
 import (
-	"container/list"
 	"fmt"
 	"strings"
 )
@@ -11,6 +10,7 @@ type HistoryLine struct {
 	Chars      []rune       // Character data
 	Attrs      []Attributes // Color/style attributes
 	CellWidths []int        // Width tracking (0=continuation, 1=normal, 2=wide)
+	Wrapped    bool         // Soft-wrapped: the line continues on the next line
 }
 
 // HistoryScreen extends NativeScreen with scrollback buffer support
@@ -18,9 +18,8 @@ type HistoryScreen struct {
 	NativeScreen // Embedded, not pointer
 
 	// History management
-	History    *list.List // Doubly-linked list of enhanced HistoryLine
-	maxHistory int        // Maximum lines to keep in History
-	HistoryPos int        // Current position in History (0 = bottom/current)
+	History    *Scrollback // Compact store of History lines (see scrollback.go)
+	HistoryPos int         // Current position in History (0 = bottom/current)
 
 	// Saved screen state for viewing History
 	savedBuffer     [][]rune
@@ -31,14 +30,17 @@ type HistoryScreen struct {
 
 	// Cell width tracking (linked from WideCharScreen)
 	cellWidths [][]int
+
+	// OSC 133 command marks, in absolute lines (see semantic_prompt.go)
+	linesPushed  int // Total lines ever added to History
+	commandMarks []CommandMark
 }
 
 // NewHistoryScreen creates a screen with scrollback buffer
 func NewHistoryScreen(columns, lines, maxHistory int) *HistoryScreen {
 	h := &HistoryScreen{
 		NativeScreen:   *NewNativeScreen(columns, lines),
-		History:        list.New(),
-		maxHistory:     maxHistory,
+		History:        NewScrollback(maxHistory),
 		HistoryPos:     0,
 		ViewingHistory: false,
 	}
@@ -51,6 +53,8 @@ func NewHistoryScreen(columns, lines, maxHistory int) *HistoryScreen {
 			h.cellWidths[i][j] = 1 // Default to normal width
 		}
 	}
+	// Let the editing functions move widths along with the cells
+	h.cellWidthRows = func() [][]int { return h.cellWidths }
 
 	return h
 }
@@ -63,15 +67,14 @@ func (h *HistoryScreen) GetHistoryLines() []string {
 
 	var HistoryLines []string
 
-	// Iterate through the History list
-	for elem := h.History.Front(); elem != nil; elem = elem.Next() {
-		if histLine, ok := elem.Value.(HistoryLine); ok {
-			// Process line with wide character awareness
-			line := h.renderLineWithWidths(histLine.Chars, histLine.CellWidths)
-			// Trim trailing spaces but preserve the line (even if empty after trim)
-			line = strings.TrimRight(line, " ")
-			HistoryLines = append(HistoryLines, line)
-		}
+	// Iterate through the History lines
+	for i := 0; i < h.History.Len(); i++ {
+		histLine := h.History.Line(i)
+		// Process line with wide character awareness
+		line := h.renderLineWithWidths(histLine.Chars, histLine.CellWidths)
+		// Trim trailing spaces but preserve the line (even if empty after trim)
+		line = strings.TrimRight(line, " ")
+		HistoryLines = append(HistoryLines, line)
 	}
 
 	return HistoryLines
@@ -85,12 +88,11 @@ func (h *HistoryScreen) GetHistoryAttributes() [][]Attributes {
 
 	var HistoryAttrs [][]Attributes
 
-	for elem := h.History.Front(); elem != nil; elem = elem.Next() {
-		if histLine, ok := elem.Value.(HistoryLine); ok {
-			// Create attribute array that matches the rendered line
-			lineAttrs := h.extractAttributesWithWidths(histLine.Attrs, histLine.CellWidths)
-			HistoryAttrs = append(HistoryAttrs, lineAttrs)
-		}
+	for i := 0; i < h.History.Len(); i++ {
+		histLine := h.History.Line(i)
+		// Create attribute array that matches the rendered line
+		lineAttrs := h.extractAttributesWithWidths(histLine.Attrs, histLine.CellWidths)
+		HistoryAttrs = append(HistoryAttrs, lineAttrs)
 	}
 
 	return HistoryAttrs
@@ -154,6 +156,13 @@ func (h *HistoryScreen) Linefeed() {
 		effectiveLines = h.lines
 	}
 
+	if h.indexInMargins() {
+		if h.newlineMode {
+			h.CarriageReturn()
+		}
+		return
+	}
+
 	if h.scrollRegionSet {
 		// Within scroll region - check if we need to scroll
 		// Also bounds-check scrollBottom against buffer
@@ -213,6 +222,7 @@ func (h *HistoryScreen) scrollWithinMargins(top, bottom int) {
 	}
 
 	// Move lines up within the margin area
+	h.scrollWrapped(top, bottom)
 	for y := top; y < bottom; y++ {
 		if y+1 < bufferLen && y < bufferLen {
 			h.buffer[y] = h.buffer[y+1]
@@ -263,6 +273,10 @@ func (h *HistoryScreen) Index() {
 		effectiveLines = h.lines
 	}
 
+	if h.indexInMargins() {
+		return
+	}
+
 	// Check if at bottom BEFORE incrementing
 	if h.cursor.Y >= effectiveLines-1 {
 		// At bottom, scroll
@@ -285,6 +299,7 @@ func (h *HistoryScreen) scrollUpInternal() {
 
 	// Move all lines up by one
 	if bufferLen > 1 {
+		h.scrollWrapped(0, bufferLen-1)
 		copy(h.buffer[0:bufferLen-1], h.buffer[1:bufferLen])
 		if len(h.attrs) > 1 {
 			copy(h.attrs[0:len(h.attrs)-1], h.attrs[1:len(h.attrs)])
@@ -329,6 +344,7 @@ func (h *HistoryScreen) addToHistory(lineNum int) {
 			Chars:      make([]rune, h.columns),
 			Attrs:      make([]Attributes, h.columns),
 			CellWidths: make([]int, h.columns),
+			Wrapped:    h.isWrapped(lineNum),
 		}
 
 		copy(line.Chars, h.buffer[lineNum])
@@ -344,13 +360,9 @@ func (h *HistoryScreen) addToHistory(lineNum int) {
 			}
 		}
 
-		// Add to History
-		h.History.PushBack(line)
-
-		// Trim History if it exceeds max
-		if h.History.Len() > h.maxHistory {
-			h.History.Remove(h.History.Front())
-		}
+		// Add to History, which drops its oldest line when full
+		h.History.Push(line)
+		h.linesPushed++
 	}
 }
 
@@ -464,14 +476,16 @@ func (h *HistoryScreen) restoreCurrentScreen() {
 		h.savedAttrs = nil
 		h.savedCellWidths = nil
 
-		// Restore cursor visibility
-		h.cursor.Hidden = false
+		// savedCursor carries the application's DECTCEM visibility, so the
+		// cursor hidden by renderHistoryView is restored along with it
 	}
 }
 
 // Replace the Draw method in history_screen.go:
 
 func (h *HistoryScreen) Draw(text string) {
+	text = h.beginDraw(text)
+
 	// Exit History mode if we're in it
 	if h.ViewingHistory {
 		h.ScrollToBottom()
@@ -479,13 +493,17 @@ func (h *HistoryScreen) Draw(text string) {
 
 	// Now draw using embedded NativeScreen's implementation
 	for _, ch := range text {
-		// Check if we need to wrap
-		if h.cursor.X >= h.columns {
+		// Check if we need to wrap, at the right margin when inside the margins
+		start, end := h.wrapColumns()
+		if h.cursor.X >= end {
 			if h.autoWrap {
-				h.cursor.X = 0
+				h.setWrapped(h.cursor.Y, true)
+				h.cursor.X = start
 
 				// SCROLL REGION FIX: Check scroll region boundaries, not just screen boundaries
-				if h.scrollRegionSet {
+				if h.indexInMargins() {
+					// Scrolled between the left and right margins
+				} else if h.scrollRegionSet {
 					// Within scroll region - check if at bottom of region
 					if h.cursor.Y >= h.scrollBottom {
 						// At bottom of scroll region - scroll within region
@@ -509,12 +527,15 @@ func (h *HistoryScreen) Draw(text string) {
 					}
 				}
 			} else {
-				h.cursor.X = h.columns - 1
+				h.cursor.X = end - 1
 			}
 		}
 
 		// Place character
 		if h.cursor.Y < h.lines && h.cursor.X < h.columns {
+			if h.insertMode {
+				h.insertCells(h.cursor.Y, h.cursor.X, 1)
+			}
 			h.buffer[h.cursor.Y][h.cursor.X] = ch
 			h.attrs[h.cursor.Y][h.cursor.X] = h.cursor.Attrs
 
@@ -539,17 +560,19 @@ func (h *HistoryScreen) EraseInDisplay(how int) {
 
 	// Clear History on full clear (ESC[2J or ESC[3J)
 	if how == 2 || how == 3 {
-		h.History.Init() // Clear the list
+		h.History.Clear()
 		h.HistoryPos = 0
+		h.clearCommandMarks()
 	}
 }
 
 // Override Reset to clear History
 func (h *HistoryScreen) Reset() {
 	h.NativeScreen.Reset()
-	h.History.Init() // Clear History
+	h.History.Clear()
 	h.HistoryPos = 0
 	h.ViewingHistory = false
+	h.clearCommandMarks()
 	h.savedBuffer = nil
 	h.savedAttrs = nil
 	h.savedCellWidths = nil
@@ -583,11 +606,22 @@ func (h *HistoryScreen) GetCursorObject() *Cursor {
 	return &h.cursor
 }
 
-// Enhanced Resize with proper cell width handling
+// Resize reflows the screen and History to the new size, rewrapping
+// soft-wrapped lines (see reflow.go)
 func (h *HistoryScreen) Resize(newCols, newLines int) {
+	h.resizeScreen(newCols, newLines, true)
+}
+
+// resizeScreen resizes the active buffers. Without reflow, rows are
+// truncated or padded and History is left alone; alternate screens use this
+// since their programs redraw on resize.
+func (h *HistoryScreen) resizeScreen(newCols, newLines int, reflow bool) {
 	if newCols <= 0 || newLines <= 0 {
 		return
 	}
+	if newCols == h.columns && newLines == h.lines {
+		return
+	}
 
 	// If we are viewing History, jump back to live view first.
 	if h.ViewingHistory {
@@ -597,14 +631,25 @@ func (h *HistoryScreen) Resize(newCols, newLines int) {
 	oldLines := h.lines
 	oldCols := h.columns
 
-	// If rows will shrink and we're not in alternate (alt handled elsewhere),
-	// push the bottom lines that would be lost into History so they remain reachable.
-	if newLines < oldLines {
-		cut := oldLines - newLines
-		start := oldLines - cut
-		for i := start; i < oldLines; i++ {
-			h.addToHistory(i)
-		}
+	if reflow {
+		screen := h.reflow(reflowScreen{
+			buffer:  h.buffer,
+			attrs:   h.attrs,
+			widths:  h.cellWidths,
+			wrapped: h.wrapped,
+			cursor:  h.cursor,
+		}, newCols, newLines)
+
+		h.buffer = screen.buffer
+		h.attrs = screen.attrs
+		h.cellWidths = screen.widths
+		h.wrapped = screen.wrapped
+		h.setGeometry(newCols, newLines)
+
+		// setGeometry clamps the cursor into the last column, but a cursor
+		// that was one past the end of a full line stays there
+		h.cursor = screen.cursor
+		return
 	}
 
 	// Resize underlying NativeScreen buffers/attrs first with column logic.
@@ -712,6 +757,22 @@ func (h *HistoryScreen) GetMaxHistoryPos() int {
 	return h.History.Len()
 }
 
+// SetMaxHistoryLines changes the History size, dropping the oldest lines
+// when shrinking
+func (h *HistoryScreen) SetMaxHistoryLines(maxLines int) {
+	h.History.SetMaxLines(maxLines)
+	h.trimCommandMarks()
+
+	if h.ViewingHistory && h.HistoryPos > h.History.Len() {
+		h.HistoryPos = h.History.Len()
+		if h.HistoryPos == 0 {
+			h.ScrollToBottom()
+		} else {
+			h.renderHistoryView()
+		}
+	}
+}
+
 // IsAtTopOfHistory returns true if we're viewing the oldest available History
 func (h *HistoryScreen) IsAtTopOfHistory() bool {
 	if !h.ViewingHistory {
@@ -825,16 +886,11 @@ func (h *HistoryScreen) renderHistoryView() {
 
 	// Fill screen with History
 	lineIdx := 0
-	elem := h.History.Front()
-
-	// Skip to start position
-	for i := 0; i < startHistoryIndex && elem != nil; i++ {
-		elem = elem.Next()
-	}
+	histIdx := startHistoryIndex
 
 	// Fill from History
-	for lineIdx < numHistoryLinesToShow && elem != nil && lineIdx < h.lines {
-		histLine := elem.Value.(HistoryLine)
+	for lineIdx < numHistoryLinesToShow && histIdx < totalHistoryLines && lineIdx < h.lines {
+		histLine := h.History.Line(histIdx)
 
 		// Ensure we don't exceed array bounds
 		if lineIdx < len(h.buffer) {
@@ -852,7 +908,7 @@ func (h *HistoryScreen) renderHistoryView() {
 			}
 		}
 
-		elem = elem.Next()
+		histIdx++
 		lineIdx++
 	}
 
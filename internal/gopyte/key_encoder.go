@@ -0,0 +1,265 @@
+package gopyte
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// Key identifies a key for EncodeKey. Keys that type text are their
+// unshifted character, as in the kitty keyboard protocol ('a', not 'A');
+// the others are the constants below.
+type Key rune
+
+// Keys with a C0 encoding, numbered by it as kitty does
+const (
+	KeyTab       Key = 0x09
+	KeyEnter     Key = 0x0d
+	KeyEscape    Key = 0x1b
+	KeyBackspace Key = 0x7f
+)
+
+// Functional keys, numbered past the end of Unicode
+const (
+	KeyUp Key = unicode.MaxRune + 1 + iota
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyInsert
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+// KeyModifiers are held modifiers, as bits of the xterm and kitty modifier
+// parameter (which is sent as 1 plus the bits)
+type KeyModifiers int
+
+const (
+	ModShift KeyModifiers = 1 << iota
+	ModAlt
+	ModCtrl
+	ModSuper
+)
+
+// functionalKey is a key sent as CSI number ; modifiers final
+type functionalKey struct {
+	number int
+	final  byte
+}
+
+// functionalKeys are the functional key sequences. Letter finals leave out
+// the number when there are no modifiers, as in CSI A.
+var functionalKeys = map[Key]functionalKey{
+	KeyUp:       {1, 'A'},
+	KeyDown:     {1, 'B'},
+	KeyRight:    {1, 'C'},
+	KeyLeft:     {1, 'D'},
+	KeyHome:     {1, 'H'},
+	KeyEnd:      {1, 'F'},
+	KeyInsert:   {2, '~'},
+	KeyDelete:   {3, '~'},
+	KeyPageUp:   {5, '~'},
+	KeyPageDown: {6, '~'},
+	KeyF1:       {11, '~'},
+	KeyF2:       {12, '~'},
+	KeyF3:       {13, '~'},
+	KeyF4:       {14, '~'},
+	KeyF5:       {15, '~'},
+	KeyF6:       {17, '~'},
+	KeyF7:       {18, '~'},
+	KeyF8:       {19, '~'},
+	KeyF9:       {20, '~'},
+	KeyF10:      {21, '~'},
+	KeyF11:      {23, '~'},
+	KeyF12:      {24, '~'},
+}
+
+// modifiedFunctionalKeys are F1-F4 with modifiers, which xterm sends as
+// CSI 1 ; modifiers P-S instead of the unmodified keys' CSI 11-14 ~
+var modifiedFunctionalKeys = map[Key]functionalKey{
+	KeyF1: {1, 'P'},
+	KeyF2: {1, 'Q'},
+	KeyF3: {1, 'R'},
+	KeyF4: {1, 'S'},
+}
+
+// kittyFunctionalKeys are the kitty protocol's own F1-F4 sequences
+var kittyFunctionalKeys = map[Key]functionalKey{
+	KeyF1: {1, 'P'},
+	KeyF2: {1, 'Q'},
+	KeyF3: {13, '~'},
+	KeyF4: {1, 'S'},
+}
+
+func (f functionalKey) encode(mods KeyModifiers) string {
+	switch {
+	case mods != 0:
+		return fmt.Sprintf("%s%d;%d%c", CSI, f.number, mods+1, f.final)
+	case f.final == '~':
+		return fmt.Sprintf("%s%d~", CSI, f.number)
+	}
+	return CSI + string(f.final)
+}
+
+// EncodeKey returns what a key press sends to the host in the given mode.
+// An empty string means the key has no encoding, as for Super chords
+// without one of the extended protocols.
+//
+// Without extended protocols keys use the traditional xterm encoding, which
+// drops modifiers it cannot express (Ctrl+Shift+a is sent as Ctrl+a).
+// modifyOtherKeys level 1 sends those as CSI 27 ; modifiers ; char ~
+// instead, and level 2 sends every Ctrl, Alt or Super chord that way. The
+// kitty protocol, when any of its flags are set, takes over entirely.
+func EncodeKey(key Key, mods KeyModifiers, mode KeyboardMode) string {
+	if mode.KittyFlags != 0 {
+		return encodeKittyKey(key, mods, mode.KittyFlags)
+	}
+	if f, ok := functionalKeys[key]; ok {
+		if modified, ok := modifiedFunctionalKeys[key]; ok && mods != 0 {
+			f = modified
+		}
+		return f.encode(mods)
+	}
+
+	seq, exact := legacyKey(key, mods)
+	if mode.ModifyOtherKeys >= 2 && mods&^ModShift != 0 || mode.ModifyOtherKeys >= 1 && !exact {
+		return fmt.Sprintf("%s27;%d;%d~", CSI, mods+1, shiftedKey(key, mods))
+	}
+	return seq
+}
+
+// legacyKey returns the traditional encoding of a key that is not a
+// functional key, and whether it carries all of the modifiers. Alt is sent
+// as an ESC prefix; Super has no encoding.
+func legacyKey(key Key, mods KeyModifiers) (string, bool) {
+	if mods&ModSuper != 0 {
+		return "", false
+	}
+	prefix := ""
+	if mods&ModAlt != 0 {
+		prefix = ESC
+	}
+	mods &^= ModAlt
+
+	switch key {
+	case KeyEnter, KeyEscape:
+		return prefix + string(rune(key)), mods == 0
+	case KeyTab:
+		if mods == ModShift {
+			return prefix + CSI + "Z", true
+		}
+		return prefix + "\t", mods == 0
+	case KeyBackspace:
+		if mods == ModCtrl {
+			return prefix + "\b", true
+		}
+		return prefix + "\x7f", mods == 0
+	}
+
+	r := rune(key)
+	switch {
+	case mods&ModCtrl != 0:
+		code, ok := controlCode(r)
+		if !ok {
+			return prefix + string(r), false
+		}
+		return prefix + string(code), mods == ModCtrl && !unicode.IsDigit(r)
+	case mods == ModShift:
+		shifted := rune(shiftedKey(key, mods))
+		return prefix + string(shifted), shifted != r
+	}
+	return prefix + string(r), true
+}
+
+// controlCode returns the C0 control a key sends with Ctrl. Digits follow
+// the VT220 layout (Ctrl+2 is NUL, Ctrl+3 ESC up to Ctrl+8 DEL).
+func controlCode(r rune) (byte, bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return byte(r - 'a' + 1), true
+	case r >= '@' && r <= '_':
+		return byte(r - '@'), true
+	case r == ' ' || r == '2':
+		return 0, true
+	case r >= '3' && r <= '7':
+		return byte(r - '3' + 0x1b), true
+	case r == '8' || r == '?':
+		return 0x7f, true
+	case r == '/':
+		return 0x1f, true
+	}
+	return 0, false
+}
+
+// shiftedKey returns the character a key types with the modifiers, which
+// for letters is the uppercase one when Shift is held. The shifted forms
+// of other keys depend on the keyboard layout and are not known here.
+func shiftedKey(key Key, mods KeyModifiers) Key {
+	if mods&ModShift != 0 {
+		return Key(unicode.ToUpper(rune(key)))
+	}
+	return key
+}
+
+// keyText returns the text a key types, 0 for none: only keys that are
+// characters do, and not when Ctrl, Alt or Super are held
+func keyText(key Key, mods KeyModifiers) rune {
+	if key < ' ' || key == KeyBackspace || key > unicode.MaxRune || mods&^ModShift != 0 {
+		return 0
+	}
+	return rune(shiftedKey(key, mods))
+}
+
+// encodeKittyKey encodes a key press with the kitty keyboard protocol:
+// CSI code[:shifted] ; modifiers [; text] u. Only presses are seen, and a
+// press is what the protocol assumes when no event type is sent, so
+// KittyReportEvents changes nothing here.
+func encodeKittyKey(key Key, mods KeyModifiers, flags int) string {
+	if f, ok := kittyFunctionalKeys[key]; ok {
+		return f.encode(mods)
+	}
+	if f, ok := functionalKeys[key]; ok {
+		return f.encode(mods)
+	}
+
+	text := keyText(key, mods)
+	if flags&KittyReportAllKeys == 0 {
+		// Text, and unmodified Enter, Tab and Backspace, stay as they are
+		// so that a shell left in this mode remains usable
+		switch {
+		case text != 0:
+			return string(text)
+		case mods == 0 && (key == KeyEnter || key == KeyTab || key == KeyBackspace):
+			return string(rune(key))
+		}
+	}
+
+	params := strconv.Itoa(int(key))
+	if flags&KittyReportAlternates != 0 {
+		if shifted := shiftedKey(key, mods); shifted != key {
+			params += ":" + strconv.Itoa(int(shifted))
+		}
+	}
+	switch {
+	case flags&KittyReportText != 0 && flags&KittyReportAllKeys != 0 && text != 0:
+		params += fmt.Sprintf(";%d;%d", mods+1, text)
+	case mods != 0:
+		params += fmt.Sprintf(";%d", mods+1)
+	}
+	return CSI + params + "u"
+}
@@ -0,0 +1,139 @@
+package gopyte
+
+import "fmt"
+
+// Most kitty keyboard flags entries kept per screen. Pushing more drops
+// the oldest, as kitty does.
+const maxKeyboardFlagsStack = 8
+
+// Kitty keyboard protocol progressive enhancement flags
+const (
+	KittyDisambiguate     = 1 << iota // Escape and modified keys as CSI u
+	KittyReportEvents                 // Press, repeat and release events
+	KittyReportAlternates             // Shifted key alongside the base key
+	KittyReportAllKeys                // Every key, text included, as CSI u
+	KittyReportText                   // Text a key types, with KittyReportAllKeys
+
+	kittyAllFlags = 1<<iota - 1
+)
+
+// KeyboardMode is how the host asked for keys to be encoded, see EncodeKey
+type KeyboardMode struct {
+	ModifyOtherKeys int // xterm modifyOtherKeys level, 0 (off) to 2
+	KittyFlags      int // Kitty protocol flags, 0 for xterm encoding
+}
+
+// dispatchMarkedCSI handles CSI sequences with a ">", "<" or "=" private
+// marker: the keyboard protocol settings, and secondary DA
+func (s *Stream) dispatchMarkedCSI(final string) {
+	param := func(i, fallback int) int {
+		if i < len(s.params) {
+			return s.params[i]
+		}
+		return fallback
+	}
+
+	switch s.marker + final {
+	case ">" + DA:
+		s.listener.ReportDeviceAttributes(param(0, 0), s.private)
+	case ">" + XTMODKEYS:
+		// Only resource 4, modifyOtherKeys, is supported. With no
+		// resource all of them go back to their defaults.
+		if len(s.params) == 0 || s.params[0] == 4 {
+			s.listener.SetModifyOtherKeys(param(1, 0))
+		}
+	case ">" + XTMODKEYSOFF:
+		if len(s.params) == 0 || s.params[0] == 4 {
+			s.listener.SetModifyOtherKeys(0)
+		}
+	case ">" + KITTYKB:
+		s.listener.PushKeyboardFlags(param(0, 0))
+	case "<" + KITTYKB:
+		s.listener.PopKeyboardFlags(param(0, 1))
+	case "=" + KITTYKB:
+		s.listener.SetKeyboardFlags(param(0, 0), param(1, 1))
+	}
+}
+
+// KeyboardMode returns the key encoding the host asked for
+func (s *NativeScreen) KeyboardMode() KeyboardMode {
+	return KeyboardMode{
+		ModifyOtherKeys: s.modifyOtherKeys,
+		KittyFlags:      s.currentKeyboardFlags(),
+	}
+}
+
+// SetModifyOtherKeys handles XTMODKEYS for modifyOtherKeys (CSI > 4 ; Pv m)
+func (s *NativeScreen) SetModifyOtherKeys(level int) {
+	s.modifyOtherKeys = max(0, min(level, 2))
+}
+
+// PushKeyboardFlags handles CSI > flags u, making flags the current kitty
+// keyboard flags until they are popped
+func (s *NativeScreen) PushKeyboardFlags(flags int) {
+	s.keyboardFlags = append(s.keyboardFlags, flags&kittyAllFlags)
+	if len(s.keyboardFlags) > maxKeyboardFlagsStack {
+		s.keyboardFlags = s.keyboardFlags[1:]
+	}
+}
+
+// PopKeyboardFlags handles CSI < count u. Popping everything goes back to
+// the legacy encoding.
+func (s *NativeScreen) PopKeyboardFlags(count int) {
+	count = max(count, 1)
+	if count >= len(s.keyboardFlags) {
+		s.keyboardFlags = nil
+		return
+	}
+	s.keyboardFlags = s.keyboardFlags[:len(s.keyboardFlags)-count]
+}
+
+// SetKeyboardFlags handles CSI = flags ; how u, changing the current kitty
+// keyboard flags in place: how is 1 to replace them, 2 to add flags and 3
+// to remove them
+func (s *NativeScreen) SetKeyboardFlags(flags, how int) {
+	flags &= kittyAllFlags
+	current := s.currentKeyboardFlags()
+	switch how {
+	case 1:
+		current = flags
+	case 2:
+		current |= flags
+	case 3:
+		current &^= flags
+	default:
+		return
+	}
+
+	if len(s.keyboardFlags) == 0 {
+		s.keyboardFlags = []int{current}
+	} else {
+		s.keyboardFlags[len(s.keyboardFlags)-1] = current
+	}
+}
+
+// ReportKeyboardFlags answers CSI ? u with CSI ? flags u
+func (s *NativeScreen) ReportKeyboardFlags() {
+	s.WriteProcessInput(fmt.Sprintf("%s?%du", CSI, s.currentKeyboardFlags()))
+}
+
+// currentKeyboardFlags returns the kitty flags on top of the stack
+func (s *NativeScreen) currentKeyboardFlags() int {
+	if len(s.keyboardFlags) == 0 {
+		return 0
+	}
+	return s.keyboardFlags[len(s.keyboardFlags)-1]
+}
+
+// swapKeyboardFlags switches kitty flags stacks when the alternate screen
+// is entered or left: each screen keeps its own
+func (s *NativeScreen) swapKeyboardFlags() {
+	s.keyboardFlags, s.inactiveKeyboardFlags = s.inactiveKeyboardFlags, s.keyboardFlags
+}
+
+// resetKeyboard goes back to the legacy key encoding on both screens
+func (s *NativeScreen) resetKeyboard() {
+	s.modifyOtherKeys = 0
+	s.keyboardFlags = nil
+	s.inactiveKeyboardFlags = nil
+}
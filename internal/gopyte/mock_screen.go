@@ -1,6 +1,9 @@
 package gopyte
 
-import "fmt"
+import (
+	"fmt"
+	"image/color"
+)
 
 // MockScreen is a test implementation that logs all calls
 type MockScreen struct {
@@ -51,14 +54,45 @@ func (s *MockScreen) EraseInDisplay(how int)              { s.log("EraseInDispla
 func (s *MockScreen) SetMode(modes []int, private bool)   { s.log("SetMode", modes, private) }
 func (s *MockScreen) ResetMode(modes []int, private bool) { s.log("ResetMode", modes, private) }
 func (s *MockScreen) SelectGraphicRendition(attrs []int)  { s.log("SelectGraphicRendition", attrs) }
+func (s *MockScreen) SetUnderlineStyle(style int)         { s.log("SetUnderlineStyle", style) }
 func (s *MockScreen) DefineCharset(code, mode string)     { s.log("DefineCharset", code, mode) }
+func (s *MockScreen) LockingShift(set int)                { s.log("LockingShift", set) }
+func (s *MockScreen) SingleShift(set int)                 { s.log("SingleShift", set) }
 func (s *MockScreen) SetMargins(top, bottom int)          { s.log("SetMargins", top, bottom) }
+func (s *MockScreen) SetLeftRightMargins(left, right int) { s.log("SetLeftRightMargins", left, right) }
+func (s *MockScreen) RepeatCharacter(count int)           { s.log("RepeatCharacter", count) }
+func (s *MockScreen) CursorForwardTab(count int)          { s.log("CursorForwardTab", count) }
+func (s *MockScreen) CursorBackTab(count int)             { s.log("CursorBackTab", count) }
+func (s *MockScreen) ScrollContentUp(count int)           { s.log("ScrollContentUp", count) }
+func (s *MockScreen) ScrollContentDown(count int)         { s.log("ScrollContentDown", count) }
+func (s *MockScreen) SoftReset()                          { s.log("SoftReset") }
 func (s *MockScreen) ReportDeviceAttributes(mode int, priv bool) {
 	s.log("ReportDeviceAttributes", mode, priv)
 }
 func (s *MockScreen) ReportDeviceStatus(mode int)   { s.log("ReportDeviceStatus", mode) }
+func (s *MockScreen) ReportStatusString(set string) { s.log("ReportStatusString", set) }
+func (s *MockScreen) ReportMode(mode int, priv bool) {
+	s.log("ReportMode", mode, priv)
+}
+func (s *MockScreen) SetModifyOtherKeys(level int) { s.log("SetModifyOtherKeys", level) }
+func (s *MockScreen) PushKeyboardFlags(flags int)  { s.log("PushKeyboardFlags", flags) }
+func (s *MockScreen) PopKeyboardFlags(count int)   { s.log("PopKeyboardFlags", count) }
+func (s *MockScreen) SetKeyboardFlags(flags, how int) {
+	s.log("SetKeyboardFlags", flags, how)
+}
+func (s *MockScreen) ReportKeyboardFlags()          { s.log("ReportKeyboardFlags") }
 func (s *MockScreen) SetTitle(title string)         { s.log("SetTitle", title) }
 func (s *MockScreen) SetIconName(name string)       { s.log("SetIconName", name) }
+func (s *MockScreen) SetHyperlink(uri string)       { s.log("SetHyperlink", uri) }
 func (s *MockScreen) AlignmentDisplay()             { s.log("AlignmentDisplay") }
 func (s *MockScreen) Debug(args ...interface{})     { s.log("Debug", args...) }
 func (s *MockScreen) WriteProcessInput(data string) { s.log("WriteProcessInput", data) }
+func (s *MockScreen) SetCursorStyle(style int)      { s.log("SetCursorStyle", style) }
+func (s *MockScreen) PromptMark(mark string, params []string) {
+	s.log("PromptMark", mark, params)
+}
+func (s *MockScreen) SetColor(slot int, c color.RGBA) { s.log("SetColor", slot, c) }
+func (s *MockScreen) ResetColor(slot int)             { s.log("ResetColor", slot) }
+func (s *MockScreen) QueryColor(slot int, terminator string) {
+	s.log("QueryColor", slot, terminator)
+}
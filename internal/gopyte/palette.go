@@ -0,0 +1,209 @@
+package gopyte
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Color slots for OSC 4/10/11/12. Slots 0-255 are the palette; the dynamic
+// colors follow them.
+const (
+	ColorForeground = 256 + iota // OSC 10
+	ColorBackground              // OSC 11
+	ColorCursor                  // OSC 12
+
+	// AllPaletteColors resets every palette entry (OSC 104 with no index)
+	AllPaletteColors = -1
+)
+
+// ColorProvider returns a slot's color before any OSC override, normally
+// from the active color scheme. ok is false to use the xterm default.
+type ColorProvider func(slot int) (c color.RGBA, ok bool)
+
+// DefaultPaletteColor returns xterm's color for a slot: the 16 ANSI colors,
+// the 6x6x6 cube and the gray ramp, then white on black
+func DefaultPaletteColor(slot int) color.RGBA {
+	ansi := [16]uint32{
+		0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+		0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+	}
+	switch {
+	case slot >= 0 && slot < 16:
+		v := ansi[slot]
+		return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+	case slot >= 16 && slot < 232:
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		n := slot - 16
+		return color.RGBA{levels[n/36], levels[(n/6)%6], levels[n%6], 0xff}
+	case slot >= 232 && slot < 256:
+		v := uint8(8 + 10*(slot-232))
+		return color.RGBA{v, v, v, 0xff}
+	case slot == ColorBackground:
+		return color.RGBA{0, 0, 0, 0xff}
+	}
+	return color.RGBA{0xff, 0xff, 0xff, 0xff}
+}
+
+// ParseColorSpec parses an X11 color spec as sent in OSC 4/10/11/12:
+// "rgb:R/G/B" with 1-4 hex digits per component, or "#RGB" with 1-4
+// digits per component
+func ParseColorSpec(spec string) (color.RGBA, bool) {
+	var parts []string
+	switch {
+	case strings.HasPrefix(spec, "rgb:"):
+		parts = strings.Split(spec[4:], "/")
+		if len(parts) != 3 {
+			return color.RGBA{}, false
+		}
+	case strings.HasPrefix(spec, "#") && len(spec) > 1 && (len(spec)-1)%3 == 0:
+		n := (len(spec) - 1) / 3
+		parts = []string{spec[1 : 1+n], spec[1+n : 1+2*n], spec[1+2*n:]}
+	default:
+		return color.RGBA{}, false
+	}
+
+	var rgb [3]uint8
+	for i, part := range parts {
+		if len(part) < 1 || len(part) > 4 {
+			return color.RGBA{}, false
+		}
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		if strings.HasPrefix(spec, "#") {
+			// "#" specs give the high bits
+			rgb[i] = uint8(v << (16 - 4*len(part)) >> 8)
+		} else {
+			// "rgb:" specs are scaled to the full range
+			rgb[i] = uint8(v * 255 / (1<<(4*len(part)) - 1))
+		}
+	}
+	return color.RGBA{rgb[0], rgb[1], rgb[2], 0xff}, true
+}
+
+// EncodeColorReply builds the reply to a color query, in the 16-bit
+// "rgb:" form xterm uses, ended with the query's terminator
+func EncodeColorReply(slot int, c color.RGBA, terminator string) string {
+	value := fmt.Sprintf("rgb:%02x%02x/%02x%02x/%02x%02x", c.R, c.R, c.G, c.G, c.B, c.B)
+	if slot < ColorForeground {
+		return fmt.Sprintf("\x1b]4;%d;%s%s", slot, value, terminator)
+	}
+	return fmt.Sprintf("\x1b]%d;%s%s", 10+slot-ColorForeground, value, terminator)
+}
+
+// handleOSCColor handles OSC 4/10/11/12 (set or query colors) and
+// OSC 104/110/111/112 (reset them)
+func (s *Stream) handleOSCColor(code, param string) {
+	var fields []string
+	if param != "" {
+		fields = strings.Split(param, ";")
+	}
+
+	switch code {
+	case "4":
+		// OSC 4 ; index ; spec [; index ; spec ...]
+		for i := 0; i+1 < len(fields); i += 2 {
+			slot, err := strconv.Atoi(fields[i])
+			if err != nil || slot < 0 || slot > 255 {
+				continue
+			}
+			s.setOrQueryColor(slot, fields[i+1])
+		}
+	case "10", "11", "12":
+		// Each further spec sets the next dynamic color
+		slot := ColorForeground + int(code[1]-'0')
+		for _, spec := range fields {
+			if slot > ColorCursor {
+				break
+			}
+			s.setOrQueryColor(slot, spec)
+			slot++
+		}
+	case "104":
+		if len(fields) == 0 {
+			s.listener.ResetColor(AllPaletteColors)
+		}
+		for _, field := range fields {
+			if slot, err := strconv.Atoi(field); err == nil && slot >= 0 && slot <= 255 {
+				s.listener.ResetColor(slot)
+			}
+		}
+	case "110", "111", "112":
+		s.listener.ResetColor(ColorForeground + int(code[2]-'0'))
+	}
+}
+
+func (s *Stream) setOrQueryColor(slot int, spec string) {
+	if spec == "?" {
+		s.listener.QueryColor(slot, s.oscTerminator)
+		return
+	}
+	if c, ok := ParseColorSpec(spec); ok {
+		s.listener.SetColor(slot, c)
+	} else {
+		s.listener.Debug("OSC color: unsupported spec", spec)
+	}
+}
+
+// SetColorProvider sets where unset colors come from when queried
+func (s *NativeScreen) SetColorProvider(provider ColorProvider) {
+	s.colorProvider = provider
+}
+
+// SetResponseHandler sets where replies to the host (color queries) go.
+// With no handler they are dropped.
+func (s *NativeScreen) SetResponseHandler(handler func(data string)) {
+	s.responder = handler
+}
+
+// SetColor overrides a palette entry or dynamic color (OSC 4/10/11/12)
+func (s *NativeScreen) SetColor(slot int, c color.RGBA) {
+	if s.colorOverrides == nil {
+		s.colorOverrides = make(map[int]color.RGBA)
+	}
+	s.colorOverrides[slot] = c
+	s.colorVersion++
+}
+
+// ResetColor drops an override, or all palette overrides for
+// AllPaletteColors (OSC 104/110/111/112)
+func (s *NativeScreen) ResetColor(slot int) {
+	for overridden := range s.colorOverrides {
+		if overridden == slot || (slot == AllPaletteColors && overridden < ColorForeground) {
+			delete(s.colorOverrides, overridden)
+		}
+	}
+	s.colorVersion++
+}
+
+// QueryColor replies with a slot's current color
+func (s *NativeScreen) QueryColor(slot int, terminator string) {
+	s.WriteProcessInput(EncodeColorReply(slot, s.Color(slot), terminator))
+}
+
+// Color returns a slot's color: the override, else the provider's color,
+// else the xterm default
+func (s *NativeScreen) Color(slot int) color.RGBA {
+	if c, ok := s.colorOverrides[slot]; ok {
+		return c
+	}
+	if s.colorProvider != nil {
+		if c, ok := s.colorProvider(slot); ok {
+			return c
+		}
+	}
+	return DefaultPaletteColor(slot)
+}
+
+// ColorOverrides returns a copy of the colors set by the host and a version
+// that changes whenever they do
+func (s *NativeScreen) ColorOverrides() (map[int]color.RGBA, int) {
+	overrides := make(map[int]color.RGBA, len(s.colorOverrides))
+	for slot, c := range s.colorOverrides {
+		overrides[slot] = c
+	}
+	return overrides, s.colorVersion
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"image/color"
 	"io"
 	"os"
 	"os/exec"
@@ -248,11 +249,23 @@ func (s *PythonScreen) CursorToLine(line int) {
 	s.call("cursor_to_line", []interface{}{line}, nil)
 }
 
+func (s *PythonScreen) CursorForwardTab(count int) {
+	for i := 0; i < count; i++ {
+		s.Tab()
+	}
+}
+
+// CursorBackTab is a no-op: pyte has no CBT
+func (s *PythonScreen) CursorBackTab(count int) {}
+
 // Screen manipulation
 func (s *PythonScreen) Reset() {
 	s.call("reset", nil, nil)
 }
 
+// SoftReset is a no-op: pyte has no DECSTR
+func (s *PythonScreen) SoftReset() {}
+
 func (s *PythonScreen) Index() {
 	s.call("index", nil, nil)
 }
@@ -306,6 +319,12 @@ func (s *PythonScreen) EraseInDisplay(how int) {
 	s.call("erase_in_display", []interface{}{how}, nil)
 }
 
+// RepeatCharacter, ScrollContentUp and ScrollContentDown are no-ops: pyte
+// has no REP, SU or SD
+func (s *PythonScreen) RepeatCharacter(count int)   {}
+func (s *PythonScreen) ScrollContentUp(count int)   {}
+func (s *PythonScreen) ScrollContentDown(count int) {}
+
 // Modes
 func (s *PythonScreen) SetMode(modes []int, private bool) {
 	args := make([]interface{}, len(modes))
@@ -332,16 +351,44 @@ func (s *PythonScreen) SelectGraphicRendition(attrs []int) {
 	s.call("select_graphic_rendition", args, nil)
 }
 
+// SetUnderlineStyle falls back to a plain underline: pyte has no styles
+func (s *PythonScreen) SetUnderlineStyle(style int) {
+	sgr := 4
+	if style == UnderlineNone {
+		sgr = 24
+	}
+	s.call("select_graphic_rendition", []interface{}{sgr}, nil)
+}
+
 // Charset
 func (s *PythonScreen) DefineCharset(code, mode string) {
 	s.call("define_charset", []interface{}{code, mode}, nil)
 }
 
+// LockingShift maps G0 and G1 to pyte's shifts; pyte has no G2 or G3
+func (s *PythonScreen) LockingShift(set int) {
+	switch set {
+	case 0:
+		s.ShiftIn()
+	case 1:
+		s.ShiftOut()
+	}
+}
+
+// SingleShift is a no-op: pyte has no G2 or G3
+func (s *PythonScreen) SingleShift(set int) {}
+
 // Margins
 func (s *PythonScreen) SetMargins(top, bottom int) {
 	s.call("set_margins", []interface{}{top, bottom}, nil)
 }
 
+// SetLeftRightMargins saves the cursor as pyte does for CSI s; it has no
+// DECSLRM
+func (s *PythonScreen) SetLeftRightMargins(left, right int) {
+	s.SaveCursor()
+}
+
 // Reports
 func (s *PythonScreen) ReportDeviceAttributes(mode int, private bool) {
 	s.call("report_device_attributes", []interface{}{mode}, map[string]interface{}{"private": private})
@@ -351,6 +398,19 @@ func (s *PythonScreen) ReportDeviceStatus(mode int) {
 	s.call("report_device_status", []interface{}{mode}, nil)
 }
 
+// ReportStatusString is a no-op: pyte has no DECRQSS
+func (s *PythonScreen) ReportStatusString(setting string) {}
+
+// ReportMode is a no-op: pyte has no DECRQM
+func (s *PythonScreen) ReportMode(mode int, private bool) {}
+
+// Keyboard protocols are no-ops: pyte has no keyboard encoder
+func (s *PythonScreen) SetModifyOtherKeys(level int)    {}
+func (s *PythonScreen) PushKeyboardFlags(flags int)     {}
+func (s *PythonScreen) PopKeyboardFlags(count int)      {}
+func (s *PythonScreen) SetKeyboardFlags(flags, how int) {}
+func (s *PythonScreen) ReportKeyboardFlags()            {}
+
 // Window operations
 func (s *PythonScreen) SetTitle(title string) {
 	s.call("set_title", []interface{}{title}, nil)
@@ -360,6 +420,21 @@ func (s *PythonScreen) SetIconName(name string) {
 	s.call("set_icon_name", []interface{}{name}, nil)
 }
 
+// SetCursorStyle is a no-op: pyte ignores DECSCUSR
+func (s *PythonScreen) SetCursorStyle(style int) {}
+
+// SetHyperlink is a no-op: pyte has no OSC 8 support to compare against
+func (s *PythonScreen) SetHyperlink(uri string) {}
+
+// PromptMark is a no-op: pyte has no OSC 133 support
+func (s *PythonScreen) PromptMark(mark string, params []string) {}
+
+// SetColor, ResetColor and QueryColor are no-ops: pyte has no OSC 4/10/11/12
+// support
+func (s *PythonScreen) SetColor(slot int, c color.RGBA)        {}
+func (s *PythonScreen) ResetColor(slot int)                    {}
+func (s *PythonScreen) QueryColor(slot int, terminator string) {}
+
 // Alignment
 func (s *PythonScreen) AlignmentDisplay() {
 	s.call("alignment_display", nil, nil)
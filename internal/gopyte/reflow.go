@@ -0,0 +1,339 @@
+package gopyte
+
+// Soft wraps and reflow.
+//
+// When autowrap moves the cursor past the end of a row, the row is flagged
+// as wrapped: its logical line continues on the next row. On resize the main
+// screen and History are joined back into logical lines and wrapped again at
+// the new width, so widening the window restores long lines and narrowing it
+// loses nothing.
+//
+// A wide character that does not fit at the end of a row moves to the next
+// row; the cells it leaves behind hold padding (a NUL with width 1) so that
+// reflow does not mistake them for spaces.
+
+// isWrapped reports whether row y continues on row y+1
+func (s *NativeScreen) isWrapped(y int) bool {
+	return y >= 0 && y < len(s.wrapped) && s.wrapped[y]
+}
+
+// setWrapped sets the soft-wrap flag of row y
+func (s *NativeScreen) setWrapped(y int, wrapped bool) {
+	if y < 0 {
+		return
+	}
+	for len(s.wrapped) <= y {
+		s.wrapped = append(s.wrapped, false)
+	}
+	s.wrapped[y] = wrapped
+}
+
+// scrollWrapped moves the flags of rows top+1..bottom up one row and clears
+// bottom, following the rows moved by scrollWithinMargins
+func (s *NativeScreen) scrollWrapped(top, bottom int) {
+	for y := top; y < bottom; y++ {
+		s.setWrapped(y, s.isWrapped(y+1))
+	}
+	s.setWrapped(bottom, false)
+}
+
+// reverseScrollWrapped moves the flags of rows top..bottom-1 down one row
+// and clears top
+func (s *NativeScreen) reverseScrollWrapped(top, bottom int) {
+	for y := bottom; y > top; y-- {
+		s.setWrapped(y, s.isWrapped(y-1))
+	}
+	s.setWrapped(top, false)
+}
+
+// clearWrapped clears the flags of rows top..bottom
+func (s *NativeScreen) clearWrapped(top, bottom int) {
+	for y := max(top, 0); y <= bottom && y < len(s.wrapped); y++ {
+		s.wrapped[y] = false
+	}
+}
+
+// isPadding reports whether a cell is padding left by a wrapped wide character
+func isPadding(c Cell) bool {
+	return c.Char == 0 && c.Width != 0
+}
+
+// isBlank reports whether a cell can be dropped from the end of a line
+func isBlank(c Cell) bool {
+	return (c.Char == ' ' || isPadding(c)) &&
+		(c.Attrs.Bg == "" || c.Attrs.Bg == "default") && !c.Attrs.Reverse
+}
+
+// reflowScreen is a screen's rows as reflowed together with History
+type reflowScreen struct {
+	buffer  [][]rune
+	attrs   [][]Attributes
+	widths  [][]int
+	wrapped []bool
+	cursor  Cursor
+}
+
+// rowCells returns the cells of one row. Missing attributes or widths (rows
+// from before width tracking) default to plain single width cells.
+func rowCells(chars []rune, attrs []Attributes, widths []int) []Cell {
+	cells := make([]Cell, len(chars))
+	for x, ch := range chars {
+		cells[x] = Cell{Char: ch, Attrs: DefaultAttributes(), Width: 1}
+		if x < len(attrs) {
+			cells[x].Attrs = attrs[x]
+		}
+		if x < len(widths) {
+			cells[x].Width = widths[x]
+		}
+	}
+	return cells
+}
+
+// blankRow returns an empty row of the given width
+func blankRow(cols int) ([]rune, []Attributes, []int) {
+	chars := make([]rune, cols)
+	attrs := make([]Attributes, cols)
+	widths := make([]int, cols)
+	for x := 0; x < cols; x++ {
+		chars[x] = ' '
+		attrs[x] = DefaultAttributes()
+		widths[x] = 1
+	}
+	return chars, attrs, widths
+}
+
+// logicalLine is a line of text as the application wrote it, before wrapping
+type logicalLine struct {
+	cells     []Cell
+	rowStarts []int // Offset of the first cell of each wrapped row
+	firstRow  int   // Index of the first wrapped row
+}
+
+// position maps an offset in the line to a wrapped row and column. An offset
+// at the end of a full row stays on that row, one past the last column,
+// like the cursor after drawing into the last column.
+func (l *logicalLine) position(offset int) (int, int) {
+	k := 0
+	for k+1 < len(l.rowStarts) && l.rowStarts[k+1] <= offset {
+		k++
+	}
+	return l.firstRow + k, offset - l.rowStarts[k]
+}
+
+// wrap lays the line out in rows of cols cells
+func (l *logicalLine) wrap(cols int) []HistoryLine {
+	chars, attrs, widths := blankRow(cols)
+	rows := []HistoryLine{}
+	l.rowStarts = []int{0}
+	col := 0
+
+	endRow := func(next int) {
+		rows = append(rows, HistoryLine{Chars: chars, Attrs: attrs, CellWidths: widths, Wrapped: true})
+		chars, attrs, widths = blankRow(cols)
+		l.rowStarts = append(l.rowStarts, next)
+		col = 0
+	}
+
+	for i := 0; i < len(l.cells); i++ {
+		c := l.cells[i]
+		span := 1
+		switch {
+		case c.Width == 2 && i+1 < len(l.cells) && l.cells[i+1].Width == 0 && cols > 1:
+			span = 2
+		case c.Width != 1 || isPadding(c):
+			// Stray padding, or a wide character that cannot be kept whole
+			c = Cell{Char: ' ', Attrs: c.Attrs, Width: 1}
+		}
+
+		if col+span > cols {
+			// A wide character straddling the edge leaves padding and
+			// moves to the next row
+			for x := col; x < cols; x++ {
+				chars[x] = 0
+			}
+			endRow(i)
+		}
+
+		chars[col] = c.Char
+		attrs[col] = c.Attrs
+		widths[col] = c.Width
+		if span == 2 {
+			i++
+			chars[col+1] = 0
+			attrs[col+1] = l.cells[i].Attrs
+			widths[col+1] = 0
+		}
+		col += span
+	}
+
+	return append(rows, HistoryLine{Chars: chars, Attrs: attrs, CellWidths: widths})
+}
+
+// Most History rows rewrapped on resize. Only the newest are, so that a
+// resize costs the same with 100k lines of scrollback as with a few
+// thousand; older rows keep the width they were wrapped at.
+const maxReflowHistoryRows = 5000
+
+// reflow rewraps the newest History rows and the given screen to newCols,
+// replacing those rows in History and returning the screen rows for a
+// screen of newLines rows. The cursor stays on the same character of its
+// logical line, and OSC 133 marks follow the lines they were recorded on.
+func (h *HistoryScreen) reflow(screen reflowScreen, newCols, newLines int) reflowScreen {
+	// Rewrap from the start of a logical line
+	start := max(h.History.Len()-maxReflowHistoryRows, 0)
+	for start > 0 && start < h.History.Len() && h.History.Line(start-1).Wrapped {
+		start++
+	}
+
+	// Physical rows, History first
+	var rows []HistoryLine
+	for i := start; i < h.History.Len(); i++ {
+		rows = append(rows, h.History.Line(i))
+	}
+	historyLen := len(rows)
+	for y := range screen.buffer {
+		row := HistoryLine{Chars: screen.buffer[y]}
+		if y < len(screen.attrs) {
+			row.Attrs = screen.attrs[y]
+		}
+		if y < len(screen.widths) {
+			row.CellWidths = screen.widths[y]
+		}
+		row.Wrapped = y < len(screen.wrapped) && screen.wrapped[y]
+		rows = append(rows, row)
+	}
+
+	cursorRow := historyLen + screen.cursor.Y
+	if cursorRow >= len(rows) {
+		cursorRow = len(rows) - 1
+	}
+
+	// Blank rows below the cursor are recreated at the bottom of the screen
+	// afterwards; dropping them lets content move down when lines get longer
+	for len(rows)-1 > cursorRow && !rows[len(rows)-2].Wrapped && rowIsBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+
+	// Join rows into logical lines, remembering where each row started
+	var lines []*logicalLine
+	rowLine := make([]int, len(rows))
+	rowOffset := make([]int, len(rows))
+	cursorLine, cursorOffset := 0, 0
+	var current *logicalLine
+	for r, row := range rows {
+		if current == nil {
+			current = &logicalLine{}
+			lines = append(lines, current)
+		}
+		rowLine[r] = len(lines) - 1
+		rowOffset[r] = len(current.cells)
+
+		cells := rowCells(row.Chars, row.Attrs, row.CellWidths)
+		keep := 0 // Cells before the cursor are never trimmed
+		if r == cursorRow {
+			keep = min(max(screen.cursor.X, 0), len(cells))
+		}
+		if row.Wrapped {
+			// Only the padding of a wrapped wide character is dropped
+			for len(cells) > keep && isPadding(cells[len(cells)-1]) {
+				cells = cells[:len(cells)-1]
+			}
+		} else {
+			for len(cells) > keep && isBlank(cells[len(cells)-1]) {
+				cells = cells[:len(cells)-1]
+			}
+		}
+		if r == cursorRow {
+			cursorLine, cursorOffset = rowLine[r], rowOffset[r]+keep
+		}
+
+		current.cells = append(current.cells, cells...)
+		if !row.Wrapped {
+			current = nil
+		}
+	}
+
+	// Wrap every logical line at the new width
+	var out []HistoryLine
+	for _, line := range lines {
+		line.firstRow = len(out)
+		out = append(out, line.wrap(newCols)...)
+	}
+
+	newCursorRow, newCursorCol := 0, 0
+	if len(lines) > 0 {
+		newCursorRow, newCursorCol = lines[cursorLine].position(cursorOffset)
+	}
+
+	// The screen shows the last rows, but always the cursor
+	screenStart := max(len(out)-newLines, 0)
+	if newCursorRow < screenStart {
+		screenStart = newCursorRow
+	}
+
+	// Move OSC 133 marks to their new lines. Marks above the rewrapped
+	// rows stay where they are.
+	base := h.trimmedLines() + start
+	newRow := func(abs int) int {
+		r := abs - base
+		switch {
+		case r < 0:
+			return abs
+		case r >= len(rows):
+			// A blank row that was dropped, or the line after the output
+			return base + len(out) + r - len(rows)
+		}
+		row, _ := lines[rowLine[r]].position(rowOffset[r])
+		return base + row
+	}
+	for i := range h.commandMarks {
+		m := &h.commandMarks[i]
+		m.PromptLine = newRow(m.PromptLine)
+		if m.OutputLine >= 0 {
+			m.OutputLine = newRow(m.OutputLine)
+		}
+		if m.EndLine >= 0 {
+			m.EndLine = newRow(m.EndLine)
+		}
+	}
+
+	// Rows above the screen replace the rewrapped History rows
+	h.History.Truncate(start)
+	for _, row := range out[:screenStart] {
+		h.History.Push(row)
+	}
+	h.linesPushed = base + screenStart
+	h.trimCommandMarks()
+
+	result := reflowScreen{
+		buffer:  make([][]rune, newLines),
+		attrs:   make([][]Attributes, newLines),
+		widths:  make([][]int, newLines),
+		wrapped: make([]bool, newLines),
+		cursor:  screen.cursor,
+	}
+	for y := 0; y < newLines; y++ {
+		if r := screenStart + y; r < len(out) {
+			result.buffer[y] = out[r].Chars
+			result.attrs[y] = out[r].Attrs
+			result.widths[y] = out[r].CellWidths
+			result.wrapped[y] = out[r].Wrapped
+		} else {
+			result.buffer[y], result.attrs[y], result.widths[y] = blankRow(newCols)
+		}
+	}
+	result.cursor.X = newCursorCol
+	result.cursor.Y = min(newCursorRow-screenStart, newLines-1)
+
+	return result
+}
+
+// rowIsBlank reports whether a row holds nothing worth keeping
+func rowIsBlank(row HistoryLine) bool {
+	for _, c := range rowCells(row.Chars, row.Attrs, row.CellWidths) {
+		if !isBlank(c) {
+			return false
+		}
+	}
+	return true
+}
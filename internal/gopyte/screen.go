@@ -2,8 +2,10 @@ package gopyte
 
 import (
 	"fmt"
+	"image/color"
 	"log"
 	"strings"
+	"time"
 )
 
 // Screen represents a native Go terminal screen
@@ -13,10 +15,22 @@ type NativeScreen struct {
 	lines   int
 
 	// Core data
-	buffer [][]rune       // The actual character data
-	attrs  [][]Attributes // Attributes for each cell
-	cursor Cursor
-	saved  *Cursor // For save/restore cursor
+	buffer  [][]rune       // The actual character data
+	attrs   [][]Attributes // Attributes for each cell
+	wrapped []bool         // Row was soft-wrapped by autowrap (see reflow.go)
+	cursor  Cursor
+	saved   *Savepoint // For save/restore cursor (DECSC/DECRC)
+
+	// Character sets (see charset.go)
+	charsets charsetState
+
+	// Editing state (see editing.go)
+	insertMode    bool           // IRM: drawn text pushes the rest of the line right
+	lrMarginMode  bool           // DECLRMM: CSI s sets left/right margins instead of saving the cursor
+	marginLeft    int            // Left margin (0-based)
+	marginRight   int            // Right margin (0-based)
+	lastChar      rune           // Last character drawn, for REP
+	cellWidthRows func() [][]int // Cell widths kept by HistoryScreen, moved along with the cells
 
 	// Simple state
 	title     string
@@ -33,8 +47,39 @@ type NativeScreen struct {
 	scrollTop       int  // Top of scroll region (0-based)
 	scrollBottom    int  // Bottom of scroll region (0-based)
 	scrollRegionSet bool // Whether custom scroll region is active
+
+	// Cursor style from DECSCUSR, CursorStyleDefault until the host sets one
+	cursorStyle int
+
+	// Keyboard protocols (see keyboard.go)
+	modifyOtherKeys       int
+	keyboardFlags         []int // Kitty flags stack, current flags last
+	inactiveKeyboardFlags []int // Stack of the other screen, main or alternate
+
+	// Synchronized update (mode 2026, see synchronized.go)
+	syncUpdate      bool
+	syncUpdateStart time.Time
+
+	// Colors set by the host with OSC 4/10/11/12 (see palette.go)
+	colorOverrides map[int]color.RGBA
+	colorVersion   int
+	colorProvider  ColorProvider
+
+	// Replies to the host, nil to drop them
+	responder func(data string)
 }
 
+// DECSCUSR cursor styles (CSI Ps SP q)
+const (
+	CursorStyleDefault         = 0 // Whatever the user configured
+	CursorStyleBlinkBlock      = 1
+	CursorStyleSteadyBlock     = 2
+	CursorStyleBlinkUnderline  = 3
+	CursorStyleSteadyUnderline = 4
+	CursorStyleBlinkBar        = 5
+	CursorStyleSteadyBar       = 6
+)
+
 type Margins struct {
 	Top    int
 	Bottom int
@@ -62,8 +107,28 @@ type Attributes struct {
 	Strikethrough bool
 	Reverse       bool
 	Blink         bool
+	Faint         bool
+	Conceal       bool
+	Overline      bool
+	Link          string // OSC 8 hyperlink target, empty if none
+
+	// UnderlineStyle is how Underscore is drawn, UnderlineSingle unless the
+	// host asked for another with SGR 21 or 4:n
+	UnderlineStyle int
+	// UnderlineColor is the SGR 58 color, empty for the text color
+	UnderlineColor string
 }
 
+// Underline styles, numbered as in SGR 4:n
+const (
+	UnderlineNone = iota
+	UnderlineSingle
+	UnderlineDouble
+	UnderlineCurly
+	UnderlineDotted
+	UnderlineDashed
+)
+
 // NewNativeScreen creates a new terminal screen
 
 func NewNativeScreen(columns, lines int) *NativeScreen {
@@ -72,6 +137,7 @@ func NewNativeScreen(columns, lines int) *NativeScreen {
 		lines:           lines,
 		buffer:          make([][]rune, lines),
 		attrs:           make([][]Attributes, lines),
+		wrapped:         make([]bool, lines),
 		cursor:          Cursor{X: 0, Y: 0},
 		autoWrap:        true,
 		newlineMode:     true, // Default to Unix behavior where LF implies CR
@@ -79,6 +145,7 @@ func NewNativeScreen(columns, lines int) *NativeScreen {
 		scrollTop:       0,
 		scrollBottom:    lines - 1,
 		scrollRegionSet: false,
+		marginRight:     columns - 1,
 	}
 
 	// Initialize buffer with spaces
@@ -99,23 +166,31 @@ func NewNativeScreen(columns, lines int) *NativeScreen {
 }
 
 func (s *NativeScreen) Draw(text string) {
+	text = s.beginDraw(text)
 	for _, ch := range text {
-		// Check if we need to wrap
-		if s.cursor.X >= s.columns {
+		// Check if we need to wrap, at the right margin when inside the margins
+		start, end := s.wrapColumns()
+		if s.cursor.X >= end {
 			if s.autoWrap {
-				s.cursor.X = 0
-				s.cursor.Y++
-				if s.cursor.Y >= s.lines {
-					s.scrollUp()
-					s.cursor.Y = s.lines - 1
+				s.setWrapped(s.cursor.Y, true)
+				s.cursor.X = start
+				if !s.indexInMargins() {
+					s.cursor.Y++
+					if s.cursor.Y >= s.lines {
+						s.scrollUp()
+						s.cursor.Y = s.lines - 1
+					}
 				}
 			} else {
-				s.cursor.X = s.columns - 1
+				s.cursor.X = end - 1
 			}
 		}
 
 		// Place character
 		if s.cursor.Y < s.lines && s.cursor.X < s.columns {
+			if s.insertMode {
+				s.insertCells(s.cursor.Y, s.cursor.X, 1)
+			}
 			s.buffer[s.cursor.Y][s.cursor.X] = ch
 			s.attrs[s.cursor.Y][s.cursor.X] = s.cursor.Attrs
 			s.cursor.X++
@@ -125,12 +200,10 @@ func (s *NativeScreen) Draw(text string) {
 
 // 8. SavePoint support (for DECSC/DECRC)
 type Savepoint struct {
-	Cursor    Cursor
-	G0Charset []rune
-	G1Charset []rune
-	Charset   int
-	Origin    bool // DECOM mode
-	Wrap      bool // DECAWM mode
+	Cursor   Cursor // Position and attributes
+	Charsets charsetState
+	Origin   bool // DECOM mode
+	Wrap     bool // DECAWM mode
 }
 
 func (s *NativeScreen) Bell() {
@@ -155,15 +228,12 @@ func (s *NativeScreen) Tab() {
 }
 
 func (s *NativeScreen) CarriageReturn() {
-	s.cursor.X = 0
-}
-
-func (s *NativeScreen) ShiftOut() {
-	// Character set switching - implement when needed
-}
-
-func (s *NativeScreen) ShiftIn() {
-	// Character set switching - implement when needed
+	// Returns to the left margin, unless already left of it
+	if s.lrMarginMode && s.cursor.X >= s.marginLeft {
+		s.cursor.X = s.marginLeft
+	} else {
+		s.cursor.X = 0
+	}
 }
 
 // === Cursor Movement ===
@@ -255,8 +325,10 @@ func (s *NativeScreen) CursorPosition(line, column int) {
 		}
 	}
 
-	// Clamp X coordinate (always to full screen width)
-	if newX < 0 {
+	// Clamp X coordinate, to the left and right margins in origin mode
+	if s.decomMode && s.lrMarginMode {
+		newX = min(max(newX+s.marginLeft, s.marginLeft), s.marginRight)
+	} else if newX < 0 {
 		newX = 0
 	} else if newX >= s.columns {
 		newX = s.columns - 1
@@ -271,7 +343,10 @@ func (s *NativeScreen) CursorPosition(line, column int) {
 
 func (s *NativeScreen) CursorToColumn(column int) {
 	s.cursor.X = column - 1
-	if s.cursor.X < 0 {
+	if s.decomMode && s.lrMarginMode {
+		// Relative to the left margin in origin mode
+		s.cursor.X = min(max(s.cursor.X+s.marginLeft, s.marginLeft), s.marginRight)
+	} else if s.cursor.X < 0 {
 		s.cursor.X = 0
 	} else if s.cursor.X >= s.columns {
 		s.cursor.X = s.columns - 1
@@ -310,14 +385,30 @@ func (s *NativeScreen) Reset() {
 			s.attrs[i][j] = Attributes{}
 		}
 	}
+	s.wrapped = make([]bool, s.lines)
 
 	// Reset cursor
 	s.cursor = Cursor{X: 0, Y: 0}
 	s.saved = nil
+	s.cursorStyle = CursorStyleDefault
+	s.charsets = charsetState{}
+	s.lastChar = 0
+
+	// Drop the colors set by the host
+	if len(s.colorOverrides) > 0 {
+		s.colorOverrides = nil
+		s.colorVersion++
+	}
 
 	// Reset modes
 	s.autoWrap = true
 	s.newlineMode = true
+	s.decomMode = false
+	s.insertMode = false
+	s.lrMarginMode = false
+	s.resetLeftRightMargins()
+	s.syncUpdate = false
+	s.resetKeyboard()
 
 	// Reset scroll regions
 	s.scrollTop = 0
@@ -337,6 +428,7 @@ func (s *NativeScreen) scrollWithinMargins(top, bottom int) {
 		s.buffer[y] = s.buffer[y+1]
 		s.attrs[y] = s.attrs[y+1]
 	}
+	s.scrollWrapped(top, bottom)
 
 	// Clear the bottom line in margin
 	s.buffer[bottom] = make([]rune, s.columns)
@@ -353,6 +445,7 @@ func (s *NativeScreen) reverseScrollWithinMargins(top, bottom int) {
 		s.buffer[y] = s.buffer[y-1]
 		s.attrs[y] = s.attrs[y-1]
 	}
+	s.reverseScrollWrapped(top, bottom)
 
 	// Clear the top line in margin
 	s.buffer[top] = make([]rune, s.columns)
@@ -363,6 +456,13 @@ func (s *NativeScreen) reverseScrollWithinMargins(top, bottom int) {
 	}
 }
 
+// resetAttributesKeepLink returns default attributes with the hyperlink of attrs
+func resetAttributesKeepLink(attrs Attributes) Attributes {
+	reset := DefaultAttributes()
+	reset.Link = attrs.Link
+	return reset
+}
+
 func DefaultAttributes() Attributes {
 	return Attributes{
 		Fg: "default",
@@ -372,39 +472,54 @@ func DefaultAttributes() Attributes {
 
 func (s *NativeScreen) SelectGraphicRendition(params []int) {
 	if len(params) == 0 || (len(params) == 1 && params[0] == 0) {
-		// Reset all attributes
-		s.cursor.Attrs = DefaultAttributes()
+		// Reset all attributes (an open hyperlink is not an SGR attribute)
+		s.cursor.Attrs = resetAttributesKeepLink(s.cursor.Attrs)
 		return
 	}
 
 	for i := 0; i < len(params); i++ {
 		switch params[i] {
 		case 0: // Reset
-			s.cursor.Attrs = DefaultAttributes()
+			s.cursor.Attrs = resetAttributesKeepLink(s.cursor.Attrs)
 		case 1: // Bold
 			s.cursor.Attrs.Bold = true
+		case 2: // Faint
+			s.cursor.Attrs.Faint = true
 		case 3: // Italic
 			s.cursor.Attrs.Italics = true
 		case 4: // Underline
-			s.cursor.Attrs.Underscore = true
+			s.SetUnderlineStyle(UnderlineSingle)
 		case 5: // Blink
 			s.cursor.Attrs.Blink = true
 		case 7: // Reverse
 			s.cursor.Attrs.Reverse = true
+		case 8: // Conceal
+			s.cursor.Attrs.Conceal = true
 		case 9: // Strikethrough
 			s.cursor.Attrs.Strikethrough = true
-		case 22: // Not bold
+		case 21: // Double underline
+			s.SetUnderlineStyle(UnderlineDouble)
+		case 22: // Neither bold nor faint
 			s.cursor.Attrs.Bold = false
+			s.cursor.Attrs.Faint = false
 		case 23: // Not italic
 			s.cursor.Attrs.Italics = false
 		case 24: // Not underline
-			s.cursor.Attrs.Underscore = false
+			s.SetUnderlineStyle(UnderlineNone)
 		case 25: // Not blink
 			s.cursor.Attrs.Blink = false
 		case 27: // Not reverse
 			s.cursor.Attrs.Reverse = false
+		case 28: // Reveal
+			s.cursor.Attrs.Conceal = false
 		case 29: // Not strikethrough
 			s.cursor.Attrs.Strikethrough = false
+		case 53: // Overline
+			s.cursor.Attrs.Overline = true
+		case 55: // Not overline
+			s.cursor.Attrs.Overline = false
+		case 59: // Default underline color
+			s.cursor.Attrs.UnderlineColor = ""
 		// Foreground colors
 		case 30:
 			s.cursor.Attrs.Fg = "black"
@@ -443,20 +558,68 @@ func (s *NativeScreen) SelectGraphicRendition(params []int) {
 			s.cursor.Attrs.Bg = "white"
 		case 49:
 			s.cursor.Attrs.Bg = "default"
-		// 256 colors
-		case 38, 48:
-			if i+2 < len(params) && params[i+1] == 5 {
-				// 256 color mode
-				color := params[i+2]
-				if params[i] == 38 {
-					s.cursor.Attrs.Fg = color256ToString(color)
-				} else {
-					s.cursor.Attrs.Bg = color256ToString(color)
+		// 256 and direct colors
+		case 38, 48, 58:
+			color, used := extendedColor(params[i+1:])
+			if color != "" {
+				switch params[i] {
+				case 38:
+					s.cursor.Attrs.Fg = color
+				case 48:
+					s.cursor.Attrs.Bg = color
+				default:
+					s.cursor.Attrs.UnderlineColor = color
 				}
-				i += 2
 			}
+			i += used
+		default:
+			// Bright colors
+			switch n := params[i]; {
+			case n >= 90 && n <= 97:
+				s.cursor.Attrs.Fg = ansiColorNames[n-90+8]
+			case n >= 100 && n <= 107:
+				s.cursor.Attrs.Bg = ansiColorNames[n-100+8]
+			}
+		}
+	}
+}
+
+// extendedColor reads the arguments of SGR 38/48/58: "5;n" for a palette
+// color, "colorN", or "2;r;g;b" for a direct color, "#rrggbb". It returns
+// the color, empty if invalid, and how many parameters it used.
+func extendedColor(args []int) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	switch args[0] {
+	case 5:
+		if len(args) < 2 {
+			return "", len(args)
+		}
+		if args[1] > 255 {
+			return "", 2
 		}
+		return color256ToString(args[1]), 2
+	case 2:
+		if len(args) < 4 {
+			return "", len(args)
+		}
+		if args[1] > 255 || args[2] > 255 || args[3] > 255 {
+			return "", 4
+		}
+		return fmt.Sprintf("#%02x%02x%02x", args[1], args[2], args[3]), 4
 	}
+	return "", 1
+}
+
+// SetUnderlineStyle sets the underline of new text, SGR 4:n. Underscore is
+// set for every style but UnderlineNone; unknown styles are ignored.
+func (s *NativeScreen) SetUnderlineStyle(style int) {
+	if style < UnderlineNone || style > UnderlineDashed {
+		return
+	}
+	s.cursor.Attrs.UnderlineStyle = style
+	s.cursor.Attrs.Underscore = style != UnderlineNone
 }
 
 // Helper for 256 color conversion
@@ -469,6 +632,9 @@ func color256ToString(n int) string {
 // In screen.go, fix the Index() method:
 
 func (s *NativeScreen) Index() {
+	if s.indexInMargins() {
+		return
+	}
 	if s.scrollRegionSet {
 		// Within scroll region - check if we need to scroll
 		if s.cursor.Y >= s.scrollBottom {
@@ -496,6 +662,12 @@ func (s *NativeScreen) Index() {
 
 // Also fix the Linefeed method in screen.go:
 func (s *NativeScreen) Linefeed() {
+	if s.indexInMargins() {
+		if s.newlineMode {
+			s.CarriageReturn()
+		}
+		return
+	}
 	if s.scrollRegionSet {
 		// Within scroll region - check if we need to scroll
 		if s.cursor.Y >= s.scrollBottom {
@@ -527,6 +699,9 @@ func (s *NativeScreen) Linefeed() {
 }
 
 func (s *NativeScreen) ReverseIndex() {
+	if s.reverseIndexInMargins() {
+		return
+	}
 	if s.scrollRegionSet {
 		// Within scroll region - check boundaries
 		if s.cursor.Y <= s.scrollTop {
@@ -562,14 +737,32 @@ func (s *NativeScreen) ClearTabStop(how int) {
 	}
 }
 
+// SaveCursor saves the cursor position and attributes, the character sets,
+// and origin and autowrap modes (DECSC)
 func (s *NativeScreen) SaveCursor() {
-	saved := s.cursor // Copy
-	s.saved = &saved
+	s.saved = &Savepoint{
+		Cursor:   s.cursor,
+		Charsets: s.charsets,
+		Origin:   s.decomMode,
+		Wrap:     s.autoWrap,
+	}
 }
 
+// RestoreCursor restores what SaveCursor saved (DECRC). With nothing saved
+// the cursor goes home with default attributes and origin mode off.
 func (s *NativeScreen) RestoreCursor() {
-	if s.saved != nil {
-		s.cursor = *s.saved
+	if s.saved == nil {
+		s.cursor = Cursor{Attrs: resetAttributesKeepLink(s.cursor.Attrs), Hidden: s.cursor.Hidden}
+		s.charsets = charsetState{}
+		s.decomMode = false
+	} else {
+		// DECTCEM visibility is not part of the saved cursor
+		hidden := s.cursor.Hidden
+		s.cursor = s.saved.Cursor
+		s.cursor.Hidden = hidden
+		s.charsets = s.saved.Charsets
+		s.decomMode = s.saved.Origin
+		s.autoWrap = s.saved.Wrap
 		// Ensure cursor is within current scroll region bounds
 		if s.scrollRegionSet {
 			if s.cursor.Y < s.scrollTop {
@@ -596,76 +789,34 @@ func (s *NativeScreen) RestoreCursor() {
 
 func (s *NativeScreen) InsertLines(count int) {
 	// Insert blank lines at cursor position within scroll region
-	top := s.scrollTop
-	bottom := s.scrollBottom
-
-	if s.cursor.Y < top || s.cursor.Y > bottom {
+	if s.cursor.Y < s.scrollTop || s.cursor.Y > s.scrollBottom {
 		return // Outside scroll region
 	}
-
-	for i := 0; i < count && s.cursor.Y <= bottom; i++ {
-		// Shift lines down within scroll region
-		for y := bottom; y > s.cursor.Y; y-- {
-			s.buffer[y] = s.buffer[y-1]
-			s.attrs[y] = s.attrs[y-1]
-		}
-
-		// Clear the inserted line
-		s.buffer[s.cursor.Y] = make([]rune, s.columns)
-		s.attrs[s.cursor.Y] = make([]Attributes, s.columns)
-		for j := 0; j < s.columns; j++ {
-			s.buffer[s.cursor.Y][j] = ' '
-			s.attrs[s.cursor.Y][j] = DefaultAttributes()
-		}
+	if left, right := s.leftRightMargins(); s.cursor.X < left || s.cursor.X > right {
+		return // Outside left/right margins
 	}
+	s.scrollRect(s.cursor.Y, s.scrollBottom, -count)
 }
 
 func (s *NativeScreen) DeleteLines(count int) {
 	// Delete lines at cursor position within scroll region
-	top := s.scrollTop
-	bottom := s.scrollBottom
-
-	if s.cursor.Y < top || s.cursor.Y > bottom {
+	if s.cursor.Y < s.scrollTop || s.cursor.Y > s.scrollBottom {
 		return // Outside scroll region
 	}
-
-	for i := 0; i < count && s.cursor.Y <= bottom; i++ {
-		// Shift lines up within scroll region
-		for y := s.cursor.Y; y < bottom; y++ {
-			s.buffer[y] = s.buffer[y+1]
-			s.attrs[y] = s.attrs[y+1]
-		}
-
-		// Clear the last line in scroll region
-		s.buffer[bottom] = make([]rune, s.columns)
-		s.attrs[bottom] = make([]Attributes, s.columns)
-		for j := 0; j < s.columns; j++ {
-			s.buffer[bottom][j] = ' '
-			s.attrs[bottom][j] = DefaultAttributes()
-		}
+	if left, right := s.leftRightMargins(); s.cursor.X < left || s.cursor.X > right {
+		return // Outside left/right margins
 	}
+	s.scrollRect(s.cursor.Y, s.scrollBottom, count)
 }
 
 func (s *NativeScreen) InsertCharacters(count int) {
-	// Insert spaces at cursor position
-	line := s.buffer[s.cursor.Y]
-	for i := 0; i < count && s.cursor.X < s.columns; i++ {
-		// Shift characters right
-		copy(line[s.cursor.X+1:], line[s.cursor.X:s.columns-1])
-		line[s.cursor.X] = ' '
-	}
+	// Insert blanks at cursor position, up to the right margin
+	s.insertCells(s.cursor.Y, s.cursor.X, count)
 }
 
 func (s *NativeScreen) DeleteCharacters(count int) {
-	// Delete characters at cursor position
-	line := s.buffer[s.cursor.Y]
-	for i := 0; i < count && s.cursor.X < s.columns; i++ {
-		// Shift characters left
-		if s.cursor.X < s.columns-1 {
-			copy(line[s.cursor.X:], line[s.cursor.X+1:])
-		}
-		line[s.columns-1] = ' '
-	}
+	// Delete characters at cursor position, up to the right margin
+	s.deleteCells(s.cursor.Y, s.cursor.X, count)
 }
 
 func (s *NativeScreen) EraseCharacters(count int) {
@@ -683,6 +834,7 @@ func (s *NativeScreen) EraseInLine(how int, private bool) {
 			s.buffer[s.cursor.Y][x] = ' '
 			s.attrs[s.cursor.Y][x] = DefaultAttributes()
 		}
+		s.setWrapped(s.cursor.Y, false)
 	case 1: // From beginning to cursor
 		for x := 0; x <= s.cursor.X && x < s.columns; x++ {
 			s.buffer[s.cursor.Y][x] = ' '
@@ -693,6 +845,7 @@ func (s *NativeScreen) EraseInLine(how int, private bool) {
 			s.buffer[s.cursor.Y][x] = ' '
 			s.attrs[s.cursor.Y][x] = DefaultAttributes()
 		}
+		s.setWrapped(s.cursor.Y, false)
 	}
 }
 
@@ -706,6 +859,7 @@ func (s *NativeScreen) EraseInDisplay(how int) {
 				s.attrs[y][x] = DefaultAttributes()
 			}
 		}
+		s.clearWrapped(s.cursor.Y, s.lines-1)
 	case 1: // From beginning to cursor
 		s.EraseInLine(1, false)
 		for y := 0; y < s.cursor.Y; y++ {
@@ -714,6 +868,7 @@ func (s *NativeScreen) EraseInDisplay(how int) {
 				s.attrs[y][x] = DefaultAttributes()
 			}
 		}
+		s.clearWrapped(0, s.cursor.Y-1)
 	case 2, 3: // Entire screen
 		for y := 0; y < s.lines; y++ {
 			for x := 0; x < s.columns; x++ {
@@ -721,6 +876,7 @@ func (s *NativeScreen) EraseInDisplay(how int) {
 				s.attrs[y][x] = DefaultAttributes()
 			}
 		}
+		s.clearWrapped(0, s.lines-1)
 	}
 }
 
@@ -733,6 +889,12 @@ func (s *NativeScreen) SetMode(modes []int, private bool) {
 			switch mode {
 			case 7: // DECAWM - Auto wrap mode
 				s.autoWrap = true
+			case 25: // DECTCEM - Show cursor
+				s.cursor.Hidden = false
+			case 69: // DECLRMM - Left/right margin mode
+				s.lrMarginMode = true
+			case 2026: // Synchronized update
+				s.beginSyncUpdate()
 			case 6: // DECOM - Origin mode
 				s.decomMode = true
 				// Move cursor to origin of scroll region (or screen if no region)
@@ -748,6 +910,8 @@ func (s *NativeScreen) SetMode(modes []int, private bool) {
 		} else {
 			// Standard modes
 			switch mode {
+			case 4: // IRM - Insert mode
+				s.insertMode = true
 			case 20: // LNM - Newline mode
 				s.newlineMode = true
 			}
@@ -762,6 +926,13 @@ func (s *NativeScreen) ResetMode(modes []int, private bool) {
 			switch mode {
 			case 7: // DECAWM - Auto wrap mode
 				s.autoWrap = false
+			case 25: // DECTCEM - Hide cursor
+				s.cursor.Hidden = true
+			case 69: // DECLRMM - Left/right margin mode
+				s.lrMarginMode = false
+				s.resetLeftRightMargins()
+			case 2026: // Synchronized update
+				s.syncUpdate = false
 			case 6: // DECOM - Origin mode
 				s.decomMode = false
 				// Move cursor to absolute screen origin
@@ -772,6 +943,8 @@ func (s *NativeScreen) ResetMode(modes []int, private bool) {
 		} else {
 			// Standard modes
 			switch mode {
+			case 4: // IRM - Insert mode
+				s.insertMode = false
 			case 20: // LNM - Newline mode
 				s.newlineMode = false
 			}
@@ -779,10 +952,6 @@ func (s *NativeScreen) ResetMode(modes []int, private bool) {
 	}
 }
 
-func (s *NativeScreen) DefineCharset(code, mode string) {
-	// TODO: Implement charset switching
-}
-
 func (s *NativeScreen) SetMargins(top, bottom int) {
 	log.Printf("SetMargins called: top=%d, bottom=%d (screen: %dx%d)", top, bottom, s.columns, s.lines)
 
@@ -828,6 +997,58 @@ func (s *NativeScreen) ReportDeviceStatus(mode int) {
 	// TODO: Implement if needed
 }
 
+// ReportMode answers DECRQM (CSI Ps $ p, or CSI ? Ps $ p for DEC modes)
+// with CSI Ps ; Pm $ y, where Pm is 1 for set, 2 for reset and 0 for modes
+// the screen does not know.
+func (s *NativeScreen) ReportMode(mode int, private bool) {
+	state, known := s.modeState(mode, private)
+	s.writeModeReport(mode, private, state, known)
+}
+
+// modeState returns whether a mode is set, and false if it is not one the
+// screen tracks
+func (s *NativeScreen) modeState(mode int, private bool) (set, known bool) {
+	if !private {
+		switch mode {
+		case 4: // IRM
+			return s.insertMode, true
+		case 20: // LNM
+			return s.newlineMode, true
+		}
+		return false, false
+	}
+
+	switch mode {
+	case 6: // DECOM
+		return s.decomMode, true
+	case 7: // DECAWM
+		return s.autoWrap, true
+	case 25: // DECTCEM
+		return !s.cursor.Hidden, true
+	case 69: // DECLRMM
+		return s.lrMarginMode, true
+	case 2026: // Synchronized update
+		return s.syncUpdate, true
+	}
+	return false, false
+}
+
+// writeModeReport sends the DECRPM reply for ReportMode
+func (s *NativeScreen) writeModeReport(mode int, private, set, known bool) {
+	prefix := CSI
+	if private {
+		prefix += "?"
+	}
+	value := 0
+	if known {
+		value = 2
+		if set {
+			value = 1
+		}
+	}
+	s.WriteProcessInput(fmt.Sprintf("%s%d;%d$y", prefix, mode, value))
+}
+
 func (s *NativeScreen) SetTitle(title string) {
 	s.title = title
 }
@@ -836,6 +1057,12 @@ func (s *NativeScreen) SetIconName(name string) {
 	s.iconName = name
 }
 
+// SetHyperlink starts (or with an empty uri ends) an OSC 8 hyperlink.
+// Cells drawn while a link is active carry it in Attributes.Link.
+func (s *NativeScreen) SetHyperlink(uri string) {
+	s.cursor.Attrs.Link = uri
+}
+
 func (s *NativeScreen) AlignmentDisplay() {
 	// Fill screen with 'E' for alignment test
 	for y := 0; y < s.lines; y++ {
@@ -843,14 +1070,18 @@ func (s *NativeScreen) AlignmentDisplay() {
 			s.buffer[y][x] = 'E'
 		}
 	}
+	s.clearWrapped(0, s.lines-1)
 }
 
 func (s *NativeScreen) Debug(args ...interface{}) {
 	// Could log somewhere if needed
 }
 
+// WriteProcessInput sends a reply to the host through the response handler
 func (s *NativeScreen) WriteProcessInput(data string) {
-	// This would write back to the process - not needed for basic emulation
+	if s.responder != nil {
+		s.responder(data)
+	}
 }
 
 // === Helper methods ===
@@ -863,6 +1094,7 @@ func (s *NativeScreen) scrollUp() {
 		// Full screen scroll
 		copy(s.buffer[0:], s.buffer[1:])
 		copy(s.attrs[0:], s.attrs[1:])
+		s.scrollWrapped(0, s.lines-1)
 
 		// Clear the last line
 		lastLine := s.lines - 1
@@ -883,6 +1115,7 @@ func (s *NativeScreen) scrollDown() {
 		// Full screen reverse scroll
 		copy(s.buffer[1:], s.buffer[0:s.lines-1])
 		copy(s.attrs[1:], s.attrs[0:s.lines-1])
+		s.reverseScrollWrapped(0, s.lines-1)
 
 		// Clear the first line
 		s.buffer[0] = make([]rune, s.columns)
@@ -908,11 +1141,33 @@ func (s *NativeScreen) GetCursor() (int, int) {
 	return s.cursor.X, s.cursor.Y
 }
 
+// SetCursorStyle handles DECSCUSR. Unknown styles are ignored.
+func (s *NativeScreen) SetCursorStyle(style int) {
+	if style >= CursorStyleDefault && style <= CursorStyleSteadyBar {
+		s.cursorStyle = style
+	}
+}
+
+// GetCursorStyle returns the DECSCUSR style set by the host
+func (s *NativeScreen) GetCursorStyle() int {
+	return s.cursorStyle
+}
+
+// IsCursorHidden reports whether the cursor is hidden (DECTCEM)
+func (s *NativeScreen) IsCursorHidden() bool {
+	return s.cursor.Hidden
+}
+
+func (s *NativeScreen) GetTitle() string {
+	return s.title
+}
+
 // Resize adjusts columns/lines on the base NativeScreen.
 // - Column shrink: hard-truncate each row; grow: right-pad with spaces + default attrs
 // - Row shrink: drop bottom rows; grow: append blank rows
 // - Rebuild tab stops every 8 cols
 // - Clamp cursor and scroll regions
+// HistoryScreen reflows wrapped lines instead (see reflow.go).
 func (s *NativeScreen) Resize(newCols, newLines int) {
 	if newCols <= 0 || newLines <= 0 {
 		return
@@ -961,6 +1216,9 @@ func (s *NativeScreen) Resize(newCols, newLines int) {
 		// shrink: keep top portion, drop bottom lines
 		s.buffer = s.buffer[:newLines]
 		s.attrs = s.attrs[:newLines]
+		if len(s.wrapped) > newLines {
+			s.wrapped = s.wrapped[:newLines]
+		}
 	} else if newLines > oldLines {
 		// grow: append blank rows
 		add := newLines - oldLines
@@ -976,6 +1234,12 @@ func (s *NativeScreen) Resize(newCols, newLines int) {
 		}
 	}
 
+	s.setGeometry(newCols, newLines)
+}
+
+// setGeometry commits a new size once the buffers have been resized:
+// clamps the cursor and scroll region and rebuilds the tab stops
+func (s *NativeScreen) setGeometry(newCols, newLines int) {
 	// Commit new geometry
 	s.columns = newCols
 	s.lines = newLines
@@ -992,6 +1256,7 @@ func (s *NativeScreen) Resize(newCols, newLines int) {
 		s.scrollBottom = newLines - 1
 		s.scrollRegionSet = false
 	}
+	s.resetLeftRightMargins()
 
 	// Clamp cursor
 	if s.cursor.Y >= s.lines {
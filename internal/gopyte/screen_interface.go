@@ -1,5 +1,7 @@
 package gopyte
 
+import "image/color"
+
 // Screen represents a terminal screen that can handle ANSI escape sequences
 type Screen interface {
 	// Basic drawing
@@ -22,15 +24,19 @@ type Screen interface {
 	CursorPosition(line, column int)
 	CursorToColumn(column int)
 	CursorToLine(line int)
+	CursorForwardTab(count int)
+	CursorBackTab(count int)
 
 	// Screen manipulation
 	Reset()
+	SoftReset()
 	Index()
 	ReverseIndex()
 	SetTabStop()
 	ClearTabStop(how int)
 	SaveCursor()
 	RestoreCursor()
+	SetCursorStyle(style int)
 
 	// Line operations
 	InsertLines(count int)
@@ -40,6 +46,9 @@ type Screen interface {
 	EraseCharacters(count int)
 	EraseInLine(how int, private bool)
 	EraseInDisplay(how int)
+	RepeatCharacter(count int)
+	ScrollContentUp(count int)
+	ScrollContentDown(count int)
 
 	// Mode setting
 	SetMode(modes []int, private bool)
@@ -47,20 +56,43 @@ type Screen interface {
 
 	// Character sets
 	DefineCharset(code, mode string)
+	LockingShift(set int)
+	SingleShift(set int)
 
 	// Scrolling regions
 	SetMargins(top, bottom int)
+	SetLeftRightMargins(left, right int)
 
 	// Graphics
 	SelectGraphicRendition(params []int)
+	SetUnderlineStyle(style int)
 
 	// Reporting
 	ReportDeviceAttributes(mode int, private bool)
 	ReportDeviceStatus(mode int)
+	ReportStatusString(setting string)
+	ReportMode(mode int, private bool)
+
+	// Keyboard protocols
+	SetModifyOtherKeys(level int)
+	PushKeyboardFlags(flags int)
+	PopKeyboardFlags(count int)
+	SetKeyboardFlags(flags, how int)
+	ReportKeyboardFlags()
 
 	// Window operations
 	SetTitle(title string)
 	SetIconName(name string)
+	SetHyperlink(uri string)
+
+	// Shell integration (OSC 133)
+	PromptMark(mark string, params []string)
+
+	// Colors (OSC 4/10/11/12 and their resets). Slots are palette indexes
+	// or ColorForeground, ColorBackground and ColorCursor.
+	SetColor(slot int, c color.RGBA)
+	ResetColor(slot int)
+	QueryColor(slot int, terminator string)
 
 	// Misc
 	AlignmentDisplay()
@@ -0,0 +1,677 @@
+package gopyte
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Scrollback stores History lines compactly so that 100k+ line scrollback
+// stays cheap:
+//
+//   - Each line keeps its text as a UTF-8 string with trailing blank cells
+//     dropped, its attributes as runs of indexes into a shared palette, and
+//     cell widths only when the line holds wide characters.
+//   - Lines live in a ring buffer that grows on demand up to its capacity,
+//     after which the oldest line is overwritten.
+//   - With spilling enabled the ring only holds the newest lines; older lines
+//     are written to a temp file a page at a time and read back on demand.
+
+const (
+	// DefaultScrollbackLines is used when no scrollback size is configured
+	DefaultScrollbackLines = 1000
+
+	// ScrollbackMemoryLines is how many lines stay in memory when spilling
+	ScrollbackMemoryLines = 10000
+
+	scrollbackPageLines = 1024            // Lines written to the spill file at a time
+	spillCompactSize    = 4 * 1024 * 1024 // Spill file size worth reclaiming dead pages at
+	paletteCompactSize  = 1024            // Palette size worth dropping unused attributes at
+)
+
+// attrRun is a run of cells sharing one attribute
+type attrRun struct {
+	attr  uint32 // Index into the Scrollback palette
+	count uint32
+}
+
+// compactLine is a HistoryLine with trailing blanks dropped and attributes
+// run-length encoded
+type compactLine struct {
+	text    string    // Kept cells, NUL for wide character continuations
+	runs    []attrRun // Attributes of the kept cells
+	widths  []uint8   // Widths of the kept cells, nil when all are 1
+	cols    int       // Cells in the line as recorded
+	wrapped bool
+}
+
+// spillPage is a page of lines in the spill file
+type spillPage struct {
+	offset int64
+	size   int
+	lines  int
+	skip   int // Lines trimmed from the front of the page
+}
+
+// Scrollback is the History of a HistoryScreen, oldest line first
+type Scrollback struct {
+	maxLines int
+
+	// Newest lines, oldest at head
+	ring []compactLine
+	head int
+	size int
+
+	// Attribute palette shared by all lines
+	palette      []Attributes
+	paletteIndex map[Attributes]uint32
+	paletteLimit int // Palette size to compact at next, 0 for paletteCompactSize
+
+	// Spilled pages, oldest first; spill is nil when spilling is disabled
+	spill        *os.File
+	spillName    string // Path to remove on close, empty once removed
+	spillEnd     int64
+	pages        []spillPage
+	spilledLines int
+	cachedPage   int64 // Offset of the page in cachedLines, -1 if none
+	cachedLines  []compactLine
+}
+
+// NewScrollback creates an in-memory scrollback keeping up to maxLines lines
+func NewScrollback(maxLines int) *Scrollback {
+	if maxLines < 0 {
+		maxLines = 0
+	}
+	return &Scrollback{
+		maxLines:     maxLines,
+		paletteIndex: make(map[Attributes]uint32),
+		cachedPage:   -1,
+	}
+}
+
+// Len returns the number of lines stored
+func (s *Scrollback) Len() int {
+	return s.spilledLines + s.size
+}
+
+// MaxLines returns the maximum number of lines kept
+func (s *Scrollback) MaxLines() int {
+	return s.maxLines
+}
+
+// PaletteLen returns the number of distinct attributes stored
+func (s *Scrollback) PaletteLen() int {
+	return len(s.palette)
+}
+
+// IsSpilling reports whether old pages are written to a temp file
+func (s *Scrollback) IsSpilling() bool {
+	return s.spill != nil
+}
+
+// ringCapacity is how many lines the ring may hold before lines are spilled
+// or overwritten
+func (s *Scrollback) ringCapacity() int {
+	if s.spill != nil {
+		return min(s.maxLines, ScrollbackMemoryLines)
+	}
+	return s.maxLines
+}
+
+// Push appends a line, dropping the oldest lines beyond MaxLines
+func (s *Scrollback) Push(line HistoryLine) {
+	if s.maxLines == 0 {
+		return
+	}
+
+	if s.size >= s.ringCapacity() {
+		if s.spill != nil && s.maxLines > s.size {
+			if err := s.spillPage(); err != nil {
+				log.Printf("Scrollback: spilling to disk failed, keeping scrollback in memory: %v", err)
+				s.rebuild(s.maxLines, false)
+			}
+		}
+		if s.size >= s.ringCapacity() {
+			s.dropRing(1)
+		}
+	}
+
+	s.pushRing(s.encode(line))
+	s.trim()
+}
+
+// Line returns line i, 0 being the oldest
+func (s *Scrollback) Line(i int) HistoryLine {
+	if i < 0 || i >= s.Len() {
+		return HistoryLine{}
+	}
+	if i >= s.spilledLines {
+		return s.decode(s.ringLine(i - s.spilledLines))
+	}
+
+	for k := range s.pages {
+		p := &s.pages[k]
+		if i < p.lines-p.skip {
+			lines, err := s.loadPage(p)
+			if err != nil || p.skip+i >= len(lines) {
+				log.Printf("Scrollback: reading spilled line failed: %v", err)
+				return HistoryLine{}
+			}
+			return s.decode(lines[p.skip+i])
+		}
+		i -= p.lines - p.skip
+	}
+	return HistoryLine{}
+}
+
+// Clear removes all lines
+func (s *Scrollback) Clear() {
+	s.ring = nil
+	s.head, s.size = 0, 0
+	s.palette = nil
+	s.paletteIndex = make(map[Attributes]uint32)
+	s.paletteLimit = 0
+	s.clearSpill()
+}
+
+// Truncate drops the newest lines, keeping the first n
+func (s *Scrollback) Truncate(n int) {
+	n = max(n, 0)
+	if n >= s.Len() {
+		return
+	}
+	if n < s.spilledLines {
+		// Spilled pages are only ever dropped from the front: store the
+		// kept lines again
+		kept := make([]HistoryLine, n)
+		for i := range kept {
+			kept[i] = s.Line(i)
+		}
+		s.Clear()
+		for _, line := range kept {
+			s.Push(line)
+		}
+		return
+	}
+
+	keep := n - s.spilledLines
+	for i := keep; i < s.size; i++ {
+		s.ring[(s.head+i)%len(s.ring)] = compactLine{}
+	}
+	s.size = keep
+	if s.size == 0 {
+		s.head = 0
+	}
+}
+
+// SetMaxLines changes how many lines are kept, dropping the oldest lines
+// when shrinking
+func (s *Scrollback) SetMaxLines(maxLines int) {
+	if maxLines < 0 {
+		maxLines = 0
+	}
+	if maxLines == s.maxLines {
+		return
+	}
+	s.rebuild(maxLines, s.spill != nil)
+}
+
+// EnableSpill writes lines beyond ScrollbackMemoryLines to a temp file in
+// dir, or the default temp directory if dir is empty
+func (s *Scrollback) EnableSpill(dir string) error {
+	if s.spill != nil {
+		return nil
+	}
+	f, err := os.CreateTemp(dir, "tetherssh-scrollback-*")
+	if err != nil {
+		return fmt.Errorf("create scrollback spill file: %w", err)
+	}
+	s.spill = f
+	s.spillName = f.Name()
+
+	// Where open files can be removed (not Windows) the file disappears now,
+	// so it cannot be left behind even if Close is never called
+	if os.Remove(s.spillName) == nil {
+		s.spillName = ""
+	}
+
+	s.rebuild(s.maxLines, true)
+	return nil
+}
+
+// DisableSpill reads spilled lines back into memory and removes the temp file
+func (s *Scrollback) DisableSpill() {
+	if s.spill == nil {
+		return
+	}
+	s.rebuild(s.maxLines, false)
+}
+
+// Close drops the spilled lines and removes the spill file
+func (s *Scrollback) Close() {
+	if s.spill == nil {
+		return
+	}
+	s.clearSpill()
+	s.removeSpillFile()
+}
+
+// rebuild re-stores the newest lines with a new size and spill mode
+func (s *Scrollback) rebuild(maxLines int, spill bool) {
+	keep := min(s.Len(), maxLines)
+	lines := make([]compactLine, 0, keep)
+	for i := s.Len() - keep; i < s.Len(); i++ {
+		if i >= s.spilledLines {
+			lines = append(lines, s.ringLine(i-s.spilledLines))
+		} else {
+			// Decode and re-encode so the line does not depend on the page
+			lines = append(lines, s.encode(s.Line(i)))
+		}
+	}
+
+	s.clearSpill()
+	if !spill && s.spill != nil {
+		s.removeSpillFile()
+	}
+
+	s.maxLines = maxLines
+	s.ring = nil
+	s.head, s.size = 0, 0
+	for _, l := range lines {
+		if s.size >= s.ringCapacity() {
+			if err := s.spillPage(); err != nil {
+				log.Printf("Scrollback: spilling to disk failed: %v", err)
+				s.dropRing(1)
+			}
+		}
+		s.pushRing(l)
+	}
+	s.compactPalette()
+}
+
+// trim drops the oldest lines beyond maxLines
+func (s *Scrollback) trim() {
+	excess := s.Len() - s.maxLines
+	for excess > 0 && len(s.pages) > 0 {
+		p := &s.pages[0]
+		if n := p.lines - p.skip; excess >= n {
+			s.pages = s.pages[1:]
+			s.spilledLines -= n
+			excess -= n
+		} else {
+			p.skip += excess
+			s.spilledLines -= excess
+			excess = 0
+		}
+	}
+	if excess > 0 {
+		s.dropRing(excess)
+	}
+	s.compactSpill()
+	s.compactPalette()
+}
+
+// Ring buffer
+
+func (s *Scrollback) ringLine(i int) compactLine {
+	return s.ring[(s.head+i)%len(s.ring)]
+}
+
+func (s *Scrollback) pushRing(l compactLine) {
+	if s.size < len(s.ring) {
+		s.ring[(s.head+s.size)%len(s.ring)] = l
+		s.size++
+		return
+	}
+	if s.head != 0 {
+		// Straighten the ring before growing it
+		ring := make([]compactLine, s.size, s.size+1)
+		for i := range ring {
+			ring[i] = s.ringLine(i)
+		}
+		s.ring, s.head = ring, 0
+	}
+	s.ring = append(s.ring, l)
+	s.size++
+}
+
+// dropRing removes the n oldest lines of the ring
+func (s *Scrollback) dropRing(n int) {
+	n = min(n, s.size)
+	for i := 0; i < n; i++ {
+		s.ring[s.head] = compactLine{}
+		s.head = (s.head + 1) % len(s.ring)
+	}
+	s.size -= n
+	if s.size == 0 {
+		s.head = 0
+	}
+}
+
+// Line encoding
+
+// paletteAttr returns the palette index of an attribute, adding it if new
+func (s *Scrollback) paletteAttr(a Attributes) uint32 {
+	if idx, ok := s.paletteIndex[a]; ok {
+		return idx
+	}
+	idx := uint32(len(s.palette))
+	s.palette = append(s.palette, a)
+	s.paletteIndex[a] = idx
+	return idx
+}
+
+// compactPalette drops the attributes of trimmed lines from the palette once
+// it has doubled in size since it was last compacted. Lines refer to the
+// palette by index, so the ring and the spilled pages are rewritten.
+func (s *Scrollback) compactPalette() {
+	if len(s.palette) < max(s.paletteLimit, paletteCompactSize) {
+		return
+	}
+
+	const unused = ^uint32(0)
+	remap := make([]uint32, len(s.palette))
+	for i := range remap {
+		remap[i] = unused
+	}
+	var palette []Attributes
+	remapLine := func(l compactLine) compactLine {
+		runs := make([]attrRun, len(l.runs))
+		for i, r := range l.runs {
+			if remap[r.attr] == unused {
+				remap[r.attr] = uint32(len(palette))
+				palette = append(palette, s.palette[r.attr])
+			}
+			runs[i] = attrRun{attr: remap[r.attr], count: r.count}
+		}
+		l.runs = runs
+		return l
+	}
+
+	if len(s.pages) > 0 {
+		// Write the rewritten pages after the current ones, then move them
+		// to the start of the file
+		start, end := s.spillEnd, s.spillEnd
+		pages := make([]spillPage, 0, len(s.pages))
+		for i := range s.pages {
+			lines, err := s.loadPage(&s.pages[i])
+			if err == nil {
+				lines = lines[s.pages[i].skip:]
+				for j := range lines {
+					lines[j] = remapLine(lines[j])
+				}
+				buf := encodePage(lines)
+				_, err = s.spill.WriteAt(buf, end)
+				pages = append(pages, spillPage{offset: end, size: len(buf), lines: len(lines)})
+				end += int64(len(buf))
+			}
+			s.cachedPage, s.cachedLines = -1, nil
+			if err != nil {
+				log.Printf("Scrollback: compacting palette failed: %v", err)
+				if err := s.spill.Truncate(start); err != nil {
+					log.Printf("Scrollback: truncating spill file failed: %v", err)
+				}
+				return
+			}
+		}
+		s.pages, s.spillEnd = pages, end
+		s.moveSpill(start)
+	}
+
+	for i := 0; i < s.size; i++ {
+		idx := (s.head + i) % len(s.ring)
+		s.ring[idx] = remapLine(s.ring[idx])
+	}
+
+	s.palette = palette
+	s.paletteIndex = make(map[Attributes]uint32, len(palette))
+	for i, a := range palette {
+		s.paletteIndex[a] = uint32(i)
+	}
+	s.paletteLimit = 2 * len(palette)
+}
+
+// isTrailingBlank reports whether a cell is what decode pads lines with
+func isTrailingBlank(ch rune, a Attributes, width int) bool {
+	return ch == ' ' && width == 1 && a == DefaultAttributes()
+}
+
+func (s *Scrollback) encode(line HistoryLine) compactLine {
+	cells := rowCells(line.Chars, line.Attrs, line.CellWidths)
+	cols := len(cells)
+	for len(cells) > 0 {
+		c := cells[len(cells)-1]
+		if !isTrailingBlank(c.Char, c.Attrs, c.Width) {
+			break
+		}
+		cells = cells[:len(cells)-1]
+	}
+
+	l := compactLine{cols: cols, wrapped: line.Wrapped}
+	text := make([]rune, len(cells))
+	for x, c := range cells {
+		text[x] = c.Char
+		idx := s.paletteAttr(c.Attrs)
+		if n := len(l.runs); n > 0 && l.runs[n-1].attr == idx {
+			l.runs[n-1].count++
+		} else {
+			l.runs = append(l.runs, attrRun{attr: idx, count: 1})
+		}
+		if c.Width != 1 && l.widths == nil {
+			l.widths = make([]uint8, len(cells))
+			for i := range l.widths {
+				l.widths[i] = 1
+			}
+		}
+		if l.widths != nil {
+			l.widths[x] = uint8(c.Width)
+		}
+	}
+	l.text = string(text)
+	return l
+}
+
+func (s *Scrollback) decode(l compactLine) HistoryLine {
+	chars, attrs, widths := blankRow(l.cols)
+	x := 0
+	for _, ch := range l.text {
+		if x < l.cols {
+			chars[x] = ch
+		}
+		x++
+	}
+	x = 0
+	for _, r := range l.runs {
+		a := s.palette[r.attr]
+		for i := uint32(0); i < r.count && x < l.cols; i++ {
+			attrs[x] = a
+			x++
+		}
+	}
+	for i, w := range l.widths {
+		if i < l.cols {
+			widths[i] = int(w)
+		}
+	}
+	return HistoryLine{Chars: chars, Attrs: attrs, CellWidths: widths, Wrapped: l.wrapped}
+}
+
+// Spill file
+//
+// A page is its lines back to back, each line being uvarints for cols,
+// wrapped, text length, run count and widths length, followed by the text
+// bytes, the runs as attr/count uvarint pairs and the widths bytes.
+
+// spillPage moves the oldest page of the ring to the spill file
+func (s *Scrollback) spillPage() error {
+	if s.spill == nil {
+		return errors.New("spilling is not enabled")
+	}
+	n := min(scrollbackPageLines, s.size)
+	if n == 0 {
+		return nil
+	}
+
+	lines := make([]compactLine, n)
+	for i := range lines {
+		lines[i] = s.ringLine(i)
+	}
+	buf := encodePage(lines)
+	if _, err := s.spill.WriteAt(buf, s.spillEnd); err != nil {
+		return err
+	}
+	s.pages = append(s.pages, spillPage{offset: s.spillEnd, size: len(buf), lines: n})
+	s.spillEnd += int64(len(buf))
+	s.spilledLines += n
+	s.dropRing(n)
+	return nil
+}
+
+// encodePage encodes lines in the spill file format
+func encodePage(lines []compactLine) []byte {
+	var buf []byte
+	for _, l := range lines {
+		wrapped := uint64(0)
+		if l.wrapped {
+			wrapped = 1
+		}
+		buf = binary.AppendUvarint(buf, uint64(l.cols))
+		buf = binary.AppendUvarint(buf, wrapped)
+		buf = binary.AppendUvarint(buf, uint64(len(l.text)))
+		buf = binary.AppendUvarint(buf, uint64(len(l.runs)))
+		buf = binary.AppendUvarint(buf, uint64(len(l.widths)))
+		buf = append(buf, l.text...)
+		for _, r := range l.runs {
+			buf = binary.AppendUvarint(buf, uint64(r.attr))
+			buf = binary.AppendUvarint(buf, uint64(r.count))
+		}
+		buf = append(buf, l.widths...)
+	}
+	return buf
+}
+
+// loadPage reads a spilled page, keeping the last page read cached
+func (s *Scrollback) loadPage(p *spillPage) ([]compactLine, error) {
+	if s.cachedPage == p.offset && s.cachedLines != nil {
+		return s.cachedLines, nil
+	}
+
+	buf := make([]byte, p.size)
+	if _, err := s.spill.ReadAt(buf, p.offset); err != nil {
+		return nil, err
+	}
+
+	lines := make([]compactLine, 0, p.lines)
+	next := func() int {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			buf = nil
+			return 0
+		}
+		buf = buf[n:]
+		return int(v)
+	}
+	take := func(n int) []byte {
+		n = min(n, len(buf))
+		b := buf[:n]
+		buf = buf[n:]
+		return b
+	}
+	for len(lines) < p.lines && len(buf) > 0 {
+		l := compactLine{cols: next(), wrapped: next() == 1}
+		textLen, runs, widths := next(), next(), next()
+		l.text = string(take(textLen))
+		l.runs = make([]attrRun, runs)
+		for i := range l.runs {
+			l.runs[i] = attrRun{attr: uint32(next()), count: uint32(next())}
+		}
+		if widths > 0 {
+			l.widths = append([]uint8(nil), take(widths)...)
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) != p.lines {
+		return nil, fmt.Errorf("page at %d: read %d of %d lines", p.offset, len(lines), p.lines)
+	}
+
+	s.cachedPage, s.cachedLines = p.offset, lines
+	return lines, nil
+}
+
+// compactSpill reclaims the space of trimmed pages once they take up most
+// of the spill file, by moving the live pages to its start
+func (s *Scrollback) compactSpill() {
+	if s.spill == nil {
+		return
+	}
+	if len(s.pages) == 0 {
+		if s.spillEnd > 0 {
+			s.clearSpill()
+		}
+		return
+	}
+
+	dead := s.pages[0].offset
+	if s.spillEnd < spillCompactSize || dead < s.spillEnd/2 {
+		return
+	}
+	s.moveSpill(dead)
+}
+
+// moveSpill moves everything from offset dead on to the start of the spill
+// file, dropping what was before it
+func (s *Scrollback) moveSpill(dead int64) {
+	// Pages only move towards the start, so copying forward is safe
+	buf := make([]byte, 64*1024)
+	var moved int64
+	for src := dead; src < s.spillEnd; {
+		n, err := s.spill.ReadAt(buf[:min(int64(len(buf)), s.spillEnd-src)], src)
+		if n > 0 {
+			if _, werr := s.spill.WriteAt(buf[:n], moved); werr != nil {
+				err = werr
+			}
+		}
+		if err != nil && err != io.EOF {
+			log.Printf("Scrollback: compacting spill file failed: %v", err)
+			return
+		}
+		src += int64(n)
+		moved += int64(n)
+	}
+
+	for i := range s.pages {
+		s.pages[i].offset -= dead
+	}
+	s.spillEnd = moved
+	s.cachedPage, s.cachedLines = -1, nil
+	if err := s.spill.Truncate(s.spillEnd); err != nil {
+		log.Printf("Scrollback: truncating spill file failed: %v", err)
+	}
+}
+
+// clearSpill forgets all spilled pages, emptying the spill file
+func (s *Scrollback) clearSpill() {
+	s.pages = nil
+	s.spilledLines = 0
+	s.spillEnd = 0
+	s.cachedPage, s.cachedLines = -1, nil
+	if s.spill != nil {
+		if err := s.spill.Truncate(0); err != nil {
+			log.Printf("Scrollback: truncating spill file failed: %v", err)
+		}
+	}
+}
+
+// removeSpillFile closes and deletes the spill file
+func (s *Scrollback) removeSpillFile() {
+	s.spill.Close()
+	if s.spillName != "" {
+		if err := os.Remove(s.spillName); err != nil {
+			log.Printf("Scrollback: removing spill file failed: %v", err)
+		}
+	}
+	s.spill, s.spillName = nil, ""
+}
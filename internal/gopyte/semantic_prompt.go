@@ -0,0 +1,151 @@
+package gopyte
+
+import "strconv"
+
+// CommandMark is one shell command delimited by OSC 133 (FinalTerm)
+// semantic prompt sequences:
+//
+//	ESC ] 133 ; A ST            prompt starts
+//	ESC ] 133 ; B ST            prompt ends, command input starts
+//	ESC ] 133 ; C ST            command runs, output starts
+//	ESC ] 133 ; D [; exit] ST   command finished
+//
+// Line numbers index the scrollback content: History lines first, then the
+// screen rows. This is the same numbering GetLines uses.
+type CommandMark struct {
+	PromptLine  int // Line the prompt was drawn on
+	OutputLine  int // First line of output, -1 if the command never ran
+	EndLine     int // Line after the last line of output, -1 while running
+	ExitCode    int // Exit status from "D;<code>", valid if HasExitCode
+	HasExitCode bool
+}
+
+// Finished reports whether the shell sent the end-of-command mark
+func (m CommandMark) Finished() bool {
+	return m.EndLine >= 0
+}
+
+// PromptMark handles an OSC 133 mark. NativeScreen has no scrollback to
+// keep marks in; HistoryScreen records them.
+func (s *NativeScreen) PromptMark(mark string, params []string) {}
+
+// PromptMark records an OSC 133 mark at the cursor line
+func (h *HistoryScreen) PromptMark(mark string, params []string) {
+	// Lines are stored as absolute numbers so they survive scrolling into
+	// History; GetCommandMarks converts them back to content lines.
+	line := h.linesPushed + h.cursor.Y
+
+	switch mark {
+	case "A":
+		// A redrawn prompt (Ctrl+L, resize) replaces the mark it redraws
+		if m := h.lastCommandMark(); m != nil && m.PromptLine == line && m.OutputLine < 0 {
+			h.commandMarks = h.commandMarks[:len(h.commandMarks)-1]
+		}
+		h.commandMarks = append(h.commandMarks, CommandMark{
+			PromptLine: line,
+			OutputLine: -1,
+			EndLine:    -1,
+		})
+		h.trimCommandMarks()
+	case "C":
+		if m := h.lastCommandMark(); m != nil && m.OutputLine < 0 {
+			m.OutputLine = line
+		}
+	case "D":
+		m := h.lastCommandMark()
+		if m == nil || m.Finished() {
+			return
+		}
+		// Output that ended without a newline still counts its last line
+		m.EndLine = line
+		if h.cursor.X > 0 {
+			m.EndLine++
+		}
+		if len(params) > 0 {
+			if code, err := strconv.Atoi(params[0]); err == nil {
+				m.ExitCode = code
+				m.HasExitCode = true
+			}
+		}
+	}
+	// "B" only separates the prompt from what the user types
+}
+
+func (h *HistoryScreen) lastCommandMark() *CommandMark {
+	if len(h.commandMarks) == 0 {
+		return nil
+	}
+	return &h.commandMarks[len(h.commandMarks)-1]
+}
+
+// trimmedLines returns how many lines have fallen off the top of History
+func (h *HistoryScreen) trimmedLines() int {
+	return h.linesPushed - h.History.Len()
+}
+
+// trimCommandMarks drops marks whose prompt is no longer in History
+func (h *HistoryScreen) trimCommandMarks() {
+	trimmed := h.trimmedLines()
+	keep := 0
+	for keep < len(h.commandMarks) && h.commandMarks[keep].PromptLine < trimmed {
+		keep++
+	}
+	h.commandMarks = h.commandMarks[keep:]
+}
+
+// clearCommandMarks forgets all marks, e.g. when History is cleared
+func (h *HistoryScreen) clearCommandMarks() {
+	h.commandMarks = nil
+}
+
+// GetCommandMarks returns the recorded commands, oldest first, with line
+// numbers relative to the current scrollback content
+func (h *HistoryScreen) GetCommandMarks() []CommandMark {
+	trimmed := h.trimmedLines()
+
+	var marks []CommandMark
+	for _, m := range h.commandMarks {
+		if m.PromptLine < trimmed {
+			continue
+		}
+		m.PromptLine -= trimmed
+		if m.OutputLine >= 0 {
+			m.OutputLine -= trimmed
+		}
+		if m.EndLine >= 0 {
+			m.EndLine -= trimmed
+		}
+		marks = append(marks, m)
+	}
+	return marks
+}
+
+// PromptMark ignores marks from programs running on the alternate screen
+func (w *WideCharScreen) PromptMark(mark string, params []string) {
+	if w.usingAlternate {
+		return
+	}
+	w.HistoryScreen.PromptMark(mark, params)
+}
+
+// GetCommandMarks returns no marks while the alternate screen is active
+func (w *WideCharScreen) GetCommandMarks() []CommandMark {
+	if w.usingAlternate {
+		return nil
+	}
+	return w.HistoryScreen.GetCommandMarks()
+}
+
+// GetLines returns scrollback content lines [start, end), History first
+func (w *WideCharScreen) GetLines(start, end int) []string {
+	return w.renderLinesInRange(start, end)
+}
+
+// GetDisplayStart returns the content line of the first line GetDisplay
+// returned, for mapping CommandMark lines onto the display
+func (w *WideCharScreen) GetDisplayStart() int {
+	if w.usingAlternate {
+		return 0
+	}
+	return w.viewportStart
+}
@@ -1,10 +1,13 @@
 package gopyte
 
 import (
+	"bytes"
 	"log"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type Stream struct {
@@ -12,18 +15,33 @@ type Stream struct {
 	strict   bool
 	useUTF8  bool
 
+	// Trailing bytes of an incomplete UTF-8 sequence, completed by the next FeedBytes
+	utf8Pending []byte
+
 	// Parser state
 	state           ParserState
 	takingPlainText bool
 	params          []int
+	subParams       []bool // Whether each param followed a ":", as in SGR 4:3
+	colon           bool   // A ":" was seen, the next param is a sub-parameter
 	currentParam    string
 	private         bool
-	oscParam        string
+	intermediate    string          // CSI intermediate byte, e.g. "$"
+	marker          string          // CSI private marker other than "?", e.g. ">"
+	osc             strings.Builder // OSC string being collected
+	oscOverflow     bool            // OSC string exceeded maxOSCLength
+	oscEscape       bool            // ESC seen inside OSC, waiting for "\\"
+	oscTerminator   string          // BEL or ST, echoed in replies
+	designate       string          // "(", ")", "*" or "+" while waiting for the charset code
+
+	// Device control string being collected (see dcs.go)
+	dcs dcsState
+
+	// OSC 52 clipboard requests, nil to ignore them
+	clipboard ClipboardHandler
 
-	// Character sets
-	g0Charset []rune
-	g1Charset []rune
-	charset   int // 0 for G0, 1 for G1
+	// DCS handlers by intermediate and final byte, e.g. "$q"
+	dcsHandlers map[string]DCSHandler
 
 	// Event mappings
 	basic  map[string]string
@@ -41,19 +59,19 @@ const (
 	StateOSC
 	StateCharset
 	StateSharp
+	StateDesignate
+	StateDCS       // DCS parameters, intermediates and final byte
+	StateDCSString // DCS data, up to ST
 )
 
 var textPattern = regexp.MustCompile(`[^\x00-\x1f\x7f\x9b]+`)
 
 func NewStream(screen Screen, strict bool) *Stream {
 	s := &Stream{
-		listener:  screen,
-		strict:    strict,
-		useUTF8:   true,
-		state:     StateGround,
-		g0Charset: LAT1_MAP,
-		g1Charset: VT100_MAP,
-		charset:   0,
+		listener: screen,
+		strict:   strict,
+		useUTF8:  true,
+		state:    StateGround,
 
 		// Direct translation of Python dicts
 		basic: map[string]string{
@@ -76,6 +94,10 @@ func NewStream(screen Screen, strict bool) *Stream {
 			HTS:   "set_tab_stop",
 			DECSC: "save_cursor",
 			DECRC: "restore_cursor",
+			SS2:   "single_shift_2",
+			SS3:   "single_shift_3",
+			LS2:   "locking_shift_2",
+			LS3:   "locking_shift_3",
 		},
 
 		sharp: map[string]string{
@@ -109,10 +131,21 @@ func NewStream(screen Screen, strict bool) *Stream {
 			SGR:     "select_graphic_rendition",
 			DSR:     "report_device_status",
 			DECSTBM: "set_margins",
+			DECSLRM: "set_left_right_margins",
 			HPA:     "cursor_to_column",
+			REP:     "repeat_character",
+			CBT:     "cursor_back_tab",
+			CHT:     "cursor_forward_tab",
+			SU:      "scroll_up",
+			SD:      "scroll_down",
 		},
 	}
 
+	s.dcsHandlers = map[string]DCSHandler{
+		"+q": termcapQuery{screen},
+		"$q": statusStringRequest{screen},
+	}
+
 	return s
 }
 
@@ -128,22 +161,14 @@ func (s *Stream) Feed(data string) {
 				s.state = StateEscape
 				i++
 			case string(CSI_C1):
+				s.resetCSI()
 				s.state = StateCSI
-				s.params = []int{}
-				s.currentParam = ""
-				s.private = false
 				i++
 			case string(OSC_C1):
-				s.state = StateOSC
-				s.oscParam = ""
+				s.startOSC()
 				i++
 			default:
 				if handler, ok := s.basic[char]; ok {
-					// Skip SI/SO in UTF-8 mode
-					if (char == SI || char == SO) && s.useUTF8 {
-						i++
-						continue
-					}
 					s.dispatch(handler)
 					i++
 				} else if char != NUL && char != DEL {
@@ -151,8 +176,10 @@ func (s *Stream) Feed(data string) {
 					start := i
 					for i < len(data) {
 						ch := data[i]
-						// Stop at any control character or escape
-						if ch < 0x20 || ch == 0x7f || ch == 0x1b || ch == 0x9b {
+						// Stop at any control character or escape. 0x9b is not
+						// checked here: as a raw byte it is a UTF-8 continuation
+						// byte (e.g. U+011B is C4 9B), never a C1 CSI.
+						if ch < 0x20 || ch == 0x7f || ch == 0x1b {
 							break
 						}
 						i++
@@ -160,6 +187,9 @@ func (s *Stream) Feed(data string) {
 					// Draw the batch of text
 					if i > start {
 						s.draw(data[start:i])
+					} else {
+						// Unhandled C0 control, drop it
+						i++
 					}
 				} else {
 					i++
@@ -170,26 +200,20 @@ func (s *Stream) Feed(data string) {
 			char := string(data[i])
 			switch char {
 			case "[":
+				s.resetCSI()
 				s.state = StateCSI
-				s.params = []int{}
-				s.currentParam = ""
-				s.private = false
 			case "]":
-				s.state = StateOSC
-				s.oscParam = ""
+				s.startOSC()
+			case "P":
+				s.startDCS()
 			case "#":
 				s.state = StateSharp
 			case "%":
 				s.state = StateCharset
-			case "(", ")":
-				if i+1 < len(data) {
-					code := string(data[i+1])
-					if !s.useUTF8 {
-						s.defineCharset(code, char)
-					}
-					i++ // Skip the next character
-				}
-				s.state = StateGround
+			case "(", ")", "*", "+":
+				// Designates G0-G3, the charset code may arrive in the next read
+				s.designate = char
+				s.state = StateDesignate
 			default:
 				if handler, ok := s.escape[char]; ok {
 					s.dispatch(handler)
@@ -206,6 +230,12 @@ func (s *Stream) Feed(data string) {
 			s.state = StateGround
 			i++
 
+		case StateDesignate:
+			// Designations apply in UTF-8 mode too, as on xterm
+			s.listener.DefineCharset(string(data[i]), s.designate)
+			s.state = StateGround
+			i++
+
 		case StateCharset:
 			// Handle charset selection (simplified)
 			char := string(data[i])
@@ -226,7 +256,7 @@ func (s *Stream) Feed(data string) {
 				if len(s.currentParam) > 6 {
 					s.currentParam = s.currentParam[:6]
 				}
-			case char == ";":
+			case char == ";" || char == ":":
 				val := 0
 				if s.currentParam != "" {
 					parsed, err := strconv.Atoi(s.currentParam)
@@ -238,22 +268,30 @@ func (s *Stream) Feed(data string) {
 					}
 				}
 				s.params = append(s.params, val)
+				s.subParams = append(s.subParams, s.colon)
 				s.currentParam = ""
+				s.colon = char == ":"
 				// Prevent too many parameters
 				if len(s.params) > 16 {
 					s.params = s.params[:16]
+					s.subParams = s.subParams[:16]
 				}
 			case char == "$":
-				// XTerm specific, skip next char
-				if i+1 < len(data) {
-					i++
-				}
-				s.state = StateGround
-			case strings.Contains(" >", char):
-				// Secondary DA, ignore
+				// XTerm specific, the sequence is ignored at its final char
+				s.intermediate = char
+			case char == " ":
+				// Intermediate for DECSCUSR (CSI Ps SP q)
+				s.intermediate = char
+			case char == "!":
+				// Intermediate for DECSTR (CSI ! p)
+				s.intermediate = char
+			case char == ">" || char == "<" || char == "=":
+				// Private markers, as in CSI > c or CSI > 1 u
+				s.marker = char
 			case char == CAN || char == SUB:
 				// Cancel sequence
 				s.draw(char)
+				s.resetCSI()
 				s.state = StateGround
 			case strings.Contains("\x07\x08\x09\x0a\x0b\x0c\x0d", char):
 				// Allowed in CSI
@@ -270,17 +308,38 @@ func (s *Stream) Feed(data string) {
 							val = 9999
 						}
 						s.params = append(s.params, val)
+						s.subParams = append(s.subParams, s.colon)
 					}
+				} else if s.colon {
+					// Empty trailing sub-parameter, as in "4:m"
+					s.params = append(s.params, 0)
+					s.subParams = append(s.subParams, true)
 				}
 
-				if handler, ok := s.csi[char]; ok {
+				if s.marker != "" {
+					s.dispatchMarkedCSI(char)
+				} else if s.private && char == KITTYKB {
+					s.listener.ReportKeyboardFlags()
+				} else if handler, ok := s.csi[char]; ok && s.intermediate == "" {
 					s.dispatchCSI(handler, s.params, s.private)
+				} else if s.intermediate == " " && char == "q" {
+					style := 0
+					if len(s.params) > 0 {
+						style = s.params[0]
+					}
+					s.listener.SetCursorStyle(style)
+				} else if s.intermediate == "!" && char == DECSTR {
+					s.listener.SoftReset()
+				} else if s.intermediate == "$" && char == DECRQM {
+					mode := 0
+					if len(s.params) > 0 {
+						mode = s.params[0]
+					}
+					s.listener.ReportMode(mode, s.private)
 				}
 
 				// Reset state
-				s.params = []int{}
-				s.currentParam = ""
-				s.private = false
+				s.resetCSI()
 				s.state = StateGround
 			}
 			i++
@@ -288,49 +347,166 @@ func (s *Stream) Feed(data string) {
 		case StateOSC:
 			char := string(data[i])
 
-			// Look for terminator
-			if char == BEL || char == string(ST_C0) || char == string(ST_C1) {
-				// Process OSC command
-				if len(s.oscParam) > 0 {
-					parts := strings.SplitN(s.oscParam, ";", 2)
-					if len(parts) == 2 {
-						code := parts[0]
-						param := parts[1]
-
-						switch code {
-						case "0", "1":
-							s.listener.SetIconName(param)
-						case "2":
-							s.listener.SetTitle(param)
-						}
-					}
+			if s.oscEscape {
+				s.oscEscape = false
+				if char != "\\" {
+					// Any other ESC aborts the string and starts a new sequence
+					s.state = StateEscape
+					continue
 				}
+				// ESC \ (ST_C0) ends the string
+				s.oscTerminator = ST_C0
+				s.dispatchOSC()
 				s.state = StateGround
-			} else if char == ESC {
-				// Check for ST_C0 (ESC \)
-				if i+1 < len(data) && string(data[i+1]) == "\\" {
-					// Same as above - process OSC
-					if len(s.oscParam) > 0 {
-						parts := strings.SplitN(s.oscParam, ";", 2)
-						if len(parts) == 2 {
-							code := parts[0]
-							param := parts[1]
-
-							switch code {
-							case "0", "1":
-								s.listener.SetIconName(param)
-							case "2":
-								s.listener.SetTitle(param)
-							}
-						}
-					}
-					i++ // Skip the backslash
-					s.state = StateGround
+			} else if char == BEL || char == string(ST_C1) {
+				s.oscTerminator = ST_C0
+				if char == BEL {
+					s.oscTerminator = BEL
 				}
+				s.dispatchOSC()
+				s.state = StateGround
+			} else if char == ESC {
+				s.oscEscape = true
 			} else {
-				s.oscParam += char
+				// Append raw bytes up to the next BEL or ESC, so UTF-8
+				// titles survive intact
+				end := i + 1
+				for end < len(data) && data[end] != BEL[0] && data[end] != ESC[0] {
+					end++
+				}
+				s.appendOSC(data[i:end])
+				i = end
+				continue
 			}
 			i++
+
+		case StateDCS, StateDCSString:
+			if !s.feedDCS(data[i]) {
+				// ESC that doesn't end the string starts a new sequence
+				continue
+			}
+			i++
+		}
+	}
+}
+
+// FeedBytes decodes raw terminal output and feeds it to the parser.
+// In UTF-8 mode an incomplete multi-byte sequence at the end of data is held
+// back and completed by the next call, so a read that splits a character
+// doesn't turn it into replacement glyphs. Invalid sequences decode to U+FFFD.
+// With UTF-8 disabled (ESC % @) every byte is taken as a Latin-1 code point.
+func (s *Stream) FeedBytes(data []byte) {
+	if len(s.utf8Pending) > 0 {
+		data = append(s.utf8Pending, data...)
+		s.utf8Pending = nil
+	}
+
+	// ESC % switches the decoding mode, so feed up to and including each
+	// '%' plus its selector byte before decoding the rest
+	for len(data) > 0 {
+		end := len(data)
+		if s.state == StateCharset {
+			// Selector byte of an ESC % split across reads
+			end = 1
+		} else if k := bytes.IndexByte(data, '%'); k >= 0 && k+2 < len(data) {
+			end = k + 2
+			if data[k+1] >= utf8.RuneSelf {
+				// Not a charset selector, don't cut a multi-byte sequence
+				end = k + 1
+			}
+		}
+		s.Feed(s.decodeBytes(data[:end], end == len(data)))
+		data = data[end:]
+	}
+}
+
+// decodeBytes converts a chunk to a string using the current charset mode.
+// When last is true an incomplete trailing UTF-8 sequence is kept in utf8Pending.
+func (s *Stream) decodeBytes(data []byte, last bool) string {
+	if !s.useUTF8 {
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+
+	// Fast path: whole chunk is complete, valid UTF-8
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for i := 0; i < len(data); {
+		if last && !utf8.FullRune(data[i:]) {
+			s.utf8Pending = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		sb.WriteRune(r) // RuneError for invalid input
+		i += size
+	}
+	return sb.String()
+}
+
+// Longest OSC string dispatched, enough for an OSC 52 copy of a large
+// selection. Longer strings are still consumed but dropped.
+const maxOSCLength = 1 << 20
+
+// startOSC enters an OSC string after ESC ]
+func (s *Stream) startOSC() {
+	s.osc.Reset()
+	s.oscOverflow = false
+	s.state = StateOSC
+}
+
+// appendOSC adds bytes to the OSC string, up to maxOSCLength
+func (s *Stream) appendOSC(data string) {
+	if s.oscOverflow {
+		return
+	}
+	if s.osc.Len()+len(data) > maxOSCLength {
+		s.oscOverflow = true
+		s.osc.Reset()
+		return
+	}
+	s.osc.WriteString(data)
+}
+
+// dispatchOSC handles a complete OSC string collected in osc
+func (s *Stream) dispatchOSC() {
+	defer s.osc.Reset()
+	if s.oscOverflow || s.osc.Len() == 0 {
+		return
+	}
+	parts := strings.SplitN(s.osc.String(), ";", 2)
+	if len(parts) == 1 {
+		// Color resets may come without parameters
+		s.handleOSCColor(parts[0], "")
+	}
+	if len(parts) == 2 {
+		code := parts[0]
+		param := parts[1]
+
+		switch code {
+		case "0", "1":
+			s.listener.SetIconName(param)
+		case "2":
+			s.listener.SetTitle(param)
+		case "8":
+			// OSC 8 ; params ; URI - empty URI closes the link
+			if link := strings.SplitN(param, ";", 2); len(link) == 2 {
+				s.listener.SetHyperlink(link[1])
+			}
+		case "4", "10", "11", "12", "104", "110", "111", "112":
+			s.handleOSCColor(code, param)
+		case "52":
+			s.handleOSC52(param)
+		case "133":
+			// OSC 133 ; mark [; params] - semantic prompt (FinalTerm)
+			fields := strings.Split(param, ";")
+			s.listener.PromptMark(fields[0], fields[1:])
 		}
 	}
 }
@@ -351,6 +527,14 @@ func (s *Stream) dispatch(handler string) {
 		s.listener.ShiftOut()
 	case "shift_in":
 		s.listener.ShiftIn()
+	case "locking_shift_2":
+		s.listener.LockingShift(2)
+	case "locking_shift_3":
+		s.listener.LockingShift(3)
+	case "single_shift_2":
+		s.listener.SingleShift(2)
+	case "single_shift_3":
+		s.listener.SingleShift(3)
 	case "reset":
 		s.listener.Reset()
 	case "index":
@@ -370,6 +554,18 @@ func (s *Stream) dispatch(handler string) {
 	}
 }
 
+// resetCSI clears what a CSI sequence collected, so that nothing left by an
+// aborted one leaks into the next
+func (s *Stream) resetCSI() {
+	s.params = []int{}
+	s.subParams = nil
+	s.colon = false
+	s.currentParam = ""
+	s.private = false
+	s.intermediate = ""
+	s.marker = ""
+}
+
 func (s *Stream) dispatchCSI(handler string, params []int, private bool) {
 	// DEBUG: Log all cursor-related CSI commands
 	if strings.Contains(handler, "cursor") {
@@ -482,7 +678,8 @@ func (s *Stream) dispatchCSI(handler string, params []int, private bool) {
 		s.listener.EraseInLine(how, private)
 
 	case "insert_lines", "delete_lines", "insert_characters",
-		"delete_characters", "erase_characters":
+		"delete_characters", "erase_characters", "repeat_character",
+		"cursor_forward_tab", "cursor_back_tab", "scroll_up", "scroll_down":
 		count := 1
 		if len(params) > 0 && params[0] > 0 {
 			count = params[0]
@@ -498,6 +695,16 @@ func (s *Stream) dispatchCSI(handler string, params []int, private bool) {
 			s.listener.DeleteCharacters(count)
 		case "erase_characters":
 			s.listener.EraseCharacters(count)
+		case "repeat_character":
+			s.listener.RepeatCharacter(count)
+		case "cursor_forward_tab":
+			s.listener.CursorForwardTab(count)
+		case "cursor_back_tab":
+			s.listener.CursorBackTab(count)
+		case "scroll_up":
+			s.listener.ScrollContentUp(count)
+		case "scroll_down":
+			s.listener.ScrollContentDown(count)
 		}
 
 	case "clear_tab_stop":
@@ -515,7 +722,7 @@ func (s *Stream) dispatchCSI(handler string, params []int, private bool) {
 		}
 
 	case "select_graphic_rendition":
-		s.listener.SelectGraphicRendition(params)
+		s.selectGraphicRendition(params)
 
 	case "report_device_attributes":
 		mode := 0
@@ -541,11 +748,71 @@ func (s *Stream) dispatchCSI(handler string, params []int, private bool) {
 		}
 		s.listener.SetMargins(top, bottom)
 
+	case "set_left_right_margins":
+		// CSI ? s is XTSAVE, not handled
+		if private {
+			break
+		}
+		var left, right int
+		if len(params) > 0 {
+			left = params[0]
+		}
+		if len(params) > 1 {
+			right = params[1]
+		}
+		s.listener.SetLeftRightMargins(left, right)
+
 	default:
 		s.listener.Debug("Unknown CSI handler:", handler, params, private)
 	}
 }
 
+// selectGraphicRendition passes SGR on to the screen. Attributes with colon
+// sub-parameters are sent on their own: "4:n" sets the underline style, and
+// the ITU forms of 38/48/58 ("2:[cs]:r:g:b", "5:n") become "2;r;g;b" and "5;n".
+func (s *Stream) selectGraphicRendition(params []int) {
+	if len(s.subParams) != len(params) || !slices.Contains(s.subParams, true) {
+		s.listener.SelectGraphicRendition(params)
+		return
+	}
+
+	var plain []int
+	flush := func() {
+		if len(plain) > 0 {
+			s.listener.SelectGraphicRendition(plain)
+			plain = nil
+		}
+	}
+	for i := 0; i < len(params); {
+		end := i + 1
+		for end < len(params) && s.subParams[end] {
+			end++
+		}
+		group := params[i:end]
+		i = end
+
+		switch {
+		case len(group) == 1:
+			plain = append(plain, group[0])
+		case group[0] == 4:
+			flush()
+			s.listener.SetUnderlineStyle(group[1])
+		case group[0] == 38 || group[0] == 48 || group[0] == 58:
+			args := group[1:]
+			if args[0] == 2 && len(args) > 4 {
+				// Drop the color space ID
+				args = append([]int{2}, args[len(args)-3:]...)
+			}
+			flush()
+			s.listener.SelectGraphicRendition(append([]int{group[0]}, args...))
+		default:
+			// Other attributes have no sub-parameters we use
+			plain = append(plain, group[0])
+		}
+	}
+	flush()
+}
+
 func (s *Stream) draw(text string) {
 	// DEBUG: Log text drawing (but limit to avoid spam)
 	if len(text) > 10 {
@@ -554,25 +821,10 @@ func (s *Stream) draw(text string) {
 		// log.Printf("STREAMS DEBUG: draw(text=%q)", text)
 	}
 
-	// Apply character set translation
-	if s.charset == 1 {
-		text = TranslateCharset(text, s.g1Charset)
-	} else {
-		text = TranslateCharset(text, s.g0Charset)
-	}
+	// Character sets are translated by the screen
 	s.listener.Draw(text)
 }
 
-func (s *Stream) defineCharset(code, mode string) {
-	if charset, ok := MAPS[code]; ok {
-		if mode == "(" {
-			s.g0Charset = charset
-		} else if mode == ")" {
-			s.g1Charset = charset
-		}
-	}
-}
-
 func (s *Stream) selectOtherCharset(code string) {
 	switch code {
 	case "@":
@@ -0,0 +1,28 @@
+package gopyte
+
+import "time"
+
+// SyncUpdateTimeout is how long a synchronized update (mode 2026) may hold
+// back rendering. Applications that die mid-frame, or never send the end
+// marker, must not freeze the display.
+const SyncUpdateTimeout = 150 * time.Millisecond
+
+// beginSyncUpdate handles CSI ? 2026 h. Setting the mode again while it is
+// already on does not extend the timeout.
+func (s *NativeScreen) beginSyncUpdate() {
+	if !s.syncUpdate {
+		s.syncUpdate = true
+		s.syncUpdateStart = time.Now()
+	}
+}
+
+// RenderDeferred reports whether rendering should wait because the host is
+// in the middle of a synchronized update. When it should, until is when the
+// update times out and the screen must be drawn anyway.
+func (s *NativeScreen) RenderDeferred(now time.Time) (until time.Time, deferred bool) {
+	if !s.syncUpdate {
+		return time.Time{}, false
+	}
+	until = s.syncUpdateStart.Add(SyncUpdateTimeout)
+	return until, now.Before(until)
+}
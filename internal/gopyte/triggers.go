@@ -0,0 +1,251 @@
+package gopyte
+
+import "regexp"
+
+// Triggers match output lines with a regex as they complete on the main
+// screen, that is when a linefeed leaves them. A soft-wrapped line is matched
+// as a whole. Highlighting is applied to the screen cells here; every other
+// action is passed to the TriggerHandler.
+//
+// Instant triggers are also matched against the unfinished cursor line after
+// each piece of text is drawn, for prompts such as "[confirm]" that wait for
+// an answer without ending the line. They fire at most once per line.
+
+// TriggerAction is what a trigger does when its pattern matches
+type TriggerAction string
+
+// Trigger actions
+const (
+	TriggerHighlight TriggerAction = "highlight" // Paint the matched text with Style
+	TriggerNotify    TriggerAction = "notify"    // Desktop notification with Text
+	TriggerBell      TriggerAction = "bell"      // Ring the bell
+	TriggerSend      TriggerAction = "send"      // Send Text to the host
+	TriggerStartLog  TriggerAction = "start_log" // Start logging completed lines
+	TriggerStopLog   TriggerAction = "stop_log"  // Stop logging
+)
+
+// Trigger is one compiled rule
+type Trigger struct {
+	Pattern *regexp.Regexp
+	Action  TriggerAction
+	Style   Attributes // Highlight colors and flags; empty colors keep the cell's
+	Text    string     // Message, text to send or log name; $1 etc. expand groups
+	Instant bool       // Also match the unfinished cursor line
+}
+
+// TriggerMatch is passed to the handler when a trigger fires
+type TriggerMatch struct {
+	Trigger *Trigger
+	Line    string // The whole line
+	Text    string // Trigger.Text with match groups expanded
+}
+
+// TriggerHandler receives trigger actions. Both methods are called while
+// output is being fed, so they must not block or feed the stream.
+type TriggerHandler interface {
+	// TriggerFired is called for every match of a non-highlight trigger
+	TriggerFired(match TriggerMatch)
+	// LineCompleted is called with every completed main screen line
+	LineCompleted(line string)
+}
+
+// triggerState is the trigger engine state kept by WideCharScreen
+type triggerState struct {
+	triggers       []*Trigger
+	triggerHandler TriggerHandler
+
+	instantLine  int    // Absolute line instantFired belongs to
+	instantFired []bool // Instant triggers already fired on instantLine
+}
+
+// lineCell locates a character of a logical line on the screen
+type lineCell struct {
+	row, col int
+}
+
+// SetTriggers replaces the triggers and handler; nil removes them
+func (w *WideCharScreen) SetTriggers(triggers []*Trigger, handler TriggerHandler) {
+	w.triggerState = triggerState{
+		triggers:       triggers,
+		triggerHandler: handler,
+		instantLine:    -1,
+		instantFired:   make([]bool, len(triggers)),
+	}
+}
+
+// Linefeed completes the cursor line before moving down
+func (w *WideCharScreen) Linefeed() {
+	w.completeLine()
+	w.HistoryScreen.Linefeed()
+}
+
+// Index completes the cursor line before moving down
+func (w *WideCharScreen) Index() {
+	w.completeLine()
+	w.HistoryScreen.Index()
+}
+
+// triggersActive reports whether lines need to be looked at
+func (w *WideCharScreen) triggersActive() bool {
+	return !w.usingAlternate && (len(w.triggers) > 0 || w.triggerHandler != nil)
+}
+
+// completeLine runs the triggers on the line the cursor is leaving
+func (w *WideCharScreen) completeLine() {
+	if !w.triggersActive() || w.cursor.Y < 0 || w.cursor.Y >= len(w.buffer) {
+		return
+	}
+	// The line continues on the next row, it is not complete yet
+	if w.isWrapped(w.cursor.Y) {
+		return
+	}
+
+	first, text, cells := w.logicalLine(w.cursor.Y)
+	line := w.linesPushed + first
+	if line != w.instantLine {
+		w.resetInstant(line)
+	}
+
+	for i, trigger := range w.triggers {
+		if trigger.Instant && w.instantFired[i] {
+			continue
+		}
+		w.runTrigger(trigger, text, cells)
+	}
+	w.resetInstant(-1)
+
+	if w.triggerHandler != nil {
+		w.triggerHandler.LineCompleted(text)
+	}
+}
+
+// checkInstantTriggers runs the instant triggers on the unfinished cursor line
+func (w *WideCharScreen) checkInstantTriggers() {
+	if !w.triggersActive() || w.cursor.Y < 0 || w.cursor.Y >= len(w.buffer) {
+		return
+	}
+
+	var text string
+	var cells []lineCell
+	scanned := false
+	for i, trigger := range w.triggers {
+		if !trigger.Instant {
+			continue
+		}
+		if !scanned {
+			var first int
+			first, text, cells = w.logicalLine(w.cursor.Y)
+			if line := w.linesPushed + first; line != w.instantLine {
+				w.resetInstant(line)
+			}
+			scanned = true
+		}
+		if !w.instantFired[i] && w.runTrigger(trigger, text, cells) {
+			w.instantFired[i] = true
+		}
+	}
+}
+
+// resetInstant starts tracking instant triggers on another line
+func (w *WideCharScreen) resetInstant(line int) {
+	w.instantLine = line
+	for i := range w.instantFired {
+		w.instantFired[i] = false
+	}
+}
+
+// runTrigger applies one trigger to a line, reporting whether it matched
+func (w *WideCharScreen) runTrigger(trigger *Trigger, text string, cells []lineCell) bool {
+	matches := trigger.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return false
+	}
+
+	if trigger.Action == TriggerHighlight {
+		for _, m := range matches {
+			w.highlight(trigger.Style, cells, runeIndex(text, m[0]), runeIndex(text, m[1]))
+		}
+		w.invalidateCache()
+		return true
+	}
+
+	if w.triggerHandler != nil {
+		expanded := trigger.Pattern.ExpandString(nil, trigger.Text, text, matches[0])
+		w.triggerHandler.TriggerFired(TriggerMatch{Trigger: trigger, Line: text, Text: string(expanded)})
+	}
+	return true
+}
+
+// logicalLine returns the first row and text of the logical line ending on
+// row y, with the screen cell of each rune. Rows wrapped in from History
+// are left out. Trailing blanks are trimmed.
+func (w *WideCharScreen) logicalLine(y int) (int, string, []lineCell) {
+	first := y
+	for first > 0 && w.isWrapped(first-1) {
+		first--
+	}
+
+	var runes []rune
+	var cells []lineCell
+	for row := first; row <= y; row++ {
+		for col, ch := range w.buffer[row] {
+			// Skip wide character continuations and wrap padding
+			if ch == 0 || (row < len(w.cellWidths) && col < len(w.cellWidths[row]) && w.cellWidths[row][col] == 0) {
+				continue
+			}
+			runes = append(runes, ch)
+			cells = append(cells, lineCell{row, col})
+		}
+	}
+
+	n := len(runes)
+	for n > 0 && runes[n-1] == ' ' {
+		n--
+	}
+	return first, string(runes[:n]), cells[:n]
+}
+
+// runeIndex converts a byte offset in text to a rune index
+func runeIndex(text string, offset int) int {
+	n := 0
+	for i := range text {
+		if i >= offset {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// highlight applies a style to the cells of runes [start, end)
+func (w *WideCharScreen) highlight(style Attributes, cells []lineCell, start, end int) {
+	for _, c := range cells[start:min(end, len(cells))] {
+		if c.row >= len(w.attrs) || c.col >= len(w.attrs[c.row]) {
+			continue
+		}
+		w.attrs[c.row][c.col] = mergeStyle(w.attrs[c.row][c.col], style)
+		// The continuation cell of a wide character takes the same style
+		if c.row < len(w.cellWidths) && c.col < len(w.cellWidths[c.row]) && w.cellWidths[c.row][c.col] == 2 &&
+			c.col+1 < len(w.attrs[c.row]) {
+			w.attrs[c.row][c.col+1] = w.attrs[c.row][c.col]
+		}
+	}
+}
+
+// mergeStyle sets the colors and flags of style on a cell's attributes
+func mergeStyle(a, style Attributes) Attributes {
+	if style.Fg != "" {
+		a.Fg = style.Fg
+	}
+	if style.Bg != "" {
+		a.Bg = style.Bg
+	}
+	a.Bold = a.Bold || style.Bold
+	a.Italics = a.Italics || style.Italics
+	if style.Underscore && !a.Underscore {
+		a.Underscore = true
+		a.UnderlineStyle = UnderlineSingle
+	}
+	a.Reverse = a.Reverse || style.Reverse
+	return a
+}
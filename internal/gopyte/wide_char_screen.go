@@ -24,6 +24,7 @@ type WideCharScreen struct {
 	altCursor      Cursor
 	mainBuffer     [][]rune
 	mainAttrs      [][]Attributes
+	mainWrapped    []bool
 	mainCursor     Cursor
 	HistoryPos     int
 
@@ -36,6 +37,9 @@ type WideCharScreen struct {
 	attributeCache     [][]Attributes // Cache for attributes
 	cacheValid         bool           // Is the cache still valid?
 	totalContentLines  int            // Total lines available (History + current)
+
+	// Output triggers (see triggers.go)
+	triggerState
 }
 
 // NewWideCharScreen creates a screen with wide character support and History
@@ -103,6 +107,8 @@ func (w *WideCharScreen) IsUsingAlternate() bool {
 
 // Override Draw to handle wide characters and emojis
 func (w *WideCharScreen) Draw(text string) {
+	text = w.beginDraw(text)
+
 	// Invalidate cache when new content arrives
 	w.invalidateCache()
 
@@ -115,6 +121,7 @@ func (w *WideCharScreen) Draw(text string) {
 	for _, ch := range text {
 		w.drawChar(ch)
 	}
+	w.checkInstantTriggers()
 }
 
 // drawChar handles a single character with width calculation
@@ -128,21 +135,33 @@ func (w *WideCharScreen) drawChar(ch rune) {
 		return
 	}
 
-	// Check if the character fits at current position
-	if w.cursor.X+charWidth > w.columns {
+	// Check if the character fits at current position, up to the right
+	// margin when inside the margins
+	start, end := w.wrapColumns()
+	if w.cursor.X+charWidth > end {
 		if w.autoWrap {
-			// Wide character doesn't fit, wrap to next line
-			w.cursor.X = 0
-			w.cursor.Y++
-			if w.cursor.Y >= w.lines {
-				if w.usingAlternate {
-					w.scrollUpNoHistory()
-				} else {
-					// Use HistoryScreen's scrolling which captures width info
-					w.addToHistory(0)
-					w.scrollUpInternal()
+			// Wide character doesn't fit: pad the rest of the row so reflow
+			// doesn't take it for spaces, and wrap to next line
+			for x := w.cursor.X; x < end; x++ {
+				w.clearCellAt(w.cursor.Y, x)
+				if w.cursor.Y < len(w.buffer) && x < len(w.buffer[w.cursor.Y]) {
+					w.buffer[w.cursor.Y][x] = 0
+				}
+			}
+			w.setWrapped(w.cursor.Y, true)
+			w.cursor.X = start
+			if !w.indexInMargins() {
+				w.cursor.Y++
+				if w.cursor.Y >= w.lines {
+					if w.usingAlternate {
+						w.scrollUpNoHistory()
+					} else {
+						// Use HistoryScreen's scrolling which captures width info
+						w.addToHistory(0)
+						w.scrollUpInternal()
+					}
+					w.cursor.Y = w.lines - 1
 				}
-				w.cursor.Y = w.lines - 1
 			}
 		} else {
 			// Can't place character at edge without wrapping
@@ -162,6 +181,10 @@ func (w *WideCharScreen) drawChar(ch rune) {
 		return
 	}
 
+	if w.insertMode {
+		w.insertCells(w.cursor.Y, w.cursor.X, charWidth)
+	}
+
 	// Clear any wide character we're overwriting
 	w.clearCellAt(w.cursor.Y, w.cursor.X)
 
@@ -229,7 +252,7 @@ func (w *WideCharScreen) GetDisplay() []string {
 	w.totalContentLines = totalLines
 
 	fmt.Printf("WideCharScreen.GetDisplay: BUFFER ANALYSIS:\n")
-	fmt.Printf("  - actualBufferLines (linked list): %d\n", actualBufferLines)
+	fmt.Printf("  - actualBufferLines (scrollback): %d\n", actualBufferLines)
 	fmt.Printf("  - HistorySize (reported): %d\n", HistorySize)
 	fmt.Printf("  - currentScreenLines: %d\n", currentScreenLines)
 	fmt.Printf("  - virtualTotalLines: %d\n", totalLines)
@@ -537,21 +560,13 @@ func (w *WideCharScreen) getHistoryLinesInRange(start, end int) []string {
 		start, end-1, HistorySize)
 
 	result := make([]string, 0, end-start)
-	index := 0
-
-	// Iterate through History list but only process lines in our range
-	for elem := w.HistoryScreen.History.Front(); elem != nil && index < end; elem = elem.Next() {
-		if index >= start {
-			if histLine, ok := elem.Value.(HistoryLine); ok {
-				line := w.renderLineWithWidths(histLine.Chars, histLine.CellWidths)
-				line = strings.TrimRight(line, " ")
-				result = append(result, line)
-			} else {
-				fmt.Printf("getHistoryLinesInRange: Invalid History line at index %d\n", index)
-				result = append(result, "")
-			}
-		}
-		index++
+
+	// Only decode the lines in our range
+	for index := max(start, 0); index < end && index < HistorySize; index++ {
+		histLine := w.HistoryScreen.History.Line(index)
+		line := w.renderLineWithWidths(histLine.Chars, histLine.CellWidths)
+		line = strings.TrimRight(line, " ")
+		result = append(result, line)
 	}
 
 	fmt.Printf("getHistoryLinesInRange: Extracted %d lines from range [%d-%d]\n",
@@ -591,17 +606,12 @@ func (w *WideCharScreen) getHistoryAttributesForLine(HistoryIndex int) []Attribu
 		return []Attributes{}
 	}
 
-	index := 0
-	for elem := w.HistoryScreen.History.Front(); elem != nil; elem = elem.Next() {
-		if index == HistoryIndex {
-			if histLine, ok := elem.Value.(HistoryLine); ok {
-				return w.extractAttributesWithWidths(histLine.Attrs, histLine.CellWidths)
-			}
-		}
-		index++
+	if HistoryIndex < 0 || HistoryIndex >= w.HistoryScreen.History.Len() {
+		return []Attributes{}
 	}
 
-	return []Attributes{}
+	histLine := w.HistoryScreen.History.Line(HistoryIndex)
+	return w.extractAttributesWithWidths(histLine.Attrs, histLine.CellWidths)
 }
 
 // scrollUpNoHistory scrolls without saving to History (for alternate screen)
@@ -610,6 +620,7 @@ func (w *WideCharScreen) scrollUpNoHistory() {
 	copy(w.buffer[0:], w.buffer[1:])
 	copy(w.attrs[0:], w.attrs[1:])
 	copy(w.cellWidths[0:], w.cellWidths[1:])
+	w.scrollWrapped(0, w.lines-1)
 
 	// Clear the last line
 	lastLine := w.lines - 1
@@ -804,13 +815,16 @@ func (w *WideCharScreen) switchToAlternate() {
 	}
 	w.mainCursor = w.cursor
 	w.mainCellWidths = w.cellWidths
+	w.mainWrapped = w.wrapped
 
 	// Switch to alternate buffers
 	w.buffer = w.altBuffer
 	w.attrs = w.altAttrs
 	w.cursor = w.altCursor
 	w.cellWidths = w.altCellWidths
+	w.wrapped = make([]bool, w.lines)
 	w.usingAlternate = true
+	w.swapKeyboardFlags()
 
 	// Update HistoryScreen's cellWidths reference
 	w.HistoryScreen.cellWidths = w.cellWidths
@@ -834,8 +848,10 @@ func (w *WideCharScreen) switchToMain() {
 		w.attrs = w.mainAttrs
 		w.cursor = w.mainCursor
 		w.cellWidths = w.mainCellWidths
+		w.wrapped = w.mainWrapped
 	}
 	w.usingAlternate = false
+	w.swapKeyboardFlags()
 
 	// Restore HistoryScreen's cellWidths reference
 	if w.cellWidths != nil {
@@ -865,6 +881,7 @@ func (w *WideCharScreen) clearScreen() {
 			}
 		}
 	}
+	w.clearWrapped(0, w.lines-1)
 	w.cursor.X = 0
 	w.cursor.Y = 0
 }
@@ -910,6 +927,16 @@ func (w *WideCharScreen) ResetMode(modes []int, private bool) {
 	}
 }
 
+// ReportMode adds the alternate screen modes to NativeScreen's DECRQM
+// replies
+func (w *WideCharScreen) ReportMode(mode int, private bool) {
+	if private && (mode == 47 || mode == 1047 || mode == 1049) {
+		w.writeModeReport(mode, private, w.usingAlternate, true)
+		return
+	}
+	w.HistoryScreen.ReportMode(mode, private)
+}
+
 // Utility methods
 func (w *WideCharScreen) GetCursor() (int, int) {
 	return w.cursor.X, w.cursor.Y
@@ -995,14 +1022,28 @@ func (w *WideCharScreen) DisableVirtualScrolling() {
 // History management
 func (w *WideCharScreen) SetMaxHistoryLines(maxLines int) {
 	if w.HistoryScreen != nil {
-		w.HistoryScreen.maxHistory = maxLines
+		w.HistoryScreen.SetMaxHistoryLines(maxLines)
+		w.invalidateCache()
+	}
+}
 
-		// Trim if necessary
-		if w.HistoryScreen.History != nil {
-			for w.HistoryScreen.History.Len() > maxLines && w.HistoryScreen.History.Len() > 0 {
-				w.HistoryScreen.History.Remove(w.HistoryScreen.History.Front())
-			}
-		}
+// SetScrollbackSpill turns spilling of old History pages to a temp file on or off
+func (w *WideCharScreen) SetScrollbackSpill(enabled bool) error {
+	if w.HistoryScreen == nil {
+		return nil
+	}
+	defer w.invalidateCache()
+	if enabled {
+		return w.HistoryScreen.History.EnableSpill("")
+	}
+	w.HistoryScreen.History.DisableSpill()
+	return nil
+}
+
+// Close releases the History spill file, if any
+func (w *WideCharScreen) Close() {
+	if w.HistoryScreen != nil {
+		w.HistoryScreen.History.Close()
 	}
 }
 
@@ -1021,8 +1062,16 @@ func (w *WideCharScreen) Resize(newCols, newLines int) {
 		w.ScrollToBottom()
 	}
 
-	// Let HistoryScreen resize first (this should resize buffer and attrs)
-	w.HistoryScreen.Resize(newCols, newLines)
+	if w.usingAlternate {
+		// Full screen programs redraw themselves on resize: only the saved
+		// main screen is reflowed, the alternate buffer is just resized
+		w.reflowMainScreen(newCols, newLines)
+		w.HistoryScreen.resizeScreen(newCols, newLines, false)
+	} else {
+		// Reflows the main screen and History
+		w.HistoryScreen.Resize(newCols, newLines)
+	}
+	w.cellWidths = w.HistoryScreen.cellWidths
 
 	// Update geometry
 	w.columns = newCols
@@ -1034,12 +1083,14 @@ func (w *WideCharScreen) Resize(newCols, newLines int) {
 
 	// Rebuild width grids
 	w.cellWidths = w.rebuildWidthGrid(w.cellWidths, newCols, newLines)
-	w.altCellWidths = w.rebuildWidthGrid(w.altCellWidths, newCols, newLines)
 
 	// Update references
-	if !w.usingAlternate {
+	w.HistoryScreen.cellWidths = w.cellWidths
+	if w.usingAlternate {
+		w.altCellWidths = w.cellWidths
+	} else {
 		w.mainCellWidths = w.cellWidths
-		w.HistoryScreen.cellWidths = w.cellWidths
+		w.altCellWidths = w.rebuildWidthGrid(w.altCellWidths, newCols, newLines)
 	}
 
 	// Resize alternate buffers
@@ -1049,6 +1100,28 @@ func (w *WideCharScreen) Resize(newCols, newLines int) {
 		len(w.buffer), len(w.cellWidths))
 }
 
+// reflowMainScreen reflows the main screen saved while the alternate screen
+// is active, together with History
+func (w *WideCharScreen) reflowMainScreen(newCols, newLines int) {
+	if w.mainBuffer == nil {
+		return
+	}
+
+	main := w.HistoryScreen.reflow(reflowScreen{
+		buffer:  w.mainBuffer,
+		attrs:   w.mainAttrs,
+		widths:  w.mainCellWidths,
+		wrapped: w.mainWrapped,
+		cursor:  w.mainCursor,
+	}, newCols, newLines)
+
+	w.mainBuffer = main.buffer
+	w.mainAttrs = main.attrs
+	w.mainCellWidths = main.widths
+	w.mainWrapped = main.wrapped
+	w.mainCursor = main.cursor
+}
+
 // ensureBufferSizes makes sure all buffers match the expected dimensions
 func (w *WideCharScreen) ensureBufferSizes(cols, lines int) {
 	// Check and fix w.buffer (inherited from HistoryScreen)